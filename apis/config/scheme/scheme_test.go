@@ -362,9 +362,11 @@ profiles:
 - pluginConfig:
   - args:
       apiVersion: kubescheduler.config.k8s.io/v1
+      enableCapacityReservation: false
       kind: CoschedulingArgs
       permitWaitingTimeSeconds: 10
       podGroupBackoffSeconds: 0
+      podGroupMaxBackoffSeconds: 0
     name: Coscheduling
   - args:
       apiVersion: kubescheduler.config.k8s.io/v1
@@ -387,6 +389,13 @@ profiles:
         insecureSkipVerify: false
         token: ""
         type: Prometheus
+      metricsPushAddress: ""
+      metricsPushCertFile: ""
+      metricsPushClientCAFile: ""
+      metricsPushKeyFile: ""
+      metricsPushTokenFile: ""
+      metricsStalenessSeconds: 0
+      metricsUpdateIntervalSeconds: 0
       targetUtilization: 60
       watcherAddress: http://deadbeef:2020
     name: TargetLoadPacking
@@ -398,6 +407,13 @@ profiles:
         insecureSkipVerify: false
         token: ""
         type: Prometheus
+      metricsPushAddress: ""
+      metricsPushCertFile: ""
+      metricsPushClientCAFile: ""
+      metricsPushKeyFile: ""
+      metricsPushTokenFile: ""
+      metricsStalenessSeconds: 0
+      metricsUpdateIntervalSeconds: 0
       safeVarianceMargin: 1
       safeVarianceSensitivity: 1
       watcherAddress: http://deadbeef:2020
@@ -410,6 +426,13 @@ profiles:
         insecureSkipVerify: false
         token: ""
         type: Prometheus
+      metricsPushAddress: ""
+      metricsPushCertFile: ""
+      metricsPushClientCAFile: ""
+      metricsPushKeyFile: ""
+      metricsPushTokenFile: ""
+      metricsStalenessSeconds: 0
+      metricsUpdateIntervalSeconds: 0
       riskLimitWeights:
         cpu: 0.5
         memory: 0.5
@@ -32,6 +32,12 @@ type CoschedulingArgs struct {
 	PermitWaitingTimeSeconds int64
 	// PodGroupBackoffSeconds is the backoff time in seconds before a pod group can be scheduled again.
 	PodGroupBackoffSeconds int64
+	// PodGroupMaxBackoffSeconds is the upper bound in seconds of the per pod group backoff,
+	// which doubles on every consecutive scheduling failure of the pod group.
+	PodGroupMaxBackoffSeconds int64
+	// EnableCapacityReservation makes the plugin hold node capacity for the remaining members of a pod group
+	// once one of its members passes PreFilter, until the pod group's schedule timeout.
+	EnableCapacityReservation bool
 }
 
 // ModeType is a "string" type.
@@ -68,6 +74,8 @@ const (
 	KubernetesMetricsServer MetricProviderType = "KubernetesMetricsServer"
 	Prometheus              MetricProviderType = "Prometheus"
 	SignalFx                MetricProviderType = "SignalFx"
+	// Kubelet scrapes the resource metrics of the kubelets, without the load watcher
+	Kubelet MetricProviderType = "Kubelet"
 )
 
 // Denote the spec of the metric provider
@@ -82,12 +90,85 @@ type MetricProviderSpec struct {
 	InsecureSkipVerify bool
 }
 
+// FallbackMode is a "string" type.
+type FallbackMode string
+
+const (
+	// MinScoreFallback scores the nodes without fresh load metrics with the minimum score
+	MinScoreFallback FallbackMode = "MinScore"
+	// AllocationFallback derives the utilization of the nodes without fresh load metrics from the requests of their pods
+	AllocationFallback FallbackMode = "Allocation"
+)
+
+// MetricsIngestionMode is a "string" type.
+type MetricsIngestionMode string
+
+const (
+	// PollIngestion polls the load metrics from the load watcher periodically
+	PollIngestion MetricsIngestionMode = "Poll"
+	// PushIngestion accepts the load metrics pushed to an endpoint of the scheduler, in the load watcher format
+	PushIngestion MetricsIngestionMode = "Push"
+)
+
+// ForecastModel is a "string" type.
+type ForecastModel string
+
+const (
+	// EWMAForecast forecasts the utilization of a node as the exponentially weighted moving average of its history
+	EWMAForecast ForecastModel = "EWMA"
+	// HoltWintersForecast forecasts the utilization of a node with the additive trend and seasonality of its history
+	HoltWintersForecast ForecastModel = "HoltWinters"
+)
+
+// RiskAggregationType is a "string" type.
+type RiskAggregationType string
+
+const (
+	// MaxRisk aggregates the risks of the resources of a node into the risk of its riskiest resource
+	MaxRisk RiskAggregationType = "Max"
+	// WeightedMeanRisk aggregates the risks of the resources of a node into their mean, weighted by resource
+	WeightedMeanRisk RiskAggregationType = "WeightedMean"
+)
+
+// ForecastSpec holds the parameters of the forecasting of the utilization of the nodes
+type ForecastSpec struct {
+	// Model fitted to the history of the utilization of each node
+	Model ForecastModel
+	// Smoothing factor of the level, in (0, 1]
+	Alpha float64
+	// Smoothing factor of the trend, in [0, 1], with the HoltWinters model
+	Beta float64
+	// Smoothing factor of the seasonality, in [0, 1], with the HoltWinters model
+	Gamma float64
+	// Length of the season, in seconds, with the HoltWinters model. Zero disables the seasonality.
+	SeasonPeriodSeconds int64
+	// Horizon, in seconds, over which the peak utilization is forecast: the expected time for the pod to start
+	HorizonSeconds int64
+}
+
 // TrimaranSpec holds common parameters for trimaran plugins
 type TrimaranSpec struct {
 	// Metric Provider to use when using load watcher as a library
 	MetricProvider MetricProviderSpec
 	// Address of load watcher service
 	WatcherAddress string
+	// Load metrics whose window ended longer ago, in seconds, are stale. Zero disables the detection.
+	MetricsStalenessSeconds int64
+	// How the nodes whose load metrics are missing or stale are scored
+	FallbackMode FallbackMode
+	// Interval, in seconds, of the updates of the load metrics
+	MetricsUpdateIntervalSeconds int64
+	// How the load metrics are ingested: polled from the load watcher, or pushed to the scheduler
+	MetricsIngestion MetricsIngestionMode
+	// Address the endpoint accepting the pushed load metrics listens on, with the Push ingestion
+	MetricsPushAddress string
+	// Certificate and key files the endpoint accepting the pushed load metrics serves TLS with
+	MetricsPushCertFile string
+	MetricsPushKeyFile  string
+	// File holding the bearer token the pushing agents must present
+	MetricsPushTokenFile string
+	// File holding the CA certificates the client certificates of the pushing agents must be signed by
+	MetricsPushClientCAFile string
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -104,6 +185,26 @@ type TargetLoadPackingArgs struct {
 	DefaultRequestsMultiplier string
 	// Node target CPU Utilization for bin packing
 	TargetUtilization int64
+	// Node target utilization per resource for bin packing, with the weight of each resource in the score.
+	// Only cpu is packed, around TargetUtilization, if no resource is listed.
+	ResourceTargets []ResourceTarget
+}
+
+const (
+	// ResourceNetwork is the network bandwidth of a node, as reported by the load watcher
+	ResourceNetwork v1.ResourceName = "network"
+	// ResourceDisk is the disk usage of a node, as reported by the load watcher
+	ResourceDisk v1.ResourceName = "disk"
+)
+
+// ResourceTarget is the node target utilization of a resource for TargetLoadPacking.
+type ResourceTarget struct {
+	// Name of the resource: cpu, memory, network or disk
+	Name v1.ResourceName
+	// Node target utilization percent of the resource
+	TargetUtilization int64
+	// Weight of the resource in the score
+	Weight int64
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -118,6 +219,8 @@ type LoadVariationRiskBalancingArgs struct {
 	SafeVarianceMargin float64
 	// Root power of standard deviation in risk value
 	SafeVarianceSensitivity float64
+	// Forecasting of the utilization of the nodes, scored instead of their current utilization if set
+	Forecast *ForecastSpec
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -132,6 +235,33 @@ type LowRiskOverCommitmentArgs struct {
 	SmoothingWindowSize int64
 	// Resources fractional weight of risk due to limits specification [0,1]
 	RiskLimitWeights map[v1.ResourceName]float64
+	// Forecasting of the utilization of the nodes, scored instead of their current utilization if set
+	Forecast *ForecastSpec
+	// How the risks of the resources of a node are aggregated
+	RiskAggregation RiskAggregationType
+	// Weights of the resources in the WeightedMean aggregation, one for the resources not listed
+	RiskAggregationWeights map[v1.ResourceName]float64
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// LoadAwareFilterArgs holds arguments used to configure LoadAwareFilter plugin.
+type LoadAwareFilterArgs struct {
+	metav1.TypeMeta
+
+	// Common parameters for trimaran plugins
+	TrimaranSpec
+	// Default requests to use for best effort QoS
+	DefaultRequests v1.ResourceList
+	// Default requests multiplier for burstable QoS
+	DefaultRequestsMultiplier string
+	// Node utilization percent per resource, predicted once the pod runs on the node, above which the node is rejected.
+	// The resources are cpu, memory, network and disk.
+	ResourceThresholds map[v1.ResourceName]int64
+	// Pods of these priority classes are not filtered
+	ExemptPriorityClasses []string
+	// Pods of these namespaces are not filtered
+	ExemptNamespaces []string
 }
 
 // ScoringStrategyType is a "string" type.
@@ -265,3 +395,15 @@ type SySchedArgs struct {
 	// CR name of the default profile for all system calls
 	DefaultProfileName string
 }
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DiskIOAwareArgs holds arguments used to configure DiskIOAware plugin.
+type DiskIOAwareArgs struct {
+	metav1.TypeMeta
+
+	// Whether to favor the nodes with the most (MostAllocated) or least (LeastAllocated) disk IO allocated
+	ScoringStrategy ScoringStrategyType
+	// Name of the estimator normalizing the disk IO requests of the pods to the capacity of the devices
+	Estimator string
+}
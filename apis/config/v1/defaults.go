@@ -27,8 +27,9 @@ import (
 )
 
 var (
-	defaultPermitWaitingTimeSeconds int64 = 60
-	defaultPodGroupBackoffSeconds   int64 = 0
+	defaultPermitWaitingTimeSeconds  int64 = 60
+	defaultPodGroupBackoffSeconds    int64 = 0
+	defaultEnableCapacityReservation       = false
 
 	defaultNodeResourcesAllocatableMode = Least
 
@@ -49,6 +50,8 @@ var (
 	DefaultRequestsMultiplier = "1.5"
 	// DefaultTargetUtilizationPercent Recommended to keep -10 than desired limit.
 	DefaultTargetUtilizationPercent int64 = 40
+	// DefaultResourceTargetWeight is the weight of a resource in the score of TargetLoadPacking.
+	DefaultResourceTargetWeight int64 = 1
 
 	// Defaults for LoadVariationRiskBalancing plugin
 
@@ -74,11 +77,44 @@ var (
 		v1.ResourceCPU:    DefaultRiskLimitWeight,
 		v1.ResourceMemory: DefaultRiskLimitWeight,
 	}
+	// DefaultRiskAggregation ranks the nodes by the risk of their riskiest resource
+	DefaultRiskAggregation = MaxRisk
+
+	// Defaults for LoadAwareFilter plugin
+
+	// DefaultResourceThresholds rejects the nodes whose cpu or memory utilization would exceed 90% with the pod.
+	DefaultResourceThresholds = map[v1.ResourceName]int64{
+		v1.ResourceCPU:    90,
+		v1.ResourceMemory: 90,
+	}
 
 	// DefaultMetricProviderType is the Kubernetes metrics server
 	DefaultMetricProviderType = KubernetesMetricsServer
 	// DefaultInsecureSkipVerify is whether to skip the certificate verification
 	DefaultInsecureSkipVerify = true
+	// DefaultMetricsStalenessSeconds is the age of the load metrics beyond which they are stale
+	DefaultMetricsStalenessSeconds int64 = 300
+	// DefaultFallbackMode scores the nodes without fresh load metrics with the minimum score
+	DefaultFallbackMode = MinScoreFallback
+	// DefaultMetricsUpdateIntervalSeconds is the interval of the updates of the load metrics
+	DefaultMetricsUpdateIntervalSeconds int64 = 30
+	// DefaultMetricsIngestion polls the load metrics from the load watcher
+	DefaultMetricsIngestion = PollIngestion
+
+	// Defaults for the forecasting of the utilization of the nodes
+
+	// DefaultForecastModel is the exponentially weighted moving average
+	DefaultForecastModel = EWMAForecast
+	// DefaultForecastAlpha is the smoothing factor of the level
+	DefaultForecastAlpha = 0.3
+	// DefaultForecastBeta is the smoothing factor of the trend
+	DefaultForecastBeta = 0.1
+	// DefaultForecastGamma is the smoothing factor of the seasonality
+	DefaultForecastGamma = 0.1
+	// DefaultForecastSeasonPeriodSeconds is a day, for the HoltWinters model to anticipate the daily peaks
+	DefaultForecastSeasonPeriodSeconds int64 = 24 * 60 * 60
+	// DefaultForecastHorizonSeconds is the expected time for a pod to start
+	DefaultForecastHorizonSeconds int64 = 60
 
 	defaultResourceSpec = []schedulerconfigv1.ResourceSpec{
 		{Name: string(v1.ResourceCPU), Weight: 1},
@@ -102,6 +138,12 @@ var (
 	DefaultSySchedProfileNamespace = "default"
 	// DefaultSySchedProfileName is the name of the default syscall profile CR for SySched plugin
 	DefaultSySchedProfileName = "all-syscalls"
+
+	// Defaults for DiskIOAware
+	// DefaultDiskIOScoringStrategy spreads the disk IO, against the noisy neighbors
+	DefaultDiskIOScoringStrategy = LeastAllocated
+	// DefaultDiskIOEstimator derives the IOPS from the throughput, and conversely, with the block size
+	DefaultDiskIOEstimator = "BlockSize"
 )
 
 // SetDefaults_CoschedulingArgs sets the default parameters for Coscheduling plugin.
@@ -112,6 +154,13 @@ func SetDefaults_CoschedulingArgs(obj *CoschedulingArgs) {
 	if obj.PodGroupBackoffSeconds == nil {
 		obj.PodGroupBackoffSeconds = &defaultPodGroupBackoffSeconds
 	}
+	if obj.PodGroupMaxBackoffSeconds == nil {
+		maxBackoff := *obj.PodGroupBackoffSeconds
+		obj.PodGroupMaxBackoffSeconds = &maxBackoff
+	}
+	if obj.EnableCapacityReservation == nil {
+		obj.EnableCapacityReservation = &defaultEnableCapacityReservation
+	}
 }
 
 // SetDefaults_NodeResourcesAllocatableArgs sets the defaults parameters for NodeResourceAllocatable.
@@ -127,12 +176,24 @@ func SetDefaults_NodeResourcesAllocatableArgs(obj *NodeResourcesAllocatableArgs)
 
 // SetDefaultTrimaranSpec sets the default parameters for common Trimaran plugins
 func SetDefaultTrimaranSpec(args *TrimaranSpec) {
-	if args.WatcherAddress == nil && args.MetricProvider.Type == "" {
+	if args.MetricsIngestion == "" {
+		args.MetricsIngestion = DefaultMetricsIngestion
+	}
+	if args.MetricsIngestion == PollIngestion && args.WatcherAddress == nil && args.MetricProvider.Type == "" {
 		args.MetricProvider.Type = DefaultMetricProviderType
 	}
 	if args.MetricProvider.Type == Prometheus && args.MetricProvider.InsecureSkipVerify == nil {
 		args.MetricProvider.InsecureSkipVerify = &DefaultInsecureSkipVerify
 	}
+	if args.MetricsStalenessSeconds == nil {
+		args.MetricsStalenessSeconds = &DefaultMetricsStalenessSeconds
+	}
+	if args.FallbackMode == "" {
+		args.FallbackMode = DefaultFallbackMode
+	}
+	if args.MetricsUpdateIntervalSeconds == nil || *args.MetricsUpdateIntervalSeconds <= 0 {
+		args.MetricsUpdateIntervalSeconds = &DefaultMetricsUpdateIntervalSeconds
+	}
 }
 
 // SetDefaults_TargetLoadPackingArgs sets the default parameters for TargetLoadPacking plugin
@@ -148,6 +209,38 @@ func SetDefaults_TargetLoadPackingArgs(args *TargetLoadPackingArgs) {
 	if args.TargetUtilization == nil || *args.TargetUtilization <= 0 {
 		args.TargetUtilization = &DefaultTargetUtilizationPercent
 	}
+	for i := range args.ResourceTargets {
+		target := &args.ResourceTargets[i]
+		if target.TargetUtilization == nil || *target.TargetUtilization <= 0 {
+			targetUtilization := *args.TargetUtilization
+			target.TargetUtilization = &targetUtilization
+		}
+		if target.Weight == nil {
+			target.Weight = &DefaultResourceTargetWeight
+		}
+	}
+}
+
+// SetDefaultForecastSpec sets the default parameters of the forecasting of the utilization of the nodes
+func SetDefaultForecastSpec(args *ForecastSpec) {
+	if args.Model == "" {
+		args.Model = DefaultForecastModel
+	}
+	if args.Alpha == nil {
+		args.Alpha = &DefaultForecastAlpha
+	}
+	if args.Beta == nil {
+		args.Beta = &DefaultForecastBeta
+	}
+	if args.Gamma == nil {
+		args.Gamma = &DefaultForecastGamma
+	}
+	if args.SeasonPeriodSeconds == nil && args.Model == HoltWintersForecast {
+		args.SeasonPeriodSeconds = &DefaultForecastSeasonPeriodSeconds
+	}
+	if args.HorizonSeconds == nil {
+		args.HorizonSeconds = &DefaultForecastHorizonSeconds
+	}
 }
 
 // SetDefaults_LoadVariationRiskBalancingArgs sets the default parameters for LoadVariationRiskBalancing plugin
@@ -159,6 +252,9 @@ func SetDefaults_LoadVariationRiskBalancingArgs(args *LoadVariationRiskBalancing
 	if args.SafeVarianceSensitivity == nil || *args.SafeVarianceSensitivity < 0 {
 		args.SafeVarianceSensitivity = &DefaultSafeVarianceSensitivity
 	}
+	if args.Forecast != nil {
+		SetDefaultForecastSpec(args.Forecast)
+	}
 }
 
 // SetDefaults_LowRiskOverCommitmentArgs sets the default parameters for LowRiskOverCommitment plugin
@@ -176,6 +272,30 @@ func SetDefaults_LowRiskOverCommitmentArgs(args *LowRiskOverCommitmentArgs) {
 			}
 		}
 	}
+	if args.Forecast != nil {
+		SetDefaultForecastSpec(args.Forecast)
+	}
+	if args.RiskAggregation == "" {
+		args.RiskAggregation = DefaultRiskAggregation
+	}
+}
+
+// SetDefaults_LoadAwareFilterArgs sets the default parameters for LoadAwareFilter plugin
+func SetDefaults_LoadAwareFilterArgs(args *LoadAwareFilterArgs) {
+	SetDefaultTrimaranSpec(&args.TrimaranSpec)
+	if args.DefaultRequests == nil {
+		args.DefaultRequests = v1.ResourceList{v1.ResourceCPU: resource.MustParse(
+			strconv.FormatInt(DefaultRequestsMilliCores, 10) + "m")}
+	}
+	if args.DefaultRequestsMultiplier == nil {
+		args.DefaultRequestsMultiplier = &DefaultRequestsMultiplier
+	}
+	if len(args.ResourceThresholds) == 0 {
+		args.ResourceThresholds = make(map[v1.ResourceName]int64, len(DefaultResourceThresholds))
+		for r, t := range DefaultResourceThresholds {
+			args.ResourceThresholds[r] = t
+		}
+	}
 }
 
 // SetDefaults_NodeResourceTopologyMatchArgs sets the default parameters for NodeResourceTopologyMatch plugin.
@@ -250,3 +370,14 @@ func SetDefaults_SySchedArgs(obj *SySchedArgs) {
 		obj.DefaultProfileName = &DefaultSySchedProfileName
 	}
 }
+
+// SetDefaults_DiskIOAwareArgs sets the default parameters for DiskIOAware plugin.
+func SetDefaults_DiskIOAwareArgs(obj *DiskIOAwareArgs) {
+	if obj.ScoringStrategy == "" {
+		obj.ScoringStrategy = DefaultDiskIOScoringStrategy
+	}
+
+	if obj.Estimator == nil {
+		obj.Estimator = &DefaultDiskIOEstimator
+	}
+}
@@ -39,8 +39,10 @@ func TestSchedulingDefaults(t *testing.T) {
 			name:   "empty config CoschedulingArgs",
 			config: &CoschedulingArgs{},
 			expect: &CoschedulingArgs{
-				PermitWaitingTimeSeconds: pointer.Int64Ptr(60),
-				PodGroupBackoffSeconds:   pointer.Int64Ptr(0),
+				PermitWaitingTimeSeconds:  pointer.Int64Ptr(60),
+				PodGroupBackoffSeconds:    pointer.Int64Ptr(0),
+				PodGroupMaxBackoffSeconds: pointer.Int64Ptr(0),
+				EnableCapacityReservation: pointer.BoolPtr(false),
 			},
 		},
 		{
@@ -50,8 +52,35 @@ func TestSchedulingDefaults(t *testing.T) {
 				PodGroupBackoffSeconds:   pointer.Int64Ptr(20),
 			},
 			expect: &CoschedulingArgs{
-				PermitWaitingTimeSeconds: pointer.Int64Ptr(60),
-				PodGroupBackoffSeconds:   pointer.Int64Ptr(20),
+				PermitWaitingTimeSeconds:  pointer.Int64Ptr(60),
+				PodGroupBackoffSeconds:    pointer.Int64Ptr(20),
+				PodGroupMaxBackoffSeconds: pointer.Int64Ptr(20),
+				EnableCapacityReservation: pointer.BoolPtr(false),
+			},
+		},
+		{
+			name: "set exponential backoff CoschedulingArgs",
+			config: &CoschedulingArgs{
+				PodGroupBackoffSeconds:    pointer.Int64Ptr(10),
+				PodGroupMaxBackoffSeconds: pointer.Int64Ptr(300),
+			},
+			expect: &CoschedulingArgs{
+				PermitWaitingTimeSeconds:  pointer.Int64Ptr(60),
+				PodGroupBackoffSeconds:    pointer.Int64Ptr(10),
+				PodGroupMaxBackoffSeconds: pointer.Int64Ptr(300),
+				EnableCapacityReservation: pointer.BoolPtr(false),
+			},
+		},
+		{
+			name: "enable capacity reservation CoschedulingArgs",
+			config: &CoschedulingArgs{
+				EnableCapacityReservation: pointer.BoolPtr(true),
+			},
+			expect: &CoschedulingArgs{
+				PermitWaitingTimeSeconds:  pointer.Int64Ptr(60),
+				PodGroupBackoffSeconds:    pointer.Int64Ptr(0),
+				PodGroupMaxBackoffSeconds: pointer.Int64Ptr(0),
+				EnableCapacityReservation: pointer.BoolPtr(true),
 			},
 		},
 		{
@@ -86,7 +115,12 @@ func TestSchedulingDefaults(t *testing.T) {
 				TrimaranSpec: TrimaranSpec{
 					MetricProvider: MetricProviderSpec{
 						Type: "KubernetesMetricsServer",
-					}},
+					},
+					MetricsStalenessSeconds:      pointer.Int64Ptr(300),
+					FallbackMode:                 "MinScore",
+					MetricsUpdateIntervalSeconds: pointer.Int64Ptr(30),
+					MetricsIngestion:             "Poll",
+				},
 				DefaultRequests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(
 					strconv.FormatInt(DefaultRequestsMilliCores, 10) + "m")},
 				DefaultRequestsMultiplier: pointer.StringPtr("1.5"),
@@ -97,19 +131,58 @@ func TestSchedulingDefaults(t *testing.T) {
 			name: "set non default TargetLoadPackingArgs",
 			config: &TargetLoadPackingArgs{
 				TrimaranSpec: TrimaranSpec{
-					WatcherAddress: pointer.StringPtr("http://localhost:2020")},
+					WatcherAddress:               pointer.StringPtr("http://localhost:2020"),
+					MetricsStalenessSeconds:      pointer.Int64Ptr(60),
+					FallbackMode:                 "Allocation",
+					MetricsUpdateIntervalSeconds: pointer.Int64Ptr(10),
+					MetricsIngestion:             "Poll",
+				},
 				DefaultRequests:           v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")},
 				DefaultRequestsMultiplier: pointer.StringPtr("2.5"),
 				TargetUtilization:         pointer.Int64Ptr(50),
 			},
 			expect: &TargetLoadPackingArgs{
 				TrimaranSpec: TrimaranSpec{
-					WatcherAddress: pointer.StringPtr("http://localhost:2020")},
+					WatcherAddress:               pointer.StringPtr("http://localhost:2020"),
+					MetricsStalenessSeconds:      pointer.Int64Ptr(60),
+					FallbackMode:                 "Allocation",
+					MetricsUpdateIntervalSeconds: pointer.Int64Ptr(10),
+					MetricsIngestion:             "Poll",
+				},
 				DefaultRequests:           v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")},
 				DefaultRequestsMultiplier: pointer.StringPtr("2.5"),
 				TargetUtilization:         pointer.Int64Ptr(50),
 			},
 		},
+		{
+			name: "set resource targets of TargetLoadPackingArgs",
+			config: &TargetLoadPackingArgs{
+				TargetUtilization: pointer.Int64Ptr(50),
+				ResourceTargets: []ResourceTarget{
+					{Name: v1.ResourceCPU},
+					{Name: v1.ResourceMemory, TargetUtilization: pointer.Int64Ptr(70), Weight: pointer.Int64Ptr(2)},
+				},
+			},
+			expect: &TargetLoadPackingArgs{
+				TrimaranSpec: TrimaranSpec{
+					MetricProvider: MetricProviderSpec{
+						Type: "KubernetesMetricsServer",
+					},
+					MetricsStalenessSeconds:      pointer.Int64Ptr(300),
+					FallbackMode:                 "MinScore",
+					MetricsUpdateIntervalSeconds: pointer.Int64Ptr(30),
+					MetricsIngestion:             "Poll",
+				},
+				DefaultRequests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(
+					strconv.FormatInt(DefaultRequestsMilliCores, 10) + "m")},
+				DefaultRequestsMultiplier: pointer.StringPtr("1.5"),
+				TargetUtilization:         pointer.Int64Ptr(50),
+				ResourceTargets: []ResourceTarget{
+					{Name: v1.ResourceCPU, TargetUtilization: pointer.Int64Ptr(50), Weight: pointer.Int64Ptr(1)},
+					{Name: v1.ResourceMemory, TargetUtilization: pointer.Int64Ptr(70), Weight: pointer.Int64Ptr(2)},
+				},
+			},
+		},
 		{
 			name:   "empty config LoadVariationRiskBalancingArgs",
 			config: &LoadVariationRiskBalancingArgs{},
@@ -117,7 +190,12 @@ func TestSchedulingDefaults(t *testing.T) {
 				TrimaranSpec: TrimaranSpec{
 					MetricProvider: MetricProviderSpec{
 						Type: "KubernetesMetricsServer",
-					}},
+					},
+					MetricsStalenessSeconds:      pointer.Int64Ptr(300),
+					FallbackMode:                 "MinScore",
+					MetricsUpdateIntervalSeconds: pointer.Int64Ptr(30),
+					MetricsIngestion:             "Poll",
+				},
 				SafeVarianceMargin:      pointer.Float64Ptr(1.0),
 				SafeVarianceSensitivity: pointer.Float64Ptr(1.0),
 			},
@@ -132,11 +210,66 @@ func TestSchedulingDefaults(t *testing.T) {
 				TrimaranSpec: TrimaranSpec{
 					MetricProvider: MetricProviderSpec{
 						Type: "KubernetesMetricsServer",
-					}},
+					},
+					MetricsStalenessSeconds:      pointer.Int64Ptr(300),
+					FallbackMode:                 "MinScore",
+					MetricsUpdateIntervalSeconds: pointer.Int64Ptr(30),
+					MetricsIngestion:             "Poll",
+				},
 				SafeVarianceMargin:      pointer.Float64Ptr(2.0),
 				SafeVarianceSensitivity: pointer.Float64Ptr(2.0),
 			},
 		},
+		{
+			name: "push metrics ingestion of LoadVariationRiskBalancingArgs",
+			config: &LoadVariationRiskBalancingArgs{
+				TrimaranSpec: TrimaranSpec{
+					MetricsIngestion:   "Push",
+					MetricsPushAddress: pointer.StringPtr(":2021"),
+				},
+			},
+			expect: &LoadVariationRiskBalancingArgs{
+				TrimaranSpec: TrimaranSpec{
+					MetricsStalenessSeconds:      pointer.Int64Ptr(300),
+					FallbackMode:                 "MinScore",
+					MetricsUpdateIntervalSeconds: pointer.Int64Ptr(30),
+					MetricsIngestion:             "Push",
+					MetricsPushAddress:           pointer.StringPtr(":2021"),
+				},
+				SafeVarianceMargin:      pointer.Float64Ptr(1.0),
+				SafeVarianceSensitivity: pointer.Float64Ptr(1.0),
+			},
+		},
+		{
+			name: "forecast of LoadVariationRiskBalancingArgs",
+			config: &LoadVariationRiskBalancingArgs{
+				Forecast: &ForecastSpec{
+					Model:          "HoltWinters",
+					HorizonSeconds: pointer.Int64Ptr(300),
+				},
+			},
+			expect: &LoadVariationRiskBalancingArgs{
+				TrimaranSpec: TrimaranSpec{
+					MetricProvider: MetricProviderSpec{
+						Type: "KubernetesMetricsServer",
+					},
+					MetricsStalenessSeconds:      pointer.Int64Ptr(300),
+					FallbackMode:                 "MinScore",
+					MetricsUpdateIntervalSeconds: pointer.Int64Ptr(30),
+					MetricsIngestion:             "Poll",
+				},
+				SafeVarianceMargin:      pointer.Float64Ptr(1.0),
+				SafeVarianceSensitivity: pointer.Float64Ptr(1.0),
+				Forecast: &ForecastSpec{
+					Model:               "HoltWinters",
+					Alpha:               pointer.Float64Ptr(0.3),
+					Beta:                pointer.Float64Ptr(0.1),
+					Gamma:               pointer.Float64Ptr(0.1),
+					SeasonPeriodSeconds: pointer.Int64Ptr(86400),
+					HorizonSeconds:      pointer.Int64Ptr(300),
+				},
+			},
+		},
 		{
 			name:   "empty config LowRiskOverCommitmentArgs",
 			config: &LowRiskOverCommitmentArgs{},
@@ -144,12 +277,18 @@ func TestSchedulingDefaults(t *testing.T) {
 				TrimaranSpec: TrimaranSpec{
 					MetricProvider: MetricProviderSpec{
 						Type: "KubernetesMetricsServer",
-					}},
+					},
+					MetricsStalenessSeconds:      pointer.Int64Ptr(300),
+					FallbackMode:                 "MinScore",
+					MetricsUpdateIntervalSeconds: pointer.Int64Ptr(30),
+					MetricsIngestion:             "Poll",
+				},
 				SmoothingWindowSize: pointer.Int64Ptr(5),
 				RiskLimitWeights: map[v1.ResourceName]float64{
 					v1.ResourceCPU:    0.5,
 					v1.ResourceMemory: 0.5,
 				},
+				RiskAggregation: "Max",
 			},
 		},
 		{
@@ -165,12 +304,73 @@ func TestSchedulingDefaults(t *testing.T) {
 				TrimaranSpec: TrimaranSpec{
 					MetricProvider: MetricProviderSpec{
 						Type: "KubernetesMetricsServer",
-					}},
+					},
+					MetricsStalenessSeconds:      pointer.Int64Ptr(300),
+					FallbackMode:                 "MinScore",
+					MetricsUpdateIntervalSeconds: pointer.Int64Ptr(30),
+					MetricsIngestion:             "Poll",
+				},
 				SmoothingWindowSize: pointer.Int64Ptr(10),
 				RiskLimitWeights: map[v1.ResourceName]float64{
 					v1.ResourceCPU:    0.2,
 					v1.ResourceMemory: 0.8,
 				},
+				RiskAggregation: "Max",
+			},
+		},
+		{
+			name: "risk aggregation of LowRiskOverCommitmentArgs",
+			config: &LowRiskOverCommitmentArgs{
+				RiskAggregation:        WeightedMeanRisk,
+				RiskAggregationWeights: map[v1.ResourceName]float64{v1.ResourceMemory: 2},
+			},
+			expect: &LowRiskOverCommitmentArgs{
+				TrimaranSpec: TrimaranSpec{
+					MetricProvider: MetricProviderSpec{
+						Type: "KubernetesMetricsServer",
+					},
+					MetricsStalenessSeconds:      pointer.Int64Ptr(300),
+					FallbackMode:                 "MinScore",
+					MetricsUpdateIntervalSeconds: pointer.Int64Ptr(30),
+					MetricsIngestion:             "Poll",
+				},
+				SmoothingWindowSize: pointer.Int64Ptr(5),
+				RiskLimitWeights: map[v1.ResourceName]float64{
+					v1.ResourceCPU:    0.5,
+					v1.ResourceMemory: 0.5,
+				},
+				RiskAggregation:        "WeightedMean",
+				RiskAggregationWeights: map[v1.ResourceName]float64{v1.ResourceMemory: 2},
+			},
+		},
+		{
+			name: "forecast of LowRiskOverCommitmentArgs",
+			config: &LowRiskOverCommitmentArgs{
+				Forecast: &ForecastSpec{},
+			},
+			expect: &LowRiskOverCommitmentArgs{
+				TrimaranSpec: TrimaranSpec{
+					MetricProvider: MetricProviderSpec{
+						Type: "KubernetesMetricsServer",
+					},
+					MetricsStalenessSeconds:      pointer.Int64Ptr(300),
+					FallbackMode:                 "MinScore",
+					MetricsUpdateIntervalSeconds: pointer.Int64Ptr(30),
+					MetricsIngestion:             "Poll",
+				},
+				SmoothingWindowSize: pointer.Int64Ptr(5),
+				RiskLimitWeights: map[v1.ResourceName]float64{
+					v1.ResourceCPU:    0.5,
+					v1.ResourceMemory: 0.5,
+				},
+				Forecast: &ForecastSpec{
+					Model:          "EWMA",
+					Alpha:          pointer.Float64Ptr(0.3),
+					Beta:           pointer.Float64Ptr(0.1),
+					Gamma:          pointer.Float64Ptr(0.1),
+					HorizonSeconds: pointer.Int64Ptr(60),
+				},
+				RiskAggregation: "Max",
 			},
 		},
 		{
@@ -186,12 +386,72 @@ func TestSchedulingDefaults(t *testing.T) {
 				TrimaranSpec: TrimaranSpec{
 					MetricProvider: MetricProviderSpec{
 						Type: "KubernetesMetricsServer",
-					}},
+					},
+					MetricsStalenessSeconds:      pointer.Int64Ptr(300),
+					FallbackMode:                 "MinScore",
+					MetricsUpdateIntervalSeconds: pointer.Int64Ptr(30),
+					MetricsIngestion:             "Poll",
+				},
 				SmoothingWindowSize: pointer.Int64Ptr(10),
 				RiskLimitWeights: map[v1.ResourceName]float64{
 					v1.ResourceCPU:    0.5,
 					v1.ResourceMemory: 0.5,
 				},
+				RiskAggregation: "Max",
+			},
+		},
+		{
+			name:   "empty config LoadAwareFilterArgs",
+			config: &LoadAwareFilterArgs{},
+			expect: &LoadAwareFilterArgs{
+				TrimaranSpec: TrimaranSpec{
+					MetricProvider: MetricProviderSpec{
+						Type: "KubernetesMetricsServer",
+					},
+					MetricsStalenessSeconds:      pointer.Int64Ptr(300),
+					FallbackMode:                 "MinScore",
+					MetricsUpdateIntervalSeconds: pointer.Int64Ptr(30),
+					MetricsIngestion:             "Poll",
+				},
+				DefaultRequests: v1.ResourceList{
+					v1.ResourceCPU: resource.MustParse("1000m"),
+				},
+				DefaultRequestsMultiplier: pointer.StringPtr("1.5"),
+				ResourceThresholds: map[v1.ResourceName]int64{
+					v1.ResourceCPU:    90,
+					v1.ResourceMemory: 90,
+				},
+			},
+		},
+		{
+			name: "set non default LoadAwareFilterArgs",
+			config: &LoadAwareFilterArgs{
+				DefaultRequestsMultiplier: pointer.StringPtr("2"),
+				ResourceThresholds: map[v1.ResourceName]int64{
+					v1.ResourceCPU: 80,
+				},
+				ExemptPriorityClasses: []string{"system-node-critical"},
+				ExemptNamespaces:      []string{"kube-system"},
+			},
+			expect: &LoadAwareFilterArgs{
+				TrimaranSpec: TrimaranSpec{
+					MetricProvider: MetricProviderSpec{
+						Type: "KubernetesMetricsServer",
+					},
+					MetricsStalenessSeconds:      pointer.Int64Ptr(300),
+					FallbackMode:                 "MinScore",
+					MetricsUpdateIntervalSeconds: pointer.Int64Ptr(30),
+					MetricsIngestion:             "Poll",
+				},
+				DefaultRequests: v1.ResourceList{
+					v1.ResourceCPU: resource.MustParse("1000m"),
+				},
+				DefaultRequestsMultiplier: pointer.StringPtr("2"),
+				ResourceThresholds: map[v1.ResourceName]int64{
+					v1.ResourceCPU: 80,
+				},
+				ExemptPriorityClasses: []string{"system-node-critical"},
+				ExemptNamespaces:      []string{"kube-system"},
 			},
 		},
 		{
@@ -274,6 +534,25 @@ func TestSchedulingDefaults(t *testing.T) {
 				DefaultProfileName:      pointer.StringPtr("all-syscalls"),
 			},
 		},
+		{
+			name:   "empty config DiskIOAwareArgs",
+			config: &DiskIOAwareArgs{},
+			expect: &DiskIOAwareArgs{
+				ScoringStrategy: LeastAllocated,
+				Estimator:       pointer.StringPtr("BlockSize"),
+			},
+		},
+		{
+			name: "set non default DiskIOAwareArgs",
+			config: &DiskIOAwareArgs{
+				ScoringStrategy: MostAllocated,
+				Estimator:       pointer.StringPtr("Vendor"),
+			},
+			expect: &DiskIOAwareArgs{
+				ScoringStrategy: MostAllocated,
+				Estimator:       pointer.StringPtr("Vendor"),
+			},
+		},
 	}
 
 	for _, tc := range tests {
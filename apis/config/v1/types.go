@@ -32,6 +32,14 @@ type CoschedulingArgs struct {
 	PermitWaitingTimeSeconds *int64 `json:"permitWaitingTimeSeconds,omitempty"`
 	// PodGroupBackoffSeconds is the backoff time in seconds before a pod group can be scheduled again.
 	PodGroupBackoffSeconds *int64 `json:"podGroupBackoffSeconds,omitempty"`
+	// PodGroupMaxBackoffSeconds is the upper bound in seconds of the per pod group backoff,
+	// which doubles on every consecutive scheduling failure of the pod group.
+	// Defaults to podGroupBackoffSeconds, i.e. a fixed backoff.
+	PodGroupMaxBackoffSeconds *int64 `json:"podGroupMaxBackoffSeconds,omitempty"`
+	// EnableCapacityReservation makes the plugin hold node capacity for the remaining members of a pod group
+	// once one of its members passes PreFilter, until the pod group's schedule timeout.
+	// Pods outside of the pod group don't see the reserved capacity at Filter. Defaults to false.
+	EnableCapacityReservation *bool `json:"enableCapacityReservation,omitempty"`
 }
 
 // ModeType is a type "string".
@@ -68,6 +76,8 @@ const (
 	KubernetesMetricsServer MetricProviderType = "KubernetesMetricsServer"
 	Prometheus              MetricProviderType = "Prometheus"
 	SignalFx                MetricProviderType = "SignalFx"
+	// Kubelet scrapes the resource metrics of the kubelets, without the load watcher
+	Kubelet MetricProviderType = "Kubelet"
 )
 
 // Denote the spec of the metric provider
@@ -82,12 +92,88 @@ type MetricProviderSpec struct {
 	InsecureSkipVerify *bool `json:"insecureSkipVerify,omitempty"`
 }
 
+// FallbackMode is a "string" type.
+type FallbackMode string
+
+const (
+	// MinScoreFallback scores the nodes without fresh load metrics with the minimum score
+	MinScoreFallback FallbackMode = "MinScore"
+	// AllocationFallback derives the utilization of the nodes without fresh load metrics from the requests of their pods
+	AllocationFallback FallbackMode = "Allocation"
+)
+
+// MetricsIngestionMode is a "string" type.
+type MetricsIngestionMode string
+
+const (
+	// PollIngestion polls the load metrics from the load watcher periodically
+	PollIngestion MetricsIngestionMode = "Poll"
+	// PushIngestion accepts the load metrics pushed to an endpoint of the scheduler, in the load watcher format
+	PushIngestion MetricsIngestionMode = "Push"
+)
+
+// ForecastModel is a "string" type.
+type ForecastModel string
+
+const (
+	// EWMAForecast forecasts the utilization of a node as the exponentially weighted moving average of its history
+	EWMAForecast ForecastModel = "EWMA"
+	// HoltWintersForecast forecasts the utilization of a node with the additive trend and seasonality of its history
+	HoltWintersForecast ForecastModel = "HoltWinters"
+)
+
+// RiskAggregationType is a "string" type.
+type RiskAggregationType string
+
+const (
+	// MaxRisk aggregates the risks of the resources of a node into the risk of its riskiest resource
+	MaxRisk RiskAggregationType = "Max"
+	// WeightedMeanRisk aggregates the risks of the resources of a node into their mean, weighted by resource
+	WeightedMeanRisk RiskAggregationType = "WeightedMean"
+)
+
+// ForecastSpec holds the parameters of the forecasting of the utilization of the nodes
+type ForecastSpec struct {
+	// Model fitted to the history of the utilization of each node: EWMA or HoltWinters
+	Model ForecastModel `json:"model,omitempty"`
+	// Smoothing factor of the level, in (0, 1]
+	Alpha *float64 `json:"alpha,omitempty"`
+	// Smoothing factor of the trend, in [0, 1], with the HoltWinters model
+	Beta *float64 `json:"beta,omitempty"`
+	// Smoothing factor of the seasonality, in [0, 1], with the HoltWinters model
+	Gamma *float64 `json:"gamma,omitempty"`
+	// Length of the season, in seconds, with the HoltWinters model. Zero disables the seasonality.
+	SeasonPeriodSeconds *int64 `json:"seasonPeriodSeconds,omitempty"`
+	// Horizon, in seconds, over which the peak utilization is forecast: the expected time for the pod to start
+	HorizonSeconds *int64 `json:"horizonSeconds,omitempty"`
+}
+
 // TrimaranSpec holds common parameters for trimaran plugins
 type TrimaranSpec struct {
 	// Metric Provider specification when using load watcher as library
 	MetricProvider MetricProviderSpec `json:"metricProvider,omitempty"`
 	// Address of load watcher service
 	WatcherAddress *string `json:"watcherAddress,omitempty"`
+	// Load metrics whose window ended longer ago, in seconds, are stale. Zero disables the detection.
+	MetricsStalenessSeconds *int64 `json:"metricsStalenessSeconds,omitempty"`
+	// How the nodes whose load metrics are missing or stale are scored: MinScore or Allocation
+	FallbackMode FallbackMode `json:"fallbackMode,omitempty"`
+	// Interval, in seconds, of the updates of the load metrics
+	MetricsUpdateIntervalSeconds *int64 `json:"metricsUpdateIntervalSeconds,omitempty"`
+	// How the load metrics are ingested: Poll from the load watcher, or Push to the scheduler
+	MetricsIngestion MetricsIngestionMode `json:"metricsIngestion,omitempty"`
+	// Address the endpoint accepting the pushed load metrics listens on, with the Push ingestion
+	MetricsPushAddress *string `json:"metricsPushAddress,omitempty"`
+	// Certificate file the endpoint accepting the pushed load metrics serves TLS with, required with the Push ingestion
+	MetricsPushCertFile *string `json:"metricsPushCertFile,omitempty"`
+	// Key file of MetricsPushCertFile, required with the Push ingestion
+	MetricsPushKeyFile *string `json:"metricsPushKeyFile,omitempty"`
+	// File holding the bearer token the pushing agents must present. With the Push ingestion, it's required
+	// unless MetricsPushClientCAFile is set.
+	MetricsPushTokenFile *string `json:"metricsPushTokenFile,omitempty"`
+	// File holding the CA certificates the client certificates of the pushing agents must be signed by. With the
+	// Push ingestion, it's required unless MetricsPushTokenFile is set.
+	MetricsPushClientCAFile *string `json:"metricsPushClientCAFile,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -105,6 +191,19 @@ type TargetLoadPackingArgs struct {
 	DefaultRequestsMultiplier *string `json:"defaultRequestsMultiplier,omitempty"`
 	// Node target CPU Utilization for bin packing
 	TargetUtilization *int64 `json:"targetUtilization,omitempty"`
+	// Node target utilization per resource for bin packing, with the weight of each resource in the score.
+	// Only cpu is packed, around TargetUtilization, if no resource is listed.
+	ResourceTargets []ResourceTarget `json:"resourceTargets,omitempty"`
+}
+
+// ResourceTarget is the node target utilization of a resource for TargetLoadPacking.
+type ResourceTarget struct {
+	// Name of the resource: cpu, memory, network or disk
+	Name v1.ResourceName `json:"name"`
+	// Node target utilization percent of the resource. Defaults to TargetUtilization.
+	TargetUtilization *int64 `json:"targetUtilization,omitempty"`
+	// Weight of the resource in the score. Defaults to 1.
+	Weight *int64 `json:"weight,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -120,6 +219,8 @@ type LoadVariationRiskBalancingArgs struct {
 	SafeVarianceMargin *float64 `json:"safeVarianceMargin,omitempty"`
 	// Root power of standard deviation in risk value
 	SafeVarianceSensitivity *float64 `json:"safeVarianceSensitivity,omitempty"`
+	// Forecasting of the utilization of the nodes, scored instead of their current utilization if set
+	Forecast *ForecastSpec `json:"forecast,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -135,6 +236,34 @@ type LowRiskOverCommitmentArgs struct {
 	SmoothingWindowSize *int64 `json:"smoothingWindowSize,omitempty"`
 	// Resources fractional weight of risk due to limits specification [0,1]
 	RiskLimitWeights map[v1.ResourceName]float64 `json:"riskLimitWeights,omitempty"`
+	// Forecasting of the utilization of the nodes, scored instead of their current utilization if set
+	Forecast *ForecastSpec `json:"forecast,omitempty"`
+	// How the risks of the resources of a node are aggregated: Max or WeightedMean
+	RiskAggregation RiskAggregationType `json:"riskAggregation,omitempty"`
+	// Weights of the resources in the WeightedMean aggregation, one for the resources not listed
+	RiskAggregationWeights map[v1.ResourceName]float64 `json:"riskAggregationWeights,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +k8s:defaulter-gen=true
+
+// LoadAwareFilterArgs holds arguments used to configure LoadAwareFilter plugin.
+type LoadAwareFilterArgs struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Common parameters for trimaran plugins
+	TrimaranSpec `json:",inline"`
+	// Default requests to use for best effort QoS
+	DefaultRequests v1.ResourceList `json:"defaultRequests,omitempty"`
+	// Default requests multiplier for burstable QoS
+	DefaultRequestsMultiplier *string `json:"defaultRequestsMultiplier,omitempty"`
+	// Node utilization percent per resource, predicted once the pod runs on the node, above which the node is rejected.
+	// The resources are cpu, memory, network and disk.
+	ResourceThresholds map[v1.ResourceName]int64 `json:"resourceThresholds,omitempty"`
+	// Pods of these priority classes are not filtered
+	ExemptPriorityClasses []string `json:"exemptPriorityClasses,omitempty"`
+	// Pods of these namespaces are not filtered
+	ExemptNamespaces []string `json:"exemptNamespaces,omitempty"`
 }
 
 // ScoringStrategyType is a "string" type.
@@ -263,3 +392,16 @@ type SySchedArgs struct {
 	// CR name of the default profile for all system calls
 	DefaultProfileName *string `json:"defaultProfileName,omitempty"`
 }
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +k8s:defaulter-gen=true
+
+// DiskIOAwareArgs holds arguments used to configure DiskIOAware plugin.
+type DiskIOAwareArgs struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Whether to favor the nodes with the most (MostAllocated) or least (LeastAllocated) disk IO allocated
+	ScoringStrategy ScoringStrategyType `json:"scoringStrategy,omitempty"`
+	// Name of the estimator normalizing the disk IO requests of the pods to the capacity of the devices
+	Estimator *string `json:"estimator,omitempty"`
+}
@@ -50,6 +50,36 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*DiskIOAwareArgs)(nil), (*config.DiskIOAwareArgs)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1_DiskIOAwareArgs_To_config_DiskIOAwareArgs(a.(*DiskIOAwareArgs), b.(*config.DiskIOAwareArgs), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*config.DiskIOAwareArgs)(nil), (*DiskIOAwareArgs)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_config_DiskIOAwareArgs_To_v1_DiskIOAwareArgs(a.(*config.DiskIOAwareArgs), b.(*DiskIOAwareArgs), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*ForecastSpec)(nil), (*config.ForecastSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1_ForecastSpec_To_config_ForecastSpec(a.(*ForecastSpec), b.(*config.ForecastSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*config.ForecastSpec)(nil), (*ForecastSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_config_ForecastSpec_To_v1_ForecastSpec(a.(*config.ForecastSpec), b.(*ForecastSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*LoadAwareFilterArgs)(nil), (*config.LoadAwareFilterArgs)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1_LoadAwareFilterArgs_To_config_LoadAwareFilterArgs(a.(*LoadAwareFilterArgs), b.(*config.LoadAwareFilterArgs), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*config.LoadAwareFilterArgs)(nil), (*LoadAwareFilterArgs)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_config_LoadAwareFilterArgs_To_v1_LoadAwareFilterArgs(a.(*config.LoadAwareFilterArgs), b.(*LoadAwareFilterArgs), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*LoadVariationRiskBalancingArgs)(nil), (*config.LoadVariationRiskBalancingArgs)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1_LoadVariationRiskBalancingArgs_To_config_LoadVariationRiskBalancingArgs(a.(*LoadVariationRiskBalancingArgs), b.(*config.LoadVariationRiskBalancingArgs), scope)
 	}); err != nil {
@@ -120,6 +150,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*ResourceTarget)(nil), (*config.ResourceTarget)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1_ResourceTarget_To_config_ResourceTarget(a.(*ResourceTarget), b.(*config.ResourceTarget), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*config.ResourceTarget)(nil), (*ResourceTarget)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_config_ResourceTarget_To_v1_ResourceTarget(a.(*config.ResourceTarget), b.(*ResourceTarget), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*ScoringStrategy)(nil), (*config.ScoringStrategy)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1_ScoringStrategy_To_config_ScoringStrategy(a.(*ScoringStrategy), b.(*config.ScoringStrategy), scope)
 	}); err != nil {
@@ -190,6 +230,12 @@ func autoConvert_v1_CoschedulingArgs_To_config_CoschedulingArgs(in *Coscheduling
 	if err := metav1.Convert_Pointer_int64_To_int64(&in.PodGroupBackoffSeconds, &out.PodGroupBackoffSeconds, s); err != nil {
 		return err
 	}
+	if err := metav1.Convert_Pointer_int64_To_int64(&in.PodGroupMaxBackoffSeconds, &out.PodGroupMaxBackoffSeconds, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_Pointer_bool_To_bool(&in.EnableCapacityReservation, &out.EnableCapacityReservation, s); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -205,6 +251,12 @@ func autoConvert_config_CoschedulingArgs_To_v1_CoschedulingArgs(in *config.Cosch
 	if err := metav1.Convert_int64_To_Pointer_int64(&in.PodGroupBackoffSeconds, &out.PodGroupBackoffSeconds, s); err != nil {
 		return err
 	}
+	if err := metav1.Convert_int64_To_Pointer_int64(&in.PodGroupMaxBackoffSeconds, &out.PodGroupMaxBackoffSeconds, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_bool_To_Pointer_bool(&in.EnableCapacityReservation, &out.EnableCapacityReservation, s); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -213,6 +265,120 @@ func Convert_config_CoschedulingArgs_To_v1_CoschedulingArgs(in *config.Coschedul
 	return autoConvert_config_CoschedulingArgs_To_v1_CoschedulingArgs(in, out, s)
 }
 
+func autoConvert_v1_DiskIOAwareArgs_To_config_DiskIOAwareArgs(in *DiskIOAwareArgs, out *config.DiskIOAwareArgs, s conversion.Scope) error {
+	out.ScoringStrategy = config.ScoringStrategyType(in.ScoringStrategy)
+	if err := metav1.Convert_Pointer_string_To_string(&in.Estimator, &out.Estimator, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_v1_DiskIOAwareArgs_To_config_DiskIOAwareArgs is an autogenerated conversion function.
+func Convert_v1_DiskIOAwareArgs_To_config_DiskIOAwareArgs(in *DiskIOAwareArgs, out *config.DiskIOAwareArgs, s conversion.Scope) error {
+	return autoConvert_v1_DiskIOAwareArgs_To_config_DiskIOAwareArgs(in, out, s)
+}
+
+func autoConvert_config_DiskIOAwareArgs_To_v1_DiskIOAwareArgs(in *config.DiskIOAwareArgs, out *DiskIOAwareArgs, s conversion.Scope) error {
+	out.ScoringStrategy = ScoringStrategyType(in.ScoringStrategy)
+	if err := metav1.Convert_string_To_Pointer_string(&in.Estimator, &out.Estimator, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_config_DiskIOAwareArgs_To_v1_DiskIOAwareArgs is an autogenerated conversion function.
+func Convert_config_DiskIOAwareArgs_To_v1_DiskIOAwareArgs(in *config.DiskIOAwareArgs, out *DiskIOAwareArgs, s conversion.Scope) error {
+	return autoConvert_config_DiskIOAwareArgs_To_v1_DiskIOAwareArgs(in, out, s)
+}
+
+func autoConvert_v1_ForecastSpec_To_config_ForecastSpec(in *ForecastSpec, out *config.ForecastSpec, s conversion.Scope) error {
+	out.Model = config.ForecastModel(in.Model)
+	if err := metav1.Convert_Pointer_float64_To_float64(&in.Alpha, &out.Alpha, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_Pointer_float64_To_float64(&in.Beta, &out.Beta, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_Pointer_float64_To_float64(&in.Gamma, &out.Gamma, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_Pointer_int64_To_int64(&in.SeasonPeriodSeconds, &out.SeasonPeriodSeconds, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_Pointer_int64_To_int64(&in.HorizonSeconds, &out.HorizonSeconds, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_v1_ForecastSpec_To_config_ForecastSpec is an autogenerated conversion function.
+func Convert_v1_ForecastSpec_To_config_ForecastSpec(in *ForecastSpec, out *config.ForecastSpec, s conversion.Scope) error {
+	return autoConvert_v1_ForecastSpec_To_config_ForecastSpec(in, out, s)
+}
+
+func autoConvert_config_ForecastSpec_To_v1_ForecastSpec(in *config.ForecastSpec, out *ForecastSpec, s conversion.Scope) error {
+	out.Model = ForecastModel(in.Model)
+	if err := metav1.Convert_float64_To_Pointer_float64(&in.Alpha, &out.Alpha, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_float64_To_Pointer_float64(&in.Beta, &out.Beta, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_float64_To_Pointer_float64(&in.Gamma, &out.Gamma, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_int64_To_Pointer_int64(&in.SeasonPeriodSeconds, &out.SeasonPeriodSeconds, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_int64_To_Pointer_int64(&in.HorizonSeconds, &out.HorizonSeconds, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_config_ForecastSpec_To_v1_ForecastSpec is an autogenerated conversion function.
+func Convert_config_ForecastSpec_To_v1_ForecastSpec(in *config.ForecastSpec, out *ForecastSpec, s conversion.Scope) error {
+	return autoConvert_config_ForecastSpec_To_v1_ForecastSpec(in, out, s)
+}
+
+func autoConvert_v1_LoadAwareFilterArgs_To_config_LoadAwareFilterArgs(in *LoadAwareFilterArgs, out *config.LoadAwareFilterArgs, s conversion.Scope) error {
+	if err := Convert_v1_TrimaranSpec_To_config_TrimaranSpec(&in.TrimaranSpec, &out.TrimaranSpec, s); err != nil {
+		return err
+	}
+	out.DefaultRequests = *(*corev1.ResourceList)(unsafe.Pointer(&in.DefaultRequests))
+	if err := metav1.Convert_Pointer_string_To_string(&in.DefaultRequestsMultiplier, &out.DefaultRequestsMultiplier, s); err != nil {
+		return err
+	}
+	out.ResourceThresholds = *(*map[corev1.ResourceName]int64)(unsafe.Pointer(&in.ResourceThresholds))
+	out.ExemptPriorityClasses = *(*[]string)(unsafe.Pointer(&in.ExemptPriorityClasses))
+	out.ExemptNamespaces = *(*[]string)(unsafe.Pointer(&in.ExemptNamespaces))
+	return nil
+}
+
+// Convert_v1_LoadAwareFilterArgs_To_config_LoadAwareFilterArgs is an autogenerated conversion function.
+func Convert_v1_LoadAwareFilterArgs_To_config_LoadAwareFilterArgs(in *LoadAwareFilterArgs, out *config.LoadAwareFilterArgs, s conversion.Scope) error {
+	return autoConvert_v1_LoadAwareFilterArgs_To_config_LoadAwareFilterArgs(in, out, s)
+}
+
+func autoConvert_config_LoadAwareFilterArgs_To_v1_LoadAwareFilterArgs(in *config.LoadAwareFilterArgs, out *LoadAwareFilterArgs, s conversion.Scope) error {
+	if err := Convert_config_TrimaranSpec_To_v1_TrimaranSpec(&in.TrimaranSpec, &out.TrimaranSpec, s); err != nil {
+		return err
+	}
+	out.DefaultRequests = *(*corev1.ResourceList)(unsafe.Pointer(&in.DefaultRequests))
+	if err := metav1.Convert_string_To_Pointer_string(&in.DefaultRequestsMultiplier, &out.DefaultRequestsMultiplier, s); err != nil {
+		return err
+	}
+	out.ResourceThresholds = *(*map[corev1.ResourceName]int64)(unsafe.Pointer(&in.ResourceThresholds))
+	out.ExemptPriorityClasses = *(*[]string)(unsafe.Pointer(&in.ExemptPriorityClasses))
+	out.ExemptNamespaces = *(*[]string)(unsafe.Pointer(&in.ExemptNamespaces))
+	return nil
+}
+
+// Convert_config_LoadAwareFilterArgs_To_v1_LoadAwareFilterArgs is an autogenerated conversion function.
+func Convert_config_LoadAwareFilterArgs_To_v1_LoadAwareFilterArgs(in *config.LoadAwareFilterArgs, out *LoadAwareFilterArgs, s conversion.Scope) error {
+	return autoConvert_config_LoadAwareFilterArgs_To_v1_LoadAwareFilterArgs(in, out, s)
+}
+
 func autoConvert_v1_LoadVariationRiskBalancingArgs_To_config_LoadVariationRiskBalancingArgs(in *LoadVariationRiskBalancingArgs, out *config.LoadVariationRiskBalancingArgs, s conversion.Scope) error {
 	if err := Convert_v1_TrimaranSpec_To_config_TrimaranSpec(&in.TrimaranSpec, &out.TrimaranSpec, s); err != nil {
 		return err
@@ -223,6 +389,15 @@ func autoConvert_v1_LoadVariationRiskBalancingArgs_To_config_LoadVariationRiskBa
 	if err := metav1.Convert_Pointer_float64_To_float64(&in.SafeVarianceSensitivity, &out.SafeVarianceSensitivity, s); err != nil {
 		return err
 	}
+	if in.Forecast != nil {
+		in, out := &in.Forecast, &out.Forecast
+		*out = new(config.ForecastSpec)
+		if err := Convert_v1_ForecastSpec_To_config_ForecastSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Forecast = nil
+	}
 	return nil
 }
 
@@ -241,6 +416,15 @@ func autoConvert_config_LoadVariationRiskBalancingArgs_To_v1_LoadVariationRiskBa
 	if err := metav1.Convert_float64_To_Pointer_float64(&in.SafeVarianceSensitivity, &out.SafeVarianceSensitivity, s); err != nil {
 		return err
 	}
+	if in.Forecast != nil {
+		in, out := &in.Forecast, &out.Forecast
+		*out = new(ForecastSpec)
+		if err := Convert_config_ForecastSpec_To_v1_ForecastSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Forecast = nil
+	}
 	return nil
 }
 
@@ -257,6 +441,17 @@ func autoConvert_v1_LowRiskOverCommitmentArgs_To_config_LowRiskOverCommitmentArg
 		return err
 	}
 	out.RiskLimitWeights = *(*map[corev1.ResourceName]float64)(unsafe.Pointer(&in.RiskLimitWeights))
+	if in.Forecast != nil {
+		in, out := &in.Forecast, &out.Forecast
+		*out = new(config.ForecastSpec)
+		if err := Convert_v1_ForecastSpec_To_config_ForecastSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Forecast = nil
+	}
+	out.RiskAggregation = config.RiskAggregationType(in.RiskAggregation)
+	out.RiskAggregationWeights = *(*map[corev1.ResourceName]float64)(unsafe.Pointer(&in.RiskAggregationWeights))
 	return nil
 }
 
@@ -273,6 +468,17 @@ func autoConvert_config_LowRiskOverCommitmentArgs_To_v1_LowRiskOverCommitmentArg
 		return err
 	}
 	out.RiskLimitWeights = *(*map[corev1.ResourceName]float64)(unsafe.Pointer(&in.RiskLimitWeights))
+	if in.Forecast != nil {
+		in, out := &in.Forecast, &out.Forecast
+		*out = new(ForecastSpec)
+		if err := Convert_config_ForecastSpec_To_v1_ForecastSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Forecast = nil
+	}
+	out.RiskAggregation = RiskAggregationType(in.RiskAggregation)
+	out.RiskAggregationWeights = *(*map[corev1.ResourceName]float64)(unsafe.Pointer(&in.RiskAggregationWeights))
 	return nil
 }
 
@@ -447,6 +653,38 @@ func Convert_config_PreemptionTolerationArgs_To_v1_PreemptionTolerationArgs(in *
 	return autoConvert_config_PreemptionTolerationArgs_To_v1_PreemptionTolerationArgs(in, out, s)
 }
 
+func autoConvert_v1_ResourceTarget_To_config_ResourceTarget(in *ResourceTarget, out *config.ResourceTarget, s conversion.Scope) error {
+	out.Name = corev1.ResourceName(in.Name)
+	if err := metav1.Convert_Pointer_int64_To_int64(&in.TargetUtilization, &out.TargetUtilization, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_Pointer_int64_To_int64(&in.Weight, &out.Weight, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_v1_ResourceTarget_To_config_ResourceTarget is an autogenerated conversion function.
+func Convert_v1_ResourceTarget_To_config_ResourceTarget(in *ResourceTarget, out *config.ResourceTarget, s conversion.Scope) error {
+	return autoConvert_v1_ResourceTarget_To_config_ResourceTarget(in, out, s)
+}
+
+func autoConvert_config_ResourceTarget_To_v1_ResourceTarget(in *config.ResourceTarget, out *ResourceTarget, s conversion.Scope) error {
+	out.Name = corev1.ResourceName(in.Name)
+	if err := metav1.Convert_int64_To_Pointer_int64(&in.TargetUtilization, &out.TargetUtilization, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_int64_To_Pointer_int64(&in.Weight, &out.Weight, s); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Convert_config_ResourceTarget_To_v1_ResourceTarget is an autogenerated conversion function.
+func Convert_config_ResourceTarget_To_v1_ResourceTarget(in *config.ResourceTarget, out *ResourceTarget, s conversion.Scope) error {
+	return autoConvert_config_ResourceTarget_To_v1_ResourceTarget(in, out, s)
+}
+
 func autoConvert_v1_ScoringStrategy_To_config_ScoringStrategy(in *ScoringStrategy, out *config.ScoringStrategy, s conversion.Scope) error {
 	out.Type = config.ScoringStrategyType(in.Type)
 	out.Resources = *(*[]apisconfig.ResourceSpec)(unsafe.Pointer(&in.Resources))
@@ -510,6 +748,17 @@ func autoConvert_v1_TargetLoadPackingArgs_To_config_TargetLoadPackingArgs(in *Ta
 	if err := metav1.Convert_Pointer_int64_To_int64(&in.TargetUtilization, &out.TargetUtilization, s); err != nil {
 		return err
 	}
+	if in.ResourceTargets != nil {
+		in, out := &in.ResourceTargets, &out.ResourceTargets
+		*out = make([]config.ResourceTarget, len(*in))
+		for i := range *in {
+			if err := Convert_v1_ResourceTarget_To_config_ResourceTarget(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.ResourceTargets = nil
+	}
 	return nil
 }
 
@@ -529,6 +778,17 @@ func autoConvert_config_TargetLoadPackingArgs_To_v1_TargetLoadPackingArgs(in *co
 	if err := metav1.Convert_int64_To_Pointer_int64(&in.TargetUtilization, &out.TargetUtilization, s); err != nil {
 		return err
 	}
+	if in.ResourceTargets != nil {
+		in, out := &in.ResourceTargets, &out.ResourceTargets
+		*out = make([]ResourceTarget, len(*in))
+		for i := range *in {
+			if err := Convert_config_ResourceTarget_To_v1_ResourceTarget(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.ResourceTargets = nil
+	}
 	return nil
 }
 
@@ -564,6 +824,29 @@ func autoConvert_v1_TrimaranSpec_To_config_TrimaranSpec(in *TrimaranSpec, out *c
 	if err := metav1.Convert_Pointer_string_To_string(&in.WatcherAddress, &out.WatcherAddress, s); err != nil {
 		return err
 	}
+	if err := metav1.Convert_Pointer_int64_To_int64(&in.MetricsStalenessSeconds, &out.MetricsStalenessSeconds, s); err != nil {
+		return err
+	}
+	out.FallbackMode = config.FallbackMode(in.FallbackMode)
+	if err := metav1.Convert_Pointer_int64_To_int64(&in.MetricsUpdateIntervalSeconds, &out.MetricsUpdateIntervalSeconds, s); err != nil {
+		return err
+	}
+	out.MetricsIngestion = config.MetricsIngestionMode(in.MetricsIngestion)
+	if err := metav1.Convert_Pointer_string_To_string(&in.MetricsPushAddress, &out.MetricsPushAddress, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_Pointer_string_To_string(&in.MetricsPushCertFile, &out.MetricsPushCertFile, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_Pointer_string_To_string(&in.MetricsPushKeyFile, &out.MetricsPushKeyFile, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_Pointer_string_To_string(&in.MetricsPushTokenFile, &out.MetricsPushTokenFile, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_Pointer_string_To_string(&in.MetricsPushClientCAFile, &out.MetricsPushClientCAFile, s); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -579,6 +862,29 @@ func autoConvert_config_TrimaranSpec_To_v1_TrimaranSpec(in *config.TrimaranSpec,
 	if err := metav1.Convert_string_To_Pointer_string(&in.WatcherAddress, &out.WatcherAddress, s); err != nil {
 		return err
 	}
+	if err := metav1.Convert_int64_To_Pointer_int64(&in.MetricsStalenessSeconds, &out.MetricsStalenessSeconds, s); err != nil {
+		return err
+	}
+	out.FallbackMode = FallbackMode(in.FallbackMode)
+	if err := metav1.Convert_int64_To_Pointer_int64(&in.MetricsUpdateIntervalSeconds, &out.MetricsUpdateIntervalSeconds, s); err != nil {
+		return err
+	}
+	out.MetricsIngestion = MetricsIngestionMode(in.MetricsIngestion)
+	if err := metav1.Convert_string_To_Pointer_string(&in.MetricsPushAddress, &out.MetricsPushAddress, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_string_To_Pointer_string(&in.MetricsPushCertFile, &out.MetricsPushCertFile, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_string_To_Pointer_string(&in.MetricsPushKeyFile, &out.MetricsPushKeyFile, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_string_To_Pointer_string(&in.MetricsPushTokenFile, &out.MetricsPushTokenFile, s); err != nil {
+		return err
+	}
+	if err := metav1.Convert_string_To_Pointer_string(&in.MetricsPushClientCAFile, &out.MetricsPushClientCAFile, s); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -41,6 +41,16 @@ func (in *CoschedulingArgs) DeepCopyInto(out *CoschedulingArgs) {
 		*out = new(int64)
 		**out = **in
 	}
+	if in.PodGroupMaxBackoffSeconds != nil {
+		in, out := &in.PodGroupMaxBackoffSeconds, &out.PodGroupMaxBackoffSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.EnableCapacityReservation != nil {
+		in, out := &in.EnableCapacityReservation, &out.EnableCapacityReservation
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
@@ -62,6 +72,132 @@ func (in *CoschedulingArgs) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskIOAwareArgs) DeepCopyInto(out *DiskIOAwareArgs) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	if in.Estimator != nil {
+		in, out := &in.Estimator, &out.Estimator
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskIOAwareArgs.
+func (in *DiskIOAwareArgs) DeepCopy() *DiskIOAwareArgs {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskIOAwareArgs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DiskIOAwareArgs) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ForecastSpec) DeepCopyInto(out *ForecastSpec) {
+	*out = *in
+	if in.Alpha != nil {
+		in, out := &in.Alpha, &out.Alpha
+		*out = new(float64)
+		**out = **in
+	}
+	if in.Beta != nil {
+		in, out := &in.Beta, &out.Beta
+		*out = new(float64)
+		**out = **in
+	}
+	if in.Gamma != nil {
+		in, out := &in.Gamma, &out.Gamma
+		*out = new(float64)
+		**out = **in
+	}
+	if in.SeasonPeriodSeconds != nil {
+		in, out := &in.SeasonPeriodSeconds, &out.SeasonPeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.HorizonSeconds != nil {
+		in, out := &in.HorizonSeconds, &out.HorizonSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ForecastSpec.
+func (in *ForecastSpec) DeepCopy() *ForecastSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ForecastSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadAwareFilterArgs) DeepCopyInto(out *LoadAwareFilterArgs) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.TrimaranSpec.DeepCopyInto(&out.TrimaranSpec)
+	if in.DefaultRequests != nil {
+		in, out := &in.DefaultRequests, &out.DefaultRequests
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.DefaultRequestsMultiplier != nil {
+		in, out := &in.DefaultRequestsMultiplier, &out.DefaultRequestsMultiplier
+		*out = new(string)
+		**out = **in
+	}
+	if in.ResourceThresholds != nil {
+		in, out := &in.ResourceThresholds, &out.ResourceThresholds
+		*out = make(map[corev1.ResourceName]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ExemptPriorityClasses != nil {
+		in, out := &in.ExemptPriorityClasses, &out.ExemptPriorityClasses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExemptNamespaces != nil {
+		in, out := &in.ExemptNamespaces, &out.ExemptNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadAwareFilterArgs.
+func (in *LoadAwareFilterArgs) DeepCopy() *LoadAwareFilterArgs {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadAwareFilterArgs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LoadAwareFilterArgs) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LoadVariationRiskBalancingArgs) DeepCopyInto(out *LoadVariationRiskBalancingArgs) {
 	*out = *in
@@ -77,6 +213,11 @@ func (in *LoadVariationRiskBalancingArgs) DeepCopyInto(out *LoadVariationRiskBal
 		*out = new(float64)
 		**out = **in
 	}
+	if in.Forecast != nil {
+		in, out := &in.Forecast, &out.Forecast
+		*out = new(ForecastSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -115,6 +256,18 @@ func (in *LowRiskOverCommitmentArgs) DeepCopyInto(out *LowRiskOverCommitmentArgs
 			(*out)[key] = val
 		}
 	}
+	if in.Forecast != nil {
+		in, out := &in.Forecast, &out.Forecast
+		*out = new(ForecastSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RiskAggregationWeights != nil {
+		in, out := &in.RiskAggregationWeights, &out.RiskAggregationWeights
+		*out = make(map[corev1.ResourceName]float64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -343,6 +496,32 @@ func (in *PreemptionTolerationArgs) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceTarget) DeepCopyInto(out *ResourceTarget) {
+	*out = *in
+	if in.TargetUtilization != nil {
+		in, out := &in.TargetUtilization, &out.TargetUtilization
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceTarget.
+func (in *ResourceTarget) DeepCopy() *ResourceTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScoringStrategy) DeepCopyInto(out *ScoringStrategy) {
 	*out = *in
@@ -421,6 +600,13 @@ func (in *TargetLoadPackingArgs) DeepCopyInto(out *TargetLoadPackingArgs) {
 		*out = new(int64)
 		**out = **in
 	}
+	if in.ResourceTargets != nil {
+		in, out := &in.ResourceTargets, &out.ResourceTargets
+		*out = make([]ResourceTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -481,6 +667,41 @@ func (in *TrimaranSpec) DeepCopyInto(out *TrimaranSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.MetricsStalenessSeconds != nil {
+		in, out := &in.MetricsStalenessSeconds, &out.MetricsStalenessSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MetricsUpdateIntervalSeconds != nil {
+		in, out := &in.MetricsUpdateIntervalSeconds, &out.MetricsUpdateIntervalSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MetricsPushAddress != nil {
+		in, out := &in.MetricsPushAddress, &out.MetricsPushAddress
+		*out = new(string)
+		**out = **in
+	}
+	if in.MetricsPushCertFile != nil {
+		in, out := &in.MetricsPushCertFile, &out.MetricsPushCertFile
+		*out = new(string)
+		**out = **in
+	}
+	if in.MetricsPushKeyFile != nil {
+		in, out := &in.MetricsPushKeyFile, &out.MetricsPushKeyFile
+		*out = new(string)
+		**out = **in
+	}
+	if in.MetricsPushTokenFile != nil {
+		in, out := &in.MetricsPushTokenFile, &out.MetricsPushTokenFile
+		*out = new(string)
+		**out = **in
+	}
+	if in.MetricsPushClientCAFile != nil {
+		in, out := &in.MetricsPushClientCAFile, &out.MetricsPushClientCAFile
+		*out = new(string)
+		**out = **in
+	}
 	return
 }
 
@@ -30,6 +30,8 @@ import (
 // All generated defaulters are covering - they call all nested defaulters.
 func RegisterDefaults(scheme *runtime.Scheme) error {
 	scheme.AddTypeDefaultingFunc(&CoschedulingArgs{}, func(obj interface{}) { SetObjectDefaults_CoschedulingArgs(obj.(*CoschedulingArgs)) })
+	scheme.AddTypeDefaultingFunc(&DiskIOAwareArgs{}, func(obj interface{}) { SetObjectDefaults_DiskIOAwareArgs(obj.(*DiskIOAwareArgs)) })
+	scheme.AddTypeDefaultingFunc(&LoadAwareFilterArgs{}, func(obj interface{}) { SetObjectDefaults_LoadAwareFilterArgs(obj.(*LoadAwareFilterArgs)) })
 	scheme.AddTypeDefaultingFunc(&LoadVariationRiskBalancingArgs{}, func(obj interface{}) {
 		SetObjectDefaults_LoadVariationRiskBalancingArgs(obj.(*LoadVariationRiskBalancingArgs))
 	})
@@ -52,6 +54,14 @@ func SetObjectDefaults_CoschedulingArgs(in *CoschedulingArgs) {
 	SetDefaults_CoschedulingArgs(in)
 }
 
+func SetObjectDefaults_DiskIOAwareArgs(in *DiskIOAwareArgs) {
+	SetDefaults_DiskIOAwareArgs(in)
+}
+
+func SetObjectDefaults_LoadAwareFilterArgs(in *LoadAwareFilterArgs) {
+	SetDefaults_LoadAwareFilterArgs(in)
+}
+
 func SetObjectDefaults_LoadVariationRiskBalancingArgs(in *LoadVariationRiskBalancingArgs) {
 	SetDefaults_LoadVariationRiskBalancingArgs(in)
 }
@@ -17,6 +17,9 @@ limitations under the License.
 package validation
 
 import (
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
@@ -30,6 +33,34 @@ var validScoringStrategy = sets.NewString(
 	string(config.LeastNUMANodes),
 )
 
+var validTrimaranResources = sets.NewString(
+	string(v1.ResourceCPU),
+	string(v1.ResourceMemory),
+	string(config.ResourceNetwork),
+	string(config.ResourceDisk),
+)
+
+var validDiskIOScoringStrategies = sets.NewString(
+	string(config.MostAllocated),
+	string(config.LeastAllocated),
+)
+
+var validRiskResources = sets.NewString(
+	string(v1.ResourceCPU),
+	string(v1.ResourceMemory),
+	string(v1.ResourceEphemeralStorage),
+)
+
+var validRiskAggregations = sets.NewString(
+	string(config.MaxRisk),
+	string(config.WeightedMeanRisk),
+)
+
+var validForecastModels = sets.NewString(
+	string(config.EWMAForecast),
+	string(config.HoltWintersForecast),
+)
+
 func ValidateNodeResourceTopologyMatchArgs(path *field.Path, args *config.NodeResourceTopologyMatchArgs) error {
 	var allErrs field.ErrorList
 	scoringStrategyTypePath := path.Child("scoringStrategy.type")
@@ -46,3 +77,118 @@ func validateScoringStrategyType(scoringStrategy config.ScoringStrategyType, pat
 	}
 	return nil
 }
+
+func ValidateTargetLoadPackingArgs(path *field.Path, args *config.TargetLoadPackingArgs) error {
+	var allErrs field.ErrorList
+	resourceTargetsPath := path.Child("resourceTargets")
+	names := sets.NewString()
+	for i, target := range args.ResourceTargets {
+		targetPath := resourceTargetsPath.Index(i)
+		if !validTrimaranResources.Has(string(target.Name)) {
+			allErrs = append(allErrs, field.NotSupported(targetPath.Child("name"), target.Name, validTrimaranResources.List()))
+		} else if names.Has(string(target.Name)) {
+			allErrs = append(allErrs, field.Duplicate(targetPath.Child("name"), target.Name))
+		}
+		names.Insert(string(target.Name))
+		if target.TargetUtilization <= 0 || target.TargetUtilization > 100 {
+			allErrs = append(allErrs, field.Invalid(targetPath.Child("targetUtilization"), target.TargetUtilization, "must be in the range (0, 100]"))
+		}
+		if target.Weight <= 0 {
+			allErrs = append(allErrs, field.Invalid(targetPath.Child("weight"), target.Weight, "must be positive"))
+		}
+	}
+
+	return allErrs.ToAggregate()
+}
+
+func ValidateLoadAwareFilterArgs(path *field.Path, args *config.LoadAwareFilterArgs) error {
+	var allErrs field.ErrorList
+	resourceThresholdsPath := path.Child("resourceThresholds")
+	for name, threshold := range args.ResourceThresholds {
+		thresholdPath := resourceThresholdsPath.Key(string(name))
+		if !validTrimaranResources.Has(string(name)) {
+			allErrs = append(allErrs, field.NotSupported(thresholdPath, name, validTrimaranResources.List()))
+		} else if threshold <= 0 || threshold > 100 {
+			allErrs = append(allErrs, field.Invalid(thresholdPath, threshold, "must be in the range (0, 100]"))
+		}
+	}
+
+	return allErrs.ToAggregate()
+}
+
+func ValidateLoadVariationRiskBalancingArgs(path *field.Path, args *config.LoadVariationRiskBalancingArgs) error {
+	var allErrs field.ErrorList
+	if args.Forecast != nil {
+		allErrs = append(allErrs, validateForecastSpec(path.Child("forecast"), args.Forecast)...)
+	}
+
+	return allErrs.ToAggregate()
+}
+
+func ValidateLowRiskOverCommitmentArgs(path *field.Path, args *config.LowRiskOverCommitmentArgs) error {
+	var allErrs field.ErrorList
+	riskLimitWeightsPath := path.Child("riskLimitWeights")
+	for name := range args.RiskLimitWeights {
+		if !validRiskResources.Has(string(name)) && !isExtendedResourceName(name) {
+			allErrs = append(allErrs, field.NotSupported(riskLimitWeightsPath.Key(string(name)), name,
+				append(validRiskResources.List(), "<extended resource>")))
+		}
+	}
+	// an empty aggregation stands for the default one
+	if args.RiskAggregation != "" && !validRiskAggregations.Has(string(args.RiskAggregation)) {
+		allErrs = append(allErrs, field.NotSupported(path.Child("riskAggregation"), args.RiskAggregation, validRiskAggregations.List()))
+	}
+	riskAggregationWeightsPath := path.Child("riskAggregationWeights")
+	for name, weight := range args.RiskAggregationWeights {
+		if weight < 0 {
+			allErrs = append(allErrs, field.Invalid(riskAggregationWeightsPath.Key(string(name)), weight, "must not be negative"))
+		}
+	}
+	if args.Forecast != nil {
+		allErrs = append(allErrs, validateForecastSpec(path.Child("forecast"), args.Forecast)...)
+	}
+
+	return allErrs.ToAggregate()
+}
+
+func validateForecastSpec(path *field.Path, spec *config.ForecastSpec) field.ErrorList {
+	var allErrs field.ErrorList
+	if !validForecastModels.Has(string(spec.Model)) {
+		allErrs = append(allErrs, field.NotSupported(path.Child("model"), spec.Model, validForecastModels.List()))
+	}
+	if spec.Alpha <= 0 || spec.Alpha > 1 {
+		allErrs = append(allErrs, field.Invalid(path.Child("alpha"), spec.Alpha, "must be in the range (0, 1]"))
+	}
+	if spec.Beta < 0 || spec.Beta > 1 {
+		allErrs = append(allErrs, field.Invalid(path.Child("beta"), spec.Beta, "must be in the range [0, 1]"))
+	}
+	if spec.Gamma < 0 || spec.Gamma > 1 {
+		allErrs = append(allErrs, field.Invalid(path.Child("gamma"), spec.Gamma, "must be in the range [0, 1]"))
+	}
+	if spec.SeasonPeriodSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("seasonPeriodSeconds"), spec.SeasonPeriodSeconds, "must not be negative"))
+	}
+	if spec.HorizonSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("horizonSeconds"), spec.HorizonSeconds, "must not be negative"))
+	}
+	return allErrs
+}
+
+func ValidateDiskIOAwareArgs(path *field.Path, args *config.DiskIOAwareArgs) error {
+	var allErrs field.ErrorList
+	if !validDiskIOScoringStrategies.Has(string(args.ScoringStrategy)) {
+		allErrs = append(allErrs, field.NotSupported(path.Child("scoringStrategy"), args.ScoringStrategy, validDiskIOScoringStrategies.List()))
+	}
+	if args.Estimator == "" {
+		allErrs = append(allErrs, field.Required(path.Child("estimator"), "the name of an estimator is required"))
+	}
+
+	return allErrs.ToAggregate()
+}
+
+// isExtendedResourceName : whether the resource is an extended resource, fully qualified outside of the
+// kubernetes.io domain, such as nvidia.com/gpu
+func isExtendedResourceName(name v1.ResourceName) bool {
+	return strings.Contains(string(name), "/") && !strings.Contains(string(name), v1.ResourceDefaultNamespacePrefix) &&
+		!strings.HasPrefix(string(name), v1.DefaultResourceRequestsPrefix)
+}
@@ -21,6 +21,8 @@ import (
 	"strings"
 	"testing"
 
+	v1 "k8s.io/api/core/v1"
+
 	"sigs.k8s.io/scheduler-plugins/apis/config"
 )
 
@@ -67,3 +69,311 @@ func TestValidateNodeResourceTopologyMatchArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateTargetLoadPackingArgs(t *testing.T) {
+	testCases := []struct {
+		args        *config.TargetLoadPackingArgs
+		expectedErr error
+		description string
+	}{
+		{
+			description: "no resource targets",
+			args:        &config.TargetLoadPackingArgs{TargetUtilization: 40},
+		},
+		{
+			description: "correct resource targets",
+			args: &config.TargetLoadPackingArgs{
+				ResourceTargets: []config.ResourceTarget{
+					{Name: v1.ResourceCPU, TargetUtilization: 40, Weight: 2},
+					{Name: v1.ResourceMemory, TargetUtilization: 60, Weight: 1},
+					{Name: config.ResourceNetwork, TargetUtilization: 80, Weight: 1},
+				},
+			},
+		},
+		{
+			description: "unsupported resource",
+			args: &config.TargetLoadPackingArgs{
+				ResourceTargets: []config.ResourceTarget{
+					{Name: "nvidia.com/gpu", TargetUtilization: 40, Weight: 1},
+				},
+			},
+			expectedErr: fmt.Errorf("resourceTargets[0].name: Unsupported value:"),
+		},
+		{
+			description: "duplicate resource",
+			args: &config.TargetLoadPackingArgs{
+				ResourceTargets: []config.ResourceTarget{
+					{Name: v1.ResourceCPU, TargetUtilization: 40, Weight: 1},
+					{Name: v1.ResourceCPU, TargetUtilization: 50, Weight: 1},
+				},
+			},
+			expectedErr: fmt.Errorf("resourceTargets[1].name: Duplicate value:"),
+		},
+		{
+			description: "target utilization out of range",
+			args: &config.TargetLoadPackingArgs{
+				ResourceTargets: []config.ResourceTarget{
+					{Name: v1.ResourceMemory, TargetUtilization: 120, Weight: 1},
+				},
+			},
+			expectedErr: fmt.Errorf("resourceTargets[0].targetUtilization: Invalid value:"),
+		},
+		{
+			description: "weight not positive",
+			args: &config.TargetLoadPackingArgs{
+				ResourceTargets: []config.ResourceTarget{
+					{Name: v1.ResourceMemory, TargetUtilization: 40, Weight: 0},
+				},
+			},
+			expectedErr: fmt.Errorf("resourceTargets[0].weight: Invalid value:"),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			err := ValidateTargetLoadPackingArgs(nil, testCase.args)
+			if testCase.expectedErr != nil {
+				if err == nil {
+					t.Fatalf("expected err to equal %v not nil", testCase.expectedErr)
+				}
+
+				if !strings.Contains(err.Error(), testCase.expectedErr.Error()) {
+					t.Errorf("expected err to contain %s in error message: %s", testCase.expectedErr.Error(), err.Error())
+				}
+			}
+			if testCase.expectedErr == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateLoadAwareFilterArgs(t *testing.T) {
+	testCases := []struct {
+		args        *config.LoadAwareFilterArgs
+		expectedErr error
+		description string
+	}{
+		{
+			description: "correct resource thresholds",
+			args: &config.LoadAwareFilterArgs{
+				ResourceThresholds: map[v1.ResourceName]int64{
+					v1.ResourceCPU:      90,
+					v1.ResourceMemory:   100,
+					config.ResourceDisk: 80,
+				},
+			},
+		},
+		{
+			description: "unsupported resource",
+			args: &config.LoadAwareFilterArgs{
+				ResourceThresholds: map[v1.ResourceName]int64{"nvidia.com/gpu": 90},
+			},
+			expectedErr: fmt.Errorf("resourceThresholds[nvidia.com/gpu]: Unsupported value:"),
+		},
+		{
+			description: "threshold out of range",
+			args: &config.LoadAwareFilterArgs{
+				ResourceThresholds: map[v1.ResourceName]int64{v1.ResourceCPU: 0},
+			},
+			expectedErr: fmt.Errorf("resourceThresholds[cpu]: Invalid value:"),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			err := ValidateLoadAwareFilterArgs(nil, testCase.args)
+			if testCase.expectedErr != nil {
+				if err == nil {
+					t.Fatalf("expected err to equal %v not nil", testCase.expectedErr)
+				}
+
+				if !strings.Contains(err.Error(), testCase.expectedErr.Error()) {
+					t.Errorf("expected err to contain %s in error message: %s", testCase.expectedErr.Error(), err.Error())
+				}
+			}
+			if testCase.expectedErr == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateLoadVariationRiskBalancingArgs(t *testing.T) {
+	validForecast := config.ForecastSpec{
+		Model:               config.HoltWintersForecast,
+		Alpha:               0.3,
+		Beta:                0.1,
+		Gamma:               0.1,
+		SeasonPeriodSeconds: 86400,
+		HorizonSeconds:      60,
+	}
+	withForecast := func(modify func(*config.ForecastSpec)) *config.LoadVariationRiskBalancingArgs {
+		forecast := validForecast
+		modify(&forecast)
+		return &config.LoadVariationRiskBalancingArgs{Forecast: &forecast}
+	}
+	testCases := []struct {
+		args        *config.LoadVariationRiskBalancingArgs
+		expectedErr error
+		description string
+	}{
+		{
+			description: "no forecast",
+			args:        &config.LoadVariationRiskBalancingArgs{},
+		},
+		{
+			description: "correct forecast",
+			args:        withForecast(func(*config.ForecastSpec) {}),
+		},
+		{
+			description: "unsupported forecast model",
+			args:        withForecast(func(f *config.ForecastSpec) { f.Model = "ARIMA" }),
+			expectedErr: fmt.Errorf("forecast.model: Unsupported value:"),
+		},
+		{
+			description: "zero alpha",
+			args:        withForecast(func(f *config.ForecastSpec) { f.Alpha = 0 }),
+			expectedErr: fmt.Errorf("forecast.alpha: Invalid value:"),
+		},
+		{
+			description: "gamma out of range",
+			args:        withForecast(func(f *config.ForecastSpec) { f.Gamma = 1.5 }),
+			expectedErr: fmt.Errorf("forecast.gamma: Invalid value:"),
+		},
+		{
+			description: "negative horizon",
+			args:        withForecast(func(f *config.ForecastSpec) { f.HorizonSeconds = -1 }),
+			expectedErr: fmt.Errorf("forecast.horizonSeconds: Invalid value:"),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			err := ValidateLoadVariationRiskBalancingArgs(nil, testCase.args)
+			if testCase.expectedErr != nil {
+				if err == nil {
+					t.Fatalf("expected err to equal %v not nil", testCase.expectedErr)
+				}
+
+				if !strings.Contains(err.Error(), testCase.expectedErr.Error()) {
+					t.Errorf("expected err to contain %s in error message: %s", testCase.expectedErr.Error(), err.Error())
+				}
+			}
+			if testCase.expectedErr == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateLowRiskOverCommitmentArgs(t *testing.T) {
+	testCases := []struct {
+		args        *config.LowRiskOverCommitmentArgs
+		expectedErr error
+		description string
+	}{
+		{
+			description: "correct args",
+			args: &config.LowRiskOverCommitmentArgs{
+				RiskLimitWeights: map[v1.ResourceName]float64{
+					v1.ResourceCPU:              0.5,
+					v1.ResourceEphemeralStorage: 0.5,
+					"nvidia.com/gpu":            0.2,
+				},
+				RiskAggregation:        config.WeightedMeanRisk,
+				RiskAggregationWeights: map[v1.ResourceName]float64{v1.ResourceMemory: 2, "nvidia.com/gpu": 0},
+			},
+		},
+		{
+			description: "default aggregation",
+			args:        &config.LowRiskOverCommitmentArgs{},
+		},
+		{
+			description: "unsupported resource",
+			args: &config.LowRiskOverCommitmentArgs{
+				RiskLimitWeights: map[v1.ResourceName]float64{v1.ResourcePods: 0.5},
+			},
+			expectedErr: fmt.Errorf("riskLimitWeights[pods]: Unsupported value:"),
+		},
+		{
+			description: "resource in the kubernetes.io domain",
+			args: &config.LowRiskOverCommitmentArgs{
+				RiskLimitWeights: map[v1.ResourceName]float64{"kubernetes.io/batch-cpu": 0.5},
+			},
+			expectedErr: fmt.Errorf("riskLimitWeights[kubernetes.io/batch-cpu]: Unsupported value:"),
+		},
+		{
+			description: "unsupported aggregation",
+			args:        &config.LowRiskOverCommitmentArgs{RiskAggregation: "Sum"},
+			expectedErr: fmt.Errorf("riskAggregation: Unsupported value:"),
+		},
+		{
+			description: "negative aggregation weight",
+			args: &config.LowRiskOverCommitmentArgs{
+				RiskAggregation:        config.WeightedMeanRisk,
+				RiskAggregationWeights: map[v1.ResourceName]float64{v1.ResourceCPU: -1},
+			},
+			expectedErr: fmt.Errorf("riskAggregationWeights[cpu]: Invalid value:"),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			err := ValidateLowRiskOverCommitmentArgs(nil, testCase.args)
+			if testCase.expectedErr != nil {
+				if err == nil {
+					t.Fatalf("expected err to equal %v not nil", testCase.expectedErr)
+				}
+
+				if !strings.Contains(err.Error(), testCase.expectedErr.Error()) {
+					t.Errorf("expected err to contain %s in error message: %s", testCase.expectedErr.Error(), err.Error())
+				}
+			}
+			if testCase.expectedErr == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateDiskIOAwareArgs(t *testing.T) {
+	testCases := []struct {
+		args        *config.DiskIOAwareArgs
+		expectedErr error
+		description string
+	}{
+		{
+			description: "correct args",
+			args:        &config.DiskIOAwareArgs{ScoringStrategy: config.MostAllocated, Estimator: "BlockSize"},
+		},
+		{
+			description: "unsupported scoring strategy",
+			args:        &config.DiskIOAwareArgs{ScoringStrategy: config.BalancedAllocation, Estimator: "BlockSize"},
+			expectedErr: fmt.Errorf("scoringStrategy: Unsupported value:"),
+		},
+		{
+			description: "no estimator",
+			args:        &config.DiskIOAwareArgs{ScoringStrategy: config.LeastAllocated},
+			expectedErr: fmt.Errorf("estimator: Required value:"),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			err := ValidateDiskIOAwareArgs(nil, testCase.args)
+			if testCase.expectedErr != nil {
+				if err == nil {
+					t.Fatalf("expected err to equal %v not nil", testCase.expectedErr)
+				}
+
+				if !strings.Contains(err.Error(), testCase.expectedErr.Error()) {
+					t.Errorf("expected err to contain %s in error message: %s", testCase.expectedErr.Error(), err.Error())
+				}
+			}
+			if testCase.expectedErr == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -52,11 +52,107 @@ func (in *CoschedulingArgs) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskIOAwareArgs) DeepCopyInto(out *DiskIOAwareArgs) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskIOAwareArgs.
+func (in *DiskIOAwareArgs) DeepCopy() *DiskIOAwareArgs {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskIOAwareArgs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DiskIOAwareArgs) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ForecastSpec) DeepCopyInto(out *ForecastSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ForecastSpec.
+func (in *ForecastSpec) DeepCopy() *ForecastSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ForecastSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadAwareFilterArgs) DeepCopyInto(out *LoadAwareFilterArgs) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.TrimaranSpec = in.TrimaranSpec
+	if in.DefaultRequests != nil {
+		in, out := &in.DefaultRequests, &out.DefaultRequests
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.ResourceThresholds != nil {
+		in, out := &in.ResourceThresholds, &out.ResourceThresholds
+		*out = make(map[v1.ResourceName]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ExemptPriorityClasses != nil {
+		in, out := &in.ExemptPriorityClasses, &out.ExemptPriorityClasses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExemptNamespaces != nil {
+		in, out := &in.ExemptNamespaces, &out.ExemptNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadAwareFilterArgs.
+func (in *LoadAwareFilterArgs) DeepCopy() *LoadAwareFilterArgs {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadAwareFilterArgs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LoadAwareFilterArgs) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LoadVariationRiskBalancingArgs) DeepCopyInto(out *LoadVariationRiskBalancingArgs) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	out.TrimaranSpec = in.TrimaranSpec
+	if in.Forecast != nil {
+		in, out := &in.Forecast, &out.Forecast
+		*out = new(ForecastSpec)
+		**out = **in
+	}
 	return
 }
 
@@ -90,6 +186,18 @@ func (in *LowRiskOverCommitmentArgs) DeepCopyInto(out *LowRiskOverCommitmentArgs
 			(*out)[key] = val
 		}
 	}
+	if in.Forecast != nil {
+		in, out := &in.Forecast, &out.Forecast
+		*out = new(ForecastSpec)
+		**out = **in
+	}
+	if in.RiskAggregationWeights != nil {
+		in, out := &in.RiskAggregationWeights, &out.RiskAggregationWeights
+		*out = make(map[v1.ResourceName]float64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -274,6 +382,22 @@ func (in *PreemptionTolerationArgs) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceTarget) DeepCopyInto(out *ResourceTarget) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceTarget.
+func (in *ResourceTarget) DeepCopy() *ResourceTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScoringStrategy) DeepCopyInto(out *ScoringStrategy) {
 	*out = *in
@@ -332,6 +456,11 @@ func (in *TargetLoadPackingArgs) DeepCopyInto(out *TargetLoadPackingArgs) {
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.ResourceTargets != nil {
+		in, out := &in.ResourceTargets, &out.ResourceTargets
+		*out = make([]ResourceTarget, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
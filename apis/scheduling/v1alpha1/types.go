@@ -55,6 +55,84 @@ type ElasticQuotaSpec struct {
 	// successfully scheduled pods.
 	// +optional
 	Max v1.ResourceList `json:"max,omitempty" protobuf:"bytes,2,rep,name=max, casttype=ResourceList,castkey=ResourceName"`
+
+	// Parent is the ElasticQuota this one is nested in. The usage of an ElasticQuota counts against the Min and
+	// Max of all its ancestors, and its pods reclaim the Min borrowed by its siblings before reclaiming from the
+	// rest of the tree.
+	// +optional
+	Parent *ElasticQuotaReference `json:"parent,omitempty" protobuf:"bytes,3,opt,name=parent"`
+
+	// NamespaceSelector selects the namespaces, in addition to its own, whose pods are subject to the ElasticQuota.
+	// A namespace with an ElasticQuota of its own isn't subject to any other; a namespace selected by several
+	// ElasticQuotas is subject to the one whose namespace comes first alphabetically.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty" protobuf:"bytes,4,opt,name=namespaceSelector"`
+
+	// Weight is the share of the capacity borrowed beyond Min the ElasticQuota is entitled to, relative to the
+	// other ElasticQuotas borrowing it. Defaults to 1.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	Weight *int32 `json:"weight,omitempty" protobuf:"varint,5,opt,name=weight"`
+
+	// ReclaimPolicy controls the preemption of the pods of the ElasticQuota when the capacity they borrow beyond
+	// Min is reclaimed by other ElasticQuotas. By default, they are preempted as soon as it's reclaimed.
+	// +optional
+	ReclaimPolicy *ReclaimPolicy `json:"reclaimPolicy,omitempty" protobuf:"bytes,6,opt,name=reclaimPolicy"`
+
+	// PriorityClassMax bounds the usage of the pods of given priority classes subject to the ElasticQuota itself,
+	// i.e. excluding the pods of its descendants. The resources missing from the Max of a priority class aren't
+	// bounded beyond the Max of the ElasticQuota.
+	// +optional
+	// +listType=map
+	// +listMapKey=priorityClassName
+	PriorityClassMax []PriorityClassResources `json:"priorityClassMax,omitempty" protobuf:"bytes,7,rep,name=priorityClassMax"`
+}
+
+// PriorityClassResources is a set of resources of the pods of a priority class.
+type PriorityClassResources struct {
+	// PriorityClassName is the name of the priority class.
+	PriorityClassName string `json:"priorityClassName" protobuf:"bytes,1,opt,name=priorityClassName"`
+
+	// Resources of the pods of the priority class.
+	// +optional
+	Resources v1.ResourceList `json:"resources,omitempty" protobuf:"bytes,2,rep,name=resources,casttype=ResourceList,castkey=ResourceName"`
+}
+
+// ReclaimPolicy controls the preemption of the pods of an ElasticQuota to reclaim the capacity they borrow.
+// It doesn't apply to the preemption of pods by more important pods of the same ElasticQuota.
+type ReclaimPolicy struct {
+	// MinRuntimeSeconds is how long a pod must have been running before it can be preempted to reclaim capacity.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MinRuntimeSeconds *int32 `json:"minRuntimeSeconds,omitempty" protobuf:"varint,1,opt,name=minRuntimeSeconds"`
+
+	// NoticeSeconds is how long a pod is notified before being preempted to reclaim capacity. The notice is
+	// the ReclaimNotice condition of the pod, whose message tells the time the pod can be preempted from.
+	// A pod still running a minute after that time is notified again before being preempted.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	NoticeSeconds *int32 `json:"noticeSeconds,omitempty" protobuf:"varint,2,opt,name=noticeSeconds"`
+
+	// MaxPreemptions is the maximum number of pods of the ElasticQuota preempted to reclaim capacity within
+	// PreemptionIntervalSeconds. Unlimited if unset.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxPreemptions *int32 `json:"maxPreemptions,omitempty" protobuf:"varint,3,opt,name=maxPreemptions"`
+
+	// PreemptionIntervalSeconds is the interval MaxPreemptions applies to. Defaults to 60.
+	// +optional
+	// +kubebuilder:default=60
+	// +kubebuilder:validation:Minimum=1
+	PreemptionIntervalSeconds *int32 `json:"preemptionIntervalSeconds,omitempty" protobuf:"varint,4,opt,name=preemptionIntervalSeconds"`
+}
+
+// ElasticQuotaReference refers to an ElasticQuota.
+type ElasticQuotaReference struct {
+	// Namespace of the ElasticQuota.
+	Namespace string `json:"namespace" protobuf:"bytes,1,opt,name=namespace"`
+
+	// Name of the ElasticQuota.
+	Name string `json:"name" protobuf:"bytes,2,opt,name=name"`
 }
 
 // ElasticQuotaStatus defines the observed use.
@@ -62,8 +140,61 @@ type ElasticQuotaStatus struct {
 	// Used is the current observed total usage of the resource in the namespace.
 	// +optional
 	Used v1.ResourceList `json:"used,omitempty" protobuf:"bytes,1,rep,name=used,casttype=ResourceList,castkey=ResourceName"`
+
+	// Borrowed is the part of Used beyond Min, borrowed from the unused Min of other ElasticQuotas.
+	// +optional
+	Borrowed v1.ResourceList `json:"borrowed,omitempty" protobuf:"bytes,2,rep,name=borrowed,casttype=ResourceList,castkey=ResourceName"`
+
+	// Lent is the part of the unused Min borrowed by the ElasticQuotas sharing the parent of the ElasticQuota, or
+	// by the other root ElasticQuotas. What they borrow is attributed to the ElasticQuotas in proportion to
+	// their unused Min.
+	// +optional
+	Lent v1.ResourceList `json:"lent,omitempty" protobuf:"bytes,3,rep,name=lent,casttype=ResourceList,castkey=ResourceName"`
+
+	// PendingDemand is the request of the unschedulable pods subject to the ElasticQuota which don't fit in the
+	// quota, i.e. which would take the ElasticQuota or one of its ancestors beyond Max, or the root ElasticQuotas
+	// beyond their aggregated Min.
+	// +optional
+	PendingDemand v1.ResourceList `json:"pendingDemand,omitempty" protobuf:"bytes,4,rep,name=pendingDemand,casttype=ResourceList,castkey=ResourceName"`
+
+	// Conditions represent the latest available observations of the ElasticQuota's usage.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" protobuf:"bytes,5,rep,name=conditions"`
+
+	// PriorityClassUsed is the usage of the running pods subject to the ElasticQuota itself, i.e. excluding the pods
+	// of its descendants, for each of their priority classes and each priority class bounded by PriorityClassMax.
+	// +optional
+	// +listType=map
+	// +listMapKey=priorityClassName
+	PriorityClassUsed []PriorityClassResources `json:"priorityClassUsed,omitempty" protobuf:"bytes,6,rep,name=priorityClassUsed"`
 }
 
+// These are the valid condition types of elasticQuotas.
+const (
+	// ElasticQuotaOverMin indicates whether the ElasticQuota uses more than its Min of some resource.
+	ElasticQuotaOverMin = "OverMin"
+
+	// ElasticQuotaAtMax indicates whether the ElasticQuota uses all of its Max of some resource.
+	ElasticQuotaAtMax = "AtMax"
+)
+
+// These are the reasons of the elasticQuota conditions.
+const (
+	// ElasticQuotaReasonBorrowing means the ElasticQuota borrows from the unused Min of other ElasticQuotas.
+	ElasticQuotaReasonBorrowing = "Borrowing"
+
+	// ElasticQuotaReasonWithinMin means the ElasticQuota uses no more than its Min.
+	ElasticQuotaReasonWithinMin = "WithinMin"
+
+	// ElasticQuotaReasonMaxReached means the ElasticQuota uses all of its Max of some resource.
+	ElasticQuotaReasonMaxReached = "MaxReached"
+
+	// ElasticQuotaReasonBelowMax means the ElasticQuota uses less than its Max.
+	ElasticQuotaReasonBelowMax = "BelowMax"
+)
+
 // +kubebuilder:object:root=true
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
@@ -102,11 +233,81 @@ const (
 	// PodGroupFinished means the `spec.minMember` pods of the pod group are successfully finished.
 	PodGroupFinished PodGroupPhase = "Finished"
 
-	// PodGroupFailed means at least one of `spec.minMember` pods have failed.
+	// PodGroupFailed means more than `spec.maxFailedMembers` pods have failed or, if unset, at least one of
+	// `spec.minMember` pods have failed.
 	PodGroupFailed PodGroupPhase = "Failed"
 
+	// PodGroupTimedOut means the pod group has been scheduling without any running pod for longer than the
+	// timeout of the controller; the controller reconciles it again once its pods run.
+	PodGroupTimedOut PodGroupPhase = "TimedOut"
+
 	// PodGroupLabel is the default label of coscheduling
 	PodGroupLabel = scheduling.GroupName + "/pod-group"
+
+	// AutoPodGroupAnnotation set to "true" on a workload makes the controller create a pod group
+	// for its pods, named after the workload.
+	AutoPodGroupAnnotation = scheduling.GroupName + "/auto-pod-group"
+)
+
+// These are the valid condition types of podGroups.
+const (
+	// PodGroupScheduled indicates whether the pod group has reached its `spec.minMember` at Permit.
+	// When it's false, the reason tells why the scheduler rejected the pod group.
+	PodGroupScheduled = "Scheduled"
+
+	// PodGroupWaitingAtPermit indicates that members of the pod group have been assumed by the scheduler
+	// and are waiting at Permit for the rest of the group; the message carries the number of waiting members.
+	PodGroupWaitingAtPermit = "WaitingAtPermit"
+
+	// PodGroupBackedOff means the scheduler has backed off the pod group after a failed scheduling
+	// attempt; the condition message carries the number of consecutive failures and the next retry time.
+	PodGroupBackedOff = "BackedOff"
+
+	// PodGroupCapacityReserved indicates whether the scheduler holds node capacity for the members of
+	// the pod group that are not scheduled yet; the condition message carries the nodes and the expiry time.
+	PodGroupCapacityReserved = "CapacityReserved"
+)
+
+// These are the reasons of the podGroup conditions set by the scheduler.
+const (
+	// PodGroupReasonNotEnoughPods means there are less pods than `spec.minMember` in the pod group.
+	PodGroupReasonNotEnoughPods = "NotEnoughPods"
+
+	// PodGroupReasonInsufficientResources means the cluster cannot satisfy `spec.minResources`.
+	PodGroupReasonInsufficientResources = "InsufficientResources"
+
+	// PodGroupReasonBackedOff means the pod group is rejected because it's being backed off.
+	PodGroupReasonBackedOff = "BackedOff"
+
+	// PodGroupReasonUnschedulable means a member of the pod group didn't pass Filter, so the group got rejected.
+	PodGroupReasonUnschedulable = "Unschedulable"
+
+	// PodGroupReasonPermitTimeout means the members waiting at Permit timed out before the group reached `spec.minMember`.
+	PodGroupReasonPermitTimeout = "PermitTimeout"
+
+	// PodGroupReasonWaiting means members of the pod group are waiting at Permit.
+	PodGroupReasonWaiting = "Waiting"
+
+	// PodGroupReasonQuorumReached means the pod group reached `spec.minMember` at Permit.
+	PodGroupReasonQuorumReached = "QuorumReached"
+
+	// PodGroupReasonReserved means node capacity is held for the members of the pod group that are not scheduled yet.
+	PodGroupReasonReserved = "Reserved"
+
+	// PodGroupReasonExpired means the backoff of the pod group ran out, or the capacity reserved for it was dropped
+	// once the schedule timeout passed.
+	PodGroupReasonExpired = "Expired"
+)
+
+// These are the pod condition types and reasons set by the scheduler.
+const (
+	// PodReclaimNotice means the capacity borrowed by the pod beyond the Min of its ElasticQuota is being
+	// reclaimed, and the pod is going to be preempted as per the reclaimPolicy of its ElasticQuota; the
+	// message carries the time the pod can be preempted from.
+	PodReclaimNotice v1.PodConditionType = "ReclaimNotice"
+
+	// PodReasonCapacityReclaimed means the capacity borrowed by the pod is reclaimed by another ElasticQuota.
+	PodReasonCapacityReclaimed = "CapacityReclaimed"
 )
 
 // PodGroup is a collection of Pod; used for batch workload.
@@ -146,6 +347,18 @@ type PodGroupSpec struct {
 
 	// ScheduleTimeoutSeconds defines the maximal time of members/tasks to wait before run the pod group;
 	ScheduleTimeoutSeconds *int32 `json:"scheduleTimeoutSeconds,omitempty"`
+
+	// TTLSecondsAfterFinished limits the lifetime of a pod group which is Finished, Failed or TimedOut;
+	// the controller deletes the pod group this many seconds after it reached one of these phases.
+	// If unset, the pod group isn't deleted.
+	// +optional
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+
+	// MaxFailedMembers is the number of failed pods the pod group tolerates; it turns Failed once more
+	// pods than this have failed. If unset, the pod group turns Failed on the first failed pod once
+	// `spec.minMember` pods have started.
+	// +optional
+	MaxFailedMembers *int32 `json:"maxFailedMembers,omitempty"`
 }
 
 // PodGroupStatus represents the current state of a pod group.
@@ -171,6 +384,16 @@ type PodGroupStatus struct {
 
 	// ScheduleStartTime of the group
 	ScheduleStartTime metav1.Time `json:"scheduleStartTime,omitempty"`
+
+	// CompletionTime is the time the pod group turned Finished, Failed or TimedOut.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Conditions represent the latest available observations of the pod group's scheduling state.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -23,6 +23,7 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -85,6 +86,21 @@ func (in *ElasticQuotaList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ElasticQuotaReference) DeepCopyInto(out *ElasticQuotaReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticQuotaReference.
+func (in *ElasticQuotaReference) DeepCopy() *ElasticQuotaReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ElasticQuotaReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ElasticQuotaSpec) DeepCopyInto(out *ElasticQuotaSpec) {
 	*out = *in
@@ -102,6 +118,33 @@ func (in *ElasticQuotaSpec) DeepCopyInto(out *ElasticQuotaSpec) {
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.Parent != nil {
+		in, out := &in.Parent, &out.Parent
+		*out = new(ElasticQuotaReference)
+		**out = **in
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ReclaimPolicy != nil {
+		in, out := &in.ReclaimPolicy, &out.ReclaimPolicy
+		*out = new(ReclaimPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PriorityClassMax != nil {
+		in, out := &in.PriorityClassMax, &out.PriorityClassMax
+		*out = make([]PriorityClassResources, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticQuotaSpec.
@@ -124,6 +167,41 @@ func (in *ElasticQuotaStatus) DeepCopyInto(out *ElasticQuotaStatus) {
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.Borrowed != nil {
+		in, out := &in.Borrowed, &out.Borrowed
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Lent != nil {
+		in, out := &in.Lent, &out.Lent
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.PendingDemand != nil {
+		in, out := &in.PendingDemand, &out.PendingDemand
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PriorityClassUsed != nil {
+		in, out := &in.PriorityClassUsed, &out.PriorityClassUsed
+		*out = make([]PriorityClassResources, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticQuotaStatus.
@@ -210,6 +288,16 @@ func (in *PodGroupSpec) DeepCopyInto(out *PodGroupSpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.TTLSecondsAfterFinished != nil {
+		in, out := &in.TTLSecondsAfterFinished, &out.TTLSecondsAfterFinished
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxFailedMembers != nil {
+		in, out := &in.MaxFailedMembers, &out.MaxFailedMembers
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodGroupSpec.
@@ -226,6 +314,17 @@ func (in *PodGroupSpec) DeepCopy() *PodGroupSpec {
 func (in *PodGroupStatus) DeepCopyInto(out *PodGroupStatus) {
 	*out = *in
 	in.ScheduleStartTime.DeepCopyInto(&out.ScheduleStartTime)
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodGroupStatus.
@@ -237,3 +336,60 @@ func (in *PodGroupStatus) DeepCopy() *PodGroupStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PriorityClassResources) DeepCopyInto(out *PriorityClassResources) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PriorityClassResources.
+func (in *PriorityClassResources) DeepCopy() *PriorityClassResources {
+	if in == nil {
+		return nil
+	}
+	out := new(PriorityClassResources)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReclaimPolicy) DeepCopyInto(out *ReclaimPolicy) {
+	*out = *in
+	if in.MinRuntimeSeconds != nil {
+		in, out := &in.MinRuntimeSeconds, &out.MinRuntimeSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.NoticeSeconds != nil {
+		in, out := &in.NoticeSeconds, &out.NoticeSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxPreemptions != nil {
+		in, out := &in.MaxPreemptions, &out.MaxPreemptions
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PreemptionIntervalSeconds != nil {
+		in, out := &in.PreemptionIntervalSeconds, &out.PreemptionIntervalSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReclaimPolicy.
+func (in *ReclaimPolicy) DeepCopy() *ReclaimPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ReclaimPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -17,7 +17,11 @@ limitations under the License.
 package app
 
 import (
+	"time"
+
 	"github.com/spf13/pflag"
+
+	schedulingv1a1 "sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
 )
 
 type ServerRunOptions struct {
@@ -27,6 +31,11 @@ type ServerRunOptions struct {
 	ApiServerBurst       int
 	Workers              int
 	EnableLeaderElection bool
+	PodGroupWorkloads    []string
+	PodGroupStuckTimeout time.Duration
+	EnableWebhooks       bool
+	WebhookPort          int
+	WebhookCertDir       string
 }
 
 func NewServerRunOptions() *ServerRunOptions {
@@ -42,4 +51,9 @@ func (s *ServerRunOptions) addAllFlags() {
 	pflag.IntVar(&s.ApiServerBurst, "burst", 10, "burst of query apiserver.")
 	pflag.IntVar(&s.Workers, "workers", 1, "workers of scheduler-plugin-controllers.")
 	pflag.BoolVar(&s.EnableLeaderElection, "enableLeaderElection", s.EnableLeaderElection, "If EnableLeaderElection for controller.")
+	pflag.BoolVar(&s.EnableWebhooks, "enableWebhooks", false, "If the controller serves the validating webhooks of PodGroup and ElasticQuota, the defaulting webhook of ElasticQuota, and the webhook labeling the pods of the --podGroupWorkloads.")
+	pflag.IntVar(&s.WebhookPort, "webhookPort", 9443, "Port the webhook server listens on.")
+	pflag.StringVar(&s.WebhookCertDir, "webhookCertDir", "", "Directory holding the tls.crt and tls.key of the webhook server; defaults to <temp-dir>/k8s-webhook-server/serving-certs.")
+	pflag.DurationVar(&s.PodGroupStuckTimeout, "podGroupStuckTimeout", 48*time.Hour, "Time after which a PodGroup which started scheduling, without any running pod, is moved to the TimedOut phase, and a PodGroup which started scheduling that long after its creation is not reconciled anymore; 0 disables both and the PodGroup keeps being reconciled.")
+	pflag.StringSliceVar(&s.PodGroupWorkloads, "podGroupWorkloads", nil, "Kinds of workloads, in the form Kind.version.group (e.g. Job.v1.batch,StatefulSet.v1.apps), to create PodGroups for when annotated with "+schedulingv1a1.AutoPodGroupAnnotation+"=true. Other than Jobs, workloads need a scale subresource, and their pods are labeled by the webhook enabled with --enableWebhooks.")
 }
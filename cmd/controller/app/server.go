@@ -17,16 +17,21 @@ limitations under the License.
 package app
 
 import (
+	"fmt"
+
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog/v2/klogr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	schedulingv1a1 "sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
 	"sigs.k8s.io/scheduler-plugins/pkg/controllers"
+	"sigs.k8s.io/scheduler-plugins/pkg/webhooks"
 )
 
 var (
@@ -56,6 +61,10 @@ func Run(s *ServerRunOptions) error {
 		LeaderElection:          s.EnableLeaderElection,
 		LeaderElectionID:        "sched-plugins-controllers",
 		LeaderElectionNamespace: "kube-system",
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Port:    s.WebhookPort,
+			CertDir: s.WebhookCertDir,
+		}),
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -63,9 +72,10 @@ func Run(s *ServerRunOptions) error {
 	}
 
 	if err = (&controllers.PodGroupReconciler{
-		Client:  mgr.GetClient(),
-		Scheme:  mgr.GetScheme(),
-		Workers: s.Workers,
+		Client:       mgr.GetClient(),
+		Scheme:       mgr.GetScheme(),
+		Workers:      s.Workers,
+		StuckTimeout: s.PodGroupStuckTimeout,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "PodGroup")
 		return err
@@ -80,11 +90,54 @@ func Run(s *ServerRunOptions) error {
 		return err
 	}
 
+	var workloads []schema.GroupVersionKind
+	for _, kind := range s.PodGroupWorkloads {
+		gvk, _ := schema.ParseKindArg(kind)
+		if gvk == nil {
+			err := fmt.Errorf("invalid workload kind %q, expected Kind.version.group", kind)
+			setupLog.Error(err, "unable to create controller", "controller", "Workload")
+			return err
+		}
+		workloads = append(workloads, *gvk)
+		if err = (&controllers.WorkloadReconciler{
+			Client:  mgr.GetClient(),
+			Scheme:  mgr.GetScheme(),
+			Workers: s.Workers,
+			GVK:     *gvk,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Workload", "kind", gvk.Kind)
+			return err
+		}
+	}
+
+	if s.EnableWebhooks {
+		if err = (&webhooks.PodGroupWebhook{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PodGroup")
+			return err
+		}
+		if err = (&webhooks.ElasticQuotaWebhook{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ElasticQuota")
+			return err
+		}
+		if len(workloads) > 0 {
+			if err = (&webhooks.PodWebhook{Workloads: workloads}).SetupWebhookWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create webhook", "webhook", "Pod")
+				return err
+			}
+		}
+	} else if len(workloads) > 0 {
+		setupLog.Info("Webhooks are disabled, only the pods of suspended Jobs get the PodGroup label of their workload")
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		return err
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	readyz := healthz.Ping
+	if s.EnableWebhooks {
+		readyz = mgr.GetWebhookServer().StartedChecker()
+	}
+	if err := mgr.AddReadyzCheck("readyz", readyz); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
 		return err
 	}
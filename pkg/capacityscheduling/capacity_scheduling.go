@@ -20,7 +20,9 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	policy "k8s.io/api/policy/v1"
@@ -28,6 +30,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/informers"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	corelisters "k8s.io/client-go/listers/core/v1"
@@ -45,6 +48,7 @@ import (
 
 	"sigs.k8s.io/scheduler-plugins/apis/scheduling"
 	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+	pglister "sigs.k8s.io/scheduler-plugins/pkg/generated/listers/scheduling/v1alpha1"
 	"sigs.k8s.io/scheduler-plugins/pkg/util"
 )
 
@@ -60,9 +64,19 @@ type CapacityScheduling struct {
 	sync.RWMutex
 	fh                framework.Handle
 	podLister         corelisters.PodLister
+	nsLister          corelisters.NamespaceLister
 	pdbLister         policylisters.PodDisruptionBudgetLister
 	client            client.Client
 	elasticQuotaInfos ElasticQuotaInfos
+	// snapshot is the last snapshot of the ElasticQuotaInfos, and changed the ElasticQuotaInfos changed since then.
+	snapshot *elasticQuotaSnapshot
+	changed  sets.Set[*ElasticQuotaInfo]
+	// nominatedPods indexes the pods nominated to run on a node by namespace.
+	nominatedPods *nominatedPodIndex
+	// reclaims records the preemptions to reclaim capacity, as limited by the reclaimPolicy of ElasticQuotas.
+	reclaims *reclaimHistory
+	// pgLister is nil if the PodGroup API isn't served.
+	pgLister pglister.PodGroupLister
 }
 
 // PreFilterState computed at PreFilter and used at PostFilter or Reserve.
@@ -88,15 +102,32 @@ func (s *PreFilterState) Clone() framework.StateData {
 // ElasticQuotaSnapshotState stores the snapshot of elasticQuotas.
 type ElasticQuotaSnapshotState struct {
 	elasticQuotaInfos ElasticQuotaInfos
+	// shared is true while elasticQuotaInfos is the snapshot shared with the plugin, which is copied on the first write.
+	shared bool
 }
 
-// Clone the ElasticQuotaSnapshot state.
+// Clone the ElasticQuotaSnapshot state. The clone of a shared snapshot shares it as well.
 func (s *ElasticQuotaSnapshotState) Clone() framework.StateData {
+	if s.shared {
+		return &ElasticQuotaSnapshotState{
+			elasticQuotaInfos: s.elasticQuotaInfos,
+			shared:            true,
+		}
+	}
 	return &ElasticQuotaSnapshotState{
 		elasticQuotaInfos: s.elasticQuotaInfos.clone(),
 	}
 }
 
+// mutableElasticQuotaInfos returns the elasticQuotas of the state to be modified, copying them first if shared.
+func (s *ElasticQuotaSnapshotState) mutableElasticQuotaInfos() ElasticQuotaInfos {
+	if s.shared {
+		s.elasticQuotaInfos = s.elasticQuotaInfos.clone()
+		s.shared = false
+	}
+	return s.elasticQuotaInfos
+}
+
 var _ framework.PreFilterPlugin = &CapacityScheduling{}
 var _ framework.PostFilterPlugin = &CapacityScheduling{}
 var _ framework.ReservePlugin = &CapacityScheduling{}
@@ -118,11 +149,14 @@ func (c *CapacityScheduling) Name() string {
 }
 
 // New initializes a new plugin and returns it.
-func New(_ context.Context, obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+func New(ctx context.Context, obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
 	c := &CapacityScheduling{
 		fh:                handle,
 		elasticQuotaInfos: NewElasticQuotaInfos(),
+		reclaims:          newReclaimHistory(),
+		nominatedPods:     newNominatedPodIndex(),
 		podLister:         handle.SharedInformerFactory().Core().V1().Pods().Lister(),
+		nsLister:          handle.SharedInformerFactory().Core().V1().Namespaces().Lister(),
 		pdbLister:         getPDBLister(handle.SharedInformerFactory()),
 	}
 
@@ -187,6 +221,63 @@ func New(_ context.Context, obj runtime.Object, handle framework.Handle) (framew
 			},
 		},
 	)
+	// The pods nominated to run on a node count against the quota of their namespace until they get assigned.
+	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if pod, ok := obj.(*v1.Pod); ok {
+				c.nominatedPods.update(pod)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if pod, ok := newObj.(*v1.Pod); ok {
+				c.nominatedPods.update(pod)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			switch t := obj.(type) {
+			case *v1.Pod:
+				c.nominatedPods.delete(t)
+			case cache.DeletedFinalStateUnknown:
+				if pod, ok := t.Obj.(*v1.Pod); ok {
+					c.nominatedPods.delete(pod)
+				}
+			}
+		},
+	})
+
+	// The namespaces selected by an ElasticQuota change along with their labels.
+	nsInformer := handle.SharedInformerFactory().Core().V1().Namespaces().Informer()
+	nsInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if ns, ok := obj.(*v1.Namespace); ok {
+				c.updateNamespace(ns)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNs, ok := oldObj.(*v1.Namespace)
+			if !ok {
+				return
+			}
+			newNs, ok := newObj.(*v1.Namespace)
+			if ok && !labels.Equals(oldNs.Labels, newNs.Labels) {
+				c.updateNamespace(newNs)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			switch t := obj.(type) {
+			case *v1.Namespace:
+				c.deleteNamespace(t)
+			case cache.DeletedFinalStateUnknown:
+				if ns, ok := t.Obj.(*v1.Namespace); ok {
+					c.deleteNamespace(ns)
+				}
+			}
+		},
+	})
+
+	if c.pgLister, err = c.newPodGroupLister(ctx, handle); err != nil {
+		return nil, err
+	}
 	klog.InfoS("CapacityScheduling start")
 	return c, nil
 }
@@ -203,10 +294,13 @@ func (c *CapacityScheduling) EventsToRegister() []framework.ClusterEventWithHint
 
 // PreFilter performs the following validations.
 // 1. Check if the (pod.request + eq.allocated) is less than eq.max.
-// 2. Check if the sum(eq's usage) > sum(eq's min).
+// 2. Check if the (pod.request + eq.allocated of the pod's priority class) is less than the max of the priority class.
+// 3. Check if the sum(eq's usage) > sum(eq's min).
+// For a member of a PodGroup, the request covers the pending members which are missing to reach its minMember,
+// each with its own request. Once admitted, their quota is held until they get reserved, the PodGroup gets rejected,
+// or its schedule timeout passes. The members beyond the ones quota is held for are admitted one at a time.
 func (c *CapacityScheduling) PreFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod) (*framework.PreFilterResult, *framework.Status) {
-	// TODO improve the efficiency of taking snapshot
-	// e.g. use a two-pointer data structure to only copy the updated EQs when necessary.
+	now := time.Now()
 	snapshotElasticQuota := c.snapshotElasticQuota()
 	podReq := computePodResourceRequest(pod)
 
@@ -224,41 +318,11 @@ func (c *CapacityScheduling) PreFilter(ctx context.Context, state *framework.Cyc
 
 	// nominatedPodsReqInEQWithPodReq is the sum of podReq and the requested resources of the Nominated Pods
 	// which subject to the same quota(namespace) and is more important than the preemptor.
-	nominatedPodsReqInEQWithPodReq := &framework.Resource{}
 	// nominatedPodsReqWithPodReq is the sum of podReq and the requested resources of the Nominated Pods
 	// which subject to the all quota(namespace). Generated Nominated Pods consist of two kinds of pods:
 	// 1. the pods subject to the same quota(namespace) and is more important than the preemptor.
 	// 2. the pods subject to the different quota(namespace) and the usage of quota(namespace) does not exceed min.
-	nominatedPodsReqWithPodReq := &framework.Resource{}
-
-	nodeList, err := c.fh.SnapshotSharedLister().NodeInfos().List()
-	if err != nil {
-		return nil, framework.NewStatus(framework.Error, fmt.Sprintf("Error getting the nodelist: %v", err))
-	}
-
-	for _, node := range nodeList {
-		nominatedPods := c.fh.NominatedPodsForNode(node.Node().Name)
-		for _, p := range nominatedPods {
-			if p.Pod.UID == pod.UID {
-				continue
-			}
-			ns := p.Pod.Namespace
-			info := c.elasticQuotaInfos[ns]
-			if info != nil {
-				pResourceRequest := util.ResourceList(computePodResourceRequest(p.Pod))
-				// If they are subject to the same quota(namespace) and p is more important than pod,
-				// p will be added to the nominatedResource and totalNominatedResource.
-				// If they aren't subject to the same quota(namespace) and the usage of quota(p's namespace) does not exceed min,
-				// p will be added to the totalNominatedResource.
-				if ns == pod.Namespace && corev1helpers.PodPriority(p.Pod) >= corev1helpers.PodPriority(pod) {
-					nominatedPodsReqInEQWithPodReq.Add(pResourceRequest)
-					nominatedPodsReqWithPodReq.Add(pResourceRequest)
-				} else if ns != pod.Namespace && !info.usedOverMin() {
-					nominatedPodsReqWithPodReq.Add(pResourceRequest)
-				}
-			}
-		}
-	}
+	nominatedPodsReqInEQWithPodReq, nominatedPodsReqWithPodReq := c.nominatedPods.requests(pod, eq, elasticQuotaInfos)
 
 	nominatedPodsReqInEQWithPodReq.Add(util.ResourceList(podReq))
 	nominatedPodsReqWithPodReq.Add(util.ResourceList(podReq))
@@ -269,10 +333,53 @@ func (c *CapacityScheduling) PreFilter(ctx context.Context, state *framework.Cyc
 	}
 	state.Write(preFilterStateKey, preFilterState)
 
+	pg, members, held := c.gangMembersToAdmit(pod, podReq, eq, now)
+	if held {
+		// The quota of the pod is held for its PodGroup.
+		return nil, framework.NewStatus(framework.Success, "")
+	}
+	if len(members) > 1 {
+		// The request of the pod itself is already accounted with the nominated pods.
+		podKey, _ := framework.GetPodKey(pod)
+		gangReq := &framework.Resource{}
+		priorityClassReqs := make(map[string]*framework.Resource)
+		for key, member := range members {
+			if priorityClassReqs[member.priorityClassName] == nil {
+				priorityClassReqs[member.priorityClassName] = &framework.Resource{}
+			}
+			priorityClassReqs[member.priorityClassName].Add(util.ResourceList(&member.request))
+			if key != podKey {
+				gangReq.Add(util.ResourceList(&member.request))
+			}
+		}
+		nominatedPodsReqInEQWithPodReq = nominatedPodsReqInEQWithPodReq.Clone()
+		nominatedPodsReqInEQWithPodReq.Add(util.ResourceList(gangReq))
+		nominatedPodsReqWithPodReq = nominatedPodsReqWithPodReq.Clone()
+		nominatedPodsReqWithPodReq.Add(util.ResourceList(gangReq))
+
+		if eq.usedOverMaxWith(nominatedPodsReqInEQWithPodReq) {
+			return nil, framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Pod %v/%v is rejected in PreFilter because ElasticQuota %v is more than Max with the %v pending members of PodGroup %v", pod.Namespace, pod.Name, eq.Namespace, len(members), pg.Name))
+		}
+		for priorityClassName, req := range priorityClassReqs {
+			if eq.priorityClassUsedOverMaxWith(priorityClassName, req) {
+				return nil, framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Pod %v/%v is rejected in PreFilter because priority class %v of ElasticQuota %v is more than Max with the %v pending members of PodGroup %v", pod.Namespace, pod.Name, priorityClassName, eq.Namespace, len(members), pg.Name))
+			}
+		}
+		if elasticQuotaInfos.aggregatedUsedOverMinWith(*nominatedPodsReqWithPodReq) {
+			return nil, framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Pod %v/%v is rejected in PreFilter because total ElasticQuota used is more than min with the %v pending members of PodGroup %v", pod.Namespace, pod.Name, len(members), pg.Name))
+		}
+		c.holdGangQuota(pg, members, now)
+		return nil, framework.NewStatus(framework.Success, "")
+	}
+
 	if eq.usedOverMaxWith(nominatedPodsReqInEQWithPodReq) {
 		return nil, framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Pod %v/%v is rejected in PreFilter because ElasticQuota %v is more than Max", pod.Namespace, pod.Name, eq.Namespace))
 	}
 
+	if eq.priorityClassUsedOverMaxWith(pod.Spec.PriorityClassName, podReq) {
+		return nil, framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Pod %v/%v is rejected in PreFilter because priority class %v of ElasticQuota %v is more than Max", pod.Namespace, pod.Name, pod.Spec.PriorityClassName, eq.Namespace))
+	}
+
 	if elasticQuotaInfos.aggregatedUsedOverMinWith(*nominatedPodsReqWithPodReq) {
 		return nil, framework.NewStatus(framework.Unschedulable, fmt.Sprintf("Pod %v/%v is rejected in PreFilter because total ElasticQuota used is more than min", pod.Namespace, pod.Name))
 	}
@@ -293,7 +400,7 @@ func (c *CapacityScheduling) AddPod(ctx context.Context, cycleState *framework.C
 		return framework.NewStatus(framework.Error, err.Error())
 	}
 
-	elasticQuotaInfo := elasticQuotaSnapshotState.elasticQuotaInfos[podToAdd.Pod.Namespace]
+	elasticQuotaInfo := elasticQuotaSnapshotState.mutableElasticQuotaInfos()[podToAdd.Pod.Namespace]
 	if elasticQuotaInfo != nil {
 		err := elasticQuotaInfo.addPodIfNotPresent(podToAdd.Pod)
 		if err != nil {
@@ -312,7 +419,7 @@ func (c *CapacityScheduling) RemovePod(ctx context.Context, cycleState *framewor
 		return framework.NewStatus(framework.Error, err.Error())
 	}
 
-	elasticQuotaInfo := elasticQuotaSnapshotState.elasticQuotaInfos[podToRemove.Pod.Namespace]
+	elasticQuotaInfo := elasticQuotaSnapshotState.mutableElasticQuotaInfos()[podToRemove.Pod.Namespace]
 	if elasticQuotaInfo != nil {
 		err = elasticQuotaInfo.deletePodIfPresent(podToRemove.Pod)
 		if err != nil {
@@ -328,19 +435,23 @@ func (c *CapacityScheduling) PostFilter(ctx context.Context, state *framework.Cy
 		metrics.PreemptionAttempts.Inc()
 	}()
 
+	p := newPreemptor(c.fh, state, c.reclaims)
 	pe := preemption.Evaluator{
 		PluginName: c.Name(),
 		Handler:    c.fh,
 		PodLister:  c.podLister,
 		PdbLister:  c.pdbLister,
 		State:      state,
-		Interface: &preemptor{
-			fh:    c.fh,
-			state: state,
-		},
+		Interface:  p,
 	}
 
-	return pe.Preempt(ctx, pod, m)
+	result, status := pe.Preempt(ctx, pod, m)
+	p.enforceReclaimPolicies(ctx, pod, result, status)
+	if status.IsSuccess() && result != nil && result.NominatingInfo != nil && result.NominatedNodeName != "" {
+		// The pod is nominated right away rather than once the nomination is reported in its status.
+		c.nominatedPods.nominate(pod, result.NominatedNodeName)
+	}
+	return result, status
 }
 
 func (c *CapacityScheduling) Reserve(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) *framework.Status {
@@ -349,6 +460,7 @@ func (c *CapacityScheduling) Reserve(ctx context.Context, state *framework.Cycle
 
 	elasticQuotaInfo := c.elasticQuotaInfos[pod.Namespace]
 	if elasticQuotaInfo != nil {
+		c.elasticQuotaChanged(elasticQuotaInfo)
 		err := elasticQuotaInfo.addPodIfNotPresent(pod)
 		if err != nil {
 			klog.ErrorS(err, "Failed to add Pod to its associated elasticQuota", "pod", klog.KObj(pod))
@@ -364,16 +476,40 @@ func (c *CapacityScheduling) Unreserve(ctx context.Context, state *framework.Cyc
 
 	elasticQuotaInfo := c.elasticQuotaInfos[pod.Namespace]
 	if elasticQuotaInfo != nil {
+		c.elasticQuotaChanged(elasticQuotaInfo)
 		err := elasticQuotaInfo.deletePodIfPresent(pod)
 		if err != nil {
 			klog.ErrorS(err, "Failed to delete Pod from its associated elasticQuota", "pod", klog.KObj(pod))
 		}
+		// A member of a PodGroup gets unreserved when Coscheduling rejects its group,
+		// so the quota held for the other members is released as well.
+		if pgName := util.GetPodGroupFullName(pod); pgName != "" {
+			elasticQuotaInfo.releaseGangQuota(pgName)
+		}
 	}
 }
 
 type preemptor struct {
-	fh    framework.Handle
-	state *framework.CycleState
+	fh       framework.Handle
+	state    *framework.CycleState
+	reclaims *reclaimHistory
+
+	sync.Mutex
+	// victims are the victims selected on each node.
+	victims map[string][]*v1.Pod
+	// notices are the victims to notify on each node rejected because the reclaimPolicy of the
+	// ElasticQuotas of its victims requires a notice.
+	notices map[string][]*v1.Pod
+}
+
+func newPreemptor(fh framework.Handle, state *framework.CycleState, reclaims *reclaimHistory) *preemptor {
+	return &preemptor{
+		fh:       fh,
+		state:    state,
+		reclaims: reclaims,
+		victims:  make(map[string][]*v1.Pod),
+		notices:  make(map[string][]*v1.Pod),
+	}
 }
 
 func (p *preemptor) OrderedScoreFuncs(ctx context.Context, nodesToVictims map[string]*extenderv1.Victims) []func(node string) int64 {
@@ -433,7 +569,7 @@ func (p *preemptor) PodEligibleToPreemptOthers(pod *v1.Pod, nominatedNodeStatus
 		podPriority := corev1helpers.PodPriority(pod)
 		preemptorEQInfo, preemptorWithEQ := elasticQuotaSnapshotState.elasticQuotaInfos[pod.Namespace]
 		if preemptorWithEQ {
-			moreThanMinWithPreemptor := preemptorEQInfo.usedOverMinWith(&preFilterState.nominatedPodsReqInEQWithPodReq)
+			total := elasticQuotaSnapshotState.elasticQuotaInfos.aggregatedMin()
 			for _, p := range nodeInfo.Pods {
 				// Checking terminating pods
 				if p.Pod.DeletionTimestamp != nil {
@@ -441,17 +577,19 @@ func (p *preemptor) PodEligibleToPreemptOthers(pod *v1.Pod, nominatedNodeStatus
 					if !withEQ {
 						continue
 					}
-					if p.Pod.Namespace == pod.Namespace && corev1helpers.PodPriority(p.Pod) < podPriority {
+					if eqInfo == preemptorEQInfo && corev1helpers.PodPriority(p.Pod) < podPriority {
 						// There is a terminating pod on the nominated node.
-						// If the terminating pod is in the same namespace with preemptor
+						// If the terminating pod is subject to the same quota with preemptor
 						// and it is less important than preemptor,
 						// return false to avoid preempting more pods.
 						return false, "not eligible due to a terminating pod on the nominated node."
-					} else if p.Pod.Namespace != pod.Namespace && !moreThanMinWithPreemptor && eqInfo.usedOverMin() {
+					} else if eqInfo != preemptorEQInfo && preemptorEQInfo.canReclaimFrom(eqInfo, &preFilterState.nominatedPodsReqInEQWithPodReq, computePodResourceRequest(p.Pod), total) {
 						// There is a terminating pod on the nominated node.
-						// The terminating pod isn't in the same namespace with preemptor.
-						// If moreThanMinWithPreemptor is false, it indicates that preemptor can preempt the pods in other EQs whose used is over min.
-						// And if the used of terminating pod's quota is over min, so the room released by terminating pod on the nominated node can be used by the preemptor.
+						// The terminating pod isn't subject to the same quota with preemptor.
+						// If the preemptor can reclaim from its quota, i.e. at their lowest common ancestor the terminating
+						// pod's subtree is over min while the preemptor's subtree with the preemptor isn't, or stays below
+						// the weighted share of the terminating pod's subtree, the room released by terminating pod on
+						// the nominated node can be used by the preemptor.
 						// return false to avoid preempting more pods.
 						return false, "not eligible due to a terminating pod on the nominated node."
 					}
@@ -517,13 +655,23 @@ func (p *preemptor) SelectVictimsOnNode(
 		return nil
 	}
 
-	elasticQuotaInfos := elasticQuotaSnapshotState.elasticQuotaInfos
+	// The potential victims are removed from the snapshot below.
+	elasticQuotaInfos := elasticQuotaSnapshotState.mutableElasticQuotaInfos()
 	podPriority := corev1helpers.PodPriority(pod)
 	preemptorElasticQuotaInfo, preemptorWithElasticQuota := elasticQuotaInfos[pod.Namespace]
+	now := time.Now()
 
 	// sort the pods in node by the priority class
 	sort.Slice(nodeInfo.Pods, func(i, j int) bool { return !schedutil.MoreImportantPod(nodeInfo.Pods[i].Pod, nodeInfo.Pods[j].Pod) })
 
+	// total is the capacity shared by the quotas, which their weighted shares are relative to.
+	total := elasticQuotaInfos.aggregatedMin()
+	// shares are the weighted shares of the quotas before removing the potential victims.
+	shares := make(map[*ElasticQuotaInfo]float64)
+	for _, eqInfo := range elasticQuotaInfos {
+		shares[eqInfo] = eqInfo.weightedShare(nil, total)
+	}
+
 	var potentialVictims []*framework.PodInfo
 	if preemptorWithElasticQuota {
 		nominatedPodsReqInEQWithPodReq = preFilterState.nominatedPodsReqInEQWithPodReq
@@ -535,31 +683,34 @@ func (p *preemptor) SelectVictimsOnNode(
 				continue
 			}
 
-			if moreThanMinWithPreemptor {
+			if eqInfo == preemptorElasticQuotaInfo {
 				// If Preemptor.Request + Quota.Used > Quota.Min:
 				// It means that its guaranteed isn't borrowed by other
 				// quotas. So that we will select the pods which subject to the
-				// same quota(namespace) with the lower priority than the
+				// same quota with the lower priority than the
 				// preemptor's priority as potential victims in a node.
-				if p.Pod.Namespace == pod.Namespace && corev1helpers.PodPriority(p.Pod) < podPriority {
+				if moreThanMinWithPreemptor && corev1helpers.PodPriority(p.Pod) < podPriority {
 					potentialVictims = append(potentialVictims, p)
 					if err := removePod(p); err != nil {
 						return nil, 0, framework.AsStatus(err)
 					}
 				}
-
-			} else {
-				// If Preemptor.Request + Quota.allocated <= Quota.min: It
-				// means that its min(guaranteed) resource is used or
-				// `borrowed` by other Quota. Potential victims in a node
-				// will be chosen from Quotas that allocates more resources
-				// than its min, i.e., borrowing resources from other
-				// Quotas.
-				if p.Pod.Namespace != pod.Namespace && eqInfo.usedOverMin() {
-					potentialVictims = append(potentialVictims, p)
-					if err := removePod(p); err != nil {
-						return nil, 0, framework.AsStatus(err)
-					}
+			} else if eqInfo.minRuntimeElapsed(p.Pod, now) &&
+				preemptorElasticQuotaInfo.canReclaimFrom(eqInfo, &nominatedPodsReqInEQWithPodReq, computePodResourceRequest(p.Pod), total) {
+				// If, at the lowest common ancestor of the quotas, Preemptor.Request + Subtree.Used <= Subtree.Min:
+				// It means that the min(guaranteed) resource of the preemptor's
+				// subtree is used or `borrowed` by the other subtrees. Potential
+				// victims in a node will be chosen from the subtrees that allocate
+				// more resources than their min, i.e., borrowing resources from
+				// other subtrees. Without parents, the subtrees are the quotas.
+				// If both subtrees borrow, the victims are chosen from the subtrees
+				// whose weighted share of the borrowed resources is higher than the
+				// preemptor's, so that the borrowed resources are shared by weight.
+				// The pods which haven't run for the minimum runtime of the reclaimPolicy of
+				// their quota aren't victims.
+				potentialVictims = append(potentialVictims, p)
+				if err := removePod(p); err != nil {
+					return nil, 0, framework.AsStatus(err)
 				}
 			}
 		}
@@ -602,11 +753,37 @@ func (p *preemptor) SelectVictimsOnNode(
 			elasticQuotaInfos.aggregatedUsedOverMinWith(podReq) {
 			return nil, 0, framework.NewStatus(framework.Unschedulable, "global quota max exceeded")
 		}
+		if preemptorElasticQuotaInfo.priorityClassUsedOverMaxWith(pod.Spec.PriorityClassName, &podReq) {
+			return nil, 0, framework.NewStatus(framework.Unschedulable, "priority class quota max exceeded")
+		}
 	}
 
 	var victims []*v1.Pod
 	numViolatingVictim := 0
-	sort.Slice(potentialVictims, func(i, j int) bool {
+	// The victims sharing the shallowest ancestor with the preemptor's quota are reprieved first,
+	// so that the pods of the closest quotas in the tree are preempted first. Among them, the victims
+	// whose subtree has the lowest weighted share are reprieved first, so that the quota furthest over
+	// its fair share is reclaimed from first.
+	victimRank := func(pi *framework.PodInfo) (int, float64) {
+		if !preemptorWithElasticQuota {
+			return 0, 0
+		}
+		lca, _, otherChild := preemptorElasticQuotaInfo.lowestCommonAncestor(elasticQuotaInfos[pi.Pod.Namespace])
+		depth := -1
+		if lca != nil {
+			depth = lca.depth()
+		}
+		return depth, shares[otherChild]
+	}
+	sort.SliceStable(potentialVictims, func(i, j int) bool {
+		di, si := victimRank(potentialVictims[i])
+		dj, sj := victimRank(potentialVictims[j])
+		if di != dj {
+			return di < dj
+		}
+		if si != sj {
+			return si < sj
+		}
 		return schedutil.MoreImportantPod(potentialVictims[i].Pod, potentialVictims[j].Pod)
 	})
 	// Try to reprieve as many pods as possible. We first try to reprieve the PDB
@@ -652,44 +829,234 @@ func (p *preemptor) SelectVictimsOnNode(
 			return nil, 0, framework.AsStatus(err)
 		}
 	}
+
+	nodeName := nodeInfo.Node().Name
+	if preemptorWithElasticQuota {
+		// The victims reclaimed from other quotas may have to be notified first, or may exceed the
+		// maximum preemptions of their quota.
+		toNotify, status := checkReclaimPolicies(victims, preemptorElasticQuotaInfo, elasticQuotaInfos, p.reclaims, now)
+		if !status.IsSuccess() {
+			if toNotify != nil {
+				p.Lock()
+				p.notices[nodeName] = toNotify
+				p.Unlock()
+			}
+			return nil, 0, status
+		}
+	}
+	p.Lock()
+	p.victims[nodeName] = victims
+	p.Unlock()
 	return victims, numViolatingVictim, framework.NewStatus(framework.Success)
 }
 
-func (c *CapacityScheduling) addElasticQuota(obj interface{}) {
-	eq := obj.(*v1alpha1.ElasticQuota)
-	oldElasticQuotaInfo := c.elasticQuotaInfos[eq.Namespace]
-	if oldElasticQuotaInfo != nil {
+// enforceReclaimPolicies records the victims preempted to reclaim capacity on the node nominated for the
+// preemptor. If no node is nominated because victims must be notified first, it notifies the victims of the
+// node with the fewest victims left to notify, unless the victims of a node are all notified already.
+func (p *preemptor) enforceReclaimPolicies(ctx context.Context, pod *v1.Pod, result *framework.PostFilterResult, status *framework.Status) {
+	elasticQuotaSnapshotState, err := getElasticQuotaSnapshotState(p.state)
+	if err != nil {
+		return
+	}
+	elasticQuotaInfos := elasticQuotaSnapshotState.elasticQuotaInfos
+	preemptorElasticQuotaInfo, ok := elasticQuotaInfos[pod.Namespace]
+	if !ok {
+		return
+	}
+	now := time.Now()
+
+	p.Lock()
+	defer p.Unlock()
+	if status.IsSuccess() && result != nil && result.NominatingInfo != nil && result.NominatedNodeName != "" {
+		for _, victim := range p.victims[result.NominatedNodeName] {
+			eqInfo, ok := elasticQuotaInfos[victim.Namespace]
+			if ok && eqInfo != preemptorElasticQuotaInfo && eqInfo.reclaimPolicy != nil && eqInfo.reclaimPolicy.MaxPreemptions != nil {
+				p.reclaims.add(eqInfo.Name(), now, 1)
+			}
+		}
 		return
 	}
 
-	elasticQuotaInfo := newElasticQuotaInfo(eq.Namespace, eq.Spec.Min, eq.Spec.Max, nil)
+	var nodeToNotify string
+	for nodeName, toNotify := range p.notices {
+		if len(toNotify) == 0 {
+			return
+		}
+		if nodeToNotify == "" || len(toNotify) < len(p.notices[nodeToNotify]) ||
+			len(toNotify) == len(p.notices[nodeToNotify]) && nodeName < nodeToNotify {
+			nodeToNotify = nodeName
+		}
+	}
+	if nodeToNotify != "" {
+		notifyReclaim(ctx, p.fh, pod, p.notices[nodeToNotify], elasticQuotaInfos, now)
+	}
+}
+
+func (c *CapacityScheduling) addElasticQuota(obj interface{}) {
+	eq := obj.(*v1alpha1.ElasticQuota)
+	elasticQuotaInfo := newElasticQuotaInfoFrom(eq)
 
 	c.Lock()
 	defer c.Unlock()
-	c.elasticQuotaInfos[eq.Namespace] = elasticQuotaInfo
+	if oldElasticQuotaInfo, ok := c.elasticQuotaInfos[eq.Namespace]; ok && oldElasticQuotaInfo.Namespace == eq.Namespace {
+		return
+	}
+	c.updateElasticQuotaTree(func(ElasticQuotaInfos, map[*ElasticQuotaInfo]*framework.Resource) {
+		c.elasticQuotaInfos[eq.Namespace] = elasticQuotaInfo
+	})
 }
 
 func (c *CapacityScheduling) updateElasticQuota(oldObj, newObj interface{}) {
 	oldEQ := oldObj.(*v1alpha1.ElasticQuota)
 	newEQ := newObj.(*v1alpha1.ElasticQuota)
-	newEQInfo := newElasticQuotaInfo(newEQ.Namespace, newEQ.Spec.Min, newEQ.Spec.Max, nil)
+	newEQInfo := newElasticQuotaInfoFrom(newEQ)
 
 	c.Lock()
 	defer c.Unlock()
 
-	oldEQInfo := c.elasticQuotaInfos[oldEQ.Namespace]
-	if oldEQInfo != nil {
-		newEQInfo.pods = oldEQInfo.pods
-		newEQInfo.Used = oldEQInfo.Used
-	}
-	c.elasticQuotaInfos[newEQ.Namespace] = newEQInfo
+	c.updateElasticQuotaTree(func(elasticQuotaInfos ElasticQuotaInfos, own map[*ElasticQuotaInfo]*framework.Resource) {
+		oldEQInfo := elasticQuotaInfos[oldEQ.Namespace]
+		if oldEQInfo != nil && oldEQInfo.Namespace == oldEQ.Namespace {
+			newEQInfo.pods = oldEQInfo.pods
+			newEQInfo.Used = oldEQInfo.Used
+			newEQInfo.gangs = oldEQInfo.gangs
+			newEQInfo.priorityClassUsed = oldEQInfo.priorityClassUsed
+			own[newEQInfo] = own[oldEQInfo]
+		}
+		elasticQuotaInfos[newEQ.Namespace] = newEQInfo
+	})
 }
 
 func (c *CapacityScheduling) deleteElasticQuota(obj interface{}) {
 	elasticQuota := obj.(*v1alpha1.ElasticQuota)
 	c.Lock()
 	defer c.Unlock()
-	delete(c.elasticQuotaInfos, elasticQuota.Namespace)
+	c.updateElasticQuotaTree(func(elasticQuotaInfos ElasticQuotaInfos, _ map[*ElasticQuotaInfo]*framework.Resource) {
+		delete(elasticQuotaInfos, elasticQuota.Namespace)
+	})
+}
+
+// updateNamespace updates the ElasticQuota selecting the namespace once it's added or its labels changed.
+func (c *CapacityScheduling) updateNamespace(ns *v1.Namespace) {
+	c.Lock()
+	defer c.Unlock()
+	c.reassignNamespace(ns.Name, c.elasticQuotaInfos.selecting(ns))
+}
+
+// deleteNamespace drops the ElasticQuota selecting the namespace once it's deleted.
+func (c *CapacityScheduling) deleteNamespace(ns *v1.Namespace) {
+	c.Lock()
+	defer c.Unlock()
+	c.reassignNamespace(ns.Name, nil)
+}
+
+// reassignNamespace makes the namespace refer to the ElasticQuotaInfo <now> selecting it, unless it has an
+// ElasticQuota of its own, and moves its pods from the ElasticQuotaInfo it referred to. Unlike
+// updateElasticQuotaTree, the other namespaces and the tree of ElasticQuotas are left as is.
+// It must be called with the lock held.
+func (c *CapacityScheduling) reassignNamespace(namespace string, now *ElasticQuotaInfo) {
+	old := c.elasticQuotaInfos[namespace]
+	if old == now || (old != nil && old.Namespace == namespace) {
+		return
+	}
+	if now == nil {
+		delete(c.elasticQuotaInfos, namespace)
+	} else {
+		c.elasticQuotaInfos[namespace] = now
+	}
+	for _, elasticQuotaInfo := range []*ElasticQuotaInfo{old, now} {
+		if elasticQuotaInfo != nil {
+			c.elasticQuotaChanged(elasticQuotaInfo)
+		}
+	}
+	c.moveNamespace(namespace, old, now)
+}
+
+// updateElasticQuotaTree applies <mutate> to the ElasticQuotaInfos, given the usage of each ElasticQuotaInfo
+// excluding its descendants, then updates the namespaces selected by the ElasticQuotas and the tree of
+// ElasticQuotas. The pods of the namespaces which are subject to another ElasticQuota are moved to it.
+// It must be called with the lock held.
+func (c *CapacityScheduling) updateElasticQuotaTree(mutate func(ElasticQuotaInfos, map[*ElasticQuotaInfo]*framework.Resource)) {
+	before := make(map[string]*ElasticQuotaInfo, len(c.elasticQuotaInfos))
+	for ns, elasticQuotaInfo := range c.elasticQuotaInfos {
+		before[ns] = elasticQuotaInfo
+	}
+	own := c.elasticQuotaInfos.ownUsage()
+	mutate(c.elasticQuotaInfos, own)
+
+	var nsList []*v1.Namespace
+	if c.nsLister != nil {
+		var err error
+		if nsList, err = c.nsLister.List(labels.Everything()); err != nil {
+			klog.ErrorS(err, "Failed to list namespaces")
+		}
+	}
+	c.elasticQuotaInfos.assignNamespaces(nsList)
+	c.elasticQuotaInfos.link(own)
+	// The next snapshot is taken from scratch, as the tree and the namespaces of the ElasticQuotas may have changed.
+	c.snapshot, c.changed = nil, nil
+
+	namespaces := sets.NewString()
+	for ns := range before {
+		namespaces.Insert(ns)
+	}
+	for ns := range c.elasticQuotaInfos {
+		namespaces.Insert(ns)
+	}
+	for ns := range namespaces {
+		old, now := before[ns], c.elasticQuotaInfos[ns]
+		if (old == nil || ns == old.Namespace) && (now == nil || ns == now.Namespace) {
+			// The pods of the namespace of an ElasticQuota are tracked by its own pod events.
+			continue
+		}
+		if old != nil && now != nil && old.Name() == now.Name() {
+			continue
+		}
+		var from *ElasticQuotaInfo
+		if old != nil {
+			from = c.elasticQuotaInfos.get(old.Name())
+		}
+		c.moveNamespace(ns, from, now)
+	}
+}
+
+// moveNamespace moves the assigned pods of the namespace, and the quota held for its PodGroups,
+// from the ElasticQuotaInfo <from> to <to>. Either may be nil.
+func (c *CapacityScheduling) moveNamespace(namespace string, from, to *ElasticQuotaInfo) {
+	if from == to {
+		return
+	}
+	pods, err := c.podLister.Pods(namespace).List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "Failed to list the pods of namespace", "namespace", namespace)
+		return
+	}
+	for _, pod := range pods {
+		if !assignedPod(pod) {
+			continue
+		}
+		if from != nil {
+			if err := from.deletePodIfPresent(pod); err != nil {
+				klog.ErrorS(err, "Failed to delete Pod from its associated elasticQuota", "pod", klog.KObj(pod))
+			}
+		}
+		if to != nil && pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed {
+			if err := to.addPodIfNotPresent(pod); err != nil {
+				klog.ErrorS(err, "Failed to add Pod to its associated elasticQuota", "pod", klog.KObj(pod))
+			}
+		}
+	}
+	if from == nil {
+		return
+	}
+	for name, gang := range from.gangs {
+		if strings.HasPrefix(name, namespace+"/") {
+			from.releaseGangQuota(name)
+			if to != nil {
+				to.holdGangQuota(name, gang.members, gang.expiresAt)
+			}
+		}
+	}
 }
 
 func (c *CapacityScheduling) addPod(obj interface{}) {
@@ -715,12 +1082,14 @@ func (c *CapacityScheduling) addPod(obj interface{}) {
 
 		if len(eqs) > 0 {
 			// only one elasticquota is supported in each namespace
-			eq := eqs[0]
-			elasticQuotaInfo = newElasticQuotaInfo(eq.Namespace, eq.Spec.Min, eq.Spec.Max, nil)
-			c.elasticQuotaInfos[eq.Namespace] = elasticQuotaInfo
+			elasticQuotaInfo = newElasticQuotaInfoFrom(&eqs[0])
+			c.updateElasticQuotaTree(func(elasticQuotaInfos ElasticQuotaInfos, _ map[*ElasticQuotaInfo]*framework.Resource) {
+				elasticQuotaInfos[elasticQuotaInfo.Namespace] = elasticQuotaInfo
+			})
 		}
 	}
 
+	c.elasticQuotaChanged(elasticQuotaInfo)
 	err := elasticQuotaInfo.addPodIfNotPresent(pod)
 	if err != nil {
 		klog.ErrorS(err, "Failed to add Pod to its associated elasticQuota", "pod", klog.KObj(pod))
@@ -741,6 +1110,7 @@ func (c *CapacityScheduling) updatePod(oldObj, newObj interface{}) {
 
 		elasticQuotaInfo := c.elasticQuotaInfos[newPod.Namespace]
 		if elasticQuotaInfo != nil {
+			c.elasticQuotaChanged(elasticQuotaInfo)
 			err := elasticQuotaInfo.deletePodIfPresent(newPod)
 			if err != nil {
 				klog.ErrorS(err, "Failed to delete Pod from its associated elasticQuota", "pod", klog.KObj(newPod))
@@ -756,6 +1126,7 @@ func (c *CapacityScheduling) deletePod(obj interface{}) {
 
 	elasticQuotaInfo := c.elasticQuotaInfos[pod.Namespace]
 	if elasticQuotaInfo != nil {
+		c.elasticQuotaChanged(elasticQuotaInfo)
 		err := elasticQuotaInfo.deletePodIfPresent(pod)
 		if err != nil {
 			klog.ErrorS(err, "Failed to delete Pod from its associated elasticQuota", "pod", klog.KObj(pod))
@@ -763,17 +1134,6 @@ func (c *CapacityScheduling) deletePod(obj interface{}) {
 	}
 }
 
-// getElasticQuotasSnapshot will return the snapshot of elasticQuotas.
-func (c *CapacityScheduling) snapshotElasticQuota() *ElasticQuotaSnapshotState {
-	c.RLock()
-	defer c.RUnlock()
-
-	elasticQuotaInfosDeepCopy := c.elasticQuotaInfos.clone()
-	return &ElasticQuotaSnapshotState{
-		elasticQuotaInfos: elasticQuotaInfosDeepCopy,
-	}
-}
-
 func getPreFilterState(cycleState *framework.CycleState) (*PreFilterState, error) {
 	c, err := cycleState.Read(preFilterStateKey)
 	if err != nil {
@@ -21,6 +21,7 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 
@@ -32,6 +33,7 @@ import (
 	clientsetfake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/events"
 	extenderv1 "k8s.io/kube-scheduler/extender/v1"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
 	plfeature "k8s.io/kubernetes/pkg/scheduler/framework/plugins/feature"
@@ -43,6 +45,7 @@ import (
 	st "k8s.io/kubernetes/pkg/scheduler/testing"
 	tf "k8s.io/kubernetes/pkg/scheduler/testing/framework"
 	imageutils "k8s.io/kubernetes/test/utils/image"
+	"k8s.io/utils/pointer"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
@@ -52,14 +55,15 @@ import (
 const ResourceGPU v1.ResourceName = "nvidia.com/gpu"
 
 var (
-	midPriority, highPriority = int32(100), int32(1000)
+	lowPriority, midPriority, highPriority = int32(10), int32(100), int32(1000)
 )
 
 func TestPreFilter(t *testing.T) {
 	type podInfo struct {
-		podName      string
-		podNamespace string
-		memReq       int64
+		podName           string
+		podNamespace      string
+		memReq            int64
+		priorityClassName string
 	}
 
 	tests := []struct {
@@ -128,6 +132,45 @@ func TestPreFilter(t *testing.T) {
 				framework.Unschedulable,
 			},
 		},
+		{
+			name: "pod exceeds the max of its priority class",
+			podInfos: []podInfo{
+				{podName: "ns1-p1", podNamespace: "ns1", memReq: 500, priorityClassName: "low"},
+				{podName: "ns1-p2", podNamespace: "ns1", memReq: 200, priorityClassName: "low"},
+				{podName: "ns1-p3", podNamespace: "ns1", memReq: 500, priorityClassName: "high"},
+				{podName: "ns1-p4", podNamespace: "ns1", memReq: 500},
+			},
+			elasticQuotas: map[string]*ElasticQuotaInfo{
+				"ns1": {
+					Namespace: "ns1",
+					Min: &framework.Resource{
+						Memory: 2000,
+					},
+					Max: &framework.Resource{
+						Memory: 3000,
+					},
+					Used: &framework.Resource{
+						Memory: 600,
+					},
+					priorityClassMax: map[string]*framework.Resource{
+						"low": {
+							Memory: 1000,
+						},
+					},
+					priorityClassUsed: map[string]*framework.Resource{
+						"low": {
+							Memory: 600,
+						},
+					},
+				},
+			},
+			expected: []framework.Code{
+				framework.Unschedulable,
+				framework.Success,
+				framework.Success,
+				framework.Success,
+			},
+		},
 		{
 			name: "without elasticQuotaInfo",
 			podInfos: []podInfo{
@@ -163,12 +206,14 @@ func TestPreFilter(t *testing.T) {
 
 			cs := &CapacityScheduling{
 				elasticQuotaInfos: tt.elasticQuotas,
+				nominatedPods:     newNominatedPodIndex(),
 				fh:                fwk,
 			}
 
 			pods := make([]*v1.Pod, 0)
 			for _, podInfo := range tt.podInfos {
 				pod := makePod(podInfo.podName, podInfo.podNamespace, podInfo.memReq, 0, 0, 0, podInfo.podName, "")
+				pod.Spec.PriorityClassName = podInfo.priorityClassName
 				pods = append(pods, pod)
 			}
 
@@ -184,6 +229,37 @@ func TestPreFilter(t *testing.T) {
 
 func TestPostFilter(t *testing.T) {
 	res := map[v1.ResourceName]string{v1.ResourceMemory: "150"}
+	now := time.Now()
+	started := func(pod *v1.Pod, d time.Duration) *v1.Pod {
+		pod.Status.StartTime = &metav1.Time{Time: now.Add(-d)}
+		return pod
+	}
+	notified := func(pod *v1.Pod, d time.Duration) *v1.Pod {
+		pod.Status.Conditions = append(pod.Status.Conditions, v1.PodCondition{
+			Type:               v1alpha1.PodReclaimNotice,
+			Status:             v1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(now.Add(-d)),
+		})
+		return pod
+	}
+	reclaimQuotas := func(policy *v1alpha1.ReclaimPolicy) map[string]*ElasticQuotaInfo {
+		return map[string]*ElasticQuotaInfo{
+			"ns1": {
+				Namespace: "ns1",
+				Max:       &framework.Resource{Memory: 200},
+				Min:       &framework.Resource{Memory: 150},
+				Used:      &framework.Resource{Memory: 50},
+			},
+			"ns2": {
+				Namespace:     "ns2",
+				name:          "eq",
+				Max:           &framework.Resource{Memory: 200},
+				Min:           &framework.Resource{Memory: 50},
+				Used:          &framework.Resource{Memory: 100},
+				reclaimPolicy: policy,
+			},
+		}
+	}
 	tests := []struct {
 		name                  string
 		pod                   *v1.Pod
@@ -191,8 +267,11 @@ func TestPostFilter(t *testing.T) {
 		nodes                 []*v1.Node
 		filteredNodesStatuses framework.NodeToStatusMap
 		elasticQuotas         map[string]*ElasticQuotaInfo
+		reclaims              map[string][]time.Time
 		wantResult            *framework.PostFilterResult
 		wantStatus            *framework.Status
+		wantNotified          []string
+		wantReclaims          int
 	}{
 		{
 			name: "in-namespace preemption",
@@ -298,6 +377,156 @@ func TestPostFilter(t *testing.T) {
 			wantResult:    framework.NewPostFilterResultWithNominatedNode("node-a"),
 			wantStatus:    framework.NewStatus(framework.Success),
 		},
+		{
+			name: "cross-namespace preemption notifies the victims first",
+			pod:  makePod("t1-p1", "ns1", 50, 0, 0, highPriority, "t1-p1", ""),
+			existPods: []*v1.Pod{
+				makePod("t1-p2", "ns1", 50, 0, 0, midPriority, "t1-p2", "node-a"),
+				makePod("t1-p3", "ns2", 50, 0, 0, midPriority, "t1-p3", "node-a"),
+				makePod("t1-p4", "ns2", 50, 0, 0, highPriority, "t1-p4", "node-a"),
+			},
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-a").Capacity(res).Obj(),
+			},
+			filteredNodesStatuses: framework.NodeToStatusMap{
+				"node-a": framework.NewStatus(framework.Unschedulable),
+			},
+			elasticQuotas: reclaimQuotas(&v1alpha1.ReclaimPolicy{NoticeSeconds: pointer.Int32(60)}),
+			wantResult:    framework.NewPostFilterResultWithNominatedNode(""),
+			wantStatus:    framework.NewStatus(framework.Unschedulable, "0/1 nodes are available: 1 victims are given a notice before being preempted."),
+			wantNotified:  []string{"t1-p3"},
+		},
+		{
+			name: "cross-namespace preemption during the notice",
+			pod:  makePod("t1-p1", "ns1", 50, 0, 0, highPriority, "t1-p1", ""),
+			existPods: []*v1.Pod{
+				makePod("t1-p2", "ns1", 50, 0, 0, midPriority, "t1-p2", "node-a"),
+				notified(makePod("t1-p3", "ns2", 50, 0, 0, midPriority, "t1-p3", "node-a"), 30*time.Second),
+				makePod("t1-p4", "ns2", 50, 0, 0, highPriority, "t1-p4", "node-a"),
+			},
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-a").Capacity(res).Obj(),
+			},
+			filteredNodesStatuses: framework.NodeToStatusMap{
+				"node-a": framework.NewStatus(framework.Unschedulable),
+			},
+			elasticQuotas: reclaimQuotas(&v1alpha1.ReclaimPolicy{NoticeSeconds: pointer.Int32(60)}),
+			wantResult:    framework.NewPostFilterResultWithNominatedNode(""),
+			wantStatus:    framework.NewStatus(framework.Unschedulable, "0/1 nodes are available: 1 victims are given a notice before being preempted."),
+		},
+		{
+			name: "cross-namespace preemption after the notice",
+			pod:  makePod("t1-p1", "ns1", 50, 0, 0, highPriority, "t1-p1", ""),
+			existPods: []*v1.Pod{
+				makePod("t1-p2", "ns1", 50, 0, 0, midPriority, "t1-p2", "node-a"),
+				notified(makePod("t1-p3", "ns2", 50, 0, 0, midPriority, "t1-p3", "node-a"), 90*time.Second),
+				makePod("t1-p4", "ns2", 50, 0, 0, highPriority, "t1-p4", "node-a"),
+			},
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-a").Capacity(res).Obj(),
+			},
+			filteredNodesStatuses: framework.NodeToStatusMap{
+				"node-a": framework.NewStatus(framework.Unschedulable),
+			},
+			elasticQuotas: reclaimQuotas(&v1alpha1.ReclaimPolicy{NoticeSeconds: pointer.Int32(60)}),
+			wantResult:    framework.NewPostFilterResultWithNominatedNode("node-a"),
+			wantStatus:    framework.NewStatus(framework.Success),
+		},
+		{
+			name: "cross-namespace preemption renews a stale notice",
+			pod:  makePod("t1-p1", "ns1", 50, 0, 0, highPriority, "t1-p1", ""),
+			existPods: []*v1.Pod{
+				makePod("t1-p2", "ns1", 50, 0, 0, midPriority, "t1-p2", "node-a"),
+				notified(makePod("t1-p3", "ns2", 50, 0, 0, midPriority, "t1-p3", "node-a"), 60*time.Second+reclaimNoticeGracePeriod+time.Second),
+				makePod("t1-p4", "ns2", 50, 0, 0, highPriority, "t1-p4", "node-a"),
+			},
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-a").Capacity(res).Obj(),
+			},
+			filteredNodesStatuses: framework.NodeToStatusMap{
+				"node-a": framework.NewStatus(framework.Unschedulable),
+			},
+			elasticQuotas: reclaimQuotas(&v1alpha1.ReclaimPolicy{NoticeSeconds: pointer.Int32(60)}),
+			wantResult:    framework.NewPostFilterResultWithNominatedNode(""),
+			wantStatus:    framework.NewStatus(framework.Unschedulable, "0/1 nodes are available: 1 victims are given a notice before being preempted."),
+			wantNotified:  []string{"t1-p3"},
+		},
+		{
+			name: "cross-namespace preemption of pods within their minimum runtime",
+			pod:  makePod("t1-p1", "ns1", 50, 0, 0, highPriority, "t1-p1", ""),
+			existPods: []*v1.Pod{
+				makePod("t1-p2", "ns1", 50, 0, 0, midPriority, "t1-p2", "node-a"),
+				started(makePod("t1-p3", "ns2", 50, 0, 0, midPriority, "t1-p3", "node-a"), time.Minute),
+				started(makePod("t1-p4", "ns2", 50, 0, 0, midPriority, "t1-p4", "node-a"), time.Minute),
+			},
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-a").Capacity(res).Obj(),
+			},
+			filteredNodesStatuses: framework.NodeToStatusMap{
+				"node-a": framework.NewStatus(framework.Unschedulable),
+			},
+			elasticQuotas: reclaimQuotas(&v1alpha1.ReclaimPolicy{MinRuntimeSeconds: pointer.Int32(3600)}),
+			wantResult:    framework.NewPostFilterResultWithNominatedNode(""),
+			wantStatus:    framework.NewStatus(framework.Unschedulable, "0/1 nodes are available: 1 No victims found on node node-a for preemptor pod t1-p1."),
+		},
+		{
+			name: "cross-namespace preemption of pods past their minimum runtime",
+			pod:  makePod("t1-p1", "ns1", 50, 0, 0, highPriority, "t1-p1", ""),
+			existPods: []*v1.Pod{
+				makePod("t1-p2", "ns1", 50, 0, 0, midPriority, "t1-p2", "node-a"),
+				started(makePod("t1-p3", "ns2", 50, 0, 0, midPriority, "t1-p3", "node-a"), time.Minute),
+				started(makePod("t1-p4", "ns2", 50, 0, 0, midPriority, "t1-p4", "node-a"), 2*time.Hour),
+			},
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-a").Capacity(res).Obj(),
+			},
+			filteredNodesStatuses: framework.NodeToStatusMap{
+				"node-a": framework.NewStatus(framework.Unschedulable),
+			},
+			elasticQuotas: reclaimQuotas(&v1alpha1.ReclaimPolicy{MinRuntimeSeconds: pointer.Int32(3600)}),
+			wantResult:    framework.NewPostFilterResultWithNominatedNode("node-a"),
+			wantStatus:    framework.NewStatus(framework.Success),
+		},
+		{
+			name: "cross-namespace preemption over the maximum preemptions",
+			pod:  makePod("t1-p1", "ns1", 50, 0, 0, highPriority, "t1-p1", ""),
+			existPods: []*v1.Pod{
+				makePod("t1-p2", "ns1", 50, 0, 0, midPriority, "t1-p2", "node-a"),
+				makePod("t1-p3", "ns2", 50, 0, 0, midPriority, "t1-p3", "node-a"),
+				makePod("t1-p4", "ns2", 50, 0, 0, midPriority, "t1-p4", "node-a"),
+			},
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-a").Capacity(res).Obj(),
+			},
+			filteredNodesStatuses: framework.NodeToStatusMap{
+				"node-a": framework.NewStatus(framework.Unschedulable),
+			},
+			elasticQuotas: reclaimQuotas(&v1alpha1.ReclaimPolicy{MaxPreemptions: pointer.Int32(2), PreemptionIntervalSeconds: pointer.Int32(60)}),
+			reclaims:      map[string][]time.Time{"ns2/eq": {now.Add(-2 * time.Minute), now.Add(-30 * time.Second), now.Add(-10 * time.Second)}},
+			wantResult:    framework.NewPostFilterResultWithNominatedNode(""),
+			wantStatus:    framework.NewStatus(framework.Unschedulable, "0/1 nodes are available: 1 preempting 1 pods of ElasticQuota ns2/eq exceeds the maximum preemptions of its reclaimPolicy."),
+			wantReclaims:  2,
+		},
+		{
+			name: "cross-namespace preemption within the maximum preemptions",
+			pod:  makePod("t1-p1", "ns1", 50, 0, 0, highPriority, "t1-p1", ""),
+			existPods: []*v1.Pod{
+				makePod("t1-p2", "ns1", 50, 0, 0, midPriority, "t1-p2", "node-a"),
+				makePod("t1-p3", "ns2", 50, 0, 0, midPriority, "t1-p3", "node-a"),
+				makePod("t1-p4", "ns2", 50, 0, 0, midPriority, "t1-p4", "node-a"),
+			},
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-a").Capacity(res).Obj(),
+			},
+			filteredNodesStatuses: framework.NodeToStatusMap{
+				"node-a": framework.NewStatus(framework.Unschedulable),
+			},
+			elasticQuotas: reclaimQuotas(&v1alpha1.ReclaimPolicy{MaxPreemptions: pointer.Int32(2), PreemptionIntervalSeconds: pointer.Int32(60)}),
+			reclaims:      map[string][]time.Time{"ns2/eq": {now.Add(-2 * time.Minute), now.Add(-90 * time.Second), now.Add(-10 * time.Second)}},
+			wantResult:    framework.NewPostFilterResultWithNominatedNode("node-a"),
+			wantStatus:    framework.NewStatus(framework.Success),
+			wantReclaims:  2,
+		},
 	}
 
 	for _, tt := range tests {
@@ -350,8 +579,14 @@ func TestPostFilter(t *testing.T) {
 			state.Write(preFilterStateKey, prefilterState)
 			state.Write(ElasticQuotaSnapshotKey, elasticQuotaSnapshotState)
 
+			reclaims := newReclaimHistory()
+			for name, times := range tt.reclaims {
+				reclaims.preemptions[name] = times
+			}
 			c := &CapacityScheduling{
 				elasticQuotaInfos: tt.elasticQuotas,
+				reclaims:          reclaims,
+				nominatedPods:     newNominatedPodIndex(),
 				fh:                fwk,
 				podLister:         informerFactory.Core().V1().Pods().Lister(),
 				pdbLister:         getPDBLister(informerFactory),
@@ -363,6 +598,24 @@ func TestPostFilter(t *testing.T) {
 			if diff := gocmp.Diff(tt.wantResult, gotResult); diff != "" {
 				t.Errorf("Unexpected postFilterResult (-want, +got):\n%s", diff)
 			}
+
+			var gotNotified []string
+			for _, existing := range tt.existPods {
+				pod, err := cs.CoreV1().Pods(existing.Namespace).Get(ctx, existing.Name, metav1.GetOptions{})
+				if err != nil {
+					continue
+				}
+				if _, condition := podutil.GetPodCondition(&pod.Status, v1alpha1.PodReclaimNotice); condition != nil &&
+					condition.Reason == v1alpha1.PodReasonCapacityReclaimed && !condition.LastTransitionTime.Before(&metav1.Time{Time: now.Truncate(time.Second)}) {
+					gotNotified = append(gotNotified, pod.Name)
+				}
+			}
+			if diff := gocmp.Diff(tt.wantNotified, gotNotified); diff != "" {
+				t.Errorf("Unexpected notified pods (-want, +got):\n%s", diff)
+			}
+			if got := reclaims.count("ns2/eq", now.Add(-time.Minute)); got != tt.wantReclaims {
+				t.Errorf("Expected %d preemptions to reclaim capacity, got %d", tt.wantReclaims, got)
+			}
 		})
 	}
 }
@@ -602,6 +855,36 @@ func TestUnreserve(t *testing.T) {
 
 func TestDryRunPreemption(t *testing.T) {
 	res := map[v1.ResourceName]string{v1.ResourceMemory: "150"}
+	org := &ElasticQuotaInfo{
+		Namespace: "org",
+		name:      "org",
+		Max:       &framework.Resource{Memory: 300},
+		Min:       &framework.Resource{Memory: 150},
+		Used:      &framework.Resource{Memory: 100},
+	}
+	teamA := &ElasticQuotaInfo{
+		Namespace: "ns1",
+		name:      "team-a",
+		parent:    org,
+		Max:       &framework.Resource{Memory: 200},
+		Min:       &framework.Resource{Memory: 100},
+		Used:      &framework.Resource{},
+	}
+	teamB := &ElasticQuotaInfo{
+		Namespace: "ns2",
+		name:      "team-b",
+		parent:    org,
+		Max:       &framework.Resource{Memory: 200},
+		Min:       &framework.Resource{Memory: 50},
+		Used:      &framework.Resource{Memory: 100},
+	}
+	other := &ElasticQuotaInfo{
+		Namespace: "ns3",
+		name:      "other",
+		Max:       &framework.Resource{Memory: 200},
+		Min:       &framework.Resource{Memory: 100},
+		Used:      &framework.Resource{Memory: 50},
+	}
 	tests := []struct {
 		name          string
 		pod           *v1.Pod
@@ -715,6 +998,91 @@ func TestDryRunPreemption(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "preemption from the quota furthest over its fair share",
+			pod:  makePod("t1-p", "ns1", 50, 0, 0, highPriority, "t1-p", ""),
+			pods: []*v1.Pod{
+				makePod("t1-p1", "ns2", 50, 0, 0, midPriority, "t1-p1", "node-a"),
+				makePod("t1-p2", "ns2", 50, 0, 0, midPriority, "t1-p2", "node-a"),
+				makePod("t1-p3", "ns3", 50, 0, 0, midPriority, "t1-p3", "node-a"),
+				makePod("t1-p4", "ns3", 50, 0, 0, midPriority, "t1-p4", "node-a"),
+			},
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-a").Capacity(map[v1.ResourceName]string{v1.ResourceMemory: "200"}).Obj(),
+			},
+			elasticQuotas: map[string]*ElasticQuotaInfo{
+				"ns1": {
+					Namespace: "ns1",
+					Max:       &framework.Resource{Memory: 1000},
+					Min:       &framework.Resource{Memory: 50},
+					Used:      &framework.Resource{Memory: 50},
+				},
+				"ns2": {
+					Namespace: "ns2",
+					Max:       &framework.Resource{Memory: 1000},
+					Min:       &framework.Resource{},
+					Used:      &framework.Resource{Memory: 150},
+				},
+				"ns3": {
+					Namespace: "ns3",
+					Max:       &framework.Resource{Memory: 1000},
+					Min:       &framework.Resource{},
+					Used:      &framework.Resource{Memory: 200},
+				},
+				"ns4": {
+					Namespace: "ns4",
+					Max:       &framework.Resource{Memory: 1000},
+					Min:       &framework.Resource{Memory: 1000},
+					Used:      &framework.Resource{},
+				},
+			},
+			nodesStatuses: framework.NodeToStatusMap{
+				"node-a": framework.NewStatus(framework.Unschedulable),
+			},
+			want: []preemption.Candidate{
+				&candidate{
+					victims: &extenderv1.Victims{
+						Pods: []*v1.Pod{
+							makePod("t1-p3", "ns3", 50, 0, 0, midPriority, "t1-p3", "node-a"),
+						},
+						NumPDBViolations: 0,
+					},
+					name: "node-a",
+				},
+			},
+		},
+		{
+			name: "preemption from a sibling quota in the tree",
+			pod:  makePod("t1-p", "ns1", 50, 0, 0, highPriority, "t1-p", ""),
+			pods: []*v1.Pod{
+				makePod("t1-p1", "ns2", 50, 0, 0, midPriority, "t1-p1", "node-a"),
+				makePod("t1-p2", "ns2", 50, 0, 0, lowPriority, "t1-p2", "node-a"),
+				makePod("t1-p3", "ns3", 50, 0, 0, midPriority, "t1-p3", "node-a"),
+			},
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-a").Capacity(res).Obj(),
+			},
+			elasticQuotas: map[string]*ElasticQuotaInfo{
+				"org": org,
+				"ns1": teamA,
+				"ns2": teamB,
+				"ns3": other,
+			},
+			nodesStatuses: framework.NodeToStatusMap{
+				"node-a": framework.NewStatus(framework.Unschedulable),
+			},
+			want: []preemption.Candidate{
+				&candidate{
+					victims: &extenderv1.Victims{
+						Pods: []*v1.Pod{
+							makePod("t1-p2", "ns2", 50, 0, 0, lowPriority, "t1-p2", "node-a"),
+						},
+						NumPDBViolations: 0,
+					},
+					name: "node-a",
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -764,10 +1132,7 @@ func TestDryRunPreemption(t *testing.T) {
 				PodLister:  fwk.SharedInformerFactory().Core().V1().Pods().Lister(),
 				PdbLister:  getPDBLister(fwk.SharedInformerFactory()),
 				State:      state,
-				Interface: &preemptor{
-					fh:    fwk,
-					state: state,
-				},
+				Interface:  newPreemptor(fwk, state, newReclaimHistory()),
 			}
 
 			nodeInfos, _ := fwk.SnapshotSharedLister().NodeInfos().List()
@@ -791,10 +1156,10 @@ func TestDryRunPreemption(t *testing.T) {
 				t.Fatalf("Unexpected candidate length: want %v, but bot %v", len(tt.want), len(got))
 			}
 			for i, c := range got {
-				if diff := gocmp.Diff(c.Victims(), got[i].Victims()); diff != "" {
+				if diff := gocmp.Diff(tt.want[i].Victims(), c.Victims()); diff != "" {
 					t.Errorf("Unexpected victims at index %v (-want, +got): %s", i, diff)
 				}
-				if diff := gocmp.Diff(c.Name(), got[i].Name()); diff != "" {
+				if diff := gocmp.Diff(tt.want[i].Name(), c.Name()); diff != "" {
 					t.Errorf("Unexpected victims at index %v (-want, +got): %s", i, diff)
 				}
 			}
@@ -1029,6 +1394,7 @@ func TestAddElasticQuota(t *testing.T) {
 			expected: map[string]*ElasticQuotaInfo{
 				"ns1": {
 					Namespace: "ns1",
+					name:      "t1-eq1",
 					pods:      sets.String{},
 					Max: &framework.Resource{
 						MilliCPU: 100,
@@ -1054,6 +1420,7 @@ func TestAddElasticQuota(t *testing.T) {
 			expected: map[string]*ElasticQuotaInfo{
 				"ns1": {
 					Namespace: "ns1",
+					name:      "t1-eq1",
 					pods:      sets.String{},
 					Max: &framework.Resource{
 						MilliCPU:         UpperBoundOfMax,
@@ -1080,6 +1447,7 @@ func TestAddElasticQuota(t *testing.T) {
 			expected: map[string]*ElasticQuotaInfo{
 				"ns1": {
 					Namespace: "ns1",
+					name:      "t1-eq1",
 					pods:      sets.String{},
 					Max: &framework.Resource{
 						MilliCPU: 100,
@@ -1106,6 +1474,7 @@ func TestAddElasticQuota(t *testing.T) {
 			expected: map[string]*ElasticQuotaInfo{
 				"ns1": {
 					Namespace: "ns1",
+					name:      "t1-eq1",
 					pods:      sets.String{},
 					Max: &framework.Resource{
 						MilliCPU:         UpperBoundOfMax,
@@ -1181,6 +1550,7 @@ func TestUpdateElasticQuota(t *testing.T) {
 			expected: map[string]*ElasticQuotaInfo{
 				"ns1": {
 					Namespace: "ns1",
+					name:      "t1-eq1",
 					pods:      sets.String{},
 					Max: &framework.Resource{
 						MilliCPU: 300,
@@ -1308,6 +1678,7 @@ func TestAddPod(t *testing.T) {
 			expected: map[string]*ElasticQuotaInfo{
 				"ns1": {
 					Namespace: "ns1",
+					name:      "t1-eq1",
 					pods:      sets.NewString("t1-p1", "t1-p2", "t1-p3"),
 					Max: &framework.Resource{
 						MilliCPU: 100,
@@ -1388,6 +1759,7 @@ func TestUpdatePod(t *testing.T) {
 			expected: map[string]*ElasticQuotaInfo{
 				"ns1": {
 					Namespace: "ns1",
+					name:      "t1-eq1",
 					pods:      sets.NewString("t1-p1"),
 					Max: &framework.Resource{
 						MilliCPU: 100,
@@ -1420,6 +1792,7 @@ func TestUpdatePod(t *testing.T) {
 			expected: map[string]*ElasticQuotaInfo{
 				"ns1": {
 					Namespace: "ns1",
+					name:      "t1-eq1",
 					pods:      sets.String{},
 					Max: &framework.Resource{
 						MilliCPU: 100,
@@ -1504,6 +1877,7 @@ func TestDeletePod(t *testing.T) {
 			expected: map[string]*ElasticQuotaInfo{
 				"ns1": {
 					Namespace: "ns1",
+					name:      "t1-eq1",
 					pods:      sets.NewString(),
 					Max: &framework.Resource{
 						MilliCPU: 100,
@@ -1537,6 +1911,7 @@ func TestDeletePod(t *testing.T) {
 			expected: map[string]*ElasticQuotaInfo{
 				"ns1": {
 					Namespace: "ns1",
+					name:      "t1-eq1",
 					pods:      sets.NewString("t1-p2"),
 					Max: &framework.Resource{
 						MilliCPU: 100,
@@ -1599,6 +1974,225 @@ func TestDeletePod(t *testing.T) {
 	}
 }
 
+func TestElasticQuotaTree(t *testing.T) {
+	makeNamespace := func(name string, labels map[string]string) *v1.Namespace {
+		return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	withTree := func(eq *v1alpha1.ElasticQuota, parent string, selector map[string]string) *v1alpha1.ElasticQuota {
+		if parent != "" {
+			eq.Spec.Parent = &v1alpha1.ElasticQuotaReference{Namespace: parent, Name: parent}
+		}
+		if selector != nil {
+			eq.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: selector}
+		}
+		return eq
+	}
+	teamA := map[string]string{"team": "a"}
+	tests := []struct {
+		name            string
+		namespaces      []*v1.Namespace
+		elasticQuotas   []*v1alpha1.ElasticQuota
+		pods            []*v1.Pod
+		updateNamespace *v1.Namespace
+		deleteNamespace *v1.Namespace
+		deleteEQ        *v1alpha1.ElasticQuota
+		expectedUsed    map[string]int64
+		expectedParent  map[string]string
+	}{
+		{
+			name: "usage is aggregated up the tree",
+			namespaces: []*v1.Namespace{
+				makeNamespace("org", nil),
+				makeNamespace("a1", teamA),
+				makeNamespace("a2", teamA),
+				makeNamespace("b", nil),
+			},
+			elasticQuotas: []*v1alpha1.ElasticQuota{
+				makeEQ("org", "org", nil, nil),
+				withTree(makeEQ("a1", "a1", nil, nil), "org", teamA),
+				withTree(makeEQ("b", "b", nil, nil), "org", nil),
+			},
+			pods: []*v1.Pod{
+				makePod("t1-p1", "a1", 10, 0, 0, midPriority, "t1-p1", "node-a"),
+				makePod("t1-p2", "a2", 20, 0, 0, midPriority, "t1-p2", "node-a"),
+				makePod("t1-p3", "b", 40, 0, 0, midPriority, "t1-p3", "node-a"),
+			},
+			expectedUsed:   map[string]int64{"org": 70, "a1": 30, "a2": 30, "b": 40},
+			expectedParent: map[string]string{"org": "", "a1": "org/org", "a2": "org/org", "b": "org/org"},
+		},
+		{
+			name: "namespace no longer selected",
+			namespaces: []*v1.Namespace{
+				makeNamespace("org", nil),
+				makeNamespace("a1", teamA),
+				makeNamespace("a2", teamA),
+			},
+			elasticQuotas: []*v1alpha1.ElasticQuota{
+				makeEQ("org", "org", nil, nil),
+				withTree(makeEQ("a1", "a1", nil, nil), "org", teamA),
+			},
+			pods: []*v1.Pod{
+				makePod("t1-p1", "a1", 10, 0, 0, midPriority, "t1-p1", "node-a"),
+				makePod("t1-p2", "a2", 20, 0, 0, midPriority, "t1-p2", "node-a"),
+			},
+			updateNamespace: makeNamespace("a2", nil),
+			expectedUsed:    map[string]int64{"org": 10, "a1": 10},
+			expectedParent:  map[string]string{"org": "", "a1": "org/org"},
+		},
+		{
+			name: "namespace newly selected",
+			namespaces: []*v1.Namespace{
+				makeNamespace("org", nil),
+				makeNamespace("a1", teamA),
+				makeNamespace("a2", nil),
+			},
+			elasticQuotas: []*v1alpha1.ElasticQuota{
+				makeEQ("org", "org", nil, nil),
+				withTree(makeEQ("a1", "a1", nil, nil), "org", teamA),
+			},
+			pods: []*v1.Pod{
+				makePod("t1-p1", "a1", 10, 0, 0, midPriority, "t1-p1", "node-a"),
+				makePod("t1-p2", "a2", 20, 0, 0, midPriority, "t1-p2", "node-a"),
+			},
+			updateNamespace: makeNamespace("a2", teamA),
+			expectedUsed:    map[string]int64{"org": 30, "a1": 30, "a2": 30},
+			expectedParent:  map[string]string{"org": "", "a1": "org/org", "a2": "org/org"},
+		},
+		{
+			name: "selected namespace deleted",
+			namespaces: []*v1.Namespace{
+				makeNamespace("org", nil),
+				makeNamespace("a1", teamA),
+				makeNamespace("a2", teamA),
+			},
+			elasticQuotas: []*v1alpha1.ElasticQuota{
+				makeEQ("org", "org", nil, nil),
+				withTree(makeEQ("a1", "a1", nil, nil), "org", teamA),
+			},
+			pods: []*v1.Pod{
+				makePod("t1-p1", "a1", 10, 0, 0, midPriority, "t1-p1", "node-a"),
+				makePod("t1-p2", "a2", 20, 0, 0, midPriority, "t1-p2", "node-a"),
+			},
+			deleteNamespace: makeNamespace("a2", teamA),
+			expectedUsed:    map[string]int64{"org": 10, "a1": 10},
+			expectedParent:  map[string]string{"org": "", "a1": "org/org"},
+		},
+		{
+			name: "namespace with its own ElasticQuota is kept",
+			namespaces: []*v1.Namespace{
+				makeNamespace("org", nil),
+				makeNamespace("a1", teamA),
+			},
+			elasticQuotas: []*v1alpha1.ElasticQuota{
+				withTree(makeEQ("org", "org", nil, nil), "", teamA),
+				withTree(makeEQ("a1", "a1", nil, nil), "org", nil),
+			},
+			pods: []*v1.Pod{
+				makePod("t1-p1", "a1", 10, 0, 0, midPriority, "t1-p1", "node-a"),
+			},
+			updateNamespace: makeNamespace("a1", map[string]string{"team": "a", "env": "prod"}),
+			expectedUsed:    map[string]int64{"org": 10, "a1": 10},
+			expectedParent:  map[string]string{"org": "", "a1": "org/org"},
+		},
+		{
+			name: "parent deleted",
+			namespaces: []*v1.Namespace{
+				makeNamespace("org", nil),
+				makeNamespace("a1", teamA),
+				makeNamespace("a2", teamA),
+			},
+			elasticQuotas: []*v1alpha1.ElasticQuota{
+				makeEQ("org", "org", nil, nil),
+				withTree(makeEQ("a1", "a1", nil, nil), "org", teamA),
+			},
+			pods: []*v1.Pod{
+				makePod("t1-p1", "a1", 10, 0, 0, midPriority, "t1-p1", "node-a"),
+				makePod("t1-p2", "a2", 20, 0, 0, midPriority, "t1-p2", "node-a"),
+			},
+			deleteEQ:       makeEQ("org", "org", nil, nil),
+			expectedUsed:   map[string]int64{"a1": 30, "a2": 30},
+			expectedParent: map[string]string{"a1": "", "a2": ""},
+		},
+		{
+			name: "parent making a cycle is ignored",
+			namespaces: []*v1.Namespace{
+				makeNamespace("a", nil),
+				makeNamespace("b", nil),
+			},
+			elasticQuotas: []*v1alpha1.ElasticQuota{
+				withTree(makeEQ("a", "a", nil, nil), "b", nil),
+				withTree(makeEQ("b", "b", nil, nil), "a", nil),
+			},
+			pods: []*v1.Pod{
+				makePod("t1-p1", "a", 10, 0, 0, midPriority, "t1-p1", "node-a"),
+				makePod("t1-p2", "b", 20, 0, 0, midPriority, "t1-p2", "node-a"),
+			},
+			expectedUsed:   map[string]int64{"a": 10, "b": 30},
+			expectedParent: map[string]string{"a": "b/b", "b": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			informerFactory := informers.NewSharedInformerFactory(clientsetfake.NewSimpleClientset(), 0)
+			nsInformer := informerFactory.Core().V1().Namespaces()
+			podInformer := informerFactory.Core().V1().Pods()
+			for _, ns := range tt.namespaces {
+				nsInformer.Informer().GetStore().Add(ns)
+			}
+			for _, pod := range tt.pods {
+				podInformer.Informer().GetStore().Add(pod)
+			}
+
+			cs := &CapacityScheduling{
+				elasticQuotaInfos: map[string]*ElasticQuotaInfo{},
+				nsLister:          nsInformer.Lister(),
+				podLister:         podInformer.Lister(),
+			}
+			for _, eq := range tt.elasticQuotas {
+				cs.addElasticQuota(eq)
+			}
+			for _, pod := range tt.pods {
+				// The pods of the namespaces without an ElasticQuota are only tracked in the pod lister.
+				if cs.elasticQuotaInfos[pod.Namespace] != nil {
+					cs.addPod(pod)
+				}
+			}
+			if tt.updateNamespace != nil {
+				nsInformer.Informer().GetStore().Update(tt.updateNamespace)
+				cs.updateNamespace(tt.updateNamespace)
+			}
+			if tt.deleteNamespace != nil {
+				nsInformer.Informer().GetStore().Delete(tt.deleteNamespace)
+				cs.deleteNamespace(tt.deleteNamespace)
+			}
+			if tt.deleteEQ != nil {
+				cs.deleteElasticQuota(tt.deleteEQ)
+			}
+
+			if len(cs.elasticQuotaInfos) != len(tt.expectedUsed) {
+				t.Errorf("expected ElasticQuotas for %v namespaces, got %v", len(tt.expectedUsed), len(cs.elasticQuotaInfos))
+			}
+			for ns, used := range tt.expectedUsed {
+				info := cs.elasticQuotaInfos[ns]
+				if info == nil {
+					t.Errorf("expected an ElasticQuota for namespace %v", ns)
+					continue
+				}
+				if info.Used.Memory != used {
+					t.Errorf("expected used memory %v in namespace %v, got %v", used, ns, info.Used.Memory)
+				}
+				var parent string
+				if info.parent != nil {
+					parent = info.parent.Name()
+				}
+				if parent != tt.expectedParent[ns] {
+					t.Errorf("expected parent %q in namespace %v, got %q", tt.expectedParent[ns], ns, parent)
+				}
+			}
+		})
+	}
+}
+
 func makePod(podName string, namespace string, memReq int64, cpuReq int64, gpuReq int64, priority int32, uid string, nodeName string) *v1.Pod {
 	pause := imageutils.GetPauseImageName()
 	pod := st.MakePod().Namespace(namespace).Name(podName).Container(pause).
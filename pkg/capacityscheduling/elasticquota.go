@@ -18,11 +18,18 @@ package capacityscheduling
 
 import (
 	"math"
+	"sort"
+	"strings"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
 	"sigs.k8s.io/scheduler-plugins/pkg/util"
 )
 
@@ -37,19 +44,37 @@ func NewElasticQuotaInfos() ElasticQuotaInfos {
 	return make(ElasticQuotaInfos)
 }
 
+// clone returns a deep copy of the ElasticQuotaInfos, keeping the namespaces which refer to the same
+// ElasticQuotaInfo and the tree of ElasticQuotaInfos.
 func (e ElasticQuotaInfos) clone() ElasticQuotaInfos {
 	elasticQuotas := make(ElasticQuotaInfos)
+	clones := make(map[*ElasticQuotaInfo]*ElasticQuotaInfo, len(e))
 	for key, elasticQuotaInfo := range e {
-		elasticQuotas[key] = elasticQuotaInfo.clone()
+		clone, ok := clones[elasticQuotaInfo]
+		if !ok {
+			clone = elasticQuotaInfo.clone()
+			clones[elasticQuotaInfo] = clone
+		}
+		elasticQuotas[key] = clone
+	}
+	for _, clone := range clones {
+		if clone.parent != nil {
+			clone.parent = clones[clone.parent]
+		}
 	}
 	return elasticQuotas
 }
 
+// aggregatedUsedOverMinWith checks whether the usage of the root ElasticQuotas with the pod request
+// exceeds their aggregated Min. The usage of nested ElasticQuotas is accounted in their root.
 func (e ElasticQuotaInfos) aggregatedUsedOverMinWith(podRequest framework.Resource) bool {
 	used := framework.NewResource(nil)
 	min := framework.NewResource(nil)
 
-	for _, elasticQuotaInfo := range e {
+	for key, elasticQuotaInfo := range e {
+		if key != elasticQuotaInfo.Namespace || elasticQuotaInfo.parent != nil {
+			continue
+		}
 		used.Add(util.ResourceList(elasticQuotaInfo.Used))
 		min.Add(util.ResourceList(elasticQuotaInfo.Min))
 	}
@@ -58,14 +83,170 @@ func (e ElasticQuotaInfos) aggregatedUsedOverMinWith(podRequest framework.Resour
 	return cmp(used, min, LowerBoundOfMin)
 }
 
+// aggregatedMin returns the aggregated Min of the root ElasticQuotas, i.e. the capacity shared by the ElasticQuotas.
+func (e ElasticQuotaInfos) aggregatedMin() *framework.Resource {
+	min := framework.NewResource(nil)
+	for key, elasticQuotaInfo := range e {
+		if key == elasticQuotaInfo.Namespace && elasticQuotaInfo.parent == nil {
+			min.Add(util.ResourceList(elasticQuotaInfo.Min))
+		}
+	}
+	return min
+}
+
+// ownUsage returns the usage of each ElasticQuotaInfo, excluding the usage of its descendants.
+func (e ElasticQuotaInfos) ownUsage() map[*ElasticQuotaInfo]*framework.Resource {
+	own := make(map[*ElasticQuotaInfo]*framework.Resource, len(e))
+	for key, elasticQuotaInfo := range e {
+		if key == elasticQuotaInfo.Namespace {
+			own[elasticQuotaInfo] = elasticQuotaInfo.Used.Clone()
+		}
+	}
+	for elasticQuotaInfo := range own {
+		if parentOwn, ok := own[elasticQuotaInfo.parent]; ok {
+			subtractResource(parentOwn, elasticQuotaInfo.Used)
+		}
+	}
+	return own
+}
+
+// link points each ElasticQuotaInfo to its parent, and sets the Used of each ElasticQuotaInfo to its own
+// usage, as returned by ownUsage, plus the usage of its descendants. The parent of the ElasticQuota closing a cycle,
+// in the alphabetical order of namespaces, is ignored.
+func (e ElasticQuotaInfos) link(own map[*ElasticQuotaInfo]*framework.Resource) {
+	var elasticQuotaInfos []*ElasticQuotaInfo
+	for key, elasticQuotaInfo := range e {
+		if key == elasticQuotaInfo.Namespace {
+			elasticQuotaInfo.parent = nil
+			elasticQuotaInfos = append(elasticQuotaInfos, elasticQuotaInfo)
+		}
+	}
+	// Cycles are broken in the same place regardless of the order of the map.
+	sort.Slice(elasticQuotaInfos, func(i, j int) bool { return elasticQuotaInfos[i].Namespace < elasticQuotaInfos[j].Namespace })
+	for _, elasticQuotaInfo := range elasticQuotaInfos {
+		if elasticQuotaInfo.parentName == "" {
+			continue
+		}
+		parent := e.get(elasticQuotaInfo.parentName)
+		for q := parent; q != nil; q = q.parent {
+			if q == elasticQuotaInfo {
+				klog.InfoS("Ignoring the parent of ElasticQuota which makes a cycle", "elasticQuota", elasticQuotaInfo.Name(), "parent", elasticQuotaInfo.parentName)
+				parent = nil
+				break
+			}
+		}
+		elasticQuotaInfo.parent = parent
+	}
+	for _, elasticQuotaInfo := range elasticQuotaInfos {
+		elasticQuotaInfo.Used = framework.NewResource(nil)
+	}
+	for _, elasticQuotaInfo := range elasticQuotaInfos {
+		if used, ok := own[elasticQuotaInfo]; ok {
+			elasticQuotaInfo.reserveResource(*used)
+		}
+	}
+}
+
+// get returns the ElasticQuotaInfo with the given namespace/name, or nil.
+func (e ElasticQuotaInfos) get(name string) *ElasticQuotaInfo {
+	namespace, _, _ := strings.Cut(name, "/")
+	if elasticQuotaInfo, ok := e[namespace]; ok && elasticQuotaInfo.Name() == name {
+		return elasticQuotaInfo
+	}
+	return nil
+}
+
+// assignNamespaces makes each of the given namespaces without an ElasticQuota of its own refer to the
+// ElasticQuota selecting it, if any. When several ElasticQuotas select a namespace, the one in the first
+// namespace in alphabetical order applies.
+func (e ElasticQuotaInfos) assignNamespaces(namespaces []*v1.Namespace) {
+	for key, elasticQuotaInfo := range e {
+		if key != elasticQuotaInfo.Namespace {
+			delete(e, key)
+		}
+	}
+	selecting := e.selectors()
+	if len(selecting) == 0 {
+		return
+	}
+	for _, ns := range namespaces {
+		if _, ok := e[ns.Name]; ok {
+			continue
+		}
+		if elasticQuotaInfo := selectorOf(selecting, ns); elasticQuotaInfo != nil {
+			e[ns.Name] = elasticQuotaInfo
+		}
+	}
+}
+
+// selecting returns the ElasticQuotaInfo whose namespace selector selects the namespace, if any.
+func (e ElasticQuotaInfos) selecting(ns *v1.Namespace) *ElasticQuotaInfo {
+	return selectorOf(e.selectors(), ns)
+}
+
+// selectors returns the ElasticQuotaInfos with a namespace selector, sorted by namespace.
+func (e ElasticQuotaInfos) selectors() []*ElasticQuotaInfo {
+	var selecting []*ElasticQuotaInfo
+	for key, elasticQuotaInfo := range e {
+		if key == elasticQuotaInfo.Namespace && elasticQuotaInfo.namespaceSelector != nil {
+			selecting = append(selecting, elasticQuotaInfo)
+		}
+	}
+	sort.Slice(selecting, func(i, j int) bool { return selecting[i].Namespace < selecting[j].Namespace })
+	return selecting
+}
+
+// selectorOf returns the first of the <selecting> ElasticQuotaInfos which selects the namespace, if any.
+func selectorOf(selecting []*ElasticQuotaInfo, ns *v1.Namespace) *ElasticQuotaInfo {
+	for _, elasticQuotaInfo := range selecting {
+		if elasticQuotaInfo.namespaceSelector.Matches(labels.Set(ns.Labels)) {
+			return elasticQuotaInfo
+		}
+	}
+	return nil
+}
+
 // ElasticQuotaInfo is a wrapper to a ElasticQuota with information.
-// Each namespace can only have one ElasticQuota.
+// Each namespace can only have one ElasticQuota. Besides its own namespace, an ElasticQuotaInfo
+// applies to the namespaces selected by its namespace selector.
 type ElasticQuotaInfo struct {
 	Namespace string
 	pods      sets.String
 	Min       *framework.Resource
 	Max       *framework.Resource
-	Used      *framework.Resource
+	// Used is the usage of the ElasticQuota and of its descendants.
+	Used *framework.Resource
+	// name is the name of the ElasticQuota.
+	name string
+	// parentName is the namespace/name of the parent ElasticQuota, if any, and parent points to it.
+	parentName string
+	parent     *ElasticQuotaInfo
+	// namespaceSelector selects the namespaces, other than its own, the ElasticQuota applies to.
+	namespaceSelector labels.Selector
+	// weight is the share of the borrowed capacity the ElasticQuota is entitled to. Zero means 1.
+	weight int32
+	// reclaimPolicy controls the preemption of the pods of the ElasticQuota to reclaim the capacity they borrow.
+	reclaimPolicy *v1alpha1.ReclaimPolicy
+	// priorityClassMax bounds the usage of the pods of each priority class subject to the ElasticQuota itself, and
+	// priorityClassUsed is the usage of the pods of each priority class, excluding the pods of its descendants.
+	priorityClassMax  map[string]*framework.Resource
+	priorityClassUsed map[string]*framework.Resource
+	// gangs stores the quota held for the members of PodGroups which are not reserved yet, keyed by
+	// the name of the PodGroup. The held quota is accounted in Used.
+	gangs map[string]*gangQuota
+}
+
+// gangQuota is the quota held for the pending members of a PodGroup once their quota got admitted at once.
+type gangQuota struct {
+	// members are the pending members the quota is held for, keyed by pod key.
+	members   map[string]gangMember
+	expiresAt time.Time
+}
+
+// gangMember is the quota held for a pending member of a PodGroup: its request, in its priority class.
+type gangMember struct {
+	request           framework.Resource
+	priorityClassName string
 }
 
 func newElasticQuotaInfo(namespace string, min, max, used v1.ResourceList) *ElasticQuotaInfo {
@@ -86,23 +267,169 @@ func newElasticQuotaInfo(namespace string, min, max, used v1.ResourceList) *Elas
 	return elasticQuotaInfo
 }
 
+// newElasticQuotaInfoFrom returns the ElasticQuotaInfo of the given ElasticQuota, not linked to its parent yet.
+func newElasticQuotaInfoFrom(eq *v1alpha1.ElasticQuota) *ElasticQuotaInfo {
+	elasticQuotaInfo := newElasticQuotaInfo(eq.Namespace, eq.Spec.Min, eq.Spec.Max, nil)
+	elasticQuotaInfo.name = eq.Name
+	elasticQuotaInfo.parentName = util.GetElasticQuotaParentName(eq)
+	selector, err := util.GetElasticQuotaNamespaceSelector(eq)
+	if err != nil {
+		klog.ErrorS(err, "Ignoring the invalid namespace selector of ElasticQuota", "elasticQuota", klog.KObj(eq))
+	}
+	elasticQuotaInfo.namespaceSelector = selector
+	if eq.Spec.Weight != nil {
+		elasticQuotaInfo.weight = *eq.Spec.Weight
+	}
+	elasticQuotaInfo.reclaimPolicy = eq.Spec.ReclaimPolicy
+	for _, limit := range eq.Spec.PriorityClassMax {
+		if elasticQuotaInfo.priorityClassMax == nil {
+			elasticQuotaInfo.priorityClassMax = make(map[string]*framework.Resource)
+		}
+		// The resources missing from the Max of a priority class aren't bounded.
+		max := makeResourceListForBound(UpperBoundOfMax)
+		for name, quantity := range limit.Resources {
+			max[name] = quantity
+		}
+		elasticQuotaInfo.priorityClassMax[limit.PriorityClassName] = framework.NewResource(max)
+	}
+	return elasticQuotaInfo
+}
+
+// Name returns the namespace/name of the ElasticQuota.
+func (e *ElasticQuotaInfo) Name() string {
+	return e.Namespace + "/" + e.name
+}
+
+// reserveResource adds the request to the usage of the ElasticQuota and of its ancestors.
 func (e *ElasticQuotaInfo) reserveResource(request framework.Resource) {
-	e.Used.Memory += request.Memory
-	e.Used.MilliCPU += request.MilliCPU
-	e.Used.EphemeralStorage += request.EphemeralStorage
-	e.Used.AllowedPodNumber += request.AllowedPodNumber
-	for name, value := range request.ScalarResources {
-		e.Used.SetScalar(name, e.Used.ScalarResources[name]+value)
+	for q := e; q != nil; q = q.parent {
+		q.Used.Memory += request.Memory
+		q.Used.MilliCPU += request.MilliCPU
+		q.Used.EphemeralStorage += request.EphemeralStorage
+		q.Used.AllowedPodNumber += request.AllowedPodNumber
+		for name, value := range request.ScalarResources {
+			q.Used.SetScalar(name, q.Used.ScalarResources[name]+value)
+		}
 	}
 }
 
+// unreserveResource takes the request out of the usage of the ElasticQuota and of its ancestors.
 func (e *ElasticQuotaInfo) unreserveResource(request framework.Resource) {
-	e.Used.Memory -= request.Memory
-	e.Used.MilliCPU -= request.MilliCPU
-	e.Used.EphemeralStorage -= request.EphemeralStorage
-	e.Used.AllowedPodNumber -= request.AllowedPodNumber
+	for q := e; q != nil; q = q.parent {
+		subtractResource(q.Used, &request)
+	}
+}
+
+// depth returns the number of ancestors of the ElasticQuota.
+func (e *ElasticQuotaInfo) depth() int {
+	var depth int
+	for q := e.parent; q != nil; q = q.parent {
+		depth++
+	}
+	return depth
+}
+
+// lowestCommonAncestor returns the lowest common ancestor of the ElasticQuotas <e> and <other>, and their
+// ancestors right below it, i.e. the subtrees which borrow from or lend to each other. If they have no common
+// ancestor, the ancestors returned are their roots. An ancestor returned is nil if the ElasticQuota is itself
+// the lowest common ancestor.
+func (e *ElasticQuotaInfo) lowestCommonAncestor(other *ElasticQuotaInfo) (lca, child, otherChild *ElasticQuotaInfo) {
+	// below maps each ancestor of e, including e itself, to its child on the path to e.
+	below := make(map[*ElasticQuotaInfo]*ElasticQuotaInfo)
+	var root *ElasticQuotaInfo
+	for q, prev := e, (*ElasticQuotaInfo)(nil); q != nil; q, prev = q.parent, q {
+		below[q] = prev
+		root = q
+	}
+	var otherRoot *ElasticQuotaInfo
+	for q, prev := other, (*ElasticQuotaInfo)(nil); q != nil; q, prev = q.parent, q {
+		if child, ok := below[q]; ok {
+			return q, child, prev
+		}
+		otherRoot = q
+	}
+	return nil, root, otherRoot
+}
+
+// canReclaimFrom returns true if a pod of the ElasticQuota requesting <podRequest> may preempt a pod of <other>
+// requesting <victimRequest>: at their lowest common ancestor, the subtree of <other> must use more than its Min,
+// and the subtree of the ElasticQuota, with the request, must either not use more than its Min, or remain below
+// the weighted share of the subtree of <other> without the victim. Shares are relative to <total>, the capacity
+// shared by the ElasticQuotas.
+func (e *ElasticQuotaInfo) canReclaimFrom(other *ElasticQuotaInfo, podRequest, victimRequest, total *framework.Resource) bool {
+	_, child, otherChild := e.lowestCommonAncestor(other)
+	if child == nil || otherChild == nil {
+		// The pods of an ElasticQuota don't reclaim from its ancestors or descendants.
+		return false
+	}
+	if !otherChild.usedOverMin() {
+		return false
+	}
+	if !child.usedOverMinWith(podRequest) {
+		return true
+	}
+	// Both subtrees borrow: the capacity goes to the one with the lowest share.
+	return child.weightedShare(podRequest, total) < otherChild.weightedShare(negateResource(victimRequest), total)
+}
+
+// weightedShare returns the dominant share of the capacity the ElasticQuota borrows beyond its Min, with the
+// given request, divided by its weight. The dominant share is the largest share of the <total> capacity across
+// cpu, memory and extended resources.
+func (e *ElasticQuotaInfo) weightedShare(podRequest, total *framework.Resource) float64 {
+	if podRequest == nil {
+		podRequest = &framework.Resource{}
+	}
+	var min framework.Resource
+	if e.Min != nil {
+		min = *e.Min
+	}
+	share := func(used, min, total int64) float64 {
+		if total <= 0 || used <= min {
+			return 0
+		}
+		return float64(used-min) / float64(total)
+	}
+	dominant := math.Max(share(e.Used.MilliCPU+podRequest.MilliCPU, min.MilliCPU, total.MilliCPU),
+		share(e.Used.Memory+podRequest.Memory, min.Memory, total.Memory))
+	for name, quantity := range total.ScalarResources {
+		dominant = math.Max(dominant, share(e.Used.ScalarResources[name]+podRequest.ScalarResources[name], min.ScalarResources[name], quantity))
+	}
+	weight := e.weight
+	if weight <= 0 {
+		weight = 1
+	}
+	return dominant / float64(weight)
+}
+
+// negateResource returns <r> with the opposite sign, or nil if <r> is nil.
+func negateResource(r *framework.Resource) *framework.Resource {
+	if r == nil {
+		return nil
+	}
+	negated := &framework.Resource{}
+	subtractResource(negated, r)
+	return negated
+}
+
+// addResource adds <request> to <r>.
+func addResource(r, request *framework.Resource) {
+	r.Memory += request.Memory
+	r.MilliCPU += request.MilliCPU
+	r.EphemeralStorage += request.EphemeralStorage
+	r.AllowedPodNumber += request.AllowedPodNumber
+	for name, value := range request.ScalarResources {
+		r.SetScalar(name, r.ScalarResources[name]+value)
+	}
+}
+
+// subtractResource takes <request> out of <r>.
+func subtractResource(r, request *framework.Resource) {
+	r.Memory -= request.Memory
+	r.MilliCPU -= request.MilliCPU
+	r.EphemeralStorage -= request.EphemeralStorage
+	r.AllowedPodNumber -= request.AllowedPodNumber
 	for name, value := range request.ScalarResources {
-		e.Used.SetScalar(name, e.Used.ScalarResources[name]-value)
+		r.SetScalar(name, r.ScalarResources[name]-value)
 	}
 }
 
@@ -114,12 +441,29 @@ func (e *ElasticQuotaInfo) usedOverMinWith(podRequest *framework.Resource) bool
 	return cmp2(podRequest, e.Used, e.Min, LowerBoundOfMin)
 }
 
-func (e *ElasticQuotaInfo) usedOverMaxWith(podRequest *framework.Resource) bool {
-	// "ElasticQuotaInfo doesn't have Max" means there are no limitations(infinite)
-	if e.Max == nil {
+// priorityClassUsedOverMaxWith checks whether the usage of the pods of the priority class with the pod request exceeds
+// the Max of the priority class in the ElasticQuota.
+func (e *ElasticQuotaInfo) priorityClassUsedOverMaxWith(priorityClassName string, podRequest *framework.Resource) bool {
+	max, ok := e.priorityClassMax[priorityClassName]
+	if !ok {
 		return false
 	}
-	return cmp2(podRequest, e.Used, e.Max, UpperBoundOfMax)
+	used, ok := e.priorityClassUsed[priorityClassName]
+	if !ok {
+		used = &framework.Resource{}
+	}
+	return cmp2(podRequest, used, max, UpperBoundOfMax)
+}
+
+// usedOverMaxWith checks whether the usage with the pod request exceeds the Max of the ElasticQuota or of any of its ancestors.
+func (e *ElasticQuotaInfo) usedOverMaxWith(podRequest *framework.Resource) bool {
+	for q := e; q != nil; q = q.parent {
+		// "ElasticQuotaInfo doesn't have Max" means there are no limitations(infinite)
+		if q.Max != nil && cmp2(podRequest, q.Used, q.Max, UpperBoundOfMax) {
+			return true
+		}
+	}
+	return false
 }
 
 func (e *ElasticQuotaInfo) usedOverMin() bool {
@@ -132,8 +476,15 @@ func (e *ElasticQuotaInfo) usedOverMin() bool {
 
 func (e *ElasticQuotaInfo) clone() *ElasticQuotaInfo {
 	newEQInfo := &ElasticQuotaInfo{
-		Namespace: e.Namespace,
-		pods:      sets.NewString(),
+		Namespace:         e.Namespace,
+		pods:              sets.NewString(),
+		name:              e.name,
+		parentName:        e.parentName,
+		parent:            e.parent,
+		namespaceSelector: e.namespaceSelector,
+		weight:            e.weight,
+		reclaimPolicy:     e.reclaimPolicy,
+		priorityClassMax:  e.priorityClassMax,
 	}
 
 	if e.Min != nil {
@@ -151,6 +502,22 @@ func (e *ElasticQuotaInfo) clone() *ElasticQuotaInfo {
 			newEQInfo.pods.Insert(pod)
 		}
 	}
+	if len(e.priorityClassUsed) > 0 {
+		newEQInfo.priorityClassUsed = make(map[string]*framework.Resource, len(e.priorityClassUsed))
+		for name, used := range e.priorityClassUsed {
+			newEQInfo.priorityClassUsed[name] = used.Clone()
+		}
+	}
+	if len(e.gangs) > 0 {
+		newEQInfo.gangs = make(map[string]*gangQuota, len(e.gangs))
+		for name, gang := range e.gangs {
+			members := make(map[string]gangMember, len(gang.members))
+			for key, member := range gang.members {
+				members[key] = member
+			}
+			newEQInfo.gangs[name] = &gangQuota{members: members, expiresAt: gang.expiresAt}
+		}
+	}
 
 	return newEQInfo
 }
@@ -166,8 +533,11 @@ func (e *ElasticQuotaInfo) addPodIfNotPresent(pod *v1.Pod) error {
 	}
 
 	e.pods.Insert(key)
+	// The pod takes over its share of the quota held for its PodGroup, if any.
+	e.consumeGangQuota(util.GetPodGroupFullName(pod), key)
 	podRequest := computePodResourceRequest(pod)
 	e.reserveResource(*podRequest)
+	e.reservePriorityClassResource(pod.Spec.PriorityClassName, podRequest)
 
 	return nil
 }
@@ -185,10 +555,105 @@ func (e *ElasticQuotaInfo) deletePodIfPresent(pod *v1.Pod) error {
 	e.pods.Delete(key)
 	podRequest := computePodResourceRequest(pod)
 	e.unreserveResource(*podRequest)
+	e.unreservePriorityClassResource(pod.Spec.PriorityClassName, podRequest)
 
 	return nil
 }
 
+// reservePriorityClassResource adds the request to the usage of the pods of the priority class.
+func (e *ElasticQuotaInfo) reservePriorityClassResource(priorityClassName string, request *framework.Resource) {
+	if priorityClassName == "" {
+		return
+	}
+	if e.priorityClassUsed == nil {
+		e.priorityClassUsed = make(map[string]*framework.Resource)
+	}
+	if _, ok := e.priorityClassUsed[priorityClassName]; !ok {
+		e.priorityClassUsed[priorityClassName] = &framework.Resource{}
+	}
+	e.priorityClassUsed[priorityClassName].Add(util.ResourceList(request))
+}
+
+// unreservePriorityClassResource takes the request out of the usage of the pods of the priority class.
+func (e *ElasticQuotaInfo) unreservePriorityClassResource(priorityClassName string, request *framework.Resource) {
+	if used, ok := e.priorityClassUsed[priorityClassName]; ok {
+		subtractResource(used, request)
+	}
+}
+
+// holdGangQuota holds the quota of the given pending members of a PodGroup, replacing the quota held so far.
+// The held quota is also accounted in the usage of the priority class of each member.
+func (e *ElasticQuotaInfo) holdGangQuota(pgName string, members map[string]gangMember, expiresAt time.Time) {
+	e.releaseGangQuota(pgName)
+	if len(members) == 0 {
+		return
+	}
+	if e.gangs == nil {
+		e.gangs = make(map[string]*gangQuota)
+	}
+	e.gangs[pgName] = &gangQuota{members: members, expiresAt: expiresAt}
+	for _, member := range members {
+		e.reserveResource(member.request)
+		e.reservePriorityClassResource(member.priorityClassName, &member.request)
+	}
+}
+
+// releaseGangQuota releases the quota held for the given PodGroup.
+func (e *ElasticQuotaInfo) releaseGangQuota(pgName string) bool {
+	gang, ok := e.gangs[pgName]
+	if !ok {
+		return false
+	}
+	for _, member := range gang.members {
+		e.unreserveResource(member.request)
+		e.unreservePriorityClassResource(member.priorityClassName, &member.request)
+	}
+	delete(e.gangs, pgName)
+	return true
+}
+
+// consumeGangQuota releases the quota held for the given member of the PodGroup, if any.
+func (e *ElasticQuotaInfo) consumeGangQuota(pgName, podKey string) {
+	gang, ok := e.gangs[pgName]
+	if !ok {
+		return
+	}
+	member, ok := gang.members[podKey]
+	if !ok {
+		return
+	}
+	e.unreserveResource(member.request)
+	e.unreservePriorityClassResource(member.priorityClassName, &member.request)
+	if delete(gang.members, podKey); len(gang.members) == 0 {
+		delete(e.gangs, pgName)
+	}
+}
+
+// hasGangQuota returns true if quota is held for the members of the given PodGroup.
+func (e *ElasticQuotaInfo) hasGangQuota(pgName string, now time.Time) bool {
+	gang, ok := e.gangs[pgName]
+	return ok && now.Before(gang.expiresAt)
+}
+
+// holdsGangMember returns true if quota is held for the given member of the PodGroup.
+func (e *ElasticQuotaInfo) holdsGangMember(pgName, podKey string, now time.Time) bool {
+	if !e.hasGangQuota(pgName, now) {
+		return false
+	}
+	_, ok := e.gangs[pgName].members[podKey]
+	return ok
+}
+
+// releaseExpiredGangQuota releases the quota held for the given PodGroup if it expired, and returns true if it was
+// released.
+func (e *ElasticQuotaInfo) releaseExpiredGangQuota(pgName string, now time.Time) bool {
+	gang, ok := e.gangs[pgName]
+	if !ok || now.Before(gang.expiresAt) {
+		return false
+	}
+	return e.releaseGangQuota(pgName)
+}
+
 func cmp(x, y *framework.Resource, bound int64) bool {
 	return cmp2(x, &framework.Resource{}, y, bound)
 }
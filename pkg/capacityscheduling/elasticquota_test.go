@@ -19,11 +19,15 @@ package capacityscheduling
 import (
 	"reflect"
 	"testing"
+	"time"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
 )
 
 func TestReserveResource(t *testing.T) {
@@ -630,3 +634,208 @@ func TestNewElasticQuotaInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestCanReclaimFrom(t *testing.T) {
+	org := &ElasticQuotaInfo{
+		Namespace: "org",
+		Min:       &framework.Resource{Memory: 100},
+		Used:      &framework.Resource{Memory: 100},
+	}
+	teamA := &ElasticQuotaInfo{
+		Namespace: "a",
+		parent:    org,
+		Min:       &framework.Resource{Memory: 60},
+		Used:      &framework.Resource{Memory: 20},
+	}
+	teamB := &ElasticQuotaInfo{
+		Namespace: "b",
+		parent:    org,
+		Min:       &framework.Resource{Memory: 40},
+		Used:      &framework.Resource{Memory: 80},
+	}
+	teamC := &ElasticQuotaInfo{
+		Namespace: "c",
+		parent:    org,
+		Min:       &framework.Resource{Memory: 40},
+		Used:      &framework.Resource{Memory: 80},
+		weight:    4,
+	}
+	other := &ElasticQuotaInfo{
+		Namespace: "other",
+		Min:       &framework.Resource{Memory: 100},
+		Used:      &framework.Resource{Memory: 50},
+	}
+	total := &framework.Resource{Memory: 200}
+	tests := []struct {
+		name          string
+		preemptor     *ElasticQuotaInfo
+		victim        *ElasticQuotaInfo
+		podRequest    *framework.Resource
+		victimRequest *framework.Resource
+		expected      bool
+	}{
+		{
+			name:       "sibling over min",
+			preemptor:  teamA,
+			victim:     teamB,
+			podRequest: &framework.Resource{Memory: 40},
+			expected:   true,
+		},
+		{
+			name:          "both borrow, preemptor below the share of the victim",
+			preemptor:     teamA,
+			victim:        teamB,
+			podRequest:    &framework.Resource{Memory: 50},
+			victimRequest: &framework.Resource{Memory: 10},
+			expected:      true,
+		},
+		{
+			name:          "both borrow, preemptor above the share of the victim without the victim",
+			preemptor:     teamA,
+			victim:        teamB,
+			podRequest:    &framework.Resource{Memory: 60},
+			victimRequest: &framework.Resource{Memory: 20},
+			expected:      false,
+		},
+		{
+			name:          "both borrow, victim below its weighted share",
+			preemptor:     teamA,
+			victim:        teamC,
+			podRequest:    &framework.Resource{Memory: 50},
+			victimRequest: &framework.Resource{Memory: 10},
+			expected:      false,
+		},
+		{
+			name:       "sibling under min",
+			preemptor:  teamB,
+			victim:     teamA,
+			podRequest: &framework.Resource{Memory: 10},
+			expected:   false,
+		},
+		{
+			name:       "root not over min is compared, not the child",
+			preemptor:  other,
+			victim:     teamB,
+			podRequest: &framework.Resource{Memory: 10},
+			expected:   false,
+		},
+		{
+			name:       "ancestor",
+			preemptor:  org,
+			victim:     teamB,
+			podRequest: &framework.Resource{Memory: 10},
+			expected:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.preemptor.canReclaimFrom(tt.victim, tt.podRequest, tt.victimRequest, total); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestElasticQuotaInfosTree(t *testing.T) {
+	org := &ElasticQuotaInfo{
+		Namespace: "org",
+		pods:      sets.NewString(),
+		Max:       &framework.Resource{Memory: 100},
+		Used:      &framework.Resource{Memory: 90},
+	}
+	team := &ElasticQuotaInfo{
+		Namespace: "ns1",
+		pods:      sets.NewString(),
+		parent:    org,
+		Max:       &framework.Resource{Memory: 200},
+		Used:      &framework.Resource{Memory: 50},
+	}
+	elasticQuotaInfos := ElasticQuotaInfos{"org": org, "ns1": team, "ns2": team}
+
+	if !team.usedOverMaxWith(&framework.Resource{Memory: 20}) {
+		t.Errorf("expected the Max of the parent to be checked")
+	}
+
+	clone := elasticQuotaInfos.clone()
+	if clone["ns1"] != clone["ns2"] {
+		t.Errorf("expected the namespaces of an ElasticQuota to share its clone")
+	}
+	if clone["ns1"].parent != clone["org"] {
+		t.Errorf("expected the parent of the clone to be the clone of the parent")
+	}
+
+	clone["ns2"].reserveResource(framework.Resource{Memory: 10})
+	if clone["org"].Used.Memory != 100 || org.Used.Memory != 90 {
+		t.Errorf("expected the usage to be reserved in the parent of the clone only, got %v and %v", clone["org"].Used.Memory, org.Used.Memory)
+	}
+}
+
+func TestPriorityClassUsedOverMaxWith(t *testing.T) {
+	eq := makeEQ("ns1", "eq", makeResourceList(1000, 1000), makeResourceList(100, 100))
+	eq.Spec.PriorityClassMax = []v1alpha1.PriorityClassResources{
+		{PriorityClassName: "low", Resources: v1.ResourceList{v1.ResourceMemory: *resource.NewQuantity(500, resource.BinarySI)}},
+	}
+	elasticQuotaInfo := newElasticQuotaInfoFrom(eq)
+
+	low := makePod("t1-p1", "ns1", 300, 100, 0, lowPriority, "t1-p1", "node-a")
+	low.Spec.PriorityClassName = "low"
+	high := makePod("t1-p2", "ns1", 300, 100, 0, highPriority, "t1-p2", "node-a")
+	high.Spec.PriorityClassName = "high"
+	for _, pod := range []*v1.Pod{low, high} {
+		if err := elasticQuotaInfo.addPodIfNotPresent(pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name              string
+		priorityClassName string
+		podRequest        *framework.Resource
+		expected          bool
+	}{
+		{
+			name:              "within the max of the priority class",
+			priorityClassName: "low",
+			podRequest:        &framework.Resource{MilliCPU: 800, Memory: 200},
+			expected:          false,
+		},
+		{
+			name:              "over the max of the priority class",
+			priorityClassName: "low",
+			podRequest:        &framework.Resource{Memory: 201},
+			expected:          true,
+		},
+		{
+			name:              "priority class without max",
+			priorityClassName: "high",
+			podRequest:        &framework.Resource{Memory: 900},
+			expected:          false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := elasticQuotaInfo.priorityClassUsedOverMaxWith(tt.priorityClassName, tt.podRequest); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if err := elasticQuotaInfo.deletePodIfPresent(low); err != nil {
+		t.Fatal(err)
+	}
+	if elasticQuotaInfo.priorityClassUsedOverMaxWith("low", &framework.Resource{Memory: 500}) {
+		t.Errorf("expected the usage of the deleted pod to be released from its priority class")
+	}
+
+	elasticQuotaInfo.holdGangQuota("ns1/pg", map[string]gangMember{
+		"ns1/pg-0": {request: framework.Resource{Memory: 200}, priorityClassName: "low"},
+		"ns1/pg-1": {request: framework.Resource{Memory: 200}, priorityClassName: "low"},
+	}, time.Now().Add(time.Minute))
+	if !elasticQuotaInfo.priorityClassUsedOverMaxWith("low", &framework.Resource{Memory: 101}) {
+		t.Errorf("expected the quota held for a PodGroup to count in the usage of its priority class")
+	}
+	elasticQuotaInfo.releaseGangQuota("ns1/pg")
+	if elasticQuotaInfo.priorityClassUsedOverMaxWith("low", &framework.Resource{Memory: 500}) {
+		t.Errorf("expected the quota released for a PodGroup to be released from its priority class")
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityscheduling
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+	pglister "sigs.k8s.io/scheduler-plugins/pkg/generated/listers/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+)
+
+// newPodGroupLister returns a PodGroup lister whose informer releases the quota held for a PodGroup once
+// Coscheduling rejects it. It returns nil if the PodGroup API isn't served, in which case the quota of
+// PodGroup members is admitted one pod at a time. The informer is shared with the other plugins, e.g. Coscheduling.
+func (c *CapacityScheduling) newPodGroupLister(ctx context.Context, handle framework.Handle) (pglister.PodGroupLister, error) {
+	served, err := util.PodGroupsServed(handle.KubeConfig())
+	if err != nil {
+		return nil, err
+	}
+	if !served {
+		klog.InfoS("PodGroup API is not available, PodGroups are admitted one pod at a time")
+		return nil, nil
+	}
+
+	pgInformerFactory, err := util.SchedulingInformerFactory(handle.KubeConfig())
+	if err != nil {
+		return nil, err
+	}
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+	registration, err := pgInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPG, ok := oldObj.(*v1alpha1.PodGroup)
+			if !ok {
+				return
+			}
+			newPG, ok := newObj.(*v1alpha1.PodGroup)
+			if !ok {
+				return
+			}
+			if podGroupRejected(oldPG, newPG) {
+				c.releaseGangQuota(newPG.Namespace, newPG.Name)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if pg, ok := obj.(*v1alpha1.PodGroup); ok {
+				c.releaseGangQuota(pg.Namespace, pg.Name)
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		if err := pgInformer.Informer().RemoveEventHandler(registration); err != nil {
+			klog.ErrorS(err, "Failed to remove the PodGroup event handler")
+		}
+	}()
+	pgInformerFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), pgInformer.Informer().HasSynced) {
+		return nil, fmt.Errorf("failed to sync the PodGroup informer")
+	}
+	return pgInformer.Lister(), nil
+}
+
+// podGroupRejected returns true if Coscheduling rejected the PodGroup, as reported by its Scheduled condition.
+func podGroupRejected(oldPG, newPG *v1alpha1.PodGroup) bool {
+	c := meta.FindStatusCondition(newPG.Status.Conditions, v1alpha1.PodGroupScheduled)
+	if c == nil || c.Status != metav1.ConditionFalse ||
+		(c.Reason != v1alpha1.PodGroupReasonUnschedulable && c.Reason != v1alpha1.PodGroupReasonPermitTimeout) {
+		return false
+	}
+	old := meta.FindStatusCondition(oldPG.Status.Conditions, v1alpha1.PodGroupScheduled)
+	return old == nil || old.Status != c.Status || old.Reason != c.Reason || old.Message != c.Message
+}
+
+// gangMembersToAdmit returns the PodGroup of the pod and its pending members whose quota is admitted along
+// with the pod, including the pod: up to the members which are missing to reach spec.minMember in the given
+// ElasticQuota. It returns a nil PodGroup if the pod doesn't belong to one, or if it's admitted by itself, as it's
+// the last missing member or a member beyond the ones quota is held for. held is true if quota is held for the pod.
+func (c *CapacityScheduling) gangMembersToAdmit(pod *v1.Pod, podReq *framework.Resource, eq *ElasticQuotaInfo, now time.Time) (pg *v1alpha1.PodGroup, members map[string]gangMember, held bool) {
+	pgName := util.GetPodGroupLabel(pod)
+	if pgName == "" || c.pgLister == nil {
+		return nil, nil, false
+	}
+	podKey, err := framework.GetPodKey(pod)
+	if err != nil {
+		return nil, nil, false
+	}
+	pgFullName := pod.Namespace + "/" + pgName
+	if eq.hasGangQuota(pgFullName, now) {
+		return nil, nil, eq.holdsGangMember(pgFullName, podKey, now)
+	}
+	pg, err = c.pgLister.PodGroups(pod.Namespace).Get(pgName)
+	if err != nil {
+		klog.V(4).InfoS("Failed to get the PodGroup of the pod", "pod", klog.KObj(pod), "podGroup", pgName, "err", err)
+		return nil, nil, false
+	}
+
+	pods, err := c.podLister.Pods(pod.Namespace).List(labels.SelectorFromSet(labels.Set{v1alpha1.PodGroupLabel: pgName}))
+	if err != nil {
+		klog.ErrorS(err, "Failed to list the members of the PodGroup", "podGroup", klog.KObj(pg))
+		return nil, nil, false
+	}
+	remaining := int(pg.Spec.MinMember)
+	var pending []*v1.Pod
+	for _, member := range pods {
+		key, err := framework.GetPodKey(member)
+		if err != nil {
+			continue
+		}
+		if eq.pods.Has(key) {
+			remaining--
+		} else if key != podKey && member.DeletionTimestamp == nil {
+			pending = append(pending, member)
+		}
+	}
+	if remaining <= 1 {
+		return nil, nil, false
+	}
+	// The pending members are admitted in a stable order, so that the same ones are picked in every attempt.
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Name < pending[j].Name })
+	if len(pending) > remaining-1 {
+		pending = pending[:remaining-1]
+	}
+	members = map[string]gangMember{podKey: {request: *podReq, priorityClassName: pod.Spec.PriorityClassName}}
+	for _, member := range pending {
+		key, _ := framework.GetPodKey(member)
+		members[key] = gangMember{request: *computePodResourceRequest(member), priorityClassName: member.Spec.PriorityClassName}
+	}
+	return pg, members, false
+}
+
+// holdGangQuota holds the quota of the given pending members of the PodGroup until its schedule timeout.
+func (c *CapacityScheduling) holdGangQuota(pg *v1alpha1.PodGroup, members map[string]gangMember, now time.Time) {
+	timeout := util.GetWaitTimeDuration(pg, nil)
+	c.Lock()
+	defer c.Unlock()
+	eq := c.elasticQuotaInfos[pg.Namespace]
+	if eq == nil {
+		return
+	}
+	c.elasticQuotaChanged(eq)
+	eq.holdGangQuota(pg.Namespace+"/"+pg.Name, members, now.Add(timeout))
+	// A hold renewed in the meantime outlives the timer, and is released by the timer of the renewal.
+	time.AfterFunc(timeout, func() { c.releaseExpiredGangQuota(pg.Namespace, pg.Name) })
+	klog.V(4).InfoS("Held quota for the members of the PodGroup", "podGroup", klog.KObj(pg), "members", len(members))
+}
+
+// releaseGangQuota releases the quota held for the members of the given PodGroup.
+func (c *CapacityScheduling) releaseGangQuota(namespace, pgName string) {
+	c.Lock()
+	defer c.Unlock()
+	eq := c.elasticQuotaInfos[namespace]
+	if eq != nil && eq.releaseGangQuota(namespace+"/"+pgName) {
+		c.elasticQuotaChanged(eq)
+		klog.V(4).InfoS("Released quota held for the members of the PodGroup", "podGroup", klog.KRef(namespace, pgName))
+	}
+}
+
+// releaseExpiredGangQuota releases the quota held for the given PodGroup if it didn't get all its members reserved
+// in time.
+func (c *CapacityScheduling) releaseExpiredGangQuota(namespace, pgName string) {
+	c.Lock()
+	defer c.Unlock()
+	eq := c.elasticQuotaInfos[namespace]
+	if eq != nil && eq.releaseExpiredGangQuota(namespace+"/"+pgName, time.Now()) {
+		c.elasticQuotaChanged(eq)
+		klog.V(4).InfoS("Released expired quota held for the members of the PodGroup", "podGroup", klog.KRef(namespace, pgName))
+	}
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityscheduling
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	tf "k8s.io/kubernetes/pkg/scheduler/testing/framework"
+	"k8s.io/utils/pointer"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+	pglister "sigs.k8s.io/scheduler-plugins/pkg/generated/listers/scheduling/v1alpha1"
+	testutil "sigs.k8s.io/scheduler-plugins/test/util"
+)
+
+func makeGangPod(podName, pgName string, memReq int64) *v1.Pod {
+	pod := makePod(podName, "ns1", memReq, 0, 0, midPriority, podName, "")
+	pod.Labels = map[string]string{v1alpha1.PodGroupLabel: pgName}
+	return pod
+}
+
+func TestPreFilterPodGroup(t *testing.T) {
+	pgs := []*v1alpha1.PodGroup{
+		testutil.MakePodGroup().Name("pg1").Namespace("ns1").MinMember(3).Obj(),
+		testutil.MakePodGroup().Name("pg2").Namespace("ns1").MinMember(3).Obj(),
+	}
+	members := []*v1.Pod{
+		makeGangPod("pg1-p1", "pg1", 400),
+		makeGangPod("pg1-p2", "pg1", 500),
+		makeGangPod("pg1-p3", "pg1", 600),
+		makeGangPod("pg1-p4", "pg1", 600),
+		makeGangPod("pg2-p1", "pg2", 300),
+		makeGangPod("pg2-p2", "pg2", 300),
+		makeGangPod("pg2-p3", "pg2", 300),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fwk, err := tf.NewFramework(
+		ctx, []tf.RegisterPluginFunc{
+			tf.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+			tf.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		}, "",
+		frameworkruntime.WithPodNominator(testutil.NewPodNominator(nil)),
+		frameworkruntime.WithSnapshotSharedLister(testutil.NewFakeSharedLister(make([]*v1.Pod, 0), make([]*v1.Node, 0))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	podInformer := informers.NewSharedInformerFactory(clientsetfake.NewSimpleClientset(), 0).Core().V1().Pods()
+	for _, pod := range members {
+		podInformer.Informer().GetStore().Add(pod)
+	}
+	pgIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, pg := range pgs {
+		pgIndexer.Add(pg)
+	}
+	c := &CapacityScheduling{
+		fh:            fwk,
+		podLister:     podInformer.Lister(),
+		pgLister:      pglister.NewPodGroupLister(pgIndexer),
+		nominatedPods: newNominatedPodIndex(),
+		elasticQuotaInfos: map[string]*ElasticQuotaInfo{
+			"ns1": {
+				Namespace: "ns1",
+				pods:      sets.NewString(),
+				Min:       &framework.Resource{Memory: 10000},
+				Max:       &framework.Resource{Memory: 2000},
+				Used:      &framework.Resource{},
+			},
+		},
+	}
+	used := func() int64 { return c.elasticQuotaInfos["ns1"].Used.Memory }
+
+	// The quota of 3 members of pg1 is admitted at once, each with its own request, and held.
+	if _, got := c.PreFilter(ctx, framework.NewCycleState(), members[0]); !got.IsSuccess() {
+		t.Fatalf("Expected the first member of pg1 to be admitted, got %v", got.Message())
+	}
+	if got := used(); got != 1500 {
+		t.Errorf("Expected the quota of 3 members to be held, got used memory %v", got)
+	}
+	// The 3 members of pg2 don't fit in the remaining quota.
+	if _, got := c.PreFilter(ctx, framework.NewCycleState(), members[4]); got.Code() != framework.Unschedulable {
+		t.Errorf("Expected pg2 to be rejected, got %v", got.Code())
+	}
+	// Neither does a pod outside of pg1, nor a member of pg1 beyond the ones quota is held for.
+	if _, got := c.PreFilter(ctx, framework.NewCycleState(), makePod("p", "ns1", 600, 0, 0, midPriority, "p", "")); got.Code() != framework.Unschedulable {
+		t.Errorf("Expected a pod outside of pg1 to be rejected, got %v", got.Code())
+	}
+	if _, got := c.PreFilter(ctx, framework.NewCycleState(), members[3]); got.Code() != framework.Unschedulable {
+		t.Errorf("Expected the member of pg1 beyond its minMember to be rejected, got %v", got.Code())
+	}
+
+	// A reserved member takes over its share of the held quota.
+	if got := c.Reserve(ctx, framework.NewCycleState(), members[0], "node-a"); !got.IsSuccess() {
+		t.Fatalf("Expected Reserve to succeed, got %v", got.Message())
+	}
+	if got := used(); got != 1500 {
+		t.Errorf("Expected used memory to stay at 1500, got %v", got)
+	}
+	// Other members are admitted by the held quota.
+	if _, got := c.PreFilter(ctx, framework.NewCycleState(), members[1]); !got.IsSuccess() {
+		t.Fatalf("Expected the second member of pg1 to be admitted, got %v", got.Message())
+	}
+	if got := used(); got != 1500 {
+		t.Errorf("Expected used memory to stay at 1500, got %v", got)
+	}
+
+	// Once Coscheduling rejects the group, its members get unreserved and the held quota is released.
+	c.Unreserve(ctx, framework.NewCycleState(), members[0], "node-a")
+	if got := used(); got != 0 {
+		t.Errorf("Expected the quota of pg1 to be released, got used memory %v", got)
+	}
+}
+
+func TestReleaseExpiredGangQuota(t *testing.T) {
+	pg := testutil.MakePodGroup().Name("pg1").Namespace("ns1").MinMember(2).Obj()
+	pg.Spec.ScheduleTimeoutSeconds = pointer.Int32(1)
+	eq := &ElasticQuotaInfo{
+		Namespace: "ns1",
+		pods:      sets.NewString(),
+		Min:       &framework.Resource{Memory: 10000},
+		Max:       &framework.Resource{Memory: 2000},
+		Used:      &framework.Resource{},
+	}
+	c := &CapacityScheduling{elasticQuotaInfos: map[string]*ElasticQuotaInfo{"ns1": eq}}
+	used := func() int64 {
+		c.RLock()
+		defer c.RUnlock()
+		return eq.Used.Memory
+	}
+
+	now := time.Now()
+	c.holdGangQuota(pg, map[string]gangMember{
+		"ns1/pg1-p1": {request: framework.Resource{Memory: 400}},
+		"ns1/pg1-p2": {request: framework.Resource{Memory: 600}},
+	}, now)
+	if got := used(); got != 1000 {
+		t.Fatalf("Expected the quota of 2 members to be held, got used memory %v", got)
+	}
+	// The quota isn't released before it expires.
+	c.Lock()
+	if eq.releaseExpiredGangQuota("ns1/pg1", now) {
+		t.Errorf("Expected the quota of pg1 not to be released before its schedule timeout")
+	}
+	c.changed = nil
+	c.Unlock()
+
+	// It's released once the schedule timeout passes, and the ElasticQuota is snapshotted again.
+	if err := wait.PollUntilContextTimeout(context.Background(), 100*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+		return used() == 0, nil
+	}); err != nil {
+		t.Fatalf("Expected the quota of pg1 to be released, got used memory %v", used())
+	}
+	c.RLock()
+	defer c.RUnlock()
+	if !c.changed.Has(eq) {
+		t.Errorf("Expected the ElasticQuota to be marked changed")
+	}
+}
+
+func TestPodGroupRejected(t *testing.T) {
+	pg := testutil.MakePodGroup().Name("pg1").Namespace("ns1").MinMember(3).Obj()
+	withScheduled := func(reason string) *v1alpha1.PodGroup {
+		p := pg.DeepCopy()
+		p.Status.Conditions = []metav1.Condition{{Type: v1alpha1.PodGroupScheduled, Status: metav1.ConditionFalse, Reason: reason}}
+		return p
+	}
+	tests := []struct {
+		name  string
+		oldPG *v1alpha1.PodGroup
+		newPG *v1alpha1.PodGroup
+		want  bool
+	}{
+		{
+			name:  "rejected in PostFilter",
+			oldPG: pg,
+			newPG: withScheduled(v1alpha1.PodGroupReasonUnschedulable),
+			want:  true,
+		},
+		{
+			name:  "timed out at Permit",
+			oldPG: withScheduled(v1alpha1.PodGroupReasonWaiting),
+			newPG: withScheduled(v1alpha1.PodGroupReasonPermitTimeout),
+			want:  true,
+		},
+		{
+			name:  "rejected in PreFilter",
+			oldPG: pg,
+			newPG: withScheduled(v1alpha1.PodGroupReasonNotEnoughPods),
+		},
+		{
+			name:  "condition unchanged",
+			oldPG: withScheduled(v1alpha1.PodGroupReasonUnschedulable),
+			newPG: withScheduled(v1alpha1.PodGroupReasonUnschedulable),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podGroupRejected(tt.oldPG, tt.newPG); got != tt.want {
+				t.Errorf("Want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
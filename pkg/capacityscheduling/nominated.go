@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityscheduling
+
+import (
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// nominatedPodIndex indexes the pods which are nominated to run on a node and not assigned yet by namespace, so that
+// PreFilter doesn't go through the nominated pods of every node.
+type nominatedPodIndex struct {
+	sync.RWMutex
+	pods map[string]map[types.UID]*nominatedPod
+}
+
+// nominatedPod is what PreFilter needs to know about a nominated pod.
+type nominatedPod struct {
+	priority int32
+	request  *framework.Resource
+}
+
+func newNominatedPodIndex() *nominatedPodIndex {
+	return &nominatedPodIndex{pods: make(map[string]map[types.UID]*nominatedPod)}
+}
+
+// update indexes the pod if it's nominated to run on a node, and drops it otherwise.
+func (i *nominatedPodIndex) update(pod *v1.Pod) {
+	i.nominate(pod, pod.Status.NominatedNodeName)
+}
+
+// nominate indexes the pod as nominated to run on the given node. The pod is dropped if the node is empty or the
+// pod is assigned already.
+func (i *nominatedPodIndex) nominate(pod *v1.Pod, nodeName string) {
+	if nodeName == "" || pod.Spec.NodeName != "" {
+		i.delete(pod)
+		return
+	}
+	i.Lock()
+	defer i.Unlock()
+	pods, ok := i.pods[pod.Namespace]
+	if !ok {
+		pods = make(map[types.UID]*nominatedPod)
+		i.pods[pod.Namespace] = pods
+	}
+	pods[pod.UID] = &nominatedPod{priority: corev1helpers.PodPriority(pod), request: computePodResourceRequest(pod)}
+}
+
+// delete drops the pod from the index.
+func (i *nominatedPodIndex) delete(pod *v1.Pod) {
+	i.Lock()
+	defer i.Unlock()
+	pods, ok := i.pods[pod.Namespace]
+	if !ok {
+		return
+	}
+	delete(pods, pod.UID)
+	if len(pods) == 0 {
+		delete(i.pods, pod.Namespace)
+	}
+}
+
+// requests returns the requests of the nominated pods, other than the pod itself, which count against the quotas
+// of the pod subject to the ElasticQuota <eq>:
+// 1. inEQ: the pods subject to the same quota and at least as important as the pod.
+// 2. total: the pods of inEQ, plus the pods subject to the other quotas whose usage does not exceed their min.
+func (i *nominatedPodIndex) requests(pod *v1.Pod, eq *ElasticQuotaInfo, elasticQuotaInfos ElasticQuotaInfos) (inEQ, total *framework.Resource) {
+	inEQ, total = &framework.Resource{}, &framework.Resource{}
+	podPriority := corev1helpers.PodPriority(pod)
+
+	i.RLock()
+	defer i.RUnlock()
+	for namespace, pods := range i.pods {
+		info := elasticQuotaInfos[namespace]
+		if info == nil || (info != eq && info.usedOverMin()) {
+			continue
+		}
+		for uid, p := range pods {
+			if uid == pod.UID {
+				continue
+			}
+			if info != eq {
+				addResource(total, p.request)
+			} else if p.priority >= podPriority {
+				addResource(inEQ, p.request)
+				addResource(total, p.request)
+			}
+		}
+	}
+	return inEQ, total
+}
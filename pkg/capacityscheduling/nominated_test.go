@@ -0,0 +1,181 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityscheduling
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+	testutil "sigs.k8s.io/scheduler-plugins/test/util"
+)
+
+func TestNominatedPodIndex(t *testing.T) {
+	nominated := func(pod *v1.Pod, nodeName string) *v1.Pod {
+		pod.Status.NominatedNodeName = nodeName
+		return pod
+	}
+	elasticQuotaInfos := ElasticQuotaInfos{
+		"ns1": {Namespace: "ns1", Min: &framework.Resource{Memory: 1000}, Used: &framework.Resource{Memory: 500}},
+		"ns2": {Namespace: "ns2", Min: &framework.Resource{Memory: 1000}, Used: &framework.Resource{Memory: 500}},
+		"ns3": {Namespace: "ns3", Min: &framework.Resource{Memory: 1000}, Used: &framework.Resource{Memory: 1500}},
+	}
+	tests := []struct {
+		name      string
+		pods      []*v1.Pod
+		deleted   []*v1.Pod
+		wantInEQ  int64
+		wantTotal int64
+	}{
+		{
+			name: "pods of the same quota at least as important as the pod",
+			pods: []*v1.Pod{
+				nominated(makePod("p1", "ns1", 10, 0, 0, highPriority, "p1", ""), "node-a"),
+				nominated(makePod("p2", "ns1", 20, 0, 0, midPriority, "p2", ""), "node-b"),
+				nominated(makePod("p3", "ns1", 40, 0, 0, lowPriority, "p3", ""), "node-b"),
+			},
+			wantInEQ:  30,
+			wantTotal: 30,
+		},
+		{
+			name: "pods of the other quotas below their min",
+			pods: []*v1.Pod{
+				nominated(makePod("p1", "ns2", 10, 0, 0, lowPriority, "p1", ""), "node-a"),
+				nominated(makePod("p2", "ns3", 20, 0, 0, highPriority, "p2", ""), "node-a"),
+				nominated(makePod("p3", "ns4", 40, 0, 0, highPriority, "p3", ""), "node-a"),
+			},
+			wantTotal: 10,
+		},
+		{
+			name: "pod itself, assigned pods and pods without nomination",
+			pods: []*v1.Pod{
+				nominated(makePod("pod", "ns1", 10, 0, 0, midPriority, "pod", ""), "node-a"),
+				nominated(makePod("p1", "ns1", 20, 0, 0, highPriority, "p1", "node-a"), "node-a"),
+				makePod("p2", "ns2", 40, 0, 0, highPriority, "p2", ""),
+			},
+		},
+		{
+			name: "deleted pods",
+			pods: []*v1.Pod{
+				nominated(makePod("p1", "ns1", 10, 0, 0, highPriority, "p1", ""), "node-a"),
+				nominated(makePod("p2", "ns2", 20, 0, 0, highPriority, "p2", ""), "node-a"),
+			},
+			deleted:   []*v1.Pod{makePod("p2", "ns2", 20, 0, 0, highPriority, "p2", "")},
+			wantInEQ:  10,
+			wantTotal: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := newNominatedPodIndex()
+			for _, pod := range tt.pods {
+				index.update(pod)
+			}
+			for _, pod := range tt.deleted {
+				index.delete(pod)
+			}
+			pod := makePod("pod", "ns1", 10, 0, 0, midPriority, "pod", "")
+			inEQ, total := index.requests(pod, elasticQuotaInfos["ns1"], elasticQuotaInfos)
+			if inEQ.Memory != tt.wantInEQ || total.Memory != tt.wantTotal {
+				t.Errorf("expected requests %v and %v, got %v and %v", tt.wantInEQ, tt.wantTotal, inEQ.Memory, total.Memory)
+			}
+		})
+	}
+}
+
+func BenchmarkNominatedPodsRequests(b *testing.B) {
+	tests := []struct {
+		name          string
+		nodes         int
+		elasticQuotas int
+		nominatedPods int
+	}{
+		{name: "500 nodes, 200 quotas, 20 nominated pods", nodes: 500, elasticQuotas: 200, nominatedPods: 20},
+		{name: "5000 nodes, 2000 quotas, 20 nominated pods", nodes: 5000, elasticQuotas: 2000, nominatedPods: 20},
+		{name: "5000 nodes, 2000 quotas, 500 nominated pods", nodes: 5000, elasticQuotas: 2000, nominatedPods: 500},
+	}
+	for _, tt := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		elasticQuotaInfos := NewElasticQuotaInfos()
+		for i := 0; i < tt.elasticQuotas; i++ {
+			ns := fmt.Sprintf("ns-%d", i)
+			elasticQuotaInfos[ns] = newElasticQuotaInfo(ns, makeResourceList(1000, 1000), nil, nil)
+		}
+		var nodes []*v1.Node
+		for i := 0; i < tt.nodes; i++ {
+			nodes = append(nodes, st.MakeNode().Name(fmt.Sprintf("node-%d", i)).Obj())
+		}
+		nominator := testutil.NewPodNominator(nil)
+		index := newNominatedPodIndex()
+		for i := 0; i < tt.nominatedPods; i++ {
+			name, ns, node := fmt.Sprintf("p-%d", i), fmt.Sprintf("ns-%d", i%tt.elasticQuotas), fmt.Sprintf("node-%d", i%tt.nodes)
+			pod := makePod(name, ns, 10, 10, 0, midPriority, name, "")
+			pod.Status.NominatedNodeName = node
+			podInfo, _ := framework.NewPodInfo(pod)
+			nominator.AddNominatedPod(klog.Background(), podInfo, &framework.NominatingInfo{NominatingMode: framework.ModeOverride, NominatedNodeName: node})
+			index.update(pod)
+		}
+		fwk, err := frameworkruntime.NewFramework(ctx, nil, nil,
+			frameworkruntime.WithPodNominator(nominator),
+			frameworkruntime.WithSnapshotSharedLister(testutil.NewFakeSharedLister(nil, nodes)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		pod := makePod("pod", "ns-0", 10, 10, 0, midPriority, "pod", "")
+		eq := elasticQuotaInfos[pod.Namespace]
+
+		b.Run(tt.name+"/per node", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				inEQ, total := &framework.Resource{}, &framework.Resource{}
+				nodeInfos, _ := fwk.SnapshotSharedLister().NodeInfos().List()
+				for _, node := range nodeInfos {
+					for _, p := range fwk.NominatedPodsForNode(node.Node().Name) {
+						if p.Pod.UID == pod.UID {
+							continue
+						}
+						info := elasticQuotaInfos[p.Pod.Namespace]
+						if info == nil {
+							continue
+						}
+						request := util.ResourceList(computePodResourceRequest(p.Pod))
+						if info == eq && corev1helpers.PodPriority(p.Pod) >= corev1helpers.PodPriority(pod) {
+							inEQ.Add(request)
+							total.Add(request)
+						} else if info != eq && !info.usedOverMin() {
+							total.Add(request)
+						}
+					}
+				}
+			}
+		})
+		b.Run(tt.name+"/by namespace", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				index.requests(pod, eq, elasticQuotaInfos)
+			}
+		})
+	}
+}
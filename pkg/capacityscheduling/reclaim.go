@@ -0,0 +1,185 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityscheduling
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	schedutil "k8s.io/kubernetes/pkg/scheduler/util"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+)
+
+const (
+	// defaultPreemptionInterval is the interval the maxPreemptions of a reclaimPolicy applies to if it isn't set.
+	defaultPreemptionInterval = 60 * time.Second
+	// reclaimNoticeGracePeriod is how long a notice is valid after the pod can be preempted. Past it, the pod didn't
+	// get preempted, e.g. because the preemptor got scheduled elsewhere, and it's notified again before being preempted.
+	reclaimNoticeGracePeriod = 60 * time.Second
+)
+
+// reclaimHistory records when the pods of each ElasticQuota got preempted to reclaim capacity,
+// to limit the number of preemptions per interval.
+type reclaimHistory struct {
+	sync.Mutex
+	// preemptions are the times of the preemptions, keyed by the namespace/name of the ElasticQuota.
+	preemptions map[string][]time.Time
+}
+
+func newReclaimHistory() *reclaimHistory {
+	return &reclaimHistory{preemptions: make(map[string][]time.Time)}
+}
+
+// count returns the number of pods of the ElasticQuota preempted since the given time, and forgets the older ones.
+func (h *reclaimHistory) count(name string, since time.Time) int {
+	h.Lock()
+	defer h.Unlock()
+	times := h.preemptions[name]
+	i := 0
+	for i < len(times) && times[i].Before(since) {
+		i++
+	}
+	if i == len(times) {
+		delete(h.preemptions, name)
+		return 0
+	}
+	h.preemptions[name] = times[i:]
+	return len(times) - i
+}
+
+// add records the preemption of n pods of the ElasticQuota at the given time.
+func (h *reclaimHistory) add(name string, at time.Time, n int) {
+	h.Lock()
+	defer h.Unlock()
+	for ; n > 0; n-- {
+		h.preemptions[name] = append(h.preemptions[name], at)
+	}
+}
+
+// minRuntimeElapsed returns true if the pod has been running for long enough to be preempted to reclaim the
+// capacity it borrows. Pods which aren't started yet count as just started.
+func (e *ElasticQuotaInfo) minRuntimeElapsed(pod *v1.Pod, now time.Time) bool {
+	if e.reclaimPolicy == nil || e.reclaimPolicy.MinRuntimeSeconds == nil {
+		return true
+	}
+	minRuntime := time.Duration(*e.reclaimPolicy.MinRuntimeSeconds) * time.Second
+	return !now.Before(schedutil.GetPodStartTime(pod).Add(minRuntime))
+}
+
+// preemptionsLeft returns the number of pods of the ElasticQuota which can still be preempted to reclaim capacity
+// in the current interval, or -1 if unlimited.
+func (e *ElasticQuotaInfo) preemptionsLeft(history *reclaimHistory, now time.Time) int {
+	if e.reclaimPolicy == nil || e.reclaimPolicy.MaxPreemptions == nil {
+		return -1
+	}
+	interval := defaultPreemptionInterval
+	if e.reclaimPolicy.PreemptionIntervalSeconds != nil && *e.reclaimPolicy.PreemptionIntervalSeconds > 0 {
+		interval = time.Duration(*e.reclaimPolicy.PreemptionIntervalSeconds) * time.Second
+	}
+	left := int(*e.reclaimPolicy.MaxPreemptions) - history.count(e.Name(), now.Add(-interval))
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// noticeDeadline returns the time the pod can be preempted from to reclaim the capacity it borrows, and whether the
+// pod has been notified already. A notice older than noticeSeconds plus reclaimNoticeGracePeriod is stale, and the
+// pod is to be notified again.
+func (e *ElasticQuotaInfo) noticeDeadline(pod *v1.Pod, now time.Time) (time.Time, bool) {
+	if e.reclaimPolicy == nil || e.reclaimPolicy.NoticeSeconds == nil || *e.reclaimPolicy.NoticeSeconds == 0 {
+		return time.Time{}, true
+	}
+	_, condition := podutil.GetPodCondition(&pod.Status, v1alpha1.PodReclaimNotice)
+	if condition == nil || condition.Status != v1.ConditionTrue {
+		return time.Time{}, false
+	}
+	deadline := condition.LastTransitionTime.Add(time.Duration(*e.reclaimPolicy.NoticeSeconds) * time.Second)
+	if now.After(deadline.Add(reclaimNoticeGracePeriod)) {
+		return time.Time{}, false
+	}
+	return deadline, true
+}
+
+// checkReclaimPolicies checks the victims selected on a node against the reclaimPolicy of their ElasticQuota.
+// If they can't be preempted yet, it returns a status telling why and, if that's because they're given a notice
+// first, the victims left to notify, which is non-nil but empty if they're all notified already. Victims of the
+// ElasticQuota of the preemptor aren't preempted to reclaim capacity.
+func checkReclaimPolicies(victims []*v1.Pod, preemptorElasticQuotaInfo *ElasticQuotaInfo, elasticQuotaInfos ElasticQuotaInfos,
+	history *reclaimHistory, now time.Time) ([]*v1.Pod, *framework.Status) {
+	reclaimed := make(map[*ElasticQuotaInfo]int)
+	toNotify := []*v1.Pod{}
+	var notified bool
+	for _, victim := range victims {
+		eqInfo, ok := elasticQuotaInfos[victim.Namespace]
+		if !ok || eqInfo == preemptorElasticQuotaInfo {
+			continue
+		}
+		reclaimed[eqInfo]++
+		if deadline, ok := eqInfo.noticeDeadline(victim, now); !ok {
+			toNotify = append(toNotify, victim)
+		} else if now.Before(deadline) {
+			notified = true
+		}
+	}
+	for eqInfo, n := range reclaimed {
+		if left := eqInfo.preemptionsLeft(history, now); left >= 0 && n > left {
+			return nil, framework.NewStatus(framework.Unschedulable,
+				fmt.Sprintf("preempting %d pods of ElasticQuota %v exceeds the maximum preemptions of its reclaimPolicy", n, eqInfo.Name()))
+		}
+	}
+	if len(toNotify) > 0 || notified {
+		return toNotify, framework.NewStatus(framework.Unschedulable, "victims are given a notice before being preempted")
+	}
+	return nil, nil
+}
+
+// notifyReclaim sets the ReclaimNotice condition of the pods whose borrowed capacity is reclaimed by the preemptor.
+// The condition replaces a stale one, so that the notice starts over.
+func notifyReclaim(ctx context.Context, fh framework.Handle, preemptor *v1.Pod, pods []*v1.Pod, elasticQuotaInfos ElasticQuotaInfos, now time.Time) {
+	for _, pod := range pods {
+		eqInfo := elasticQuotaInfos[pod.Namespace]
+		notice := time.Duration(*eqInfo.reclaimPolicy.NoticeSeconds) * time.Second
+		condition := v1.PodCondition{
+			Type:               v1alpha1.PodReclaimNotice,
+			Status:             v1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(now),
+			Reason:             v1alpha1.PodReasonCapacityReclaimed,
+			Message: fmt.Sprintf("The capacity borrowed by the pod is reclaimed by %v, it can be preempted from %v",
+				klog.KObj(preemptor), now.Add(notice).UTC().Format(time.RFC3339)),
+		}
+		newStatus := pod.Status.DeepCopy()
+		if i, _ := podutil.GetPodCondition(newStatus, v1alpha1.PodReclaimNotice); i >= 0 {
+			newStatus.Conditions[i] = condition
+		} else {
+			newStatus.Conditions = append(newStatus.Conditions, condition)
+		}
+		if err := schedutil.PatchPodStatus(ctx, fh.ClientSet(), pod, newStatus); err != nil {
+			klog.ErrorS(err, "Failed to notify the pod of the reclaim of its capacity", "pod", klog.KObj(pod), "preemptor", klog.KObj(preemptor))
+			continue
+		}
+		klog.V(3).InfoS("Notified the pod of the reclaim of its capacity", "pod", klog.KObj(pod), "preemptor", klog.KObj(preemptor), "notice", notice)
+	}
+}
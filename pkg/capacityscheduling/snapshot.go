@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityscheduling
+
+import (
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// elasticQuotaSnapshot is a copy of the ElasticQuotaInfos of the plugin, which is shared by the scheduling cycles
+// and must not be modified. It's updated incrementally: a new snapshot only copies the ElasticQuotaInfos which
+// changed since the previous one, along with their descendants, and reuses the copies of the others.
+type elasticQuotaSnapshot struct {
+	elasticQuotaInfos ElasticQuotaInfos
+	// clones maps the ElasticQuotaInfos of the plugin to their copy in the snapshot.
+	clones map[*ElasticQuotaInfo]*ElasticQuotaInfo
+}
+
+// update returns the snapshot of <elasticQuotaInfos>, given the ElasticQuotaInfos which changed since the snapshot
+// <s> was taken, including the ancestors of the ones whose usage changed. <s> is returned as is if nothing changed,
+// and a full snapshot is taken if <s> is nil.
+func (s *elasticQuotaSnapshot) update(elasticQuotaInfos ElasticQuotaInfos, changed sets.Set[*ElasticQuotaInfo]) *elasticQuotaSnapshot {
+	if s != nil && changed.Len() == 0 {
+		return s
+	}
+	next := &elasticQuotaSnapshot{
+		elasticQuotaInfos: make(ElasticQuotaInfos, len(elasticQuotaInfos)),
+		clones:            make(map[*ElasticQuotaInfo]*ElasticQuotaInfo, len(elasticQuotaInfos)),
+	}
+	var cloneOf func(*ElasticQuotaInfo) *ElasticQuotaInfo
+	cloneOf = func(elasticQuotaInfo *ElasticQuotaInfo) *ElasticQuotaInfo {
+		if clone, ok := next.clones[elasticQuotaInfo]; ok {
+			return clone
+		}
+		var parent *ElasticQuotaInfo
+		if elasticQuotaInfo.parent != nil {
+			parent = cloneOf(elasticQuotaInfo.parent)
+		}
+		var clone *ElasticQuotaInfo
+		if s != nil && !changed.Has(elasticQuotaInfo) {
+			// The previous copy can't be reused if its parent got copied again.
+			if prev, ok := s.clones[elasticQuotaInfo]; ok && prev.parent == parent {
+				clone = prev
+			}
+		}
+		if clone == nil {
+			clone = elasticQuotaInfo.clone()
+			clone.parent = parent
+		}
+		next.clones[elasticQuotaInfo] = clone
+		return clone
+	}
+	for key, elasticQuotaInfo := range elasticQuotaInfos {
+		next.elasticQuotaInfos[key] = cloneOf(elasticQuotaInfo)
+	}
+	return next
+}
+
+// elasticQuotaChanged records that the ElasticQuotaInfo changed since the last snapshot, along with its ancestors
+// whose usage includes its usage. It must be called with the lock held.
+func (c *CapacityScheduling) elasticQuotaChanged(elasticQuotaInfo *ElasticQuotaInfo) {
+	if c.changed == nil {
+		c.changed = sets.New[*ElasticQuotaInfo]()
+	}
+	for q := elasticQuotaInfo; q != nil; q = q.parent {
+		c.changed.Insert(q)
+	}
+}
+
+// snapshotElasticQuota returns the snapshot of the ElasticQuotas, which is shared until a pod is added to or
+// removed from it in the cycle state. The snapshot is only taken again, under the write lock, if an ElasticQuotaInfo
+// changed since the previous one.
+func (c *CapacityScheduling) snapshotElasticQuota() *ElasticQuotaSnapshotState {
+	c.RLock()
+	if c.snapshot != nil && c.changed.Len() == 0 {
+		defer c.RUnlock()
+		return &ElasticQuotaSnapshotState{
+			elasticQuotaInfos: c.snapshot.elasticQuotaInfos,
+			shared:            true,
+		}
+	}
+	c.RUnlock()
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.snapshot = c.snapshot.update(c.elasticQuotaInfos, c.changed)
+	c.changed = nil
+	return &ElasticQuotaSnapshotState{
+		elasticQuotaInfos: c.snapshot.elasticQuotaInfos,
+		shared:            true,
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityscheduling
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestSnapshotElasticQuota(t *testing.T) {
+	org := &ElasticQuotaInfo{Namespace: "org", pods: sets.NewString(), Used: &framework.Resource{}}
+	team := &ElasticQuotaInfo{Namespace: "team", pods: sets.NewString(), parent: org, Used: &framework.Resource{}}
+	other := &ElasticQuotaInfo{Namespace: "other", pods: sets.NewString(), Used: &framework.Resource{}}
+	c := &CapacityScheduling{
+		elasticQuotaInfos: ElasticQuotaInfos{"org": org, "team": team, "team-2": team, "other": other},
+	}
+
+	first := c.snapshotElasticQuota()
+	if !first.shared {
+		t.Fatalf("expected the snapshot to be shared")
+	}
+	if got := first.elasticQuotaInfos["team"].parent; got != first.elasticQuotaInfos["org"] {
+		t.Errorf("expected the parent of the snapshot to be the snapshot of the parent")
+	}
+	if second := c.snapshotElasticQuota(); second.elasticQuotaInfos["org"] != first.elasticQuotaInfos["org"] {
+		t.Errorf("expected the snapshot to be reused while the ElasticQuotas are unchanged")
+	}
+
+	// A change of the parent is copied along with its children, the other ElasticQuotas are reused.
+	c.Lock()
+	c.elasticQuotaChanged(org)
+	if err := org.addPodIfNotPresent(makePod("p1", "org", 100, 0, 0, midPriority, "p1", "node-a")); err != nil {
+		t.Fatal(err)
+	}
+	c.Unlock()
+	third := c.snapshotElasticQuota()
+	if third.elasticQuotaInfos["org"].Used.Memory != 100 || first.elasticQuotaInfos["org"].Used.Memory != 0 {
+		t.Errorf("expected the change to be in the new snapshot only, got %v and %v",
+			third.elasticQuotaInfos["org"].Used.Memory, first.elasticQuotaInfos["org"].Used.Memory)
+	}
+	if third.elasticQuotaInfos["team"] == first.elasticQuotaInfos["team"] || third.elasticQuotaInfos["team"].parent != third.elasticQuotaInfos["org"] {
+		t.Errorf("expected the children of a changed ElasticQuota to be copied again")
+	}
+	if third.elasticQuotaInfos["team"] != third.elasticQuotaInfos["team-2"] {
+		t.Errorf("expected the namespaces of an ElasticQuota to share its snapshot")
+	}
+	if third.elasticQuotaInfos["other"] != first.elasticQuotaInfos["other"] {
+		t.Errorf("expected the unchanged ElasticQuotas to be reused")
+	}
+
+	// A change of the child is copied along with its parent.
+	c.Lock()
+	c.elasticQuotaChanged(team)
+	team.reserveResource(framework.Resource{Memory: 50})
+	c.Unlock()
+	fourth := c.snapshotElasticQuota()
+	if fourth.elasticQuotaInfos["org"].Used.Memory != 150 || fourth.elasticQuotaInfos["team"].Used.Memory != 50 {
+		t.Errorf("expected the usage of the child and its parent to be updated, got %v and %v",
+			fourth.elasticQuotaInfos["org"].Used.Memory, fourth.elasticQuotaInfos["team"].Used.Memory)
+	}
+	if fourth.elasticQuotaInfos["other"] != first.elasticQuotaInfos["other"] {
+		t.Errorf("expected the unchanged ElasticQuotas to be reused")
+	}
+}
+
+func TestElasticQuotaSnapshotStateCopyOnWrite(t *testing.T) {
+	c := &CapacityScheduling{
+		elasticQuotaInfos: ElasticQuotaInfos{
+			"ns1": {Namespace: "ns1", pods: sets.NewString(), Used: &framework.Resource{}},
+		},
+	}
+	state := c.snapshotElasticQuota()
+	clone := state.Clone().(*ElasticQuotaSnapshotState)
+	if clone.elasticQuotaInfos["ns1"] != state.elasticQuotaInfos["ns1"] {
+		t.Errorf("expected the clone of a shared snapshot to share it")
+	}
+
+	pod := makePod("p1", "ns1", 100, 0, 0, midPriority, "p1", "node-a")
+	if err := clone.mutableElasticQuotaInfos()["ns1"].addPodIfNotPresent(pod); err != nil {
+		t.Fatal(err)
+	}
+	if clone.shared || clone.elasticQuotaInfos["ns1"].Used.Memory != 100 {
+		t.Errorf("expected the pod to be added to a copy of the snapshot")
+	}
+	if state.elasticQuotaInfos["ns1"].Used.Memory != 0 || c.snapshotElasticQuota().elasticQuotaInfos["ns1"].Used.Memory != 0 {
+		t.Errorf("expected the shared snapshot to be left unchanged")
+	}
+
+	if again := clone.Clone().(*ElasticQuotaSnapshotState); again.shared || again.elasticQuotaInfos["ns1"] == clone.elasticQuotaInfos["ns1"] {
+		t.Errorf("expected the clone of a modified snapshot to be a copy")
+	}
+}
+
+func BenchmarkSnapshotElasticQuota(b *testing.B) {
+	tests := []struct {
+		name          string
+		elasticQuotas int
+		podsPerQuota  int
+	}{
+		{name: "200 quotas with 50 pods each", elasticQuotas: 200, podsPerQuota: 50},
+		{name: "2000 quotas with 50 pods each", elasticQuotas: 2000, podsPerQuota: 50},
+	}
+	for _, tt := range tests {
+		newPlugin := func() *CapacityScheduling {
+			c := &CapacityScheduling{elasticQuotaInfos: NewElasticQuotaInfos()}
+			for i := 0; i < tt.elasticQuotas; i++ {
+				ns := fmt.Sprintf("ns-%d", i)
+				eq := newElasticQuotaInfo(ns, makeResourceList(1000, 1000), nil, nil)
+				for j := 0; j < tt.podsPerQuota; j++ {
+					name := fmt.Sprintf("%s-pod-%d", ns, j)
+					if err := eq.addPodIfNotPresent(makePod(name, ns, 10, 10, 0, midPriority, name, "node")); err != nil {
+						b.Fatal(err)
+					}
+				}
+				c.elasticQuotaInfos[ns] = eq
+			}
+			return c
+		}
+		// Each scheduling cycle follows the reservation of a pod in one of the quotas.
+		reserve := func(c *CapacityScheduling, i int) {
+			ns := fmt.Sprintf("ns-%d", i%tt.elasticQuotas)
+			name := fmt.Sprintf("%s-new-%d", ns, i)
+			c.Reserve(nil, nil, makePod(name, ns, 10, 10, 0, midPriority, name, "node"), "node")
+		}
+
+		b.Run(tt.name+"/full copy", func(b *testing.B) {
+			c := newPlugin()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				reserve(c, i)
+				c.RLock()
+				_ = c.elasticQuotaInfos.clone()
+				c.RUnlock()
+			}
+		})
+		b.Run(tt.name+"/incremental", func(b *testing.B) {
+			c := newPlugin()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				reserve(c, i)
+				_ = c.snapshotElasticQuota()
+			}
+		})
+	}
+}
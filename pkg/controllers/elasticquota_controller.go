@@ -19,21 +19,31 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	v1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	quota "k8s.io/apiserver/pkg/quota/v1"
 	"k8s.io/client-go/tools/record"
 
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
 	schedv1alpha1 "sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
 )
 
 type ElasticQuotaReconciler struct {
@@ -47,40 +57,48 @@ type ElasticQuotaReconciler struct {
 // +kubebuilder:rbac:groups=scheduling.x-k8s.io,resources=elasticquota,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=scheduling.x-k8s.io,resources=elasticquota/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=scheduling.x-k8s.io,resources=elasticquota/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 func (r *ElasticQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 	log.Info("reconciling")
-	eqList := &schedv1alpha1.ElasticQuotaList{}
-	if err := r.List(ctx, eqList, client.InNamespace(req.Namespace)); err != nil {
-		if apierrs.IsNotFound(err) {
-			log.V(5).Info("no elasticquota found")
-			return ctrl.Result{}, nil
-		}
-		log.V(3).Error(err, "Unable to retrieve elasticquota")
+	eq, err := r.getElasticQuota(ctx, req.Namespace)
+	if err != nil {
 		return ctrl.Result{}, err
 	}
-
-	// TODO: When elastic quota supports multiple instances in a namespace, modify this
-	if len(eqList.Items) == 0 {
+	if eq == nil {
 		log.V(5).Info("no elasticquota found")
 		return ctrl.Result{}, nil
 	}
 
-	eq := &eqList.Items[0]
-	used, err := r.computeElasticQuotaUsed(ctx, req.Namespace, eq)
-	if err != nil {
+	// The ElasticQuotas of other namespaces may select the namespace or be the children of its ElasticQuota.
+	eqList := &schedv1alpha1.ElasticQuotaList{}
+	if err := r.List(ctx, eqList); err != nil {
+		log.V(3).Error(err, "Unable to retrieve elasticquota")
 		return ctrl.Result{}, err
 	}
 
-	// Ignore this loop if the usage value has not changed
-	if apiequality.Semantic.DeepEqual(used, eq.Status.Used) {
-		return ctrl.Result{}, nil
+	pods, err := r.listElasticQuotaPods(ctx, eq, eqList.Items)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
+	parents := elasticQuotaParents(eqList.Items)
+	used := computeElasticQuotaUsed(eq, eqList.Items, parents, pods)
 
 	// create a usage object that is based on the elastic quota version that will handle updates
 	// by default, we set used to the current status
 	newEQ := eq.DeepCopy()
 	newEQ.Status.Used = used
+	newEQ.Status.Borrowed = quota.RemoveZeros(quota.SubtractWithNonNegativeResult(used, eq.Spec.Min))
+	newEQ.Status.Lent = computeElasticQuotaLent(eq, used, eqList.Items, parents)
+	newEQ.Status.PendingDemand = computeElasticQuotaPendingDemand(eq, used, eqList.Items, parents, pods)
+	newEQ.Status.PriorityClassUsed = computeElasticQuotaPriorityClassUsed(eq, pods)
+	setElasticQuotaConditions(newEQ)
+
+	// Ignore this loop if the status has not changed
+	if apiequality.Semantic.DeepEqual(newEQ.Status, eq.Status) {
+		return ctrl.Result{}, nil
+	}
+
 	if err = r.patchElasticQuota(ctx, eq, newEQ); err != nil {
 		return ctrl.Result{}, err
 	}
@@ -93,19 +111,319 @@ func (r *ElasticQuotaReconciler) patchElasticQuota(ctx context.Context, old, new
 	return r.Status().Patch(ctx, new, patch)
 }
 
-func (r *ElasticQuotaReconciler) computeElasticQuotaUsed(ctx context.Context, namespace string, eq *schedv1alpha1.ElasticQuota) (v1.ResourceList, error) {
+// getElasticQuota returns the ElasticQuota the pods of the namespace are subject to, or nil if there's none. Only the
+// ElasticQuotas of the namespace, or else the ElasticQuotas with a namespace selector, are listed.
+func (r *ElasticQuotaReconciler) getElasticQuota(ctx context.Context, namespace string) (*schedv1alpha1.ElasticQuota, error) {
+	eqList := &schedv1alpha1.ElasticQuotaList{}
+	if err := r.List(ctx, eqList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	if len(eqList.Items) == 0 {
+		if err := r.List(ctx, eqList, client.MatchingFields{elasticQuotaSelectorIndex: "true"}); err != nil {
+			return nil, err
+		}
+		if len(eqList.Items) == 0 {
+			return nil, nil
+		}
+	}
+	ns := &v1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		if !apierrs.IsNotFound(err) {
+			return nil, err
+		}
+		ns.Name = namespace
+	}
+	return util.GetElasticQuotaForNamespace(ns, eqList.Items), nil
+}
+
+// listElasticQuotaPods returns the pods of the namespaces subject to the ElasticQuota.
+func (r *ElasticQuotaReconciler) listElasticQuotaPods(ctx context.Context, eq *schedv1alpha1.ElasticQuota, eqs []schedv1alpha1.ElasticQuota) ([]v1.Pod, error) {
+	namespaces, err := r.elasticQuotaNamespaces(ctx, eq, eqs)
+	if err != nil {
+		return nil, err
+	}
+	var pods []v1.Pod
+	for _, namespace := range namespaces {
+		podList := &v1.PodList{}
+		if err := r.List(ctx, podList, client.InNamespace(namespace)); err != nil {
+			return nil, err
+		}
+		pods = append(pods, podList.Items...)
+	}
+	return pods, nil
+}
+
+// computeElasticQuotaUsed returns the usage of the running pods subject to the ElasticQuota, plus the usage of
+// its children.
+func computeElasticQuotaUsed(eq *schedv1alpha1.ElasticQuota, eqs []schedv1alpha1.ElasticQuota,
+	parents map[types.NamespacedName]types.NamespacedName, pods []v1.Pod) v1.ResourceList {
 	used := newZeroUsed(eq)
-	podList := &v1.PodList{}
-	if err := r.List(ctx, podList, client.InNamespace(namespace)); err != nil {
+	for i := range pods {
+		if pods[i].Status.Phase == v1.PodRunning {
+			used = quota.Add(used, computePodResourceRequest(&pods[i]))
+		}
+	}
+
+	key := client.ObjectKeyFromObject(eq)
+	for i := range eqs {
+		if parent, ok := parents[client.ObjectKeyFromObject(&eqs[i])]; ok && parent == key {
+			used = quota.Add(used, eqs[i].Status.Used)
+		}
+	}
+	return used
+}
+
+// computeElasticQuotaPriorityClassUsed returns the usage of the running pods subject to the ElasticQuota per priority
+// class, sorted by name. Unlike its usage, it doesn't include the usage of its children. The priority classes with a
+// Max are always reported.
+func computeElasticQuotaPriorityClassUsed(eq *schedv1alpha1.ElasticQuota, pods []v1.Pod) []schedv1alpha1.PriorityClassResources {
+	used := make(map[string]v1.ResourceList)
+	for _, limit := range eq.Spec.PriorityClassMax {
+		zero := v1.ResourceList{}
+		for name := range limit.Resources {
+			zero[name] = *resource.NewQuantity(0, resource.DecimalSI)
+		}
+		used[limit.PriorityClassName] = zero
+	}
+	for i := range pods {
+		name := pods[i].Spec.PriorityClassName
+		if name == "" || pods[i].Status.Phase != v1.PodRunning {
+			continue
+		}
+		used[name] = quota.Add(used[name], computePodResourceRequest(&pods[i]))
+	}
+	if len(used) == 0 {
+		return nil
+	}
+	result := make([]schedv1alpha1.PriorityClassResources, 0, len(used))
+	for name, resources := range used {
+		result = append(result, schedv1alpha1.PriorityClassResources{PriorityClassName: name, Resources: resources})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].PriorityClassName < result[j].PriorityClassName })
+	return result
+}
+
+// computeElasticQuotaLent returns the part of the unused Min of the ElasticQuota borrowed by its siblings, i.e. the
+// ElasticQuotas sharing its parent or, for a root ElasticQuota, the other roots. What the siblings borrow is
+// attributed to the siblings lending it in proportion to their unused Min.
+func computeElasticQuotaLent(eq *schedv1alpha1.ElasticQuota, used v1.ResourceList, eqs []schedv1alpha1.ElasticQuota,
+	parents map[types.NamespacedName]types.NamespacedName) v1.ResourceList {
+	key := client.ObjectKeyFromObject(eq)
+	parent, hasParent := parents[key]
+	unused := quota.SubtractWithNonNegativeResult(eq.Spec.Min, used)
+	totalUnused, totalBorrowed := v1.ResourceList{}, v1.ResourceList{}
+	for i := range eqs {
+		siblingKey := client.ObjectKeyFromObject(&eqs[i])
+		if siblingParent, ok := parents[siblingKey]; ok != hasParent || siblingParent != parent {
+			continue
+		}
+		siblingUsed := eqs[i].Status.Used
+		if siblingKey == key {
+			siblingUsed = used
+		}
+		totalUnused = quota.Add(totalUnused, quota.SubtractWithNonNegativeResult(eqs[i].Spec.Min, siblingUsed))
+		totalBorrowed = quota.Add(totalBorrowed, quota.SubtractWithNonNegativeResult(siblingUsed, eqs[i].Spec.Min))
+	}
+
+	lent := v1.ResourceList{}
+	for name, quantity := range unused {
+		borrowed, total := totalBorrowed[name], totalUnused[name]
+		if quantity.IsZero() || borrowed.IsZero() {
+			continue
+		}
+		if borrowed.Cmp(total) >= 0 {
+			lent[name] = quantity.DeepCopy()
+			continue
+		}
+		share := float64(borrowed.MilliValue()) / float64(total.MilliValue())
+		lent[name] = *resource.NewMilliQuantity(int64(float64(quantity.MilliValue())*share), quantity.Format)
+	}
+	return lent
+}
+
+// computeElasticQuotaPendingDemand returns the request of the unschedulable pods subject to the ElasticQuota which
+// don't fit in the quota: they would take the ElasticQuota or one of its ancestors beyond Max, or the root
+// ElasticQuotas beyond their aggregated Min, which CapacityScheduling rejects them for.
+func computeElasticQuotaPendingDemand(eq *schedv1alpha1.ElasticQuota, used v1.ResourceList, eqs []schedv1alpha1.ElasticQuota,
+	parents map[types.NamespacedName]types.NamespacedName, pods []v1.Pod) v1.ResourceList {
+	key := client.ObjectKeyFromObject(eq)
+	byKey := make(map[types.NamespacedName]*schedv1alpha1.ElasticQuota, len(eqs))
+	for i := range eqs {
+		byKey[client.ObjectKeyFromObject(&eqs[i])] = &eqs[i]
+	}
+	usedOf := func(k types.NamespacedName) v1.ResourceList {
+		if k == key {
+			return used
+		}
+		return byKey[k].Status.Used
+	}
+
+	// The ElasticQuota and its ancestors, each bounded by its Max.
+	chain := []types.NamespacedName{key}
+	for k, ok := parents[key]; ok; k, ok = parents[k] {
+		chain = append(chain, k)
+	}
+	rootsUsed, rootsMin := v1.ResourceList{}, v1.ResourceList{}
+	for k, e := range byKey {
+		if _, ok := parents[k]; !ok {
+			rootsUsed = quota.Add(rootsUsed, usedOf(k))
+			rootsMin = quota.Add(rootsMin, e.Spec.Min)
+		}
+	}
+
+	demand := v1.ResourceList{}
+	for i := range pods {
+		if !unschedulablePod(&pods[i]) {
+			continue
+		}
+		request := computePodResourceRequest(&pods[i])
+		blocked := exceeds(quota.Add(rootsUsed, request), rootsMin, false)
+		for _, k := range chain {
+			if blocked {
+				break
+			}
+			blocked = byKey[k].Spec.Max != nil && exceeds(quota.Add(usedOf(k), request), byKey[k].Spec.Max, true)
+		}
+		if blocked {
+			demand = quota.Add(demand, request)
+		}
+	}
+	return quota.RemoveZeros(demand)
+}
+
+// exceeds returns true if the usage exceeds the limit of some resource. Missing limits are zero, except for extended
+// resources if boundedOnly is set, like CapacityScheduling bounds only cpu, memory and ephemeral-storage to zero when
+// they are missing from Max.
+func exceeds(usage, limits v1.ResourceList, boundedOnly bool) bool {
+	for name, quantity := range usage {
+		limit, ok := limits[name]
+		if !ok && boundedOnly && name != v1.ResourceCPU && name != v1.ResourceMemory && name != v1.ResourceEphemeralStorage {
+			continue
+		}
+		if quantity.Cmp(limit) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// unschedulablePod returns true if the scheduler failed to schedule the pod.
+func unschedulablePod(pod *v1.Pod) bool {
+	if pod.Spec.NodeName != "" || pod.Status.Phase != v1.PodPending {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled {
+			return condition.Status == v1.ConditionFalse && condition.Reason == v1.PodReasonUnschedulable
+		}
+	}
+	return false
+}
+
+// setElasticQuotaConditions sets the OverMin and AtMax conditions of the ElasticQuota from its status.
+func setElasticQuotaConditions(eq *schedv1alpha1.ElasticQuota) {
+	overMin := metav1.Condition{
+		Type:               schedv1alpha1.ElasticQuotaOverMin,
+		Status:             metav1.ConditionFalse,
+		Reason:             schedv1alpha1.ElasticQuotaReasonWithinMin,
+		Message:            "The ElasticQuota uses no more than its min",
+		ObservedGeneration: eq.Generation,
+	}
+	if len(eq.Status.Borrowed) > 0 {
+		overMin.Status = metav1.ConditionTrue
+		overMin.Reason = schedv1alpha1.ElasticQuotaReasonBorrowing
+		overMin.Message = fmt.Sprintf("The ElasticQuota borrows %v beyond its min", resourceNames(eq.Status.Borrowed))
+	}
+	meta.SetStatusCondition(&eq.Status.Conditions, overMin)
+
+	atMax := metav1.Condition{
+		Type:               schedv1alpha1.ElasticQuotaAtMax,
+		Status:             metav1.ConditionFalse,
+		Reason:             schedv1alpha1.ElasticQuotaReasonBelowMax,
+		Message:            "The ElasticQuota uses less than its max",
+		ObservedGeneration: eq.Generation,
+	}
+	reached := v1.ResourceList{}
+	for name, max := range eq.Spec.Max {
+		if used := eq.Status.Used[name]; !max.IsZero() && used.Cmp(max) >= 0 {
+			reached[name] = max
+		}
+	}
+	if len(reached) > 0 {
+		atMax.Status = metav1.ConditionTrue
+		atMax.Reason = schedv1alpha1.ElasticQuotaReasonMaxReached
+		atMax.Message = fmt.Sprintf("The ElasticQuota uses all of its max of %v", resourceNames(reached))
+	}
+	meta.SetStatusCondition(&eq.Status.Conditions, atMax)
+}
+
+// resourceNames returns the sorted names of the resources, separated by commas.
+func resourceNames(resources v1.ResourceList) string {
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// elasticQuotaNamespaces returns the namespace of the ElasticQuota and the namespaces it selects.
+func (r *ElasticQuotaReconciler) elasticQuotaNamespaces(ctx context.Context, eq *schedv1alpha1.ElasticQuota, eqs []schedv1alpha1.ElasticQuota) ([]string, error) {
+	namespaces := []string{eq.Namespace}
+	selector, err := util.GetElasticQuotaNamespaceSelector(eq)
+	if err != nil || selector == nil {
+		return namespaces, nil
+	}
+	nsList := &v1.NamespaceList{}
+	if err := r.List(ctx, nsList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
 		return nil, err
 	}
+	for i := range nsList.Items {
+		ns := &nsList.Items[i]
+		if ns.Name == eq.Namespace {
+			continue
+		}
+		// The namespace may have an ElasticQuota of its own, or be selected by another ElasticQuota first.
+		if selected := util.GetElasticQuotaForNamespace(ns, eqs); selected != nil && selected.Namespace == eq.Namespace && selected.Name == eq.Name {
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+	return namespaces, nil
+}
+
+// elasticQuotaParents returns the parent of each ElasticQuota whose parent exists. As in the scheduler, the
+// parent of the ElasticQuota closing a cycle, in the alphabetical order of namespaces, is ignored.
+func elasticQuotaParents(eqs []schedv1alpha1.ElasticQuota) map[types.NamespacedName]types.NamespacedName {
+	exists := make(map[types.NamespacedName]bool, len(eqs))
+	sorted := make([]*schedv1alpha1.ElasticQuota, 0, len(eqs))
+	for i := range eqs {
+		exists[client.ObjectKeyFromObject(&eqs[i])] = true
+		sorted = append(sorted, &eqs[i])
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Namespace < sorted[j].Namespace })
 
-	for _, p := range podList.Items {
-		if p.Status.Phase == v1.PodRunning {
-			used = quota.Add(used, computePodResourceRequest(&p))
+	parents := make(map[types.NamespacedName]types.NamespacedName)
+	for _, eq := range sorted {
+		if eq.Spec.Parent == nil {
+			continue
+		}
+		key := client.ObjectKeyFromObject(eq)
+		parent := types.NamespacedName{Namespace: eq.Spec.Parent.Namespace, Name: eq.Spec.Parent.Name}
+		if !exists[parent] {
+			continue
+		}
+		cycle := false
+		for p, ok := parent, true; ok; p, ok = parents[p] {
+			if p == key {
+				cycle = true
+				break
+			}
+		}
+		if !cycle {
+			parents[key] = parent
 		}
 	}
-	return used, nil
+	return parents
 }
 
 // computePodResourceRequest returns a v1.ResourceList that covers the largest
@@ -168,10 +486,90 @@ func newZeroUsed(eq *schedv1alpha1.ElasticQuota) v1.ResourceList {
 	return res
 }
 
+// relatedRequests enqueues the ElasticQuotas whose status depends on the status of an ElasticQuota: its parent,
+// whose usage includes the usage of the ElasticQuota, its siblings, which it may borrow from, and its children,
+// whose pending demand depends on the usage of their ancestors.
+func (r *ElasticQuotaReconciler) relatedRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	eq, ok := obj.(*schedv1alpha1.ElasticQuota)
+	if !ok {
+		return nil
+	}
+	eqList := &schedv1alpha1.ElasticQuotaList{}
+	if err := r.List(ctx, eqList); err != nil {
+		log.FromContext(ctx).Error(err, "Unable to retrieve elasticquota")
+		return nil
+	}
+	parents := elasticQuotaParents(eqList.Items)
+	key := client.ObjectKeyFromObject(eq)
+	parent, hasParent := parents[key]
+
+	var requests []reconcile.Request
+	if hasParent {
+		requests = append(requests, reconcile.Request{NamespacedName: parent})
+	}
+	for i := range eqList.Items {
+		otherKey := client.ObjectKeyFromObject(&eqList.Items[i])
+		if otherKey == key {
+			continue
+		}
+		otherParent, ok := parents[otherKey]
+		if ok == hasParent && otherParent == parent || ok && otherParent == key {
+			requests = append(requests, reconcile.Request{NamespacedName: otherKey})
+		}
+	}
+	return requests
+}
+
+// podRequests enqueues the ElasticQuota the pod is subject to, if any, so that the events of its pods are
+// coalesced into a single request.
+func (r *ElasticQuotaReconciler) podRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	eq, err := r.getElasticQuota(ctx, obj.GetNamespace())
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Unable to retrieve elasticquota")
+		return nil
+	}
+	if eq == nil {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(eq)}}
+}
+
+// selectingRequests enqueues the ElasticQuotas which may select a namespace.
+func (r *ElasticQuotaReconciler) selectingRequests(ctx context.Context, _ client.Object) []reconcile.Request {
+	eqList := &schedv1alpha1.ElasticQuotaList{}
+	if err := r.List(ctx, eqList, client.MatchingFields{elasticQuotaSelectorIndex: "true"}); err != nil {
+		log.FromContext(ctx).Error(err, "Unable to retrieve elasticquota")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(eqList.Items))
+	for i := range eqList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&eqList.Items[i])})
+	}
+	return requests
+}
+
+// elasticQuotaSelectorIndex indexes the ElasticQuotas with a namespace selector under "true".
+const elasticQuotaSelectorIndex = "spec.namespaceSelector"
+
+func indexElasticQuotaSelector(obj client.Object) []string {
+	if eq, ok := obj.(*schedv1alpha1.ElasticQuota); ok && eq.Spec.NamespaceSelector != nil {
+		return []string{"true"}
+	}
+	return nil
+}
+
 func (r *ElasticQuotaReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.recorder = mgr.GetEventRecorderFor("ElasticQuotaController")
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &schedv1alpha1.ElasticQuota{},
+		elasticQuotaSelectorIndex, indexElasticQuotaSelector); err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
-		Watches(&v1.Pod{}, &handler.EnqueueRequestForObject{}).
+		Watches(&v1.Pod{}, handler.EnqueueRequestsFromMapFunc(r.podRequests)).
+		Watches(&schedv1alpha1.ElasticQuota{}, handler.EnqueueRequestsFromMapFunc(r.relatedRequests)).
+		// Only the labels of a namespace decide which ElasticQuota selects it.
+		Watches(&v1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.selectingRequests),
+			builder.WithPredicates(predicate.LabelChangedPredicate{})).
 		For(&schedv1alpha1.ElasticQuota{}).
 		WithOptions(controller.Options{MaxConcurrentReconciles: r.Workers}).
 		Complete(r)
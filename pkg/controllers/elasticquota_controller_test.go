@@ -20,11 +20,14 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -37,6 +40,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
 	testutil "sigs.k8s.io/scheduler-plugins/test/integration"
 )
@@ -45,6 +49,7 @@ func TestElasticQuotaController_Run(t *testing.T) {
 	ctx := context.TODO()
 	cases := []struct {
 		name          string
+		namespaces    []*v1.Namespace
 		elasticQuotas []*v1alpha1.ElasticQuota
 		pods          []*v1.Pod
 		want          []*v1alpha1.ElasticQuota
@@ -181,11 +186,124 @@ func TestElasticQuotaController_Run(t *testing.T) {
 					Used(testutil.MakeResourceList().CPU(0).Mem(0).GPU(0).Obj()).Obj(),
 			},
 		},
+		{
+			name: "usage aggregated up the tree",
+			namespaces: []*v1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "t7-ns1", Labels: map[string]string{"team": "t7"}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "t7-ns2", Labels: map[string]string{"team": "t7"}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "t7-ns3", Labels: map[string]string{"team": "t7"}}},
+			},
+			elasticQuotas: []*v1alpha1.ElasticQuota{
+				testutil.MakeEQ("t7-ns1", "t7-team").
+					Parent("t7-org", "t7-org").NamespaceSelector(map[string]string{"team": "t7"}).
+					Max(testutil.MakeResourceList().CPU(50).Mem(15).Obj()).Obj(),
+				testutil.MakeEQ("t7-ns3", "t7-eq3").
+					Max(testutil.MakeResourceList().CPU(50).Mem(15).Obj()).Obj(),
+				testutil.MakeEQ("t7-org", "t7-org").
+					Max(testutil.MakeResourceList().CPU(50).Mem(15).Obj()).Obj(),
+			},
+			pods: []*v1.Pod{
+				testutil.MakePod("t7-ns1", "pod1").Phase(v1.PodRunning).
+					Container(testutil.MakeResourceList().CPU(1).Mem(2).Obj()).Obj(),
+				testutil.MakePod("t7-ns2", "pod2").Phase(v1.PodRunning).
+					Container(testutil.MakeResourceList().CPU(2).Mem(1).Obj()).Obj(),
+				testutil.MakePod("t7-ns3", "pod3").Phase(v1.PodRunning).
+					Container(testutil.MakeResourceList().CPU(4).Mem(4).Obj()).Obj(),
+				testutil.MakePod("t7-org", "pod4").Phase(v1.PodRunning).
+					Container(testutil.MakeResourceList().CPU(1).Mem(1).Obj()).Obj(),
+			},
+			want: []*v1alpha1.ElasticQuota{
+				testutil.MakeEQ("t7-ns1", "t7-team").
+					Used(testutil.MakeResourceList().CPU(3).Mem(3).Obj()).Obj(),
+				testutil.MakeEQ("t7-ns3", "t7-eq3").
+					Used(testutil.MakeResourceList().CPU(4).Mem(4).Obj()).Obj(),
+				testutil.MakeEQ("t7-org", "t7-org").
+					Used(testutil.MakeResourceList().CPU(4).Mem(4).Obj()).Obj(),
+			},
+		},
+		{
+			name: "borrowed, lent and pending demand",
+			elasticQuotas: []*v1alpha1.ElasticQuota{
+				testutil.MakeEQ("t8-ns1", "t8-eq1").
+					Min(testutil.MakeResourceList().CPU(4).Mem(4).Obj()).
+					Max(testutil.MakeResourceList().CPU(10).Mem(10).Obj()).Obj(),
+				testutil.MakeEQ("t8-ns2", "t8-eq2").
+					Min(testutil.MakeResourceList().CPU(4).Mem(4).Obj()).
+					Max(testutil.MakeResourceList().CPU(1).Mem(10).Obj()).Obj(),
+				testutil.MakeEQ("t8-ns3", "t8-eq3").
+					Min(testutil.MakeResourceList().CPU(2).Mem(2).Obj()).Obj(),
+			},
+			pods: []*v1.Pod{
+				testutil.MakePod("t8-ns1", "pod1").Phase(v1.PodRunning).
+					Container(testutil.MakeResourceList().CPU(6).Mem(2).Obj()).Obj(),
+				// Beyond the max of t8-eq1.
+				testutil.MakePod("t8-ns1", "pod2").Phase(v1.PodPending).Unschedulable().
+					Container(testutil.MakeResourceList().CPU(5).Mem(1).Obj()).Obj(),
+				testutil.MakePod("t8-ns2", "pod3").Phase(v1.PodRunning).
+					Container(testutil.MakeResourceList().CPU(1).Mem(1).Obj()).Obj(),
+				// Within the max of t8-eq2 and the aggregated min.
+				testutil.MakePod("t8-ns2", "pod4").Phase(v1.PodPending).Unschedulable().
+					Container(testutil.MakeResourceList().Mem(1).Obj()).Obj(),
+				// Not rejected by the scheduler yet.
+				testutil.MakePod("t8-ns3", "pod5").Phase(v1.PodPending).
+					Container(testutil.MakeResourceList().CPU(100).Obj()).Obj(),
+			},
+			want: []*v1alpha1.ElasticQuota{
+				testutil.MakeEQ("t8-ns1", "t8-eq1").
+					Used(testutil.MakeResourceList().CPU(6).Mem(2).Obj()).
+					Borrowed(testutil.MakeResourceList().CPU(2).Obj()).
+					Lent(v1.ResourceList{}).
+					PendingDemand(testutil.MakeResourceList().CPU(5).Mem(1).Obj()).
+					Condition(v1alpha1.ElasticQuotaOverMin, metav1.ConditionTrue).
+					Condition(v1alpha1.ElasticQuotaAtMax, metav1.ConditionFalse).Obj(),
+				// The cpu borrowed by t8-eq1 is lent by t8-eq2 and t8-eq3 in proportion to their unused min.
+				testutil.MakeEQ("t8-ns2", "t8-eq2").
+					Used(testutil.MakeResourceList().CPU(1).Mem(1).Obj()).
+					Borrowed(v1.ResourceList{}).
+					Lent(v1.ResourceList{v1.ResourceCPU: resource.MustParse("1200m")}).
+					PendingDemand(v1.ResourceList{}).
+					Condition(v1alpha1.ElasticQuotaOverMin, metav1.ConditionFalse).
+					Condition(v1alpha1.ElasticQuotaAtMax, metav1.ConditionTrue).Obj(),
+				testutil.MakeEQ("t8-ns3", "t8-eq3").
+					Used(testutil.MakeResourceList().CPU(0).Mem(0).Obj()).
+					Lent(v1.ResourceList{v1.ResourceCPU: resource.MustParse("800m")}).
+					PendingDemand(v1.ResourceList{}).
+					Condition(v1alpha1.ElasticQuotaOverMin, metav1.ConditionFalse).
+					Condition(v1alpha1.ElasticQuotaAtMax, metav1.ConditionFalse).Obj(),
+			},
+		},
+		{
+			name: "usage per priority class",
+			elasticQuotas: []*v1alpha1.ElasticQuota{
+				testutil.MakeEQ("t9-ns1", "t9-eq1").
+					Min(testutil.MakeResourceList().CPU(4).Mem(4).Obj()).
+					Max(testutil.MakeResourceList().CPU(10).Mem(10).Obj()).
+					PriorityClassMax("low", testutil.MakeResourceList().CPU(2).Obj()).
+					PriorityClassMax("spot", testutil.MakeResourceList().CPU(1).Obj()).Obj(),
+			},
+			pods: []*v1.Pod{
+				testutil.MakePod("t9-ns1", "pod1").Phase(v1.PodRunning).PriorityClassName("low").
+					Container(testutil.MakeResourceList().CPU(1).Mem(2).Obj()).Obj(),
+				testutil.MakePod("t9-ns1", "pod2").Phase(v1.PodRunning).PriorityClassName("high").
+					Container(testutil.MakeResourceList().CPU(3).Mem(1).Obj()).Obj(),
+				testutil.MakePod("t9-ns1", "pod3").Phase(v1.PodPending).PriorityClassName("low").
+					Container(testutil.MakeResourceList().CPU(1).Obj()).Obj(),
+				testutil.MakePod("t9-ns1", "pod4").Phase(v1.PodRunning).
+					Container(testutil.MakeResourceList().CPU(1).Obj()).Obj(),
+			},
+			want: []*v1alpha1.ElasticQuota{
+				testutil.MakeEQ("t9-ns1", "t9-eq1").
+					Used(testutil.MakeResourceList().CPU(5).Mem(3).Obj()).
+					PriorityClassUsed("high", testutil.MakeResourceList().CPU(3).Mem(1).Obj()).
+					PriorityClassUsed("low", testutil.MakeResourceList().CPU(1).Mem(2).Obj()).
+					PriorityClassUsed("spot", testutil.MakeResourceList().CPU(0).Obj()).Obj(),
+			},
+		},
 	}
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			controller, kClient := setUpEQ(ctx, t, c.elasticQuotas, c.pods)
+			controller, kClient := setUpEQ(ctx, t, c.namespaces, c.elasticQuotas, c.pods)
 			for _, pod := range c.pods {
 				if _, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{
 					Namespace: pod.Namespace,
@@ -219,6 +337,34 @@ func TestElasticQuotaController_Run(t *testing.T) {
 					if !quota.Equals(eq.Status.Used, v.Status.Used) {
 						return false, fmt.Errorf("%v: want %v, got %v", c.name, v.Status.Used, eq.Status.Used)
 					}
+					for _, check := range []struct {
+						field     string
+						want, got v1.ResourceList
+					}{
+						{"borrowed", v.Status.Borrowed, eq.Status.Borrowed},
+						{"lent", v.Status.Lent, eq.Status.Lent},
+						{"pendingDemand", v.Status.PendingDemand, eq.Status.PendingDemand},
+					} {
+						if check.want != nil && !quota.Equals(check.want, check.got) {
+							return false, fmt.Errorf("%v: want %v %v, got %v", c.name, check.field, check.want, check.got)
+						}
+					}
+					if v.Status.PriorityClassUsed != nil {
+						if len(eq.Status.PriorityClassUsed) != len(v.Status.PriorityClassUsed) {
+							return false, fmt.Errorf("%v: want priorityClassUsed %v, got %v", c.name, v.Status.PriorityClassUsed, eq.Status.PriorityClassUsed)
+						}
+						for i, want := range v.Status.PriorityClassUsed {
+							got := eq.Status.PriorityClassUsed[i]
+							if got.PriorityClassName != want.PriorityClassName || !quota.Equals(got.Resources, want.Resources) {
+								return false, fmt.Errorf("%v: want priorityClassUsed %v, got %v", c.name, v.Status.PriorityClassUsed, eq.Status.PriorityClassUsed)
+							}
+						}
+					}
+					for _, want := range v.Status.Conditions {
+						if !meta.IsStatusConditionPresentAndEqual(eq.Status.Conditions, want.Type, want.Status) {
+							return false, fmt.Errorf("%v: want condition %v %v, got %v", c.name, want.Type, want.Status, eq.Status.Conditions)
+						}
+					}
 				}
 				return true, nil
 			})
@@ -230,8 +376,39 @@ func TestElasticQuotaController_Run(t *testing.T) {
 	}
 }
 
+func TestElasticQuotaController_PodRequests(t *testing.T) {
+	ctx := context.TODO()
+	team := map[string]string{"team": "a"}
+	namespaces := []*v1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "ns1", Labels: team}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "ns2", Labels: team}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "ns3", Labels: team}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "ns4"}},
+	}
+	eqs := []*v1alpha1.ElasticQuota{
+		testutil.MakeEQ("ns1", "team").NamespaceSelector(team).Obj(),
+		testutil.MakeEQ("ns3", "own").Obj(),
+	}
+	controller, _ := setUpEQ(ctx, t, namespaces, eqs, nil)
+	for _, tt := range []struct {
+		namespace string
+		want      []reconcile.Request
+	}{
+		{namespace: "ns1", want: []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "team"}}}},
+		{namespace: "ns2", want: []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "team"}}}},
+		{namespace: "ns3", want: []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "ns3", Name: "own"}}}},
+		{namespace: "ns4"},
+	} {
+		pod := testutil.MakePod(tt.namespace, "pod").Obj()
+		if got := controller.podRequests(ctx, pod); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("namespace %v: want requests %v, got %v", tt.namespace, tt.want, got)
+		}
+	}
+}
+
 func setUpEQ(ctx context.Context,
 	t *testing.T,
+	namespaces []*v1.Namespace,
 	eqs []*v1alpha1.ElasticQuota,
 	pods []*v1.Pod) (*ElasticQuotaReconciler, client.WithWatch) {
 	s := scheme.Scheme
@@ -240,7 +417,13 @@ func setUpEQ(ctx context.Context,
 	client := fake.NewClientBuilder().
 		WithScheme(s).
 		WithStatusSubresource(&v1alpha1.ElasticQuota{}).
+		WithIndex(&v1alpha1.ElasticQuota{}, elasticQuotaSelectorIndex, indexElasticQuotaSelector).
 		Build()
+	for _, ns := range namespaces {
+		if err := client.Create(ctx, ns); err != nil {
+			t.Fatal("setup controller", err)
+		}
+	}
 	for _, eq := range eqs {
 		err := client.Create(ctx, eq)
 		if errors.IsAlreadyExists(err) {
@@ -26,6 +26,7 @@ import (
 	"github.com/go-logr/logr"
 	v1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -48,11 +49,17 @@ type PodGroupReconciler struct {
 	client.Client
 	Scheme  *runtime.Scheme
 	Workers int
+	// StuckTimeout is the time after which a pod group which started scheduling, without any running pod,
+	// is moved to the TimedOut phase. It's measured from the status.scheduleStartTime the controller records
+	// when the group enters the Scheduling phase. A group without running pod whose scheduling started longer
+	// than StuckTimeout after its creation is not reconciled anymore. Zero disables both.
+	StuckTimeout time.Duration
 }
 
 // +kubebuilder:rbac:groups=scheduling.x-k8s.io,resources=podgroups,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=scheduling.x-k8s.io,resources=podgroups/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=scheduling.x-k8s.io,resources=podgroups/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -78,14 +85,14 @@ func (r *PodGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 	if pg.Status.Phase == schedv1alpha1.PodGroupFinished ||
 		pg.Status.Phase == schedv1alpha1.PodGroupFailed {
-		return ctrl.Result{}, nil
+		return r.cleanUpCompleted(ctx, pg)
 	}
-	// If startScheduleTime - createTime > 2days,
+	// If startScheduleTime - createTime > StuckTimeout,
 	// do not reconcile again because pod may have been GCed
-	if (pg.Status.Phase == schedv1alpha1.PodGroupScheduling || pg.Status.Phase == schedv1alpha1.PodGroupPending) && pg.Status.Running == 0 &&
-		pg.Status.ScheduleStartTime.Sub(pg.CreationTimestamp.Time) > 48*time.Hour {
-		r.recorder.Event(pg, v1.EventTypeWarning,
-			"Timeout", "schedule time longer than 48 hours")
+	if r.StuckTimeout > 0 && (pg.Status.Phase == schedv1alpha1.PodGroupScheduling || pg.Status.Phase == schedv1alpha1.PodGroupPending) && pg.Status.Running == 0 &&
+		pg.Status.ScheduleStartTime.Sub(pg.CreationTimestamp.Time) > r.StuckTimeout {
+		r.recorder.Eventf(pg, v1.EventTypeWarning,
+			"Timeout", "schedule time longer than %v", r.StuckTimeout)
 		return ctrl.Result{}, nil
 	}
 
@@ -99,6 +106,13 @@ func (r *PodGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, err
 	}
 	pods := podList.Items
+	// A timed out group is only reconciled again once its pods run, e.g. after the cluster got scaled up.
+	if pg.Status.Phase == schedv1alpha1.PodGroupTimedOut {
+		if running, succeeded, _ := getCurrentPodStats(pods); running+succeeded == 0 {
+			return r.cleanUpCompleted(ctx, pg)
+		}
+		log.V(3).Info("Pods of timed out pod group are running, reconciling it again")
+	}
 
 	pgCopy := pg.DeepCopy()
 	switch pgCopy.Status.Phase {
@@ -124,8 +138,7 @@ func (r *PodGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 			pgCopy.Status.Phase = schedv1alpha1.PodGroupRunning
 		}
 		// Final state of pod group
-		if pgCopy.Status.Failed != 0 &&
-			pgCopy.Status.Failed+pgCopy.Status.Running+pgCopy.Status.Succeeded >= pg.Spec.MinMember {
+		if isPodGroupFailed(pgCopy) {
 			pgCopy.Status.Phase = schedv1alpha1.PodGroupFailed
 		}
 		if pgCopy.Status.Succeeded >= pg.Spec.MinMember {
@@ -133,16 +146,98 @@ func (r *PodGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		}
 	}
 
+	if r.StuckTimeout > 0 {
+		// The timeout runs from the time the group started scheduling, once it has all its pods.
+		if pgCopy.Status.Phase == schedv1alpha1.PodGroupScheduling && pg.Status.Phase != schedv1alpha1.PodGroupScheduling {
+			pgCopy.Status.ScheduleStartTime = metav1.Now()
+		}
+		if r.isStuck(pgCopy) && time.Since(pgCopy.Status.ScheduleStartTime.Time) > r.StuckTimeout {
+			pgCopy.Status.Phase = schedv1alpha1.PodGroupTimedOut
+			r.recorder.Eventf(pg, v1.EventTypeWarning,
+				"Timeout", "schedule time longer than %v", r.StuckTimeout)
+		}
+	}
+
 	return r.patchPodGroup(ctx, pg, pgCopy)
 }
 
 func (r *PodGroupReconciler) patchPodGroup(ctx context.Context, old, new *schedv1alpha1.PodGroup) (ctrl.Result, error) {
+	if !isPodGroupCompleted(new) {
+		new.Status.CompletionTime = nil
+	} else if new.Status.CompletionTime == nil {
+		now := metav1.Now()
+		new.Status.CompletionTime = &now
+	}
 	patch := client.MergeFrom(old)
 	if err := r.Status().Patch(ctx, new, patch); err != nil {
 		return ctrl.Result{}, err
 	}
-	err := r.Patch(ctx, new, patch)
-	return ctrl.Result{}, err
+	if err := r.Patch(ctx, new, patch); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Requeue the group when it's due for deletion or timeout, as no pod event may trigger it.
+	var requeueAfter time.Duration
+	if isPodGroupCompleted(new) {
+		if ttl := new.Spec.TTLSecondsAfterFinished; ttl != nil {
+			requeueAfter = time.Until(new.Status.CompletionTime.Add(time.Duration(*ttl) * time.Second))
+		}
+	} else if r.isStuck(new) {
+		requeueAfter = time.Until(new.Status.ScheduleStartTime.Add(r.StuckTimeout))
+	}
+	if requeueAfter > 0 {
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// cleanUpCompleted deletes the completed pod group once its TTL expires.
+func (r *PodGroupReconciler) cleanUpCompleted(ctx context.Context, pg *schedv1alpha1.PodGroup) (ctrl.Result, error) {
+	if pg.Spec.TTLSecondsAfterFinished == nil {
+		return ctrl.Result{}, nil
+	}
+	if pg.Status.CompletionTime == nil {
+		// The group completed before it recorded its completion time, start the TTL now.
+		return r.patchPodGroup(ctx, pg, pg.DeepCopy())
+	}
+	expireAt := pg.Status.CompletionTime.Add(time.Duration(*pg.Spec.TTLSecondsAfterFinished) * time.Second)
+	if remaining := time.Until(expireAt); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	log.FromContext(ctx).V(3).Info("Deleting pod group whose TTL after finished expired", "phase", pg.Status.Phase)
+	// Guard against the group having been updated since it was read, e.g. with a new TTL.
+	if err := r.Delete(ctx, pg, client.Preconditions{UID: &pg.UID, ResourceVersion: &pg.ResourceVersion}); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	r.recorder.Eventf(pg, v1.EventTypeNormal, "Deleted", "Deleted pod group %v after finished", pg.Status.Phase)
+	return ctrl.Result{}, nil
+}
+
+// isStuck returns true if the pod group started scheduling but none of its pods is running,
+// and the controller times out such groups.
+func (r *PodGroupReconciler) isStuck(pg *schedv1alpha1.PodGroup) bool {
+	return r.StuckTimeout > 0 && pg.Status.Phase == schedv1alpha1.PodGroupScheduling &&
+		pg.Status.Running == 0 && !pg.Status.ScheduleStartTime.IsZero()
+}
+
+// isPodGroupCompleted returns true if the pod group is in a terminal phase.
+func isPodGroupCompleted(pg *schedv1alpha1.PodGroup) bool {
+	switch pg.Status.Phase {
+	case schedv1alpha1.PodGroupFinished, schedv1alpha1.PodGroupFailed, schedv1alpha1.PodGroupTimedOut:
+		return true
+	}
+	return false
+}
+
+// isPodGroupFailed returns true if more pods of the group have failed than it tolerates.
+// Without spec.maxFailedMembers, the group fails on any failed pod once minMember pods have started.
+func isPodGroupFailed(pg *schedv1alpha1.PodGroup) bool {
+	if pg.Spec.MaxFailedMembers != nil {
+		return pg.Status.Failed > *pg.Spec.MaxFailedMembers
+	}
+	return pg.Status.Failed != 0 &&
+		pg.Status.Failed+pg.Status.Running+pg.Status.Succeeded >= pg.Spec.MinMember
 }
 
 func getCurrentPodStats(pods []v1.Pod) (int32, int32, int32) {
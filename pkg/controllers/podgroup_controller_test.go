@@ -24,6 +24,7 @@ import (
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -31,6 +32,7 @@ import (
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2/klogr"
 	st "k8s.io/kubernetes/pkg/scheduler/testing"
+	"k8s.io/utils/pointer"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -268,6 +270,189 @@ func TestFillGroupStatusOccupied(t *testing.T) {
 	}
 }
 
+func TestPodGroupCompletion(t *testing.T) {
+	ctx := context.TODO()
+	completed := func(phase v1alpha1.PodGroupPhase, ttl *int32, completedAgo time.Duration) *v1alpha1.PodGroup {
+		pg := makePG("pg", 2, phase, nil)
+		pg.Spec.TTLSecondsAfterFinished = ttl
+		if completedAgo != 0 {
+			pg.Status.CompletionTime = &metav1.Time{Time: time.Now().Add(-completedAgo)}
+		}
+		return pg
+	}
+	withMaxFailed := func(pg *v1alpha1.PodGroup, maxFailed int32) *v1alpha1.PodGroup {
+		pg.Spec.MaxFailedMembers = &maxFailed
+		return pg
+	}
+	// scheduling returns a pod group which started scheduling <startedAgo>, an hour after its creation.
+	scheduling := func(startedAgo time.Duration) *v1alpha1.PodGroup {
+		pg := makePG("pg", 2, v1alpha1.PodGroupScheduling, &metav1.Time{Time: time.Now().Add(-startedAgo - time.Hour)})
+		pg.Status.ScheduleStartTime = metav1.Time{Time: time.Now().Add(-startedAgo)}
+		return pg
+	}
+	cases := []struct {
+		name              string
+		pg                *v1alpha1.PodGroup
+		pods              []*v1.Pod
+		desiredGroupPhase v1alpha1.PodGroupPhase
+		desiredDeleted    bool
+		desiredRequeue    bool
+		noStuckTimeout    bool
+	}{
+		{
+			name:              "finished group is deleted once its TTL expires",
+			pg:                completed(v1alpha1.PodGroupFinished, pointer.Int32(60), 2*time.Minute),
+			desiredGroupPhase: v1alpha1.PodGroupFinished,
+			desiredDeleted:    true,
+		},
+		{
+			name:              "timed out group is deleted once its TTL expires",
+			pg:                completed(v1alpha1.PodGroupTimedOut, pointer.Int32(0), time.Second),
+			desiredGroupPhase: v1alpha1.PodGroupTimedOut,
+			desiredDeleted:    true,
+		},
+		{
+			name:              "failed group is kept until its TTL expires",
+			pg:                completed(v1alpha1.PodGroupFailed, pointer.Int32(600), time.Minute),
+			desiredGroupPhase: v1alpha1.PodGroupFailed,
+			desiredRequeue:    true,
+		},
+		{
+			name:              "finished group without TTL is kept",
+			pg:                completed(v1alpha1.PodGroupFinished, nil, 72*time.Hour),
+			desiredGroupPhase: v1alpha1.PodGroupFinished,
+		},
+		{
+			name:              "finished group without completion time starts its TTL",
+			pg:                completed(v1alpha1.PodGroupFinished, pointer.Int32(60), 0),
+			desiredGroupPhase: v1alpha1.PodGroupFinished,
+			desiredRequeue:    true,
+		},
+		{
+			name:              "group finishing starts its TTL",
+			pg:                completed(v1alpha1.PodGroupRunning, pointer.Int32(60), 0),
+			pods:              makePods([]string{"pod1", "pod2"}, "pg", v1.PodSucceeded, nil),
+			desiredGroupPhase: v1alpha1.PodGroupFinished,
+			desiredRequeue:    true,
+		},
+		{
+			name:              "group tolerates failed pods up to maxFailedMembers",
+			pg:                withMaxFailed(makePG("pg", 2, v1alpha1.PodGroupRunning, nil), 1),
+			pods:              append(makePods([]string{"pod1", "pod2"}, "pg", v1.PodRunning, nil), makePods([]string{"pod3"}, "pg", v1.PodFailed, nil)...),
+			desiredGroupPhase: v1alpha1.PodGroupRunning,
+		},
+		{
+			name:              "group fails beyond maxFailedMembers",
+			pg:                withMaxFailed(makePG("pg", 2, v1alpha1.PodGroupRunning, nil), 1),
+			pods:              append(makePods([]string{"pod1", "pod2"}, "pg", v1.PodRunning, nil), makePods([]string{"pod3", "pod4"}, "pg", v1.PodFailed, nil)...),
+			desiredGroupPhase: v1alpha1.PodGroupFailed,
+		},
+		{
+			name:              "group fails on the first failed pod without maxFailedMembers",
+			pg:                makePG("pg", 2, v1alpha1.PodGroupRunning, nil),
+			pods:              append(makePods([]string{"pod1", "pod2"}, "pg", v1.PodRunning, nil), makePods([]string{"pod3"}, "pg", v1.PodFailed, nil)...),
+			desiredGroupPhase: v1alpha1.PodGroupFailed,
+		},
+		{
+			name:              "stuck group is requeued until it times out",
+			pg:                scheduling(time.Hour),
+			pods:              makePods([]string{"pod1", "pod2"}, "pg", v1.PodPending, nil),
+			desiredGroupPhase: v1alpha1.PodGroupScheduling,
+			desiredRequeue:    true,
+		},
+		{
+			name:              "group times out after scheduling for too long",
+			pg:                scheduling(49 * time.Hour),
+			pods:              makePods([]string{"pod1", "pod2"}, "pg", v1.PodPending, nil),
+			desiredGroupPhase: v1alpha1.PodGroupTimedOut,
+		},
+		{
+			name:              "group whose pods started running doesn't time out",
+			pg:                scheduling(49 * time.Hour),
+			pods:              makePods([]string{"pod1", "pod2"}, "pg", v1.PodRunning, nil),
+			desiredGroupPhase: v1alpha1.PodGroupRunning,
+		},
+		{
+			name:              "group doesn't time out with the timeout disabled",
+			pg:                scheduling(49 * time.Hour),
+			pods:              makePods([]string{"pod1", "pod2"}, "pg", v1.PodPending, nil),
+			desiredGroupPhase: v1alpha1.PodGroupScheduling,
+			noStuckTimeout:    true,
+		},
+		{
+			name:              "group created a day ago starts its timeout when scheduling",
+			pg:                makePG("pg", 2, v1alpha1.PodGroupPending, &metav1.Time{Time: time.Now().Add(-24 * time.Hour)}),
+			pods:              makePods([]string{"pod1", "pod2"}, "pg", v1.PodPending, nil),
+			desiredGroupPhase: v1alpha1.PodGroupScheduling,
+			desiredRequeue:    true,
+		},
+		{
+			name:              "timed out group recovers when its pods run",
+			pg:                completed(v1alpha1.PodGroupTimedOut, pointer.Int32(60), time.Second),
+			pods:              makePods([]string{"pod1", "pod2"}, "pg", v1.PodRunning, nil),
+			desiredGroupPhase: v1alpha1.PodGroupRunning,
+		},
+		{
+			name:              "timed out group without running pods is kept until its TTL expires",
+			pg:                completed(v1alpha1.PodGroupTimedOut, pointer.Int32(60), time.Second),
+			pods:              makePods([]string{"pod1", "pod2"}, "pg", v1.PodPending, nil),
+			desiredGroupPhase: v1alpha1.PodGroupTimedOut,
+			desiredRequeue:    true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := scheme.Scheme
+			s.AddKnownTypes(v1alpha1.SchemeGroupVersion, c.pg)
+			objs := []runtime.Object{c.pg}
+			for _, p := range c.pods {
+				objs = append(objs, p)
+			}
+			kClient := fake.NewClientBuilder().
+				WithScheme(s).
+				WithStatusSubresource(&v1alpha1.PodGroup{}).
+				WithRuntimeObjects(objs...).
+				Build()
+			controller := &PodGroupReconciler{
+				Client:       kClient,
+				Scheme:       s,
+				StuckTimeout: 48 * time.Hour,
+				recorder:     record.NewFakeRecorder(3),
+				log:          klogr.New().WithName("podGroupTest"),
+			}
+			if c.noStuckTimeout {
+				controller.StuckTimeout = 0
+			}
+
+			result, err := controller.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "pg", Namespace: "default"}})
+			if err != nil {
+				t.Fatalf("reconcile: (%v)", err)
+			}
+			if requeue := result.RequeueAfter > 0; requeue != c.desiredRequeue {
+				t.Errorf("want requeue %v, got %v", c.desiredRequeue, result.RequeueAfter)
+			}
+
+			pg := &v1alpha1.PodGroup{}
+			err = kClient.Get(ctx, types.NamespacedName{Name: "pg", Namespace: "default"}, pg)
+			if c.desiredDeleted {
+				if !apierrs.IsNotFound(err) {
+					t.Fatalf("want pod group deleted, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pg.Status.Phase != c.desiredGroupPhase {
+				t.Fatalf("want %v, got %v", c.desiredGroupPhase, pg.Status.Phase)
+			}
+			if completed := pg.Status.CompletionTime != nil; completed != isPodGroupCompleted(pg) {
+				t.Errorf("want completion time set %v, got %v", isPodGroupCompleted(pg), pg.Status.CompletionTime)
+			}
+		})
+	}
+}
+
 func setUp(ctx context.Context,
 	podNames []string,
 	pgName string,
@@ -294,6 +479,8 @@ func setUp(ctx context.Context,
 		Client:   client,
 		Scheme:   s,
 		recorder: record.NewFakeRecorder(3),
+		// the default of --podGroupStuckTimeout
+		StuckTimeout: 48 * time.Hour,
 
 		log: klogr.New().WithName("podGroupTest"),
 	}
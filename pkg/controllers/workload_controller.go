@@ -0,0 +1,248 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/go-logr/logr"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	schedv1alpha1 "sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+)
+
+// WorkloadReconciler creates a PodGroup for each workload of kind GVK annotated with
+// schedv1alpha1.AutoPodGroupAnnotation, and keeps it in sync with the workload.
+// batch/v1 Jobs are supported, as well as any workload with a scale subresource.
+type WorkloadReconciler struct {
+	log      logr.Logger
+	recorder record.EventRecorder
+	// getReplicas returns the number of replicas of a workload, read from its scale subresource by default.
+	getReplicas func(ctx context.Context, obj *unstructured.Unstructured) (int32, error)
+
+	client.Client
+	Scheme  *runtime.Scheme
+	Workers int
+	// GVK is the kind of workloads to reconcile.
+	GVK schema.GroupVersionKind
+}
+
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=apps,resources=deployments;replicasets;statefulsets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments/scale;replicasets/scale;statefulsets/scale,verbs=get
+
+// Reconcile creates, updates or deletes the PodGroup of a workload.
+// The PodGroup is named after the workload and controlled by it, so it gets garbage collected with the workload.
+// Its MinMember is the parallelism of a Job, capped by its completions, or the replicas of other workloads;
+// its MinResources is the request of the pod template times MinMember.
+func (r *WorkloadReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(r.GVK)
+	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		if apierrs.IsNotFound(err) {
+			log.V(5).Info("Workload has been deleted")
+			return ctrl.Result{}, nil
+		}
+		log.V(3).Error(err, "Unable to retrieve workload")
+		return ctrl.Result{}, err
+	}
+	if obj.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+
+	pg := &schedv1alpha1.PodGroup{}
+	exists := true
+	if err := r.Get(ctx, req.NamespacedName, pg); err != nil {
+		if !apierrs.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		exists = false
+	}
+	if exists && !metav1.IsControlledBy(pg, obj) {
+		r.recorder.Eventf(obj, v1.EventTypeWarning, "PodGroupConflict",
+			"PodGroup %v already exists and is not controlled by %v %v", pg.Name, r.GVK.Kind, obj.GetName())
+		return ctrl.Result{}, nil
+	}
+
+	if obj.GetAnnotations()[schedv1alpha1.AutoPodGroupAnnotation] != "true" {
+		if exists {
+			log.V(3).Info("Deleting the PodGroup of a workload which is no longer annotated", "podGroup", pg.Name)
+			return ctrl.Result{}, client.IgnoreNotFound(r.Delete(ctx, pg))
+		}
+		return ctrl.Result{}, nil
+	}
+
+	spec, err := r.podGroupSpec(ctx, obj)
+	if err != nil {
+		log.Error(err, "Unable to compute the PodGroup of the workload")
+		return ctrl.Result{}, err
+	}
+	if spec.MinMember < 1 {
+		log.V(4).Info("Workload has no pods to schedule, leaving its PodGroup as is")
+		return ctrl.Result{}, nil
+	}
+
+	if !exists {
+		pg = &schedv1alpha1.PodGroup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            obj.GetName(),
+				Namespace:       obj.GetNamespace(),
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(obj, r.GVK)},
+			},
+			Spec: spec,
+		}
+		if err := r.Create(ctx, pg); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.recorder.Eventf(obj, v1.EventTypeNormal, "PodGroupCreated", "Created PodGroup %v with minMember %v", pg.Name, spec.MinMember)
+	} else if pg.Spec.MinMember != spec.MinMember || !apiequality.Semantic.DeepEqual(pg.Spec.MinResources, spec.MinResources) {
+		pgCopy := pg.DeepCopy()
+		pgCopy.Spec.MinMember = spec.MinMember
+		pgCopy.Spec.MinResources = spec.MinResources
+		if err := r.Patch(ctx, pgCopy, client.MergeFrom(pg)); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, r.injectPodGroupLabel(ctx, obj)
+}
+
+// podGroupSpec returns the spec of the PodGroup of the given workload. The scheduling timeout is left to the user.
+func (r *WorkloadReconciler) podGroupSpec(ctx context.Context, obj *unstructured.Unstructured) (schedv1alpha1.PodGroupSpec, error) {
+	var minMember int32
+	if r.GVK.Group == batchv1.GroupName && r.GVK.Kind == "Job" {
+		minMember = jobMinMember(obj)
+	} else {
+		replicas, err := r.getReplicas(ctx, obj)
+		if err != nil {
+			return schedv1alpha1.PodGroupSpec{}, err
+		}
+		minMember = replicas
+	}
+
+	template := &v1.PodTemplateSpec{}
+	if m, found, err := unstructured.NestedMap(obj.Object, "spec", "template"); err != nil {
+		return schedv1alpha1.PodGroupSpec{}, err
+	} else if found {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, template); err != nil {
+			return schedv1alpha1.PodGroupSpec{}, err
+		}
+	}
+	var minResources v1.ResourceList
+	if minMember > 0 {
+		for name, quantity := range util.GetPodEffectiveRequest(&v1.Pod{Spec: template.Spec}) {
+			quantity.Mul(int64(minMember))
+			if minResources == nil {
+				minResources = make(v1.ResourceList)
+			}
+			minResources[name] = quantity
+		}
+	}
+	return schedv1alpha1.PodGroupSpec{MinMember: minMember, MinResources: minResources}, nil
+}
+
+// jobMinMember returns the number of pods of a Job running at once: its parallelism, capped by its completions.
+func jobMinMember(obj *unstructured.Unstructured) int32 {
+	parallelism, found, err := unstructured.NestedInt64(obj.Object, "spec", "parallelism")
+	if err != nil || !found {
+		parallelism = 1
+	}
+	if completions, found, err := unstructured.NestedInt64(obj.Object, "spec", "completions"); err == nil && found && completions < parallelism {
+		return int32(completions)
+	}
+	return int32(parallelism)
+}
+
+// scaleReplicas returns the replicas of the given workload from its scale subresource.
+func (r *WorkloadReconciler) scaleReplicas(ctx context.Context, obj *unstructured.Unstructured) (int32, error) {
+	scale := &unstructured.Unstructured{}
+	scale.SetGroupVersionKind(autoscalingv1.SchemeGroupVersion.WithKind("Scale"))
+	if err := r.SubResource("scale").Get(ctx, obj, scale); err != nil {
+		return 0, err
+	}
+	replicas, _, err := unstructured.NestedInt64(scale.Object, "spec", "replicas")
+	return int32(replicas), err
+}
+
+// injectPodGroupLabel adds the PodGroup label to the pod template of a suspended Job, so that its pods get
+// created with it. The template of a running Job is immutable, and updating the template of other workloads
+// would roll out their pods: their pods are labeled by the Pod webhook as they're created instead. A PodGroup
+// label set by the user is never overwritten.
+func (r *WorkloadReconciler) injectPodGroupLabel(ctx context.Context, obj *unstructured.Unstructured) error {
+	if r.GVK.Group != batchv1.GroupName || r.GVK.Kind != "Job" {
+		return nil
+	}
+	labels, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+	if pgName, ok := labels[schedv1alpha1.PodGroupLabel]; ok {
+		if pgName != obj.GetName() {
+			r.recorder.Eventf(obj, v1.EventTypeWarning, "PodGroupLabelConflict",
+				"Pod template is labeled with PodGroup %v, leaving it as is", pgName)
+		}
+		return nil
+	}
+	if suspended, _, _ := unstructured.NestedBool(obj.Object, "spec", "suspend"); !suspended {
+		log.FromContext(ctx).V(4).Info("Pod template of a running Job is immutable, leaving the labeling of its pods to the Pod webhook")
+		return nil
+	}
+	return r.Patch(ctx, obj, podGroupLabelPatch(obj.GetName(), "spec", "template", "metadata", "labels"))
+}
+
+// podGroupLabelPatch returns a merge patch setting the PodGroup label to <pgName> in the labels at the given path.
+func podGroupLabelPatch(pgName string, path ...string) client.Patch {
+	patch := map[string]interface{}{schedv1alpha1.PodGroupLabel: pgName}
+	for i := len(path) - 1; i >= 0; i-- {
+		patch = map[string]interface{}{path[i]: patch}
+	}
+	data, _ := json.Marshal(patch)
+	return client.RawPatch(types.MergePatchType, data)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *WorkloadReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.recorder = mgr.GetEventRecorderFor("PodGroupWorkloadController")
+	r.log = mgr.GetLogger()
+	if r.getReplicas == nil {
+		r.getReplicas = r.scaleReplicas
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(r.GVK)
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(strings.ToLower(r.GVK.Kind) + "-podgroup").
+		For(obj).
+		Owns(&schedv1alpha1.PodGroup{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.Workers}).
+		Complete(r)
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2/klogr"
+	"k8s.io/utils/pointer"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+)
+
+func TestWorkloadController_Run(t *testing.T) {
+	jobGVK := batchv1.SchemeGroupVersion.WithKind("Job")
+	deploymentGVK := appsv1.SchemeGroupVersion.WithKind("Deployment")
+	annotated := map[string]string{v1alpha1.AutoPodGroupAnnotation: "true"}
+	template := v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "w"}},
+		Spec: v1.PodSpec{Containers: []v1.Container{{
+			Name:  "c",
+			Image: "i",
+			Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("1"),
+				v1.ResourceMemory: resource.MustParse("1Gi"),
+			}},
+		}}},
+	}
+	makeJob := func(annotations map[string]string, parallelism, completions *int32) *batchv1.Job {
+		return &batchv1.Job{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+			ObjectMeta: metav1.ObjectMeta{Name: "w", Namespace: "ns", UID: "job-uid", Annotations: annotations},
+			Spec: batchv1.JobSpec{
+				Parallelism: parallelism,
+				Completions: completions,
+				Selector:    &metav1.LabelSelector{MatchLabels: map[string]string{"app": "w"}},
+				Template:    template,
+			},
+		}
+	}
+	deployment := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "w", Namespace: "ns", UID: "deployment-uid", Annotations: annotated},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: pointer.Int32(4),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "w"}},
+			Template: template,
+		},
+	}
+	makePodGroup := func(minMember int32, owner metav1.Object, gvk schema.GroupVersionKind) *v1alpha1.PodGroup {
+		pg := &v1alpha1.PodGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "w", Namespace: "ns"},
+			Spec:       v1alpha1.PodGroupSpec{MinMember: minMember},
+		}
+		if owner != nil {
+			pg.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(owner, gvk)}
+		}
+		return pg
+	}
+	suspend := func(job *batchv1.Job) *batchv1.Job {
+		job.Spec.Suspend = pointer.Bool(true)
+		return job
+	}
+	labeledJob := suspend(makeJob(annotated, pointer.Int32(2), nil))
+	labeledJob.Spec.Template.Labels = map[string]string{"app": "w", v1alpha1.PodGroupLabel: "custom"}
+
+	cases := []struct {
+		name         string
+		gvk          schema.GroupVersionKind
+		objs         []client.Object
+		wantPodGroup *v1alpha1.PodGroupSpec
+		// wantTemplateLabel is the expected PodGroup label of the pod template, "" for none.
+		wantTemplateLabel string
+	}{
+		{
+			name: "Job parallelism capped by completions",
+			gvk:  jobGVK,
+			objs: []client.Object{suspend(makeJob(annotated, pointer.Int32(3), pointer.Int32(2)))},
+			wantPodGroup: &v1alpha1.PodGroupSpec{MinMember: 2, MinResources: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("2Gi"),
+			}},
+			wantTemplateLabel: "w",
+		},
+		{
+			name: "Job without parallelism",
+			gvk:  jobGVK,
+			objs: []client.Object{suspend(makeJob(annotated, nil, nil))},
+			wantPodGroup: &v1alpha1.PodGroupSpec{MinMember: 1, MinResources: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("1"),
+				v1.ResourceMemory: resource.MustParse("1Gi"),
+			}},
+			wantTemplateLabel: "w",
+		},
+		{
+			name: "Deployment replicas from scale, template is left untouched",
+			gvk:  deploymentGVK,
+			objs: []client.Object{deployment},
+			wantPodGroup: &v1alpha1.PodGroupSpec{MinMember: 4, MinResources: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("4"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			}},
+		},
+		{
+			name: "stale PodGroup is updated",
+			gvk:  jobGVK,
+			objs: []client.Object{suspend(makeJob(annotated, pointer.Int32(3), nil)), makePodGroup(1, makeJob(nil, nil, nil), jobGVK)},
+			wantPodGroup: &v1alpha1.PodGroupSpec{MinMember: 3, MinResources: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("3"),
+				v1.ResourceMemory: resource.MustParse("3Gi"),
+			}},
+			wantTemplateLabel: "w",
+		},
+		{
+			name: "PodGroup of a workload no longer annotated is deleted",
+			gvk:  jobGVK,
+			objs: []client.Object{makeJob(nil, pointer.Int32(3), nil), makePodGroup(3, makeJob(nil, nil, nil), jobGVK)},
+		},
+		{
+			name:         "PodGroup not controlled by the workload is left untouched",
+			gvk:          jobGVK,
+			objs:         []client.Object{makeJob(annotated, pointer.Int32(3), nil), makePodGroup(5, nil, jobGVK)},
+			wantPodGroup: &v1alpha1.PodGroupSpec{MinMember: 5},
+		},
+		{
+			name: "template of a running Job is left untouched",
+			gvk:  jobGVK,
+			objs: []client.Object{makeJob(annotated, pointer.Int32(2), nil)},
+			wantPodGroup: &v1alpha1.PodGroupSpec{MinMember: 2, MinResources: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("2Gi"),
+			}},
+		},
+		{
+			name: "PodGroup label set by the user on the template is kept",
+			gvk:  jobGVK,
+			objs: []client.Object{labeledJob},
+			wantPodGroup: &v1alpha1.PodGroupSpec{MinMember: 2, MinResources: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("2Gi"),
+			}},
+			wantTemplateLabel: "custom",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := runtime.NewScheme()
+			utilruntime.Must(clientgoscheme.AddToScheme(s))
+			utilruntime.Must(v1alpha1.AddToScheme(s))
+			cl := fake.NewClientBuilder().WithScheme(s).WithObjects(c.objs...).Build()
+			r := &WorkloadReconciler{
+				Client:   cl,
+				Scheme:   s,
+				GVK:      c.gvk,
+				recorder: record.NewFakeRecorder(3),
+				log:      klogr.New().WithName("workloadTest"),
+				getReplicas: func(_ context.Context, obj *unstructured.Unstructured) (int32, error) {
+					replicas, _, err := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+					return int32(replicas), err
+				},
+			}
+
+			key := types.NamespacedName{Namespace: "ns", Name: "w"}
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatal(err)
+			}
+
+			pg := &v1alpha1.PodGroup{}
+			err := cl.Get(ctx, key, pg)
+			if c.wantPodGroup == nil {
+				if !apierrs.IsNotFound(err) {
+					t.Errorf("Want no PodGroup, got %v, err %v", pg.Spec, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pg.Spec.MinMember != c.wantPodGroup.MinMember {
+				t.Errorf("Want minMember %v, got %v", c.wantPodGroup.MinMember, pg.Spec.MinMember)
+			}
+			for name, want := range c.wantPodGroup.MinResources {
+				if got := pg.Spec.MinResources[name]; got.Cmp(want) != 0 {
+					t.Errorf("Want minResources %v %v, got %v", name, want.String(), got.String())
+				}
+			}
+
+			obj := &unstructured.Unstructured{}
+			obj.SetGroupVersionKind(c.gvk)
+			if err := cl.Get(ctx, key, obj); err != nil {
+				t.Fatal(err)
+			}
+			labels, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+			if got := labels[v1alpha1.PodGroupLabel]; got != c.wantTemplateLabel {
+				t.Errorf("Want PodGroup label in template %q, got %q", c.wantTemplateLabel, got)
+			}
+		})
+	}
+}
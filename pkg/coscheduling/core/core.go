@@ -24,17 +24,22 @@ import (
 
 	gochache "github.com/patrickmn/go-cache"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	informerv1 "k8s.io/client-go/informers/core/v1"
 	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/events"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+	pginformer "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions/scheduling/v1alpha1"
+	pglister "sigs.k8s.io/scheduler-plugins/pkg/generated/listers/scheduling/v1alpha1"
 	"sigs.k8s.io/scheduler-plugins/pkg/util"
 )
 
@@ -54,10 +59,23 @@ const (
 
 type PermitState struct {
 	Activate bool
+	// WaitDeadline is the time after which the pod waiting at Permit times out.
+	WaitDeadline time.Time
 }
 
 func (s *PermitState) Clone() framework.StateData {
-	return &PermitState{Activate: s.Activate}
+	return &PermitState{Activate: s.Activate, WaitDeadline: s.WaitDeadline}
+}
+
+// IsPermitTimedOut returns true if the pod of the given cycle has been waiting at Permit
+// past its wait deadline.
+func IsPermitTimedOut(state *framework.CycleState) bool {
+	c, err := state.Read(permitStateKey)
+	if err != nil {
+		return false
+	}
+	s, ok := c.(*PermitState)
+	return ok && !s.WaitDeadline.IsZero() && !time.Now().Before(s.WaitDeadline)
 }
 
 // Manager defines the interfaces for PodGroup management.
@@ -69,13 +87,33 @@ type Manager interface {
 	DeletePermittedPodGroup(string)
 	CalculateAssignedPods(string, string) int
 	ActivateSiblings(pod *corev1.Pod, state *framework.CycleState)
-	BackoffPodGroup(string, time.Duration)
+	BackoffPodGroup(*v1alpha1.PodGroup, time.Duration, time.Duration) *PodGroupBackoff
+	GetPodGroupBackoff(string) *PodGroupBackoff
+	ResetPodGroupBackoff(*v1alpha1.PodGroup)
+	UpdatePodGroupCondition(context.Context, *v1alpha1.PodGroup, metav1.Condition)
+	ReserveCapacity(context.Context, *corev1.Pod, string, func(*framework.NodeInfo) bool)
+	CheckReservedCapacity(*corev1.Pod, *framework.NodeInfo) error
+	ConsumeReservation(*corev1.Pod, string)
+	ReleaseReservation(context.Context, *v1alpha1.PodGroup, string, string)
+	GetReservation(string) *Reservation
+}
+
+// PodGroupBackoff describes the backoff state of a PodGroup that failed scheduling.
+type PodGroupBackoff struct {
+	// Attempts is the number of consecutive scheduling failures of the PodGroup.
+	Attempts int32
+	// Duration is the backoff applied after the last failure.
+	Duration time.Duration
+	// RetryAt is the time after which the PodGroup can be scheduled again.
+	RetryAt time.Time
 }
 
 // PodGroupManager defines the scheduling operation called
 type PodGroupManager struct {
-	// client is a generic controller-runtime client to manipulate both core resources and PodGroups.
+	// client is a generic controller-runtime client to update the status of PodGroups.
 	client client.Client
+	// pgLister is podgroup lister
+	pgLister pglister.PodGroupLister
 	// snapshotSharedLister is pod shared list
 	snapshotSharedLister framework.SharedLister
 	// scheduleTimeout is the default timeout for podgroup scheduling.
@@ -83,31 +121,153 @@ type PodGroupManager struct {
 	scheduleTimeout *time.Duration
 	// permittedPG stores the podgroup name which has passed the pre resource check.
 	permittedPG *gochache.Cache
-	// backedOffPG stores the podgorup name which failed scheudling recently.
+	// backedOffPG stores the backoff state of the podgroups which failed scheduling recently.
+	// An entry outlives its backoff, so that consecutive failures keep growing the backoff.
 	backedOffPG *gochache.Cache
 	// podLister is pod lister
 	podLister listerv1.PodLister
+	// statusUpdater writes the scheduler-owned conditions of podgroups.
+	statusUpdater *statusUpdater
+	// creationTimestamps caches the creation timestamp of the podgroup of each pod,
+	// as it's looked up for every comparison of the queue sort.
+	creationTimestamps *creationTimestampCache
+	// reservations stores the node capacity held for podgroups, keyed by <namespace>/<name>.
+	// It's guarded by the lock of the manager.
+	reservations map[string]*Reservation
 	sync.RWMutex
 }
 
 // NewPodGroupManager creates a new operation object.
-func NewPodGroupManager(client client.Client, snapshotSharedLister framework.SharedLister, scheduleTimeout *time.Duration, podInformer informerv1.PodInformer) *PodGroupManager {
+func NewPodGroupManager(ctx context.Context, client client.Client, snapshotSharedLister framework.SharedLister, scheduleTimeout *time.Duration,
+	pgInformer pginformer.PodGroupInformer, podInformer informerv1.PodInformer, recorder events.EventRecorder) *PodGroupManager {
 	pgMgr := &PodGroupManager{
 		client:               client,
+		pgLister:             pgInformer.Lister(),
 		snapshotSharedLister: snapshotSharedLister,
 		scheduleTimeout:      scheduleTimeout,
 		podLister:            podInformer.Lister(),
 		permittedPG:          gochache.New(3*time.Second, 3*time.Second),
 		backedOffPG:          gochache.New(10*time.Second, 10*time.Second),
+		statusUpdater:        newStatusUpdater(client, pgInformer.Lister(), recorder),
+		creationTimestamps:   newCreationTimestampCache(),
+		reservations:         make(map[string]*Reservation),
 	}
+	pgMgr.creationTimestamps.addEventHandlers(pgInformer, podInformer)
+	pgMgr.statusUpdater.run(ctx)
+	go wait.UntilWithContext(ctx, pgMgr.releaseExpiredReservations, reservationGCPeriod)
+	pgInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if pg, ok := obj.(*v1alpha1.PodGroup); ok {
+				pgMgr.Lock()
+				delete(pgMgr.reservations, GetNamespacedName(pg))
+				pgMgr.Unlock()
+			}
+		},
+	})
 	return pgMgr
 }
 
-func (pgMgr *PodGroupManager) BackoffPodGroup(pgName string, backoff time.Duration) {
+// BackoffPodGroup backs off the given PodGroup. The backoff starts at <backoff> and doubles
+// on every consecutive failure, up to <maxBackoff>. The state is kept in memory only; it's
+// published as a BackedOff condition of the PodGroup by the status updater in the background.
+func (pgMgr *PodGroupManager) BackoffPodGroup(pg *v1alpha1.PodGroup, backoff, maxBackoff time.Duration) *PodGroupBackoff {
 	if backoff == time.Duration(0) {
+		return nil
+	}
+	if maxBackoff < backoff {
+		maxBackoff = backoff
+	}
+
+	pgFullName := GetNamespacedName(pg)
+	pgMgr.Lock()
+	state := &PodGroupBackoff{Attempts: 1, Duration: backoff}
+	if last := pgMgr.GetPodGroupBackoff(pgFullName); last != nil {
+		state.Attempts = last.Attempts + 1
+		state.Duration = last.Duration * 2
+		if state.Duration > maxBackoff {
+			state.Duration = maxBackoff
+		}
+	}
+	state.RetryAt = time.Now().Add(state.Duration)
+	// Keep the state around for another <maxBackoff> after it expires. A PodGroup that doesn't
+	// fail again within that window starts over from the initial backoff.
+	pgMgr.backedOffPG.Set(pgFullName, state, state.Duration+maxBackoff)
+	pgMgr.Unlock()
+	// The members rejected while backed off are only requeued by an event, so the end of the backoff is
+	// published as an update of the PodGroup.
+	time.AfterFunc(state.Duration, func() { pgMgr.expirePodGroupBackoff(pgFullName, state) })
+
+	klog.V(3).InfoS("Backoff PodGroup", "podGroup", klog.KObj(pg), "attempts", state.Attempts, "backoff", state.Duration)
+	// Every backoff carries a new retry time, so it bypasses the rate limiting of condition updates.
+	pgMgr.statusUpdater.update(pg, metav1.Condition{
+		Type:   v1alpha1.PodGroupBackedOff,
+		Status: metav1.ConditionTrue,
+		Reason: v1alpha1.PodGroupReasonUnschedulable,
+		Message: fmt.Sprintf("PodGroup failed scheduling %d time(s) in a row, backed off for %v until %v",
+			state.Attempts, state.Duration, state.RetryAt.UTC().Format(time.RFC3339)),
+	}, true)
+	return state
+}
+
+// expirePodGroupBackoff turns off the BackedOff condition of the PodGroup once <state> runs out,
+// unless the PodGroup got backed off again or reset meanwhile.
+func (pgMgr *PodGroupManager) expirePodGroupBackoff(pgFullName string, state *PodGroupBackoff) {
+	if pgMgr.GetPodGroupBackoff(pgFullName) != state {
+		return
+	}
+	namespace, name, err := cache.SplitMetaNamespaceKey(pgFullName)
+	if err != nil {
+		return
+	}
+	pg, err := pgMgr.pgLister.PodGroups(namespace).Get(name)
+	if err != nil {
+		return
+	}
+	klog.V(3).InfoS("Backoff of PodGroup expired", "podGroup", klog.KObj(pg), "attempts", state.Attempts)
+	pgMgr.statusUpdater.update(pg, metav1.Condition{
+		Type:    v1alpha1.PodGroupBackedOff,
+		Status:  metav1.ConditionFalse,
+		Reason:  v1alpha1.PodGroupReasonExpired,
+		Message: fmt.Sprintf("Backoff after %d failure(s) in a row expired", state.Attempts),
+	}, true)
+}
+
+// GetPodGroupBackoff returns the backoff state of the given PodGroup, or nil if it hasn't failed recently.
+func (pgMgr *PodGroupManager) GetPodGroupBackoff(pgFullName string) *PodGroupBackoff {
+	if v, exist := pgMgr.backedOffPG.Get(pgFullName); exist {
+		if state, ok := v.(*PodGroupBackoff); ok {
+			return state
+		}
+	}
+	return nil
+}
+
+// ResetPodGroupBackoff forgets the backoff state of a PodGroup that got scheduled,
+// and clears its BackedOff condition.
+func (pgMgr *PodGroupManager) ResetPodGroupBackoff(pg *v1alpha1.PodGroup) {
+	pgFullName := GetNamespacedName(pg)
+	// The condition of a recent backoff may not have been written yet, so the in-memory
+	// state decides as well as the PodGroup at hand.
+	backedOff := pgMgr.GetPodGroupBackoff(pgFullName) != nil
+	pgMgr.backedOffPG.Delete(pgFullName)
+	if !backedOff && !meta.IsStatusConditionTrue(pg.Status.Conditions, v1alpha1.PodGroupBackedOff) {
 		return
 	}
-	pgMgr.backedOffPG.Add(pgName, nil, backoff)
+	pgMgr.statusUpdater.update(pg, metav1.Condition{
+		Type:    v1alpha1.PodGroupBackedOff,
+		Status:  metav1.ConditionFalse,
+		Reason:  v1alpha1.PodGroupReasonQuorumReached,
+		Message: "PodGroup reached its minMember at Permit",
+	}, true)
+}
+
+// UpdatePodGroupCondition queues the given condition for the status of the PodGroup. Updates are rate limited,
+// so that the members of a PodGroup going through the same extension point don't patch it over and over.
+func (pgMgr *PodGroupManager) UpdatePodGroupCondition(ctx context.Context, pg *v1alpha1.PodGroup, condition metav1.Condition) {
+	pgMgr.statusUpdater.update(pg, condition, false)
 }
 
 // ActivateSiblings stashes the pods belonging to the same PodGroup of the given pod
@@ -165,7 +325,13 @@ func (pgMgr *PodGroupManager) PreFilter(ctx context.Context, pod *corev1.Pod) er
 		return nil
 	}
 
-	if _, exist := pgMgr.backedOffPG.Get(pgFullName); exist {
+	if state := pgMgr.GetPodGroupBackoff(pgFullName); state != nil && time.Now().Before(state.RetryAt) {
+		pgMgr.UpdatePodGroupCondition(ctx, pg, metav1.Condition{
+			Type:    v1alpha1.PodGroupScheduled,
+			Status:  metav1.ConditionFalse,
+			Reason:  v1alpha1.PodGroupReasonBackedOff,
+			Message: fmt.Sprintf("PodGroup is backed off until %v", state.RetryAt.UTC().Format(time.RFC3339)),
+		})
 		return fmt.Errorf("podGroup %v failed recently", pgFullName)
 	}
 
@@ -177,6 +343,12 @@ func (pgMgr *PodGroupManager) PreFilter(ctx context.Context, pod *corev1.Pod) er
 	}
 
 	if len(pods) < int(pg.Spec.MinMember) {
+		pgMgr.UpdatePodGroupCondition(ctx, pg, metav1.Condition{
+			Type:    v1alpha1.PodGroupScheduled,
+			Status:  metav1.ConditionFalse,
+			Reason:  v1alpha1.PodGroupReasonNotEnoughPods,
+			Message: fmt.Sprintf("%v of %v pods of the group are present", len(pods), pg.Spec.MinMember),
+		})
 		return fmt.Errorf("pre-filter pod %v cannot find enough sibling pods, "+
 			"current pods number: %v, minMember of group: %v", pod.Name, len(pods), pg.Spec.MinMember)
 	}
@@ -203,6 +375,12 @@ func (pgMgr *PodGroupManager) PreFilter(ctx context.Context, pod *corev1.Pod) er
 	err = CheckClusterResource(ctx, nodes, minResources, pgFullName)
 	if err != nil {
 		klog.ErrorS(err, "Failed to PreFilter", "podGroup", klog.KObj(pg))
+		pgMgr.UpdatePodGroupCondition(ctx, pg, metav1.Condition{
+			Type:    v1alpha1.PodGroupScheduled,
+			Status:  metav1.ConditionFalse,
+			Reason:  v1alpha1.PodGroupReasonInsufficientResources,
+			Message: fmt.Sprintf("Cluster cannot satisfy the minResources of the group: %v", err),
+		})
 		return err
 	}
 	pgMgr.permittedPG.Add(pgFullName, pgFullName, *pgMgr.scheduleTimeout)
@@ -224,9 +402,29 @@ func (pgMgr *PodGroupManager) Permit(ctx context.Context, state *framework.Cycle
 	// The number of pods that have been assigned nodes is calculated from the snapshot.
 	// The current pod in not included in the snapshot during the current scheduling cycle.
 	if int32(assigned)+1 >= pg.Spec.MinMember {
+		pgMgr.ResetPodGroupBackoff(pg)
+		pgMgr.UpdatePodGroupCondition(ctx, pg, metav1.Condition{
+			Type:    v1alpha1.PodGroupWaitingAtPermit,
+			Status:  metav1.ConditionFalse,
+			Reason:  v1alpha1.PodGroupReasonQuorumReached,
+			Message: fmt.Sprintf("%v of %v members are assigned", assigned+1, pg.Spec.MinMember),
+		})
+		pgMgr.UpdatePodGroupCondition(ctx, pg, metav1.Condition{
+			Type:    v1alpha1.PodGroupScheduled,
+			Status:  metav1.ConditionTrue,
+			Reason:  v1alpha1.PodGroupReasonQuorumReached,
+			Message: fmt.Sprintf("%v of %v members are assigned", assigned+1, pg.Spec.MinMember),
+		})
 		return Success
 	}
 
+	pgMgr.UpdatePodGroupCondition(ctx, pg, metav1.Condition{
+		Type:    v1alpha1.PodGroupWaitingAtPermit,
+		Status:  metav1.ConditionTrue,
+		Reason:  v1alpha1.PodGroupReasonWaiting,
+		Message: fmt.Sprintf("%v of %v members are waiting at Permit", assigned+1, pg.Spec.MinMember),
+	})
+	permitState := &PermitState{WaitDeadline: time.Now().Add(util.GetWaitTimeDuration(pg, pgMgr.scheduleTimeout))}
 	if assigned == 0 {
 		// Given we've reached Permit(), it's mean all PreFilter checks (minMember & minResource)
 		// already pass through, so if assigned == 0, it could be due to:
@@ -236,22 +434,27 @@ func (pgMgr *PodGroupManager) Permit(ctx context.Context, state *framework.Cycle
 		// its siblings.
 		// It'd be in-efficient if we trigger activating siblings unconditionally.
 		// See https://github.com/kubernetes-sigs/scheduler-plugins/issues/682
-		state.Write(permitStateKey, &PermitState{Activate: true})
+		permitState.Activate = true
 	}
+	state.Write(permitStateKey, permitState)
 
 	return Wait
 }
 
 // GetCreationTimestamp returns the creation time of a podGroup or a pod.
 func (pgMgr *PodGroupManager) GetCreationTimestamp(pod *corev1.Pod, ts time.Time) time.Time {
+	if t, ok := pgMgr.creationTimestamps.get(pod); ok {
+		return t
+	}
 	pgName := util.GetPodGroupLabel(pod)
 	if len(pgName) == 0 {
 		return ts
 	}
-	var pg v1alpha1.PodGroup
-	if err := pgMgr.client.Get(context.TODO(), types.NamespacedName{Namespace: pod.Namespace, Name: pgName}, &pg); err != nil {
+	pg, err := pgMgr.pgLister.PodGroups(pod.Namespace).Get(pgName)
+	if err != nil {
 		return ts
 	}
+	pgMgr.creationTimestamps.set(pod, pg)
 	return pg.CreationTimestamp.Time
 }
 
@@ -261,16 +464,17 @@ func (pgMgr *PodGroupManager) DeletePermittedPodGroup(pgFullName string) {
 }
 
 // GetPodGroup returns the PodGroup that a Pod belongs to in cache.
+// The returned PodGroup is shared with the informer cache and must not be modified.
 func (pgMgr *PodGroupManager) GetPodGroup(ctx context.Context, pod *corev1.Pod) (string, *v1alpha1.PodGroup) {
 	pgName := util.GetPodGroupLabel(pod)
 	if len(pgName) == 0 {
 		return "", nil
 	}
-	var pg v1alpha1.PodGroup
-	if err := pgMgr.client.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: pgName}, &pg); err != nil {
+	pg, err := pgMgr.pgLister.PodGroups(pod.Namespace).Get(pgName)
+	if err != nil {
 		return fmt.Sprintf("%v/%v", pod.Namespace, pgName), nil
 	}
-	return fmt.Sprintf("%v/%v", pod.Namespace, pgName), &pg
+	return fmt.Sprintf("%v/%v", pod.Namespace, pgName), pg
 }
 
 // CalculateAssignedPods returns the number of pods that has been assigned nodes: assumed or bound.
@@ -23,14 +23,21 @@ import (
 
 	gochache "github.com/patrickmn/go-cache"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	clientsetfake "k8s.io/client-go/kubernetes/fake"
 	clicache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/events"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	st "k8s.io/kubernetes/pkg/scheduler/testing"
 	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+	pgfake "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned/fake"
+	pginformers "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
 	tu "sigs.k8s.io/scheduler-plugins/test/util"
 )
 
@@ -162,9 +169,12 @@ func TestPreFilter(t *testing.T) {
 			cs := clientsetfake.NewSimpleClientset()
 			informerFactory := informers.NewSharedInformerFactory(cs, 0)
 			podInformer := informerFactory.Core().V1().Pods()
+			pgInformerFactory := pginformers.NewSharedInformerFactory(pgfake.NewSimpleClientset(), 0)
+			pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
 
 			pgMgr := &PodGroupManager{
 				client:               client,
+				pgLister:             pgInformer.Lister(),
 				snapshotSharedLister: tu.NewFakeSharedLister(tt.pendingPods, nodes),
 				podLister:            podInformer.Lister(),
 				scheduleTimeout:      &scheduleTimeout,
@@ -173,6 +183,13 @@ func TestPreFilter(t *testing.T) {
 			}
 
 			informerFactory.Start(ctx.Done())
+			pgInformerFactory.Start(ctx.Done())
+			if !clicache.WaitForCacheSync(ctx.Done(), pgInformer.Informer().HasSynced) {
+				t.Fatal("WaitForCacheSync failed")
+			}
+			for _, pg := range tt.pgs {
+				pgInformer.Informer().GetStore().Add(pg)
+			}
 			if !clicache.WaitForCacheSync(ctx.Done(), podInformer.Informer().HasSynced) {
 				t.Fatal("WaitForCacheSync failed")
 			}
@@ -263,15 +280,26 @@ func TestPermit(t *testing.T) {
 			cs := clientsetfake.NewSimpleClientset()
 			informerFactory := informers.NewSharedInformerFactory(cs, 0)
 			podInformer := informerFactory.Core().V1().Pods()
+			pgInformerFactory := pginformers.NewSharedInformerFactory(pgfake.NewSimpleClientset(), 0)
+			pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
 
 			pgMgr := &PodGroupManager{
 				client:               client,
+				pgLister:             pgInformer.Lister(),
 				snapshotSharedLister: tu.NewFakeSharedLister(tt.existingPods, nodes),
 				podLister:            podInformer.Lister(),
 				scheduleTimeout:      &scheduleTimeout,
+				backedOffPG:          newCache(),
 			}
 
 			informerFactory.Start(ctx.Done())
+			pgInformerFactory.Start(ctx.Done())
+			if !clicache.WaitForCacheSync(ctx.Done(), pgInformer.Informer().HasSynced) {
+				t.Fatal("WaitForCacheSync failed")
+			}
+			for _, pg := range tt.pgs {
+				pgInformer.Informer().GetStore().Add(pg)
+			}
 			if !clicache.WaitForCacheSync(ctx.Done(), podInformer.Informer().HasSynced) {
 				t.Fatal("WaitForCacheSync failed")
 			}
@@ -357,6 +385,265 @@ func TestCheckClusterResource(t *testing.T) {
 	}
 }
 
+func TestBackoffPodGroup(t *testing.T) {
+	backoff, maxBackoff := 10*time.Second, 30*time.Second
+	pg := tu.MakePodGroup().Name("pg1").Namespace("ns").MinMember(2).Obj()
+	tests := []struct {
+		name     string
+		failures int
+		reset    bool
+		// unwritten resets the backoff before its condition got written.
+		unwritten bool
+		want      *PodGroupBackoff
+	}{
+		{
+			name:     "first failure uses the initial backoff",
+			failures: 1,
+			want:     &PodGroupBackoff{Attempts: 1, Duration: 10 * time.Second},
+		},
+		{
+			name:     "consecutive failures double the backoff",
+			failures: 2,
+			want:     &PodGroupBackoff{Attempts: 2, Duration: 20 * time.Second},
+		},
+		{
+			name:     "backoff is capped",
+			failures: 4,
+			want:     &PodGroupBackoff{Attempts: 4, Duration: 30 * time.Second},
+		},
+		{
+			name:     "backoff is reset once the pod group gets scheduled",
+			failures: 3,
+			reset:    true,
+		},
+		{
+			name:      "backoff is reset before its condition is written",
+			failures:  1,
+			reset:     true,
+			unwritten: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client, err := tu.NewFakeClient(pg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u := newTestStatusUpdater(t, client, nil)
+			pgMgr := &PodGroupManager{
+				client:        client,
+				pgLister:      u.pgLister,
+				backedOffPG:   newCache(),
+				statusUpdater: u.statusUpdater,
+			}
+
+			var latest *v1alpha1.PodGroup
+			for i := 0; i < tt.failures; i++ {
+				pgMgr.BackoffPodGroup(u.get(pg.Namespace, pg.Name), backoff, maxBackoff)
+				got := &v1alpha1.PodGroup{}
+				if err := client.Get(ctx, types.NamespacedName{Namespace: pg.Namespace, Name: pg.Name}, got); err != nil {
+					t.Fatal(err)
+				}
+				if i == 0 && meta.FindStatusCondition(got.Status.Conditions, v1alpha1.PodGroupBackedOff) != nil {
+					t.Errorf("Expected the backoff not to be written synchronously, got %v", got.Status.Conditions)
+				}
+				if !tt.unwritten {
+					u.flush()
+				}
+			}
+			latest = u.get(pg.Namespace, pg.Name)
+			if !tt.unwritten && !meta.IsStatusConditionTrue(latest.Status.Conditions, v1alpha1.PodGroupBackedOff) {
+				t.Errorf("Expected condition %v to be true, got %v", v1alpha1.PodGroupBackedOff, latest.Status.Conditions)
+			}
+			if tt.reset {
+				pgMgr.ResetPodGroupBackoff(latest)
+				u.flush()
+				latest = u.get(pg.Namespace, pg.Name)
+				if meta.IsStatusConditionTrue(latest.Status.Conditions, v1alpha1.PodGroupBackedOff) {
+					t.Errorf("Expected condition %v to be cleared, got %v", v1alpha1.PodGroupBackedOff, latest.Status.Conditions)
+				}
+			}
+
+			got := pgMgr.GetPodGroupBackoff("ns/pg1")
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("Expected no backoff, got %+v", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("Expected backoff %+v, got nil", tt.want)
+			}
+			if got.Attempts != tt.want.Attempts || got.Duration != tt.want.Duration {
+				t.Errorf("Expected backoff %+v, got %+v", tt.want, got)
+			}
+			if err := pgMgr.PreFilter(ctx, st.MakePod().Name("p").Namespace("ns").Label(v1alpha1.PodGroupLabel, "pg1").Obj()); err == nil {
+				t.Errorf("Expected PreFilter to reject a backed off pod group")
+			}
+		})
+	}
+}
+
+func TestPodGroupBackoffExpiry(t *testing.T) {
+	ctx := context.Background()
+	pg := tu.MakePodGroup().Name("pg1").Namespace("ns").MinMember(2).Obj()
+	client, err := tu.NewFakeClient(pg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := newTestStatusUpdater(t, client, nil)
+	pgMgr := &PodGroupManager{
+		client:        client,
+		pgLister:      u.pgLister,
+		backedOffPG:   newCache(),
+		statusUpdater: u.statusUpdater,
+	}
+
+	pgMgr.BackoffPodGroup(u.get(pg.Namespace, pg.Name), 50*time.Millisecond, time.Second)
+	u.flush()
+	if !meta.IsStatusConditionTrue(u.get(pg.Namespace, pg.Name).Status.Conditions, v1alpha1.PodGroupBackedOff) {
+		t.Fatalf("Expected condition %v to be true", v1alpha1.PodGroupBackedOff)
+	}
+	// The end of the backoff is published, so that the members rejected meanwhile get requeued.
+	if err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, wait.ForeverTestTimeout, false, func(context.Context) (bool, error) {
+		return u.queue.Len() > 0, nil
+	}); err != nil {
+		t.Fatalf("Expected the end of the backoff to be queued: %v", err)
+	}
+	u.flush()
+	c := meta.FindStatusCondition(u.get(pg.Namespace, pg.Name).Status.Conditions, v1alpha1.PodGroupBackedOff)
+	if c == nil || c.Status != metav1.ConditionFalse || c.Reason != v1alpha1.PodGroupReasonExpired {
+		t.Errorf("Expected condition %v to be false with reason %v, got %v", v1alpha1.PodGroupBackedOff, v1alpha1.PodGroupReasonExpired, c)
+	}
+	// The state is kept, so that another failure keeps growing the backoff.
+	if state := pgMgr.GetPodGroupBackoff("ns/pg1"); state == nil || state.Attempts != 1 {
+		t.Errorf("Expected the backoff state to be kept, got %+v", state)
+	}
+}
+
+func TestUpdatePodGroupCondition(t *testing.T) {
+	pg := tu.MakePodGroup().Name("pg1").Namespace("ns").MinMember(3).Obj()
+	tests := []struct {
+		name       string
+		conditions []metav1.Condition
+		want       metav1.Condition
+		wantEvents int
+	}{
+		{
+			name: "first condition gets written",
+			conditions: []metav1.Condition{
+				{Type: v1alpha1.PodGroupScheduled, Status: metav1.ConditionFalse, Reason: v1alpha1.PodGroupReasonNotEnoughPods, Message: "1 of 3"},
+			},
+			want:       metav1.Condition{Type: v1alpha1.PodGroupScheduled, Status: metav1.ConditionFalse, Reason: v1alpha1.PodGroupReasonNotEnoughPods, Message: "1 of 3"},
+			wantEvents: 1,
+		},
+		{
+			name: "same status and reason is rate limited",
+			conditions: []metav1.Condition{
+				{Type: v1alpha1.PodGroupScheduled, Status: metav1.ConditionFalse, Reason: v1alpha1.PodGroupReasonNotEnoughPods, Message: "1 of 3"},
+				{Type: v1alpha1.PodGroupScheduled, Status: metav1.ConditionFalse, Reason: v1alpha1.PodGroupReasonNotEnoughPods, Message: "2 of 3"},
+			},
+			want:       metav1.Condition{Type: v1alpha1.PodGroupScheduled, Status: metav1.ConditionFalse, Reason: v1alpha1.PodGroupReasonNotEnoughPods, Message: "1 of 3"},
+			wantEvents: 1,
+		},
+		{
+			name: "a new reason is written right away",
+			conditions: []metav1.Condition{
+				{Type: v1alpha1.PodGroupScheduled, Status: metav1.ConditionFalse, Reason: v1alpha1.PodGroupReasonNotEnoughPods, Message: "1 of 3"},
+				{Type: v1alpha1.PodGroupScheduled, Status: metav1.ConditionFalse, Reason: v1alpha1.PodGroupReasonInsufficientResources, Message: "resource gap"},
+			},
+			want:       metav1.Condition{Type: v1alpha1.PodGroupScheduled, Status: metav1.ConditionFalse, Reason: v1alpha1.PodGroupReasonInsufficientResources, Message: "resource gap"},
+			wantEvents: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client, err := tu.NewFakeClient(pg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			recorder := events.NewFakeRecorder(10)
+			u := newTestStatusUpdater(t, client, recorder)
+			pgMgr := &PodGroupManager{client: client, statusUpdater: u.statusUpdater}
+			for _, c := range tt.conditions {
+				pgMgr.UpdatePodGroupCondition(ctx, u.get(pg.Namespace, pg.Name), c)
+				u.flush()
+			}
+
+			got := u.get(pg.Namespace, pg.Name)
+			c := meta.FindStatusCondition(got.Status.Conditions, tt.want.Type)
+			if c == nil {
+				t.Fatalf("Expected condition %v, got %v", tt.want.Type, got.Status.Conditions)
+			}
+			if c.Status != tt.want.Status || c.Reason != tt.want.Reason || c.Message != tt.want.Message {
+				t.Errorf("Expected condition %+v, got %+v", tt.want, *c)
+			}
+			if len(recorder.Events) != tt.wantEvents {
+				t.Errorf("Expected %v events, got %v", tt.wantEvents, len(recorder.Events))
+			}
+		})
+	}
+}
+
+func TestGetCreationTimestamp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	recreated := created.Add(time.Minute)
+	pod := st.MakePod().Name("p").Namespace("ns").UID("p").Label(v1alpha1.PodGroupLabel, "pg1").Obj()
+	podWithoutPG := st.MakePod().Name("p2").Namespace("ns").UID("p2").Obj()
+
+	cs := clientsetfake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(cs, 0)
+	podInformer := informerFactory.Core().V1().Pods()
+	pgClient := pgfake.NewSimpleClientset()
+	pgInformerFactory := pginformers.NewSharedInformerFactory(pgClient, 0)
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+	pgMgr := NewPodGroupManager(ctx, nil, nil, nil, pgInformer, podInformer, nil)
+	informerFactory.Start(ctx.Done())
+	pgInformerFactory.Start(ctx.Done())
+	if !clicache.WaitForCacheSync(ctx.Done(), pgInformer.Informer().HasSynced, podInformer.Informer().HasSynced) {
+		t.Fatal("WaitForCacheSync failed")
+	}
+
+	waitForTimestamp := func(want time.Time) {
+		t.Helper()
+		if err := wait.PollUntilContextTimeout(ctx, 10*time.Millisecond, 5*time.Second, true, func(context.Context) (bool, error) {
+			return pgMgr.GetCreationTimestamp(pod, time.Time{}).Equal(want), nil
+		}); err != nil {
+			t.Fatalf("Want creation timestamp %v, got %v", want, pgMgr.GetCreationTimestamp(pod, time.Time{}))
+		}
+	}
+
+	// The pod's own timestamp is used until its PodGroup shows up.
+	if got := pgMgr.GetCreationTimestamp(pod, created.Add(time.Hour)); !got.Equal(created.Add(time.Hour)) {
+		t.Errorf("Want the pod's timestamp %v, got %v", created.Add(time.Hour), got)
+	}
+	pg := tu.MakePodGroup().Name("pg1").Namespace("ns").MinMember(1).Time(created).Obj()
+	if _, err := pgClient.SchedulingV1alpha1().PodGroups("ns").Create(ctx, pg, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForTimestamp(created)
+	if got := pgMgr.GetCreationTimestamp(podWithoutPG, recreated); !got.Equal(recreated) {
+		t.Errorf("Want the pod's timestamp %v, got %v", recreated, got)
+	}
+
+	// A recreated PodGroup is looked up again.
+	if err := pgClient.SchedulingV1alpha1().PodGroups("ns").Delete(ctx, "pg1", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	pg = tu.MakePodGroup().Name("pg1").Namespace("ns").MinMember(1).Time(recreated).Obj()
+	if _, err := pgClient.SchedulingV1alpha1().PodGroups("ns").Create(ctx, pg, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitForTimestamp(recreated)
+}
+
 func newCache() *gochache.Cache {
 	return gochache.New(10*time.Second, 10*time.Second)
 }
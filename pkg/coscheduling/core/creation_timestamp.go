@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	informerv1 "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+	pginformer "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+)
+
+// podGroupTimestamp is the creation timestamp of the podgroup a pod belongs to.
+type podGroupTimestamp struct {
+	pgFullName string
+	timestamp  time.Time
+}
+
+// creationTimestampCache caches, per pod, the creation timestamp of the podgroup it belongs to.
+// An entry is dropped when the pod is deleted or moved to another podgroup, or when its podgroup
+// is deleted, so that a recreated podgroup is looked up again.
+type creationTimestampCache struct {
+	sync.RWMutex
+	entries map[types.UID]podGroupTimestamp
+}
+
+func newCreationTimestampCache() *creationTimestampCache {
+	return &creationTimestampCache{entries: make(map[types.UID]podGroupTimestamp)}
+}
+
+func (c *creationTimestampCache) get(pod *corev1.Pod) (time.Time, bool) {
+	if c == nil || pod.UID == "" {
+		return time.Time{}, false
+	}
+	c.RLock()
+	defer c.RUnlock()
+	e, ok := c.entries[pod.UID]
+	return e.timestamp, ok
+}
+
+func (c *creationTimestampCache) set(pod *corev1.Pod, pg *v1alpha1.PodGroup) {
+	if c == nil || pod.UID == "" {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.entries[pod.UID] = podGroupTimestamp{pgFullName: GetNamespacedName(pg), timestamp: pg.CreationTimestamp.Time}
+}
+
+func (c *creationTimestampCache) deletePod(pod *corev1.Pod) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.entries, pod.UID)
+}
+
+func (c *creationTimestampCache) deletePodGroup(pg *v1alpha1.PodGroup) {
+	pgFullName := GetNamespacedName(pg)
+	c.Lock()
+	defer c.Unlock()
+	for uid, e := range c.entries {
+		if e.pgFullName == pgFullName {
+			delete(c.entries, uid)
+		}
+	}
+}
+
+func (c *creationTimestampCache) addEventHandlers(pgInformer pginformer.PodGroupInformer, podInformer informerv1.PodInformer) {
+	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod, ok := oldObj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			newPod, ok := newObj.(*corev1.Pod)
+			if !ok {
+				return
+			}
+			if util.GetPodGroupLabel(oldPod) != util.GetPodGroupLabel(newPod) {
+				c.deletePod(newPod)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if pod, ok := obj.(*corev1.Pod); ok {
+				c.deletePod(pod)
+			}
+		},
+	})
+	pgInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if pg, ok := obj.(*v1alpha1.PodGroup); ok {
+				c.deletePodGroup(pg)
+			}
+		},
+	})
+}
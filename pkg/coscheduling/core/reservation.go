@@ -0,0 +1,360 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+)
+
+// reservationGCPeriod is how often the reservations which outlived their PodGroup's schedule timeout are dropped.
+const reservationGCPeriod = 5 * time.Second
+
+// Reservation is the node capacity held for the members of a PodGroup that are not assigned yet.
+type Reservation struct {
+	// Members maps the UID of each member the reservation holds capacity for to its placement.
+	Members map[types.UID]*ReservedMember
+	// ExpiresAt is the time after which the reservation no longer holds capacity.
+	ExpiresAt time.Time
+}
+
+// ReservedMember is the capacity held for a single member of a PodGroup.
+type ReservedMember struct {
+	// Node is the name of the node holding the capacity of the member.
+	Node string
+	// Request is the resource request of the member.
+	Request *framework.Resource
+}
+
+func (r *Reservation) expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
+
+// nodes returns the number of members each node holds capacity for.
+func (r *Reservation) nodes() map[string]int {
+	nodes := make(map[string]int)
+	for _, m := range r.Members {
+		nodes[m.Node]++
+	}
+	return nodes
+}
+
+// memberOn returns the UID of a member placed on the given node, or of any member if there is none.
+func (r *Reservation) memberOn(nodeName string) types.UID {
+	var uid types.UID
+	for u, m := range r.Members {
+		if m.Node == nodeName {
+			return u
+		}
+		uid = u
+	}
+	return uid
+}
+
+// String returns the nodes of the reservation as "<node>(<members>)", sorted by node name.
+func (r *Reservation) String() string {
+	var nodes []string
+	for name, count := range r.nodes() {
+		nodes = append(nodes, fmt.Sprintf("%v(%d)", name, count))
+	}
+	sort.Strings(nodes)
+	return strings.Join(nodes, ", ")
+}
+
+// ReserveCapacity holds node capacity for the members of the pod's PodGroup which are not assigned yet, once the
+// pod is placed on <nodeName>, until the PodGroup's schedule timeout. Each member is sized by its own request and
+// only placed on the nodes accepted by <feasible>; capacity is only reserved if all of these members fit in the
+// cluster at once. A PodGroup which already holds a reservation keeps it.
+func (pgMgr *PodGroupManager) ReserveCapacity(ctx context.Context, pod *corev1.Pod, nodeName string, feasible func(*framework.NodeInfo) bool) {
+	pgFullName, pg := pgMgr.GetPodGroup(ctx, pod)
+	if pg == nil || pg.Spec.MinMember <= 1 {
+		return
+	}
+	if pgMgr.GetReservation(pgFullName) != nil {
+		return
+	}
+	nodes, err := pgMgr.snapshotSharedLister.NodeInfos().List()
+	if err != nil {
+		klog.ErrorS(err, "Cannot get nodeInfos from frameworkHandle")
+		return
+	}
+	// The pod itself is not in the snapshot of the current scheduling cycle.
+	assigned := sets.New[types.UID](pod.UID)
+	for _, nodeInfo := range nodes {
+		for _, podInfo := range nodeInfo.Pods {
+			if p := podInfo.Pod; util.GetPodGroupLabel(p) == pg.Name && p.Namespace == pg.Namespace && p.Spec.NodeName != "" {
+				assigned.Insert(p.UID)
+			}
+		}
+	}
+	remaining := int(pg.Spec.MinMember) - assigned.Len()
+	if remaining <= 0 {
+		return
+	}
+	members, err := pgMgr.pendingMembers(pg, assigned)
+	if err != nil {
+		klog.ErrorS(err, "Cannot list the members of PodGroup", "podGroup", klog.KObj(pg))
+		return
+	}
+	if len(members) < remaining {
+		klog.V(4).InfoS("Not enough pending members to reserve capacity for PodGroup", "podGroup", klog.KObj(pg), "pending", len(members))
+		return
+	}
+	// The filters are run before taking the lock, as the Filter of the plugin reads the reservations.
+	var candidates []*framework.NodeInfo
+	for _, nodeInfo := range nodes {
+		if nodeInfo.Node() != nil && feasible(nodeInfo) {
+			candidates = append(candidates, nodeInfo)
+		}
+	}
+
+	now := time.Now()
+	pgMgr.Lock()
+	if r, ok := pgMgr.reservations[pgFullName]; ok && !r.expired(now) {
+		pgMgr.Unlock()
+		return
+	}
+	free := make(map[string]*framework.Resource, len(candidates))
+	for _, nodeInfo := range candidates {
+		free[nodeInfo.Node().Name] = pgMgr.freeResourceLocked(nodeInfo, pgFullName, now)
+	}
+	if f, ok := free[nodeName]; ok {
+		subtract(f, framework.NewResource(util.GetPodEffectiveRequest(pod)))
+	}
+	reservation := &Reservation{
+		Members:   make(map[types.UID]*ReservedMember, remaining),
+		ExpiresAt: now.Add(util.GetWaitTimeDuration(pg, pgMgr.scheduleTimeout)),
+	}
+	for _, member := range members[:remaining] {
+		request := framework.NewResource(util.GetPodEffectiveRequest(member))
+		for _, nodeInfo := range candidates {
+			if name := nodeInfo.Node().Name; fits(request, free[name]) {
+				subtract(free[name], request)
+				reservation.Members[member.UID] = &ReservedMember{Node: name, Request: request}
+				break
+			}
+		}
+		if _, ok := reservation.Members[member.UID]; !ok {
+			pgMgr.Unlock()
+			klog.V(4).InfoS("Not enough capacity to reserve for PodGroup", "podGroup", klog.KObj(pg), "unplaced", klog.KObj(member))
+			return
+		}
+	}
+	pgMgr.reservations[pgFullName] = reservation
+	pgMgr.Unlock()
+
+	klog.V(3).InfoS("Reserved capacity for PodGroup", "podGroup", klog.KObj(pg), "nodes", reservation.String(), "expiresAt", reservation.ExpiresAt)
+	pgMgr.UpdatePodGroupCondition(ctx, pg, metav1.Condition{
+		Type:   v1alpha1.PodGroupCapacityReserved,
+		Status: metav1.ConditionTrue,
+		Reason: v1alpha1.PodGroupReasonReserved,
+		Message: fmt.Sprintf("Capacity of %d member(s) is reserved on nodes %v until %v",
+			len(reservation.Members), reservation.String(), reservation.ExpiresAt.UTC().Format(time.RFC3339)),
+	})
+}
+
+// pendingMembers returns the members of the PodGroup which are neither assigned nor being deleted, the ones
+// with the largest request first, so that they are placed while the nodes have the most room.
+func (pgMgr *PodGroupManager) pendingMembers(pg *v1alpha1.PodGroup, assigned sets.Set[types.UID]) ([]*corev1.Pod, error) {
+	pods, err := pgMgr.podLister.Pods(pg.Namespace).List(
+		labels.SelectorFromSet(labels.Set{v1alpha1.PodGroupLabel: pg.Name}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var members []*corev1.Pod
+	requests := make(map[types.UID]*framework.Resource)
+	for _, p := range pods {
+		if assigned.Has(p.UID) || p.Spec.NodeName != "" || p.DeletionTimestamp != nil {
+			continue
+		}
+		members = append(members, p)
+		requests[p.UID] = framework.NewResource(util.GetPodEffectiveRequest(p))
+	}
+	sort.Slice(members, func(i, j int) bool {
+		ri, rj := requests[members[i].UID], requests[members[j].UID]
+		if ri.MilliCPU != rj.MilliCPU {
+			return ri.MilliCPU > rj.MilliCPU
+		}
+		if ri.Memory != rj.Memory {
+			return ri.Memory > rj.Memory
+		}
+		return members[i].Name < members[j].Name
+	})
+	return members, nil
+}
+
+// CheckReservedCapacity returns an error if the pod doesn't fit on the node once the capacity reserved
+// for other PodGroups is taken out of it.
+func (pgMgr *PodGroupManager) CheckReservedCapacity(pod *corev1.Pod, nodeInfo *framework.NodeInfo) error {
+	pgMgr.RLock()
+	defer pgMgr.RUnlock()
+	if len(pgMgr.reservations) == 0 {
+		return nil
+	}
+	free := pgMgr.freeResourceLocked(nodeInfo, util.GetPodGroupFullName(pod), time.Now())
+	if !fits(framework.NewResource(util.GetPodEffectiveRequest(pod)), free) {
+		return fmt.Errorf("node capacity is reserved for a PodGroup")
+	}
+	return nil
+}
+
+// ConsumeReservation takes the capacity of a member assigned to the given node out of its PodGroup's reservation.
+// A member the reservation doesn't hold capacity for takes the capacity of another one, placed on the same node
+// if possible.
+func (pgMgr *PodGroupManager) ConsumeReservation(pod *corev1.Pod, nodeName string) {
+	pgFullName := util.GetPodGroupFullName(pod)
+	pgMgr.Lock()
+	defer pgMgr.Unlock()
+	r, ok := pgMgr.reservations[pgFullName]
+	if !ok {
+		return
+	}
+	uid := pod.UID
+	if _, ok := r.Members[uid]; !ok {
+		uid = r.memberOn(nodeName)
+	}
+	delete(r.Members, uid)
+	if len(r.Members) == 0 {
+		delete(pgMgr.reservations, pgFullName)
+	}
+}
+
+// ReleaseReservation drops the reservation of the given PodGroup, if any, and reports it with the given reason.
+func (pgMgr *PodGroupManager) ReleaseReservation(ctx context.Context, pg *v1alpha1.PodGroup, reason, message string) {
+	pgFullName := GetNamespacedName(pg)
+	pgMgr.Lock()
+	_, ok := pgMgr.reservations[pgFullName]
+	delete(pgMgr.reservations, pgFullName)
+	pgMgr.Unlock()
+	if !ok {
+		return
+	}
+
+	klog.V(3).InfoS("Released capacity reserved for PodGroup", "podGroup", klog.KObj(pg), "reason", reason)
+	pgMgr.UpdatePodGroupCondition(ctx, pg, metav1.Condition{
+		Type:    v1alpha1.PodGroupCapacityReserved,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+// releaseExpiredReservations drops the reservations which outlived the schedule timeout of their PodGroup,
+// and reports it in the status of the PodGroup.
+func (pgMgr *PodGroupManager) releaseExpiredReservations(ctx context.Context) {
+	now := time.Now()
+	var expired []string
+	pgMgr.Lock()
+	for pgFullName, r := range pgMgr.reservations {
+		if r.expired(now) {
+			delete(pgMgr.reservations, pgFullName)
+			expired = append(expired, pgFullName)
+		}
+	}
+	pgMgr.Unlock()
+
+	for _, pgFullName := range expired {
+		namespace, name, err := cache.SplitMetaNamespaceKey(pgFullName)
+		if err != nil {
+			continue
+		}
+		pg, err := pgMgr.pgLister.PodGroups(namespace).Get(name)
+		if err != nil {
+			continue
+		}
+		klog.V(3).InfoS("Dropped expired capacity reservation of PodGroup", "podGroup", klog.KObj(pg))
+		pgMgr.UpdatePodGroupCondition(ctx, pg, metav1.Condition{
+			Type:    v1alpha1.PodGroupCapacityReserved,
+			Status:  metav1.ConditionFalse,
+			Reason:  v1alpha1.PodGroupReasonExpired,
+			Message: "Capacity reservation expired before the PodGroup reached its minMember",
+		})
+	}
+}
+
+// GetReservation returns the reservation of the given PodGroup, or nil if it doesn't hold one.
+func (pgMgr *PodGroupManager) GetReservation(pgFullName string) *Reservation {
+	pgMgr.RLock()
+	defer pgMgr.RUnlock()
+	if r, ok := pgMgr.reservations[pgFullName]; ok && !r.expired(time.Now()) {
+		return r
+	}
+	return nil
+}
+
+// freeResourceLocked returns the resource left on the node once the capacity reserved for PodGroups
+// other than <pgFullName> is taken out of it. The caller must hold the lock of pgMgr.
+func (pgMgr *PodGroupManager) freeResourceLocked(nodeInfo *framework.NodeInfo, pgFullName string, now time.Time) *framework.Resource {
+	free := nodeInfo.Allocatable.Clone()
+	subtract(free, nodeInfo.Requested)
+	free.AllowedPodNumber -= len(nodeInfo.Pods)
+	if nodeInfo.Node() == nil {
+		return free
+	}
+	for name, r := range pgMgr.reservations {
+		if name == pgFullName || r.expired(now) {
+			continue
+		}
+		for _, m := range r.Members {
+			if m.Node == nodeInfo.Node().Name {
+				subtract(free, m.Request)
+			}
+		}
+	}
+	return free
+}
+
+// fits returns true if <request> fits in <free>, counting one pod.
+func fits(request, free *framework.Resource) bool {
+	if free.AllowedPodNumber < 1 || request.MilliCPU > free.MilliCPU || request.Memory > free.Memory ||
+		request.EphemeralStorage > free.EphemeralStorage {
+		return false
+	}
+	for name, quantity := range request.ScalarResources {
+		if quantity > free.ScalarResources[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// subtract takes <request>, counting one pod, out of <free>.
+func subtract(free, request *framework.Resource) {
+	free.AllowedPodNumber--
+	free.MilliCPU -= request.MilliCPU
+	free.Memory -= request.Memory
+	free.EphemeralStorage -= request.EphemeralStorage
+	for name, quantity := range request.ScalarResources {
+		free.SetScalar(name, free.ScalarResources[name]-quantity)
+	}
+}
@@ -0,0 +1,286 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	clicache "k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+	tu "sigs.k8s.io/scheduler-plugins/test/util"
+)
+
+func TestReserveCapacity(t *testing.T) {
+	scheduleTimeout := 10 * time.Second
+	capacity := map[corev1.ResourceName]string{corev1.ResourceCPU: "2"}
+	request := map[corev1.ResourceName]string{corev1.ResourceCPU: "1"}
+	nodes := []*corev1.Node{
+		st.MakeNode().Name("node-a").Capacity(capacity).Obj(),
+		st.MakeNode().Name("node-b").Capacity(capacity).Obj(),
+	}
+	makeMember := func(name string, request map[corev1.ResourceName]string) *corev1.Pod {
+		return st.MakePod().Name(name).Namespace("ns").UID(name).Label(v1alpha1.PodGroupLabel, "pg1").Req(request).Obj()
+	}
+	// member is placed on node-a in the scheduling cycle reserving the capacity.
+	member := makeMember("p1", request)
+	outsider := st.MakePod().Name("p").Namespace("ns").UID("p").Req(request).Obj()
+	tests := []struct {
+		name         string
+		minMember    int32
+		pendingPods  []*corev1.Pod
+		existingPods []*corev1.Pod
+		// infeasible are the nodes which don't pass the filters.
+		infeasible []string
+		// wantNodes is the expected reservation, nil if none.
+		wantNodes map[string]int
+		// wantOutsiderFits is whether a pod outside of the pod group fits on each node once member is assigned.
+		wantOutsiderFits map[string]bool
+	}{
+		{
+			name:             "reserve the capacity of the other members",
+			minMember:        4,
+			pendingPods:      []*corev1.Pod{makeMember("p2", request), makeMember("p3", request), makeMember("p4", request)},
+			wantNodes:        map[string]int{"node-a": 1, "node-b": 2},
+			wantOutsiderFits: map[string]bool{"node-a": false, "node-b": false},
+		},
+		{
+			name:             "assigned members are not reserved",
+			minMember:        4,
+			pendingPods:      []*corev1.Pod{makeMember("p2", request), makeMember("p3", request)},
+			existingPods:     []*corev1.Pod{st.MakePod().Name("p0").Namespace("ns").UID("p0").Label(v1alpha1.PodGroupLabel, "pg1").Req(request).Node("node-a").Obj()},
+			wantNodes:        map[string]int{"node-b": 2},
+			wantOutsiderFits: map[string]bool{"node-a": false, "node-b": false},
+		},
+		{
+			name:             "members are sized by their own request",
+			minMember:        3,
+			pendingPods:      []*corev1.Pod{makeMember("p2", request), makeMember("p3", map[corev1.ResourceName]string{corev1.ResourceCPU: "2"})},
+			wantNodes:        map[string]int{"node-a": 1, "node-b": 1},
+			wantOutsiderFits: map[string]bool{"node-a": false, "node-b": false},
+		},
+		{
+			name:             "only the nodes passing the filters hold capacity",
+			minMember:        3,
+			pendingPods:      []*corev1.Pod{makeMember("p2", request), makeMember("p3", request)},
+			infeasible:       []string{"node-b"},
+			wantOutsiderFits: map[string]bool{"node-a": true, "node-b": true},
+		},
+		{
+			name:             "no reservation if the members don't fit at once",
+			minMember:        5,
+			pendingPods:      []*corev1.Pod{makeMember("p2", request), makeMember("p3", request), makeMember("p4", request), makeMember("p5", request)},
+			wantOutsiderFits: map[string]bool{"node-a": true, "node-b": true},
+		},
+		{
+			name:             "no reservation for a single member",
+			minMember:        1,
+			wantOutsiderFits: map[string]bool{"node-a": true, "node-b": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			pg := tu.MakePodGroup().Name("pg1").Namespace("ns").MinMember(tt.minMember).Obj()
+			client, err := tu.NewFakeClient(pg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u := newTestStatusUpdater(t, client, nil)
+			podIndexer := clicache.NewIndexer(clicache.MetaNamespaceKeyFunc, clicache.Indexers{clicache.NamespaceIndex: clicache.MetaNamespaceIndexFunc})
+			for _, p := range append(append([]*corev1.Pod{member}, tt.pendingPods...), tt.existingPods...) {
+				if err := podIndexer.Add(p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			pgMgr := &PodGroupManager{
+				client:               client,
+				pgLister:             u.pgLister,
+				podLister:            listerv1.NewPodLister(podIndexer),
+				snapshotSharedLister: tu.NewFakeSharedLister(tt.existingPods, nodes),
+				scheduleTimeout:      &scheduleTimeout,
+				statusUpdater:        u.statusUpdater,
+				reservations:         make(map[string]*Reservation),
+			}
+
+			infeasible := sets.New(tt.infeasible...)
+			pgMgr.ReserveCapacity(ctx, member, "node-a", func(nodeInfo *framework.NodeInfo) bool {
+				return !infeasible.Has(nodeInfo.Node().Name)
+			})
+			r := pgMgr.GetReservation("ns/pg1")
+			if tt.wantNodes == nil {
+				if r != nil {
+					t.Fatalf("Expected no reservation, got %v", r)
+				}
+			} else {
+				if r == nil {
+					t.Fatalf("Expected reservation on %v, got nil", tt.wantNodes)
+				}
+				if got := r.nodes(); !reflect.DeepEqual(got, tt.wantNodes) {
+					t.Errorf("Expected reservation on %v, got %v", tt.wantNodes, got)
+				}
+				u.flush()
+				latest := u.get("ns", "pg1")
+				if !meta.IsStatusConditionTrue(latest.Status.Conditions, v1alpha1.PodGroupCapacityReserved) {
+					t.Errorf("Expected condition %v to be true, got %v", v1alpha1.PodGroupCapacityReserved, latest.Status.Conditions)
+				}
+			}
+
+			// The next scheduling cycles see the member on node-a.
+			assigned := member.DeepCopy()
+			assigned.Spec.NodeName = "node-a"
+			snapshot := tu.NewFakeSharedLister(append(tt.existingPods, assigned), nodes)
+			for name, want := range tt.wantOutsiderFits {
+				nodeInfo, err := snapshot.NodeInfos().Get(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := pgMgr.CheckReservedCapacity(outsider, nodeInfo) == nil; got != want {
+					t.Errorf("Expected outsider to fit on %v: %v, got %v", name, want, got)
+				}
+			}
+			// The capacity reserved for its own pod group is visible to a member.
+			for _, p := range tt.pendingPods {
+				if r == nil {
+					break
+				}
+				if m, ok := r.Members[p.UID]; ok {
+					nodeInfo, err := snapshot.NodeInfos().Get(m.Node)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if err := pgMgr.CheckReservedCapacity(p, nodeInfo); err != nil {
+						t.Errorf("Expected member %v to fit on %v, got %v", p.Name, m.Node, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestConsumeAndReleaseReservation(t *testing.T) {
+	ctx := context.Background()
+	pg := tu.MakePodGroup().Name("pg1").Namespace("ns").MinMember(4).Obj()
+	client, err := tu.NewFakeClient(pg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := newTestStatusUpdater(t, client, nil)
+	request := &framework.Resource{MilliCPU: 1000}
+	pgMgr := &PodGroupManager{
+		client:        client,
+		statusUpdater: u.statusUpdater,
+		reservations: map[string]*Reservation{
+			"ns/pg1": {
+				Members: map[types.UID]*ReservedMember{
+					"p1": {Node: "node-a", Request: request},
+					"p2": {Node: "node-a", Request: request},
+					"p3": {Node: "node-b", Request: request},
+				},
+				ExpiresAt: time.Now().Add(time.Minute),
+			},
+		},
+	}
+	makeMember := func(name string) *corev1.Pod {
+		return st.MakePod().Name(name).Namespace("ns").UID(name).Label(v1alpha1.PodGroupLabel, "pg1").Obj()
+	}
+
+	// A member takes its own share, wherever it's placed.
+	pgMgr.ConsumeReservation(makeMember("p3"), "node-c")
+	if got, want := pgMgr.GetReservation("ns/pg1").nodes(), map[string]int{"node-a": 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected reservation on %v, got %v", want, got)
+	}
+	// A member the reservation doesn't hold capacity for takes the share of another one on the same node.
+	pgMgr.ConsumeReservation(makeMember("p4"), "node-a")
+	if got, want := pgMgr.GetReservation("ns/pg1").nodes(), map[string]int{"node-a": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected reservation on %v, got %v", want, got)
+	}
+
+	pgMgr.ReleaseReservation(ctx, u.get("ns", "pg1"), v1alpha1.PodGroupReasonQuorumReached, "")
+	if r := pgMgr.GetReservation("ns/pg1"); r != nil {
+		t.Errorf("Expected the reservation to be released, got %v", r)
+	}
+	u.flush()
+	latest := u.get("ns", "pg1")
+	if c := meta.FindStatusCondition(latest.Status.Conditions, v1alpha1.PodGroupCapacityReserved); c == nil || c.Reason != v1alpha1.PodGroupReasonQuorumReached {
+		t.Errorf("Expected condition %v with reason %v, got %v", v1alpha1.PodGroupCapacityReserved, v1alpha1.PodGroupReasonQuorumReached, c)
+	}
+
+	// An expired reservation doesn't hold capacity.
+	pgMgr.reservations["ns/pg1"] = &Reservation{
+		Members:   map[types.UID]*ReservedMember{"p1": {Node: "node-a", Request: request}},
+		ExpiresAt: time.Now().Add(-time.Second),
+	}
+	if r := pgMgr.GetReservation("ns/pg1"); r != nil {
+		t.Errorf("Expected the reservation to be expired, got %v", r)
+	}
+}
+
+func TestReleaseExpiredReservations(t *testing.T) {
+	ctx := context.Background()
+	expired := tu.MakePodGroup().Name("pg1").Namespace("ns").MinMember(2).Obj()
+	active := tu.MakePodGroup().Name("pg2").Namespace("ns").MinMember(2).Obj()
+	client, err := tu.NewFakeClient(expired, active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := newTestStatusUpdater(t, client, nil)
+	request := &framework.Resource{MilliCPU: 1000}
+	pgMgr := &PodGroupManager{
+		client:        client,
+		pgLister:      u.pgLister,
+		statusUpdater: u.statusUpdater,
+		reservations: map[string]*Reservation{
+			"ns/pg1": {
+				Members:   map[types.UID]*ReservedMember{"p1": {Node: "node-a", Request: request}},
+				ExpiresAt: time.Now().Add(-time.Second),
+			},
+			"ns/pg2": {
+				Members:   map[types.UID]*ReservedMember{"p2": {Node: "node-a", Request: request}},
+				ExpiresAt: time.Now().Add(time.Minute),
+			},
+		},
+	}
+
+	pgMgr.releaseExpiredReservations(ctx)
+	if _, ok := pgMgr.reservations["ns/pg1"]; ok {
+		t.Errorf("Expected the expired reservation to be dropped")
+	}
+	if _, ok := pgMgr.reservations["ns/pg2"]; !ok {
+		t.Errorf("Expected the active reservation to be kept")
+	}
+	u.flush()
+	if c := meta.FindStatusCondition(u.get("ns", "pg1").Status.Conditions, v1alpha1.PodGroupCapacityReserved); c == nil ||
+		c.Status != metav1.ConditionFalse || c.Reason != v1alpha1.PodGroupReasonExpired {
+		t.Errorf("Expected condition %v to be false with reason %v, got %v", v1alpha1.PodGroupCapacityReserved, v1alpha1.PodGroupReasonExpired, c)
+	}
+	if c := meta.FindStatusCondition(u.get("ns", "pg2").Status.Conditions, v1alpha1.PodGroupCapacityReserved); c != nil {
+		t.Errorf("Expected no condition %v, got %v", v1alpha1.PodGroupCapacityReserved, c)
+	}
+}
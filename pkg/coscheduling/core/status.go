@@ -0,0 +1,222 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	gochache "github.com/patrickmn/go-cache"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+	pglister "sigs.k8s.io/scheduler-plugins/pkg/generated/listers/scheduling/v1alpha1"
+)
+
+// conditionUpdateInterval is the minimum interval between two updates of a PodGroup condition
+// that carry the same status and reason. Updates that change the status or the reason are
+// written right away.
+const conditionUpdateInterval = 10 * time.Second
+
+// statusUpdater patches the scheduler-owned conditions into the status of PodGroups, and
+// records an Event on the PodGroup for every condition it writes.
+// As the conditions are computed in PreFilter, PostFilter and Permit for each member of a
+// PodGroup, updates are rate limited per PodGroup and condition type, and written by a
+// worker off the scheduling cycle: the conditions are queued by PodGroup, and the latest
+// one of each type is patched at once.
+type statusUpdater struct {
+	client   client.Client
+	pgLister pglister.PodGroupLister
+	recorder events.EventRecorder
+	// lastUpdated stores the last condition queued, keyed by <namespace>/<name>/<type>.
+	lastUpdated *gochache.Cache
+	// queue holds the <namespace>/<name> keys of the PodGroups with pending conditions.
+	queue workqueue.RateLimitingInterface
+	// pending stores the conditions waiting to be written, by PodGroup key and condition type.
+	pending map[string]map[string]metav1.Condition
+	sync.Mutex
+}
+
+func newStatusUpdater(client client.Client, pgLister pglister.PodGroupLister, recorder events.EventRecorder) *statusUpdater {
+	return &statusUpdater{
+		client:      client,
+		pgLister:    pgLister,
+		recorder:    recorder,
+		lastUpdated: gochache.New(conditionUpdateInterval, conditionUpdateInterval),
+		queue:       workqueue.NewRateLimitingQueueWithConfig(workqueue.DefaultControllerRateLimiter(), workqueue.RateLimitingQueueConfig{Name: "podgroup-status"}),
+		pending:     make(map[string]map[string]metav1.Condition),
+	}
+}
+
+// run writes the queued conditions until the context is done.
+func (u *statusUpdater) run(ctx context.Context) {
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		for u.processNextItem(ctx) {
+		}
+	}, time.Second)
+	go func() {
+		<-ctx.Done()
+		u.queue.ShutDown()
+	}()
+}
+
+// update queues the given condition for the status of <pg>. The update is skipped if <pg>, as
+// found in the informer cache, already carries the condition. Unless <force> is set, it's also
+// skipped if the same status and reason have been queued within conditionUpdateInterval.
+func (u *statusUpdater) update(pg *v1alpha1.PodGroup, condition metav1.Condition, force bool) {
+	if u == nil || pg == nil {
+		return
+	}
+	if c := meta.FindStatusCondition(pg.Status.Conditions, condition.Type); c != nil &&
+		c.Status == condition.Status && c.Reason == condition.Reason && c.Message == condition.Message {
+		return
+	}
+	pgKey := GetNamespacedName(pg)
+	key := fmt.Sprintf("%v/%v", pgKey, condition.Type)
+	if !force {
+		if v, ok := u.lastUpdated.Get(key); ok {
+			if last := v.(metav1.Condition); last.Status == condition.Status && last.Reason == condition.Reason {
+				return
+			}
+		}
+	}
+	u.lastUpdated.Set(key, condition, gochache.DefaultExpiration)
+
+	u.Lock()
+	if u.pending[pgKey] == nil {
+		u.pending[pgKey] = make(map[string]metav1.Condition)
+	}
+	u.pending[pgKey][condition.Type] = condition
+	u.Unlock()
+	u.queue.Add(pgKey)
+}
+
+// processNextItem writes the pending conditions of the next PodGroup in the queue. It returns
+// false once the queue is shut down.
+func (u *statusUpdater) processNextItem(ctx context.Context) bool {
+	key, quit := u.queue.Get()
+	if quit {
+		return false
+	}
+	defer u.queue.Done(key)
+
+	if err := u.sync(ctx, key.(string)); err != nil {
+		klog.ErrorS(err, "Failed to update PodGroup conditions", "podGroup", key)
+		u.queue.AddRateLimited(key)
+		return true
+	}
+	u.queue.Forget(key)
+	return true
+}
+
+// sync patches the pending conditions of the given PodGroup into its status.
+func (u *statusUpdater) sync(ctx context.Context, key string) error {
+	u.Lock()
+	conditions := u.pending[key]
+	delete(u.pending, key)
+	u.Unlock()
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil
+	}
+	pg, err := u.pgLister.PodGroups(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		u.requeue(key, conditions)
+		return err
+	}
+	types := make([]string, 0, len(conditions))
+	for t := range conditions {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	pgCopy := pg.DeepCopy()
+	var written []metav1.Condition
+	for _, t := range types {
+		condition := conditions[t]
+		condition.ObservedGeneration = pg.Generation
+		if meta.SetStatusCondition(&pgCopy.Status.Conditions, condition) {
+			written = append(written, condition)
+		}
+	}
+	if len(written) == 0 {
+		return nil
+	}
+
+	// Conditions is a list, which a merge patch replaces as a whole. Guard the patch with the
+	// resourceVersion of the cached PodGroup, so that it's retried on a fresher copy rather than
+	// overwriting the conditions set by others in the meantime.
+	patch := client.MergeFromWithOptions(pg, client.MergeFromWithOptimisticLock{})
+	if err := u.client.Status().Patch(ctx, pgCopy, patch); err != nil {
+		u.requeue(key, conditions)
+		return err
+	}
+
+	if u.recorder != nil {
+		for _, condition := range written {
+			eventType := corev1.EventTypeNormal
+			if isFailureCondition(condition) {
+				eventType = corev1.EventTypeWarning
+			}
+			u.recorder.Eventf(pg, nil, eventType, condition.Reason, "Scheduling", "%s", condition.Message)
+		}
+	}
+	return nil
+}
+
+// requeue puts back the conditions that failed to be written, unless newer ones of the same
+// type have been queued since.
+func (u *statusUpdater) requeue(key string, conditions map[string]metav1.Condition) {
+	u.Lock()
+	defer u.Unlock()
+	if u.pending[key] == nil {
+		u.pending[key] = make(map[string]metav1.Condition)
+	}
+	for t, condition := range conditions {
+		if _, ok := u.pending[key][t]; !ok {
+			u.pending[key][t] = condition
+		}
+	}
+}
+
+// isFailureCondition returns true if the given condition reports a scheduling failure of a PodGroup.
+func isFailureCondition(condition metav1.Condition) bool {
+	switch condition.Type {
+	case v1alpha1.PodGroupScheduled:
+		return condition.Status == metav1.ConditionFalse
+	case v1alpha1.PodGroupBackedOff:
+		return condition.Status == metav1.ConditionTrue
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clicache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+	pglister "sigs.k8s.io/scheduler-plugins/pkg/generated/listers/scheduling/v1alpha1"
+	tu "sigs.k8s.io/scheduler-plugins/test/util"
+)
+
+// testStatusUpdater is a status updater reading the PodGroups from a lister that tests sync
+// with the client, in place of an informer.
+type testStatusUpdater struct {
+	*statusUpdater
+	t       *testing.T
+	client  client.Client
+	indexer clicache.Indexer
+}
+
+func newTestStatusUpdater(t *testing.T, c client.Client, recorder events.EventRecorder) *testStatusUpdater {
+	indexer := clicache.NewIndexer(clicache.MetaNamespaceKeyFunc, clicache.Indexers{clicache.NamespaceIndex: clicache.MetaNamespaceIndexFunc})
+	u := &testStatusUpdater{
+		statusUpdater: newStatusUpdater(c, pglister.NewPodGroupLister(indexer), recorder),
+		t:             t,
+		client:        c,
+		indexer:       indexer,
+	}
+	u.syncLister()
+	return u
+}
+
+// syncLister copies the PodGroups of the client into the lister.
+func (u *testStatusUpdater) syncLister() {
+	pgs := &v1alpha1.PodGroupList{}
+	if err := u.client.List(context.Background(), pgs); err != nil {
+		u.t.Fatal(err)
+	}
+	for i := range pgs.Items {
+		if err := u.indexer.Update(&pgs.Items[i]); err != nil {
+			u.t.Fatal(err)
+		}
+	}
+}
+
+// flush writes the queued conditions like the worker does, and syncs the lister with the result.
+func (u *testStatusUpdater) flush() {
+	for u.queue.Len() > 0 {
+		u.processNextItem(context.Background())
+	}
+	u.syncLister()
+}
+
+// get returns the PodGroup as cached by the lister.
+func (u *testStatusUpdater) get(namespace, name string) *v1alpha1.PodGroup {
+	pg, err := u.pgLister.PodGroups(namespace).Get(name)
+	if err != nil {
+		u.t.Fatal(err)
+	}
+	return pg
+}
+
+func TestStatusUpdater(t *testing.T) {
+	ctx := context.Background()
+	pg := tu.MakePodGroup().Name("pg1").Namespace("ns").MinMember(3).Obj()
+	c, err := tu.NewFakeClient(pg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := events.NewFakeRecorder(10)
+	u := newTestStatusUpdater(t, c, recorder)
+
+	scheduled := metav1.Condition{Type: v1alpha1.PodGroupScheduled, Status: metav1.ConditionFalse, Reason: v1alpha1.PodGroupReasonNotEnoughPods, Message: "1 of 3 %v"}
+	waiting := metav1.Condition{Type: v1alpha1.PodGroupWaitingAtPermit, Status: metav1.ConditionTrue, Reason: v1alpha1.PodGroupReasonWaiting, Message: "1 of 3"}
+	u.update(u.get("ns", "pg1"), scheduled, false)
+	u.update(u.get("ns", "pg1"), waiting, false)
+
+	// Nothing is written within the scheduling cycle.
+	latest := &v1alpha1.PodGroup{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "ns", Name: "pg1"}, latest); err != nil {
+		t.Fatal(err)
+	}
+	if len(latest.Status.Conditions) != 0 {
+		t.Errorf("Expected no condition before the updates are flushed, got %v", latest.Status.Conditions)
+	}
+
+	// The conditions queued for a PodGroup are written by a single patch.
+	u.flush()
+	got := u.get("ns", "pg1")
+	for _, want := range []metav1.Condition{scheduled, waiting} {
+		if c := meta.FindStatusCondition(got.Status.Conditions, want.Type); c == nil || c.Reason != want.Reason || c.Message != want.Message {
+			t.Errorf("Expected condition %+v, got %v", want, got.Status.Conditions)
+		}
+	}
+	if len(recorder.Events) != 2 {
+		t.Fatalf("Expected 2 events, got %v", len(recorder.Events))
+	}
+	// The message isn't interpreted as a format.
+	if e := <-recorder.Events; e != "Warning NotEnoughPods 1 of 3 %v" {
+		t.Errorf("Expected the message of the condition in the event, got %q", e)
+	}
+	<-recorder.Events
+
+	// A condition the cached PodGroup already carries isn't queued, even when forced.
+	u.update(got, scheduled, true)
+	if u.queue.Len() != 0 {
+		t.Errorf("Expected the unchanged condition to be skipped, got %v PodGroups queued", u.queue.Len())
+	}
+
+	// A condition failing to be written on a stale copy is retried on a fresh one.
+	other := got.DeepCopy()
+	other.Status.Phase = v1alpha1.PodGroupScheduling
+	if err := c.Status().Update(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	scheduled.Reason, scheduled.Message = v1alpha1.PodGroupReasonUnschedulable, "unschedulable"
+	u.update(got, scheduled, false)
+	u.processNextItem(ctx)
+	if _, ok := u.pending["ns/pg1"][v1alpha1.PodGroupScheduled]; !ok {
+		t.Fatalf("Expected the condition to be pending after a conflict")
+	}
+	u.syncLister()
+	if err := u.sync(ctx, "ns/pg1"); err != nil {
+		t.Fatal(err)
+	}
+	u.syncLister()
+	got = u.get("ns", "pg1")
+	if c := meta.FindStatusCondition(got.Status.Conditions, v1alpha1.PodGroupScheduled); c == nil || c.Reason != v1alpha1.PodGroupReasonUnschedulable {
+		t.Errorf("Expected the condition to be retried, got %v", got.Status.Conditions)
+	}
+	if got.Status.Phase != v1alpha1.PodGroupScheduling {
+		t.Errorf("Expected the concurrent update to be kept, got phase %v", got.Status.Phase)
+	}
+}
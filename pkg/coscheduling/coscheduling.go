@@ -19,11 +19,14 @@ package coscheduling
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	clientscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/cache"
 	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
@@ -44,10 +47,13 @@ type Coscheduling struct {
 	pgMgr            core.Manager
 	scheduleTimeout  *time.Duration
 	pgBackoff        *time.Duration
+	pgMaxBackoff     *time.Duration
+	reserveCapacity  bool
 }
 
 var _ framework.QueueSortPlugin = &Coscheduling{}
 var _ framework.PreFilterPlugin = &Coscheduling{}
+var _ framework.FilterPlugin = &Coscheduling{}
 var _ framework.PostFilterPlugin = &Coscheduling{}
 var _ framework.PermitPlugin = &Coscheduling{}
 var _ framework.ReservePlugin = &Coscheduling{}
@@ -57,10 +63,38 @@ var _ framework.EnqueueExtensions = &Coscheduling{}
 const (
 	// Name is the name of the plugin used in Registry and configurations.
 	Name = "Coscheduling"
+
+	feasibleNodesStateKey = Name + "FeasibleNodes"
 )
 
+// feasibleNodesState records the nodes which passed Filter for the pod in the scheduling cycle, where the capacity
+// for the rest of its PodGroup is reserved at Reserve. Filter runs in parallel for the nodes.
+type feasibleNodesState struct {
+	sync.Mutex
+	nodes sets.Set[string]
+}
+
+// Clone returns a copy of the state, so that the nodes passing Filter in a preemption dry run aren't recorded.
+func (s *feasibleNodesState) Clone() framework.StateData {
+	s.Lock()
+	defer s.Unlock()
+	return &feasibleNodesState{nodes: s.nodes.Clone()}
+}
+
+func (s *feasibleNodesState) add(nodeName string) {
+	s.Lock()
+	defer s.Unlock()
+	s.nodes.Insert(nodeName)
+}
+
+func (s *feasibleNodesState) has(nodeName string) bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.nodes.Has(nodeName)
+}
+
 // New initializes and returns a new Coscheduling plugin.
-func New(_ context.Context, obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+func New(ctx context.Context, obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
 	args, ok := obj.(*config.CoschedulingArgs)
 	if !ok {
 		return nil, fmt.Errorf("want args to be of type CoschedulingArgs, got %T", obj)
@@ -78,18 +112,29 @@ func New(_ context.Context, obj runtime.Object, handle framework.Handle) (framew
 	// Performance improvement when retrieving list of objects by namespace or we'll log 'index not exist' warning.
 	handle.SharedInformerFactory().Core().V1().Pods().Informer().AddIndexers(cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
 
+	// The PodGroup informer is shared with the other plugins, e.g. CapacityScheduling.
+	pgInformerFactory, err := util.SchedulingInformerFactory(handle.KubeConfig())
+	if err != nil {
+		return nil, err
+	}
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+
 	scheduleTimeDuration := time.Duration(args.PermitWaitingTimeSeconds) * time.Second
 	pgMgr := core.NewPodGroupManager(
+		ctx,
 		client,
 		handle.SnapshotSharedLister(),
 		&scheduleTimeDuration,
+		pgInformer,
 		// Keep the podInformer (from frameworkHandle) as the single source of Pods.
 		handle.SharedInformerFactory().Core().V1().Pods(),
+		handle.EventRecorder(),
 	)
 	plugin := &Coscheduling{
 		frameworkHandler: handle,
 		pgMgr:            pgMgr,
 		scheduleTimeout:  &scheduleTimeDuration,
+		reserveCapacity:  args.EnableCapacityReservation,
 	}
 	if args.PodGroupBackoffSeconds < 0 {
 		err := fmt.Errorf("parse arguments failed")
@@ -99,6 +144,19 @@ func New(_ context.Context, obj runtime.Object, handle framework.Handle) (framew
 		pgBackoff := time.Duration(args.PodGroupBackoffSeconds) * time.Second
 		plugin.pgBackoff = &pgBackoff
 	}
+	if args.PodGroupMaxBackoffSeconds < 0 {
+		err := fmt.Errorf("parse arguments failed")
+		klog.ErrorS(err, "PodGroupMaxBackoffSeconds cannot be negative")
+		return nil, err
+	} else if args.PodGroupMaxBackoffSeconds > 0 {
+		pgMaxBackoff := time.Duration(args.PodGroupMaxBackoffSeconds) * time.Second
+		plugin.pgMaxBackoff = &pgMaxBackoff
+	}
+
+	pgInformerFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), pgInformer.Informer().HasSynced) {
+		return nil, fmt.Errorf("failed to sync the PodGroup informer")
+	}
 	return plugin, nil
 }
 
@@ -107,8 +165,9 @@ func (cs *Coscheduling) EventsToRegister() []framework.ClusterEventWithHint {
 	// https://git.k8s.io/kubernetes/pkg/scheduler/eventhandlers.go#L403-L410
 	pgGVK := fmt.Sprintf("podgroups.v1alpha1.%v", scheduling.GroupName)
 	return []framework.ClusterEventWithHint{
-		{Event: framework.ClusterEvent{Resource: framework.Pod, ActionType: framework.Add}},
-		{Event: framework.ClusterEvent{Resource: framework.GVK(pgGVK), ActionType: framework.Add | framework.Update}},
+		{Event: framework.ClusterEvent{Resource: framework.Pod, ActionType: framework.Add}, QueueingHintFn: cs.isSchedulableAfterPodAdded},
+		{Event: framework.ClusterEvent{Resource: framework.Pod, ActionType: framework.Delete}, QueueingHintFn: cs.isSchedulableAfterPodDeleted},
+		{Event: framework.ClusterEvent{Resource: framework.GVK(pgGVK), ActionType: framework.Add | framework.Update}, QueueingHintFn: cs.isSchedulableAfterPodGroupChanged},
 	}
 }
 
@@ -145,9 +204,29 @@ func (cs *Coscheduling) PreFilter(ctx context.Context, state *framework.CycleSta
 		klog.ErrorS(err, "PreFilter failed", "pod", klog.KObj(pod))
 		return nil, framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
 	}
+	if cs.reserveCapacity {
+		state.Write(feasibleNodesStateKey, &feasibleNodesState{nodes: sets.New[string]()})
+	}
 	return nil, framework.NewStatus(framework.Success, "")
 }
 
+// Filter rejects the node if the pod doesn't fit once the capacity reserved for other PodGroups
+// is taken out of it, and records the nodes it accepts. It's a no-op unless capacity reservation is enabled.
+func (cs *Coscheduling) Filter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	if !cs.reserveCapacity {
+		return nil
+	}
+	if err := cs.pgMgr.CheckReservedCapacity(pod, nodeInfo); err != nil {
+		return framework.NewStatus(framework.Unschedulable, err.Error())
+	}
+	if c, err := state.Read(feasibleNodesStateKey); err == nil {
+		if s, ok := c.(*feasibleNodesState); ok {
+			s.add(nodeInfo.Node().Name)
+		}
+	}
+	return nil
+}
+
 // PostFilter is used to reject a group of pods if a pod does not pass PreFilter or Filter.
 func (cs *Coscheduling) PostFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod,
 	filteredNodeStatusMap framework.NodeToStatusMap) (*framework.PostFilterResult, *framework.Status) {
@@ -187,11 +266,23 @@ func (cs *Coscheduling) PostFilter(ctx context.Context, state *framework.CycleSt
 			labels.SelectorFromSet(labels.Set{v1alpha1.PodGroupLabel: util.GetPodGroupLabel(pod)}),
 		)
 		if err == nil && len(pods) >= int(pg.Spec.MinMember) {
-			cs.pgMgr.BackoffPodGroup(pgName, *cs.pgBackoff)
+			var maxBackoff time.Duration
+			if cs.pgMaxBackoff != nil {
+				maxBackoff = *cs.pgMaxBackoff
+			}
+			cs.pgMgr.BackoffPodGroup(pg, *cs.pgBackoff, maxBackoff)
 		}
 	}
 
 	cs.pgMgr.DeletePermittedPodGroup(pgName)
+	msg := fmt.Sprintf("Pod %v is unschedulable, %v of %v members are assigned", pod.Name, assigned, pg.Spec.MinMember)
+	cs.pgMgr.ReleaseReservation(ctx, pg, v1alpha1.PodGroupReasonUnschedulable, msg)
+	cs.pgMgr.UpdatePodGroupCondition(ctx, pg, metav1.Condition{
+		Type:    v1alpha1.PodGroupScheduled,
+		Status:  metav1.ConditionFalse,
+		Reason:  v1alpha1.PodGroupReasonUnschedulable,
+		Message: msg,
+	})
 	return &framework.PostFilterResult{}, framework.NewStatus(framework.Unschedulable,
 		fmt.Sprintf("PodGroup %v gets rejected due to Pod %v is unschedulable even after PostFilter", pgName, pod.Name))
 }
@@ -229,6 +320,10 @@ func (cs *Coscheduling) Permit(ctx context.Context, state *framework.CycleState,
 			}
 		})
 		klog.V(3).InfoS("Permit allows", "pod", klog.KObj(pod))
+		if _, pg := cs.pgMgr.GetPodGroup(ctx, pod); pg != nil {
+			cs.pgMgr.ReleaseReservation(ctx, pg, v1alpha1.PodGroupReasonQuorumReached,
+				fmt.Sprintf("PodGroup reached minMember %v", pg.Spec.MinMember))
+		}
 		retStatus = framework.NewStatus(framework.Success)
 		waitTime = 0
 	}
@@ -237,7 +332,26 @@ func (cs *Coscheduling) Permit(ctx context.Context, state *framework.CycleState,
 }
 
 // Reserve is the functions invoked by the framework at "reserve" extension point.
+// The pod takes its share of the capacity reserved for its PodGroup. If the PodGroup holds no reservation,
+// capacity is reserved for the rest of its members on the nodes which passed Filter for the pod in this cycle,
+// rather than running the filters again on every node.
 func (cs *Coscheduling) Reserve(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeName string) *framework.Status {
+	if !cs.reserveCapacity {
+		return nil
+	}
+	cs.pgMgr.ConsumeReservation(pod, nodeName)
+	c, err := state.Read(feasibleNodesStateKey)
+	if err != nil {
+		klog.V(5).InfoS("No feasible nodes recorded to reserve capacity on", "pod", klog.KObj(pod))
+		return nil
+	}
+	feasibleNodes, ok := c.(*feasibleNodesState)
+	if !ok {
+		return nil
+	}
+	cs.pgMgr.ReserveCapacity(ctx, pod, nodeName, func(nodeInfo *framework.NodeInfo) bool {
+		return feasibleNodes.has(nodeInfo.Node().Name)
+	})
 	return nil
 }
 
@@ -254,4 +368,21 @@ func (cs *Coscheduling) Unreserve(ctx context.Context, state *framework.CycleSta
 		}
 	})
 	cs.pgMgr.DeletePermittedPodGroup(pgName)
+	// Only the pods which timed out report it; their siblings get rejected as a consequence.
+	if core.IsPermitTimedOut(state) {
+		msg := fmt.Sprintf("Pod %v timed out waiting at Permit for the group to reach minMember %v", pod.Name, pg.Spec.MinMember)
+		cs.pgMgr.ReleaseReservation(ctx, pg, v1alpha1.PodGroupReasonPermitTimeout, msg)
+		cs.pgMgr.UpdatePodGroupCondition(ctx, pg, metav1.Condition{
+			Type:    v1alpha1.PodGroupWaitingAtPermit,
+			Status:  metav1.ConditionFalse,
+			Reason:  v1alpha1.PodGroupReasonPermitTimeout,
+			Message: msg,
+		})
+		cs.pgMgr.UpdatePodGroupCondition(ctx, pg, metav1.Condition{
+			Type:    v1alpha1.PodGroupScheduled,
+			Status:  metav1.ConditionFalse,
+			Reason:  v1alpha1.PodGroupReasonPermitTimeout,
+			Message: msg,
+		})
+	}
 }
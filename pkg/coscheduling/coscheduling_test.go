@@ -27,6 +27,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/informers"
 	clientsetfake "k8s.io/client-go/kubernetes/fake"
 	clicache "k8s.io/client-go/tools/cache"
@@ -41,6 +42,8 @@ import (
 	_ "sigs.k8s.io/scheduler-plugins/apis/config/scheme"
 	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
 	"sigs.k8s.io/scheduler-plugins/pkg/coscheduling/core"
+	pgfake "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned/fake"
+	pginformers "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
 	tu "sigs.k8s.io/scheduler-plugins/test/util"
 )
 
@@ -98,6 +101,8 @@ func TestPodGroupBackoffTime(t *testing.T) {
 			cs := clientsetfake.NewSimpleClientset()
 			informerFactory := informers.NewSharedInformerFactory(cs, 0)
 			podInformer := informerFactory.Core().V1().Pods()
+			pgInformerFactory := pginformers.NewSharedInformerFactory(pgfake.NewSimpleClientset(), 0)
+			pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
 			registeredPlugins := []tf.RegisterPluginFunc{
 				tf.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
 				tf.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
@@ -108,11 +113,14 @@ func TestPodGroupBackoffTime(t *testing.T) {
 			}
 
 			pgMgr := core.NewPodGroupManager(
+				ctx,
 				client,
 				tu.NewFakeSharedLister(tt.pods, nodes),
 				// In this UT, 5 seconds should suffice to test the PreFilter's return code.
 				pointer.Duration(5*time.Second),
+				pgInformer,
 				podInformer,
+				nil,
 			)
 			pl := &Coscheduling{
 				frameworkHandler: f,
@@ -122,6 +130,13 @@ func TestPodGroupBackoffTime(t *testing.T) {
 			}
 
 			informerFactory.Start(ctx.Done())
+			pgInformerFactory.Start(ctx.Done())
+			if !clicache.WaitForCacheSync(ctx.Done(), pgInformer.Informer().HasSynced) {
+				t.Fatal("WaitForCacheSync failed")
+			}
+			for _, pg := range tt.pgs {
+				pgInformer.Informer().GetStore().Add(pg)
+			}
 			if !clicache.WaitForCacheSync(ctx.Done(), podInformer.Informer().HasSynced) {
 				t.Fatal("WaitForCacheSync failed")
 			}
@@ -414,10 +429,19 @@ func TestLess(t *testing.T) {
 			cs := clientsetfake.NewSimpleClientset()
 			informerFactory := informers.NewSharedInformerFactory(cs, 0)
 			podInformer := informerFactory.Core().V1().Pods()
+			pgInformerFactory := pginformers.NewSharedInformerFactory(pgfake.NewSimpleClientset(), 0)
+			pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
 
-			pl := &Coscheduling{pgMgr: core.NewPodGroupManager(client, nil, nil, podInformer)}
+			pl := &Coscheduling{pgMgr: core.NewPodGroupManager(ctx, client, nil, nil, pgInformer, podInformer, nil)}
 
 			informerFactory.Start(ctx.Done())
+			pgInformerFactory.Start(ctx.Done())
+			if !clicache.WaitForCacheSync(ctx.Done(), pgInformer.Informer().HasSynced) {
+				t.Fatal("WaitForCacheSync failed")
+			}
+			for _, pg := range tt.pgs {
+				pgInformer.Informer().GetStore().Add(pg)
+			}
 			if !clicache.WaitForCacheSync(ctx.Done(), podInformer.Informer().HasSynced) {
 				t.Fatal("WaitForCacheSync failed")
 			}
@@ -502,14 +526,23 @@ func TestPermit(t *testing.T) {
 			cs := clientsetfake.NewSimpleClientset()
 			informerFactory := informers.NewSharedInformerFactory(cs, 0)
 			podInformer := informerFactory.Core().V1().Pods()
+			pgInformerFactory := pginformers.NewSharedInformerFactory(pgfake.NewSimpleClientset(), 0)
+			pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
 
 			pl := &Coscheduling{
 				frameworkHandler: f,
-				pgMgr:            core.NewPodGroupManager(client, tu.NewFakeSharedLister(nil, nodes), nil, podInformer),
+				pgMgr:            core.NewPodGroupManager(ctx, client, tu.NewFakeSharedLister(nil, nodes), nil, pgInformer, podInformer, nil),
 				scheduleTimeout:  &scheduleTimeout,
 			}
 
 			informerFactory.Start(ctx.Done())
+			pgInformerFactory.Start(ctx.Done())
+			if !clicache.WaitForCacheSync(ctx.Done(), pgInformer.Informer().HasSynced) {
+				t.Fatal("WaitForCacheSync failed")
+			}
+			for _, pg := range tt.pgs {
+				pgInformer.Informer().GetStore().Add(pg)
+			}
 			if !clicache.WaitForCacheSync(ctx.Done(), podInformer.Informer().HasSynced) {
 				t.Fatal("WaitForCacheSync failed")
 			}
@@ -606,19 +639,31 @@ func TestPostFilter(t *testing.T) {
 			cs := clientsetfake.NewSimpleClientset()
 			informerFactory := informers.NewSharedInformerFactory(cs, 0)
 			podInformer := informerFactory.Core().V1().Pods()
+			pgInformerFactory := pginformers.NewSharedInformerFactory(pgfake.NewSimpleClientset(), 0)
+			pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
 
 			pl := &Coscheduling{
 				frameworkHandler: f,
 				pgMgr: core.NewPodGroupManager(
+					ctx,
 					client,
 					tu.NewFakeSharedLister(tt.existingPods, nodes),
 					&scheduleTimeout,
+					pgInformer,
 					podInformer,
+					nil,
 				),
 				scheduleTimeout: &scheduleTimeout,
 			}
 
 			informerFactory.Start(ctx.Done())
+			pgInformerFactory.Start(ctx.Done())
+			if !clicache.WaitForCacheSync(ctx.Done(), pgInformer.Informer().HasSynced) {
+				t.Fatal("WaitForCacheSync failed")
+			}
+			for _, pg := range tt.pgs {
+				pgInformer.Informer().GetStore().Add(pg)
+			}
 			if !clicache.WaitForCacheSync(ctx.Done(), podInformer.Informer().HasSynced) {
 				t.Fatal("WaitForCacheSync failed")
 			}
@@ -633,3 +678,120 @@ func TestPostFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterRecordsFeasibleNodes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scheduleTimeout := 10 * time.Second
+	nodes := []*v1.Node{
+		st.MakeNode().Name("node-a").Capacity(map[v1.ResourceName]string{v1.ResourceCPU: "4"}).Obj(),
+		st.MakeNode().Name("node-b").Capacity(map[v1.ResourceName]string{v1.ResourceCPU: "4"}).Obj(),
+	}
+	client, err := tu.NewFakeClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	informerFactory := informers.NewSharedInformerFactory(clientsetfake.NewSimpleClientset(), 0)
+	pgInformerFactory := pginformers.NewSharedInformerFactory(pgfake.NewSimpleClientset(), 0)
+	pl := &Coscheduling{
+		pgMgr: core.NewPodGroupManager(
+			ctx,
+			client,
+			tu.NewFakeSharedLister(nil, nodes),
+			&scheduleTimeout,
+			pgInformerFactory.Scheduling().V1alpha1().PodGroups(),
+			informerFactory.Core().V1().Pods(),
+			nil,
+		),
+		scheduleTimeout: &scheduleTimeout,
+		reserveCapacity: true,
+	}
+
+	pod := st.MakePod().Name("p").Namespace("ns").UID("p").Obj()
+	state := framework.NewCycleState()
+	state.Write(feasibleNodesStateKey, &feasibleNodesState{nodes: sets.New[string]()})
+	nodeInfo := framework.NewNodeInfo()
+	nodeInfo.SetNode(nodes[0])
+	if status := pl.Filter(ctx, state, pod, nodeInfo); !status.IsSuccess() {
+		t.Fatalf("Filter failed: %v", status)
+	}
+
+	// The nodes passing Filter in a preemption dry run, on a copy of the state, aren't recorded.
+	dryRunNodeInfo := framework.NewNodeInfo()
+	dryRunNodeInfo.SetNode(nodes[1])
+	if status := pl.Filter(ctx, state.Clone(), pod, dryRunNodeInfo); !status.IsSuccess() {
+		t.Fatalf("Filter failed: %v", status)
+	}
+
+	c, err := state.Read(feasibleNodesStateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feasibleNodes := c.(*feasibleNodesState)
+	if !feasibleNodes.has("node-a") || feasibleNodes.has("node-b") {
+		t.Errorf("Want only node-a recorded as feasible, but got %v", sets.List(feasibleNodes.nodes))
+	}
+}
+
+func BenchmarkLess(b *testing.B) {
+	tests := []struct {
+		name       string
+		pgNum      int
+		podsPerPG  int
+		pgsMissing bool
+	}{
+		{name: "1000 pods in 100 pod groups", pgNum: 100, podsPerPG: 10},
+		{name: "5000 pods in 500 pod groups", pgNum: 500, podsPerPG: 10},
+		{name: "5000 pods in 50 pod groups", pgNum: 50, podsPerPG: 100},
+		{name: "5000 pods without pod groups", pgNum: 500, podsPerPG: 10, pgsMissing: true},
+	}
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			cs := clientsetfake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(cs, 0)
+			podInformer := informerFactory.Core().V1().Pods()
+			pgInformerFactory := pginformers.NewSharedInformerFactory(pgfake.NewSimpleClientset(), 0)
+			pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+			pl := &Coscheduling{pgMgr: core.NewPodGroupManager(ctx, nil, nil, nil, pgInformer, podInformer, nil)}
+			informerFactory.Start(ctx.Done())
+			pgInformerFactory.Start(ctx.Done())
+			if !clicache.WaitForCacheSync(ctx.Done(), pgInformer.Informer().HasSynced) {
+				b.Fatal("WaitForCacheSync failed")
+			}
+
+			now := time.Now()
+			var podInfos []*framework.QueuedPodInfo
+			for i := 0; i < tt.pgNum; i++ {
+				pgName := fmt.Sprintf("pg-%d", i)
+				if !tt.pgsMissing {
+					pg := tu.MakePodGroup().Name(pgName).Namespace("ns").MinMember(int32(tt.podsPerPG)).
+						Time(now.Add(time.Duration(i%7) * time.Second)).Obj()
+					pgInformer.Informer().GetStore().Add(pg)
+				}
+				for j := 0; j < tt.podsPerPG; j++ {
+					name := fmt.Sprintf("%s-pod-%d", pgName, j)
+					pod := st.MakePod().Name(name).Namespace("ns").UID(name).Label(v1alpha1.PodGroupLabel, pgName).Obj()
+					podInfo, err := framework.NewPodInfo(pod)
+					if err != nil {
+						b.Fatal(err)
+					}
+					podInfos = append(podInfos, &framework.QueuedPodInfo{
+						PodInfo:                 podInfo,
+						InitialAttemptTimestamp: ptrTime(now.Add(time.Duration(j) * time.Millisecond)),
+					})
+				}
+			}
+
+			queue := make([]*framework.QueuedPodInfo, len(podInfos))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				copy(queue, podInfos)
+				sort.Slice(queue, func(i, j int) bool { return pl.Less(queue[i], queue[j]) })
+			}
+		})
+	}
+}
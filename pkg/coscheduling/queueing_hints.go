@@ -0,0 +1,156 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package coscheduling
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	schedutil "k8s.io/kubernetes/pkg/scheduler/util"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/util"
+)
+
+// isSchedulableAfterPodAdded requeues the pod only when a sibling is added, as a new member
+// may let its PodGroup reach minMember.
+func (cs *Coscheduling) isSchedulableAfterPodAdded(logger klog.Logger, pod *v1.Pod, oldObj, newObj interface{}) (framework.QueueingHint, error) {
+	_, addedPod, err := schedutil.As[*v1.Pod](oldObj, newObj)
+	if err != nil {
+		return framework.Queue, err
+	}
+	pgName := util.GetPodGroupLabel(pod)
+	if pgName == "" || addedPod.Namespace != pod.Namespace || util.GetPodGroupLabel(addedPod) != pgName {
+		logger.V(5).Info("added pod is not a sibling of the pod", "pod", klog.KObj(pod), "addedPod", klog.KObj(addedPod))
+		return framework.QueueSkip, nil
+	}
+	logger.V(5).Info("sibling of the pod was added", "pod", klog.KObj(pod), "addedPod", klog.KObj(addedPod))
+	return framework.Queue, nil
+}
+
+// isSchedulableAfterPodDeleted requeues the pod when an assigned pod is deleted and the resources it
+// frees may help: the PodGroup of the pod got rejected on a resource gap, or the pod may have been
+// rejected by the capacity reserved for other PodGroups.
+func (cs *Coscheduling) isSchedulableAfterPodDeleted(logger klog.Logger, pod *v1.Pod, oldObj, newObj interface{}) (framework.QueueingHint, error) {
+	deletedPod, _, err := schedutil.As[*v1.Pod](oldObj, newObj)
+	if err != nil {
+		return framework.Queue, err
+	}
+	if deletedPod.Spec.NodeName == "" {
+		logger.V(5).Info("deleted pod was not assigned, it frees no resources", "pod", klog.KObj(pod), "deletedPod", klog.KObj(deletedPod))
+		return framework.QueueSkip, nil
+	}
+	if cs.reserveCapacity {
+		return framework.Queue, nil
+	}
+	_, pg := cs.pgMgr.GetPodGroup(context.Background(), pod)
+	if pg == nil {
+		return framework.QueueSkip, nil
+	}
+	if c := meta.FindStatusCondition(pg.Status.Conditions, v1alpha1.PodGroupScheduled); c == nil ||
+		c.Status != metav1.ConditionFalse || c.Reason != v1alpha1.PodGroupReasonInsufficientResources {
+		logger.V(5).Info("PodGroup of the pod was not rejected on a resource gap", "pod", klog.KObj(pod), "podGroup", klog.KObj(pg))
+		return framework.QueueSkip, nil
+	}
+	logger.V(5).Info("deleted pod freed resources for the PodGroup", "pod", klog.KObj(pod), "podGroup", klog.KObj(pg), "deletedPod", klog.KObj(deletedPod))
+	return framework.Queue, nil
+}
+
+// isSchedulableAfterPodGroupChanged requeues the pod when its PodGroup is added, or updated in a way which
+// may change the outcome of PreFilter: minMember, minResources or scheduleTimeoutSeconds, or the end of its
+// backoff. Updates of the status of other PodGroups only requeue the pods held off by the capacity reserved
+// for another PodGroup, once it's released.
+func (cs *Coscheduling) isSchedulableAfterPodGroupChanged(logger klog.Logger, pod *v1.Pod, oldObj, newObj interface{}) (framework.QueueingHint, error) {
+	oldPG, newPG, err := asPodGroups(oldObj, newObj)
+	if err != nil {
+		return framework.Queue, err
+	}
+	if newPG.Namespace != pod.Namespace || newPG.Name != util.GetPodGroupLabel(pod) {
+		if cs.reserveCapacity && oldPG != nil && isReservationReleased(oldPG, newPG) {
+			logger.V(5).Info("capacity reserved for another PodGroup was released", "pod", klog.KObj(pod), "podGroup", klog.KObj(newPG))
+			return framework.Queue, nil
+		}
+		return framework.QueueSkip, nil
+	}
+	if oldPG == nil {
+		logger.V(5).Info("PodGroup of the pod was added", "pod", klog.KObj(pod), "podGroup", klog.KObj(newPG))
+		return framework.Queue, nil
+	}
+	if oldPG.Spec.MinMember != newPG.Spec.MinMember ||
+		!apiequality.Semantic.DeepEqual(oldPG.Spec.MinResources, newPG.Spec.MinResources) ||
+		!apiequality.Semantic.DeepEqual(oldPG.Spec.ScheduleTimeoutSeconds, newPG.Spec.ScheduleTimeoutSeconds) {
+		logger.V(5).Info("spec of the PodGroup of the pod was updated", "pod", klog.KObj(pod), "podGroup", klog.KObj(newPG))
+		return framework.Queue, nil
+	}
+	if meta.IsStatusConditionTrue(oldPG.Status.Conditions, v1alpha1.PodGroupBackedOff) &&
+		!meta.IsStatusConditionTrue(newPG.Status.Conditions, v1alpha1.PodGroupBackedOff) {
+		logger.V(5).Info("backoff of the PodGroup of the pod ended", "pod", klog.KObj(pod), "podGroup", klog.KObj(newPG))
+		return framework.Queue, nil
+	}
+	return framework.QueueSkip, nil
+}
+
+// isReservationReleased returns true if the PodGroup got its CapacityReserved condition turned off.
+func isReservationReleased(oldPG, newPG *v1alpha1.PodGroup) bool {
+	return meta.IsStatusConditionTrue(oldPG.Status.Conditions, v1alpha1.PodGroupCapacityReserved) &&
+		!meta.IsStatusConditionTrue(newPG.Status.Conditions, v1alpha1.PodGroupCapacityReserved)
+}
+
+// asPodGroups converts the objects of a PodGroup event to PodGroups. The scheduler watches custom
+// resources with a dynamic informer, so they are usually delivered as unstructured objects.
+func asPodGroups(oldObj, newObj interface{}) (*v1alpha1.PodGroup, *v1alpha1.PodGroup, error) {
+	newPG, err := asPodGroup(newObj)
+	if err != nil {
+		return nil, nil, err
+	}
+	if newPG == nil {
+		return nil, nil, fmt.Errorf("expected PodGroup, but got nil")
+	}
+	oldPG, err := asPodGroup(oldObj)
+	if err != nil {
+		return nil, nil, err
+	}
+	return oldPG, newPG, nil
+}
+
+func asPodGroup(obj interface{}) (*v1alpha1.PodGroup, error) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	switch t := obj.(type) {
+	case nil:
+		return nil, nil
+	case *v1alpha1.PodGroup:
+		return t, nil
+	case *unstructured.Unstructured:
+		pg := &v1alpha1.PodGroup{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(t.UnstructuredContent(), pg); err != nil {
+			return nil, err
+		}
+		return pg, nil
+	}
+	return nil, fmt.Errorf("expected PodGroup, but got %T", obj)
+}
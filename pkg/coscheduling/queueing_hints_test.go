@@ -0,0 +1,272 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package coscheduling
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	clicache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2/ktesting"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/coscheduling/core"
+	pgfake "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned/fake"
+	pginformers "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
+)
+
+func newTestPlugin(t *testing.T, ctx context.Context, reserveCapacity bool, pgs ...runtime.Object) *Coscheduling {
+	informerFactory := informers.NewSharedInformerFactory(clientsetfake.NewSimpleClientset(), 0)
+	podInformer := informerFactory.Core().V1().Pods()
+	pgInformerFactory := pginformers.NewSharedInformerFactory(pgfake.NewSimpleClientset(pgs...), 0)
+	pgInformer := pgInformerFactory.Scheduling().V1alpha1().PodGroups()
+	pl := &Coscheduling{
+		pgMgr:           core.NewPodGroupManager(ctx, nil, nil, nil, pgInformer, podInformer, nil),
+		reserveCapacity: reserveCapacity,
+	}
+	informerFactory.Start(ctx.Done())
+	pgInformerFactory.Start(ctx.Done())
+	if !clicache.WaitForCacheSync(ctx.Done(), pgInformer.Informer().HasSynced, podInformer.Informer().HasSynced) {
+		t.Fatal("WaitForCacheSync failed")
+	}
+	return pl
+}
+
+func withCondition(pg *v1alpha1.PodGroup, conditionType string, status metav1.ConditionStatus, reason string) *v1alpha1.PodGroup {
+	pg = pg.DeepCopy()
+	pg.Status.Conditions = append(pg.Status.Conditions, metav1.Condition{Type: conditionType, Status: status, Reason: reason})
+	return pg
+}
+
+func toUnstructured(t *testing.T, pg *v1alpha1.PodGroup) *unstructured.Unstructured {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &unstructured.Unstructured{Object: obj}
+}
+
+func TestIsSchedulableAfterPodAdded(t *testing.T) {
+	pod := st.MakePod().Name("p1").Namespace("ns").Label(v1alpha1.PodGroupLabel, "pg1").Obj()
+	tests := []struct {
+		name     string
+		pod      *v1.Pod
+		addedPod *v1.Pod
+		want     framework.QueueingHint
+	}{
+		{
+			name:     "sibling added",
+			pod:      pod,
+			addedPod: st.MakePod().Name("p2").Namespace("ns").Label(v1alpha1.PodGroupLabel, "pg1").Obj(),
+			want:     framework.Queue,
+		},
+		{
+			name:     "pod of another pod group added",
+			pod:      pod,
+			addedPod: st.MakePod().Name("p2").Namespace("ns").Label(v1alpha1.PodGroupLabel, "pg2").Obj(),
+			want:     framework.QueueSkip,
+		},
+		{
+			name:     "pod of a pod group with the same name in another namespace added",
+			pod:      pod,
+			addedPod: st.MakePod().Name("p2").Namespace("ns2").Label(v1alpha1.PodGroupLabel, "pg1").Obj(),
+			want:     framework.QueueSkip,
+		},
+		{
+			name:     "pod without pod group",
+			pod:      st.MakePod().Name("p1").Namespace("ns").Obj(),
+			addedPod: st.MakePod().Name("p2").Namespace("ns").Obj(),
+			want:     framework.QueueSkip,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, _ := ktesting.NewTestContext(t)
+			pl := &Coscheduling{}
+			got, err := pl.isSchedulableAfterPodAdded(logger, tt.pod, nil, tt.addedPod)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIsSchedulableAfterPodDeleted(t *testing.T) {
+	pod := st.MakePod().Name("p1").Namespace("ns").Label(v1alpha1.PodGroupLabel, "pg1").Obj()
+	assignedPod := st.MakePod().Name("p").Namespace("other").Node("node").Obj()
+	pg := &v1alpha1.PodGroup{ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns"}}
+	tests := []struct {
+		name            string
+		pg              *v1alpha1.PodGroup
+		reserveCapacity bool
+		deletedPod      *v1.Pod
+		want            framework.QueueingHint
+	}{
+		{
+			name:       "assigned pod deleted, pod group rejected on a resource gap",
+			pg:         withCondition(pg, v1alpha1.PodGroupScheduled, metav1.ConditionFalse, v1alpha1.PodGroupReasonInsufficientResources),
+			deletedPod: assignedPod,
+			want:       framework.Queue,
+		},
+		{
+			name:       "assigned pod deleted, pod group rejected for missing pods",
+			pg:         withCondition(pg, v1alpha1.PodGroupScheduled, metav1.ConditionFalse, v1alpha1.PodGroupReasonNotEnoughPods),
+			deletedPod: assignedPod,
+			want:       framework.QueueSkip,
+		},
+		{
+			name:            "assigned pod deleted with capacity reservation",
+			pg:              pg,
+			reserveCapacity: true,
+			deletedPod:      assignedPod,
+			want:            framework.Queue,
+		},
+		{
+			name:            "pending pod deleted",
+			pg:              withCondition(pg, v1alpha1.PodGroupScheduled, metav1.ConditionFalse, v1alpha1.PodGroupReasonInsufficientResources),
+			reserveCapacity: true,
+			deletedPod:      st.MakePod().Name("p").Namespace("other").Obj(),
+			want:            framework.QueueSkip,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, ctx := ktesting.NewTestContext(t)
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			pl := newTestPlugin(t, ctx, tt.reserveCapacity, tt.pg)
+			got, err := pl.isSchedulableAfterPodDeleted(logger, pod, clicache.DeletedFinalStateUnknown{Obj: tt.deletedPod}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIsSchedulableAfterPodGroupChanged(t *testing.T) {
+	pod := st.MakePod().Name("p1").Namespace("ns").Label(v1alpha1.PodGroupLabel, "pg1").Obj()
+	pg := &v1alpha1.PodGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns"},
+		Spec:       v1alpha1.PodGroupSpec{MinMember: 3},
+	}
+	other := &v1alpha1.PodGroup{ObjectMeta: metav1.ObjectMeta{Name: "pg2", Namespace: "ns"}}
+	timeout := int32(30)
+	tests := []struct {
+		name            string
+		reserveCapacity bool
+		oldPG           *v1alpha1.PodGroup
+		newPG           *v1alpha1.PodGroup
+		want            framework.QueueingHint
+	}{
+		{
+			name:  "pod group added",
+			newPG: pg,
+			want:  framework.Queue,
+		},
+		{
+			name:  "another pod group added",
+			newPG: other,
+			want:  framework.QueueSkip,
+		},
+		{
+			name:  "minMember updated",
+			oldPG: pg,
+			newPG: func() *v1alpha1.PodGroup { p := pg.DeepCopy(); p.Spec.MinMember = 2; return p }(),
+			want:  framework.Queue,
+		},
+		{
+			name:  "minResources updated",
+			oldPG: pg,
+			newPG: func() *v1alpha1.PodGroup {
+				p := pg.DeepCopy()
+				p.Spec.MinResources = v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}
+				return p
+			}(),
+			want: framework.Queue,
+		},
+		{
+			name:  "scheduleTimeoutSeconds updated",
+			oldPG: pg,
+			newPG: func() *v1alpha1.PodGroup { p := pg.DeepCopy(); p.Spec.ScheduleTimeoutSeconds = &timeout; return p }(),
+			want:  framework.Queue,
+		},
+		{
+			name:  "status updated",
+			oldPG: pg,
+			newPG: func() *v1alpha1.PodGroup { p := pg.DeepCopy(); p.Status.Running = 1; return p }(),
+			want:  framework.QueueSkip,
+		},
+		{
+			name:  "backoff of the pod group expired",
+			oldPG: withCondition(pg, v1alpha1.PodGroupBackedOff, metav1.ConditionTrue, v1alpha1.PodGroupReasonUnschedulable),
+			newPG: withCondition(pg, v1alpha1.PodGroupBackedOff, metav1.ConditionFalse, v1alpha1.PodGroupReasonExpired),
+			want:  framework.Queue,
+		},
+		{
+			name:  "backoff of another pod group expired",
+			oldPG: withCondition(other, v1alpha1.PodGroupBackedOff, metav1.ConditionTrue, v1alpha1.PodGroupReasonUnschedulable),
+			newPG: withCondition(other, v1alpha1.PodGroupBackedOff, metav1.ConditionFalse, v1alpha1.PodGroupReasonExpired),
+			want:  framework.QueueSkip,
+		},
+		{
+			name:            "capacity reserved for another pod group released",
+			reserveCapacity: true,
+			oldPG:           withCondition(other, v1alpha1.PodGroupCapacityReserved, metav1.ConditionTrue, v1alpha1.PodGroupReasonReserved),
+			newPG:           withCondition(other, v1alpha1.PodGroupCapacityReserved, metav1.ConditionFalse, v1alpha1.PodGroupReasonQuorumReached),
+			want:            framework.Queue,
+		},
+		{
+			name:  "capacity reserved for another pod group released without capacity reservation",
+			oldPG: withCondition(other, v1alpha1.PodGroupCapacityReserved, metav1.ConditionTrue, v1alpha1.PodGroupReasonReserved),
+			newPG: withCondition(other, v1alpha1.PodGroupCapacityReserved, metav1.ConditionFalse, v1alpha1.PodGroupReasonQuorumReached),
+			want:  framework.QueueSkip,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, _ := ktesting.NewTestContext(t)
+			pl := &Coscheduling{reserveCapacity: tt.reserveCapacity}
+			// The scheduler delivers custom resources as unstructured objects.
+			var oldObj interface{}
+			if tt.oldPG != nil {
+				oldObj = toUnstructured(t, tt.oldPG)
+			}
+			got, err := pl.isSchedulableAfterPodGroupChanged(logger, pod, oldObj, toUnstructured(t, tt.newPG))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
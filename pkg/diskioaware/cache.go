@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diskioaware
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ioCache : the disk IO allocated on the nodes to the pods reserved or assigned to them
+type ioCache struct {
+	// for safe access to pods and nodes
+	mu sync.RWMutex
+	// allocation of the pods by UID
+	pods map[types.UID]podIO
+	// allocated bandwidth of the nodes by device class
+	nodes map[string]map[string]IOBandwidth
+}
+
+// podIO : the normalized disk IO allocated to a pod on a node by device class
+type podIO struct {
+	nodeName  string
+	bandwidth map[string]IOBandwidth
+}
+
+func newIOCache() *ioCache {
+	return &ioCache{
+		pods:  make(map[types.UID]podIO),
+		nodes: make(map[string]map[string]IOBandwidth),
+	}
+}
+
+// addPod : allocate the bandwidth to a pod on a node, replacing its previous allocation if any
+func (c *ioCache) addPod(uid types.UID, nodeName string, bandwidth map[string]IOBandwidth) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removePodLocked(uid)
+	c.pods[uid] = podIO{nodeName: nodeName, bandwidth: bandwidth}
+	allocated, ok := c.nodes[nodeName]
+	if !ok {
+		allocated = make(map[string]IOBandwidth)
+		c.nodes[nodeName] = allocated
+	}
+	for deviceClass, b := range bandwidth {
+		total := allocated[deviceClass]
+		total.Add(b)
+		allocated[deviceClass] = total
+	}
+}
+
+// removePod : release the bandwidth allocated to a pod, if any
+func (c *ioCache) removePod(uid types.UID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removePodLocked(uid)
+}
+
+func (c *ioCache) removePodLocked(uid types.UID) {
+	pod, ok := c.pods[uid]
+	if !ok {
+		return
+	}
+	delete(c.pods, uid)
+	allocated := c.nodes[pod.nodeName]
+	for deviceClass, b := range pod.bandwidth {
+		total := allocated[deviceClass]
+		total.Add(IOBandwidth{ReadBPS: -b.ReadBPS, WriteBPS: -b.WriteBPS, ReadIOPS: -b.ReadIOPS, WriteIOPS: -b.WriteIOPS})
+		if total.IsZero() {
+			delete(allocated, deviceClass)
+		} else {
+			allocated[deviceClass] = total
+		}
+	}
+	if len(allocated) == 0 {
+		delete(c.nodes, pod.nodeName)
+	}
+}
+
+// allocated : the bandwidth allocated on a node by device class
+func (c *ioCache) allocated(nodeName string) map[string]IOBandwidth {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	allocated := make(map[string]IOBandwidth, len(c.nodes[nodeName]))
+	for deviceClass, b := range c.nodes[nodeName] {
+		allocated[deviceClass] = b
+	}
+	return allocated
+}
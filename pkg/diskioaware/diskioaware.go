@@ -0,0 +1,307 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package diskioaware plugin schedules the pods requesting disk IO bandwidth on the nodes whose devices have the
+// capacity for it, as described in kep/624. The requests are normalized to the capacity reported by the IO driver
+// of the nodes by a pluggable estimator, and the bandwidth reserved for the pods is tracked by the plugin.
+package diskioaware
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"sigs.k8s.io/scheduler-plugins/apis/config"
+	"sigs.k8s.io/scheduler-plugins/apis/config/validation"
+)
+
+const (
+	// Name : name of plugin
+	Name = "DiskIOAware"
+	// key of the disk IO of the pod in the cycle state
+	stateKey = Name + "StateKey"
+
+	ErrReasonNoCapacity     = "node(s) didn't report disk IO capacity"
+	ErrReasonNoDeviceClass  = "node(s) didn't have the requested disk IO device class"
+	ErrReasonInsufficientIO = "node(s) had insufficient disk IO"
+)
+
+// DiskIOAware : scheduler plugin
+type DiskIOAware struct {
+	handle          framework.Handle
+	scoringStrategy config.ScoringStrategyType
+	estimator       Estimator
+	source          MetricsSource
+	cache           *ioCache
+}
+
+var _ framework.PreFilterPlugin = &DiskIOAware{}
+var _ framework.FilterPlugin = &DiskIOAware{}
+var _ framework.ScorePlugin = &DiskIOAware{}
+var _ framework.ReservePlugin = &DiskIOAware{}
+var _ framework.EnqueueExtensions = &DiskIOAware{}
+
+// stateData : the normalized disk IO requested by the pod by device class
+type stateData struct {
+	bandwidth map[string]IOBandwidth
+}
+
+func (s *stateData) Clone() framework.StateData {
+	return s
+}
+
+// New : create an instance of a DiskIOAware plugin
+func New(ctx context.Context, obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+	klog.V(4).InfoS("Creating new instance of the DiskIOAware plugin")
+	args, ok := obj.(*config.DiskIOAwareArgs)
+	if !ok {
+		return nil, fmt.Errorf("want args to be of type DiskIOAwareArgs, got %T", obj)
+	}
+	if err := validation.ValidateDiskIOAwareArgs(nil, args); err != nil {
+		return nil, err
+	}
+	estimator, ok := getEstimator(args.Estimator)
+	if !ok {
+		return nil, fmt.Errorf("unknown disk IO estimator %q", args.Estimator)
+	}
+	klog.V(4).InfoS("Using DiskIOAwareArgs", "scoringStrategy", args.ScoringStrategy, "estimator", args.Estimator)
+
+	pl := &DiskIOAware{
+		handle:          handle,
+		scoringStrategy: args.ScoringStrategy,
+		estimator:       estimator,
+		source:          annotationSource{},
+		cache:           newIOCache(),
+	}
+
+	// the disk IO of the pods assigned to the nodes is allocated until they terminate
+	podInformer := handle.SharedInformerFactory().Core().V1().Pods().Informer()
+	podInformer.AddEventHandler(
+		cache.FilteringResourceEventHandler{
+			FilterFunc: func(obj interface{}) bool {
+				switch t := obj.(type) {
+				case *v1.Pod:
+					return assignedPod(t)
+				case cache.DeletedFinalStateUnknown:
+					if pod, ok := t.Obj.(*v1.Pod); ok {
+						return assignedPod(pod)
+					}
+					return false
+				default:
+					return false
+				}
+			},
+			Handler: cache.ResourceEventHandlerFuncs{
+				AddFunc: pl.updatePod,
+				UpdateFunc: func(oldObj, newObj interface{}) {
+					pl.updatePod(newObj)
+				},
+				DeleteFunc: pl.deletePod,
+			},
+		},
+	)
+	return pl, nil
+}
+
+// Name : name of plugin
+func (pl *DiskIOAware) Name() string {
+	return Name
+}
+
+// EventsToRegister : the nodes reporting more capacity, and the pods releasing theirs, may make a pod schedulable
+func (pl *DiskIOAware) EventsToRegister() []framework.ClusterEventWithHint {
+	return []framework.ClusterEventWithHint{
+		{Event: framework.ClusterEvent{Resource: framework.Node, ActionType: framework.Add | framework.Update}},
+		{Event: framework.ClusterEvent{Resource: framework.Pod, ActionType: framework.Delete}},
+	}
+}
+
+// PreFilter : normalize the disk IO requested by the pod, skipping the pods which don't request any
+func (pl *DiskIOAware) PreFilter(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod) (*framework.PreFilterResult, *framework.Status) {
+	bandwidth, err := pl.podBandwidth(pod)
+	cycleState.Write(stateKey, &stateData{bandwidth: bandwidth})
+	if err != nil {
+		return nil, framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf("invalid disk IO request: %v", err))
+	}
+	if len(bandwidth) == 0 {
+		return nil, framework.NewStatus(framework.Skip)
+	}
+	return nil, nil
+}
+
+// PreFilterExtensions : not used
+func (pl *DiskIOAware) PreFilterExtensions() framework.PreFilterExtensions {
+	return nil
+}
+
+// Filter : check that the devices of the node have the capacity for the disk IO requested by the pod
+func (pl *DiskIOAware) Filter(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	bandwidth, err := getBandwidth(cycleState)
+	if err != nil {
+		return framework.AsStatus(err)
+	}
+	capacity, ok := pl.source.NodeCapacity(nodeInfo.Node())
+	if !ok {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, ErrReasonNoCapacity)
+	}
+	allocated := pl.cache.allocated(nodeInfo.Node().Name)
+	for deviceClass, b := range bandwidth {
+		c, ok := capacity[deviceClass]
+		if !ok {
+			return framework.NewStatus(framework.UnschedulableAndUnresolvable, ErrReasonNoDeviceClass)
+		}
+		if !b.Fits(allocated[deviceClass], c) {
+			klog.V(6).InfoS("Insufficient disk IO", "pod", klog.KObj(pod), "nodeName", nodeInfo.Node().Name,
+				"deviceClass", deviceClass, "requested", b, "allocated", allocated[deviceClass], "capacity", c)
+			return framework.NewStatus(framework.Unschedulable, ErrReasonInsufficientIO)
+		}
+	}
+	return nil
+}
+
+// Score : score the node by its disk IO utilization once the pod is added, favoring the most allocated devices or
+// the least allocated ones, depending on the scoring strategy
+func (pl *DiskIOAware) Score(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeName string) (int64, *framework.Status) {
+	bandwidth, err := getBandwidth(cycleState)
+	if err != nil {
+		return framework.MinNodeScore, framework.AsStatus(err)
+	}
+	nodeInfo, err := pl.handle.SnapshotSharedLister().NodeInfos().Get(nodeName)
+	if err != nil {
+		return framework.MinNodeScore, framework.NewStatus(framework.Error, fmt.Sprintf("getting node %q from Snapshot: %v", nodeName, err))
+	}
+	capacity, ok := pl.source.NodeCapacity(nodeInfo.Node())
+	if !ok || len(bandwidth) == 0 {
+		return framework.MinNodeScore, nil
+	}
+	allocated := pl.cache.allocated(nodeName)
+	var utilization float64
+	for deviceClass, b := range bandwidth {
+		utilization += b.Utilization(allocated[deviceClass], capacity[deviceClass])
+	}
+	utilization /= float64(len(bandwidth))
+	if pl.scoringStrategy == config.LeastAllocated {
+		utilization = 1 - utilization
+	}
+	score := int64(math.Round(utilization * float64(framework.MaxNodeScore)))
+	klog.V(6).InfoS("Calculating score", "pod", klog.KObj(pod), "nodeName", nodeName, "score", score)
+	return score, nil
+}
+
+// ScoreExtensions : not used, the scores are already normalized
+func (pl *DiskIOAware) ScoreExtensions() framework.ScoreExtensions {
+	return nil
+}
+
+// Reserve : allocate the disk IO of the pod on the node
+func (pl *DiskIOAware) Reserve(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeName string) *framework.Status {
+	bandwidth, err := getBandwidth(cycleState)
+	if err != nil {
+		return framework.AsStatus(err)
+	}
+	if len(bandwidth) > 0 {
+		pl.cache.addPod(pod.UID, nodeName, bandwidth)
+	}
+	return nil
+}
+
+// Unreserve : release the disk IO of the pod
+func (pl *DiskIOAware) Unreserve(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeName string) {
+	pl.cache.removePod(pod.UID)
+}
+
+// podBandwidth : the normalized disk IO requested by a pod by device class, empty if it doesn't request any
+func (pl *DiskIOAware) podBandwidth(pod *v1.Pod) (map[string]IOBandwidth, error) {
+	annotation, ok := pod.Annotations[PodIORequestAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	requests, err := parseIORequests(annotation)
+	if err != nil {
+		return nil, err
+	}
+	bandwidth := make(map[string]IOBandwidth, len(requests))
+	for deviceClass, request := range requests {
+		if request.IsZero() {
+			continue
+		}
+		b, err := pl.estimator.EstimateRequest(deviceClass, request)
+		if err != nil {
+			return nil, fmt.Errorf("estimating the disk IO of device class %q: %w", deviceClass, err)
+		}
+		bandwidth[deviceClass] = b
+	}
+	return bandwidth, nil
+}
+
+// updatePod : allocate the disk IO of an assigned pod, or release it once the pod terminates
+func (pl *DiskIOAware) updatePod(obj interface{}) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		pl.cache.removePod(pod.UID)
+		return
+	}
+	bandwidth, err := pl.podBandwidth(pod)
+	if err != nil {
+		klog.ErrorS(err, "Invalid disk IO request of pod", "pod", klog.KObj(pod))
+	}
+	if len(bandwidth) == 0 {
+		pl.cache.removePod(pod.UID)
+		return
+	}
+	pl.cache.addPod(pod.UID, pod.Spec.NodeName, bandwidth)
+}
+
+// deletePod : release the disk IO of a deleted pod
+func (pl *DiskIOAware) deletePod(obj interface{}) {
+	var pod *v1.Pod
+	switch t := obj.(type) {
+	case *v1.Pod:
+		pod = t
+	case cache.DeletedFinalStateUnknown:
+		pod, _ = t.Obj.(*v1.Pod)
+	}
+	if pod != nil {
+		pl.cache.removePod(pod.UID)
+	}
+}
+
+// getBandwidth : the disk IO requested by the pod, computed in PreFilter
+func getBandwidth(cycleState *framework.CycleState) (map[string]IOBandwidth, error) {
+	c, err := cycleState.Read(stateKey)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %q from cycleState: %w", stateKey, err)
+	}
+	s, ok := c.(*stateData)
+	if !ok {
+		return nil, fmt.Errorf("%+v convert to DiskIOAware.stateData error", c)
+	}
+	return s.bandwidth, nil
+}
+
+// assignedPod : whether the pod is assigned to a node
+func assignedPod(pod *v1.Pod) bool {
+	return len(pod.Spec.NodeName) != 0
+}
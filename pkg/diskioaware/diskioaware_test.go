@@ -0,0 +1,313 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diskioaware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	testClientSet "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	schedConfig "k8s.io/kubernetes/pkg/scheduler/apis/config"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
+	"k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+	tf "k8s.io/kubernetes/pkg/scheduler/testing/framework"
+
+	"sigs.k8s.io/scheduler-plugins/apis/config"
+	testutil "sigs.k8s.io/scheduler-plugins/test/util"
+)
+
+// fakeSource : the capacity of the nodes by name, the nodes not listed don't report any
+type fakeSource map[string]map[string]IOBandwidth
+
+func (s fakeSource) NodeCapacity(node *v1.Node) (map[string]IOBandwidth, bool) {
+	capacity, ok := s[node.Name]
+	return capacity, ok
+}
+
+var testSource = fakeSource{
+	"node-1": {"nvme": {ReadBPS: 100 << 20, WriteBPS: 100 << 20, ReadIOPS: 1000, WriteIOPS: 1000}},
+	"node-2": {"nvme": {ReadBPS: 400 << 20, WriteBPS: 400 << 20, ReadIOPS: 4000, WriteIOPS: 4000}},
+	"node-3": {"hdd": {ReadBPS: 100 << 20, WriteBPS: 100 << 20, ReadIOPS: 1000, WriteIOPS: 1000}},
+}
+
+// ioPod : a pod requesting disk IO with the annotation, none if empty
+func ioPod(name string, annotation string) *v1.Pod {
+	pod := st.MakePod().Name(name).UID(name).Namespace("default").Obj()
+	if annotation != "" {
+		pod.Annotations = map[string]string{PodIORequestAnnotation: annotation}
+	}
+	return pod
+}
+
+func newTestPlugin(ctx context.Context, t *testing.T, args *config.DiskIOAwareArgs, nodes []*v1.Node) *DiskIOAware {
+	registeredPlugins := []tf.RegisterPluginFunc{
+		tf.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		tf.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+	}
+	cs := testClientSet.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(cs, 0)
+	fh, err := testutil.NewFramework(ctx, registeredPlugins, []schedConfig.PluginConfig{{Name: Name, Args: args}},
+		"default-scheduler", runtime.WithClientSet(cs), runtime.WithInformerFactory(informerFactory),
+		runtime.WithSnapshotSharedLister(testutil.NewFakeSharedLister(nil, nodes)))
+	assert.Nil(t, err)
+	p, err := New(ctx, args, fh)
+	assert.Nil(t, err)
+	pl := p.(*DiskIOAware)
+	pl.source = testSource
+	return pl
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    *config.DiskIOAwareArgs
+		wantErr bool
+	}{
+		{
+			name: "default estimator",
+			args: &config.DiskIOAwareArgs{ScoringStrategy: config.LeastAllocated, Estimator: BlockSizeEstimatorName},
+		},
+		{
+			name:    "unknown estimator",
+			args:    &config.DiskIOAwareArgs{ScoringStrategy: config.LeastAllocated, Estimator: "Vendor"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid scoring strategy",
+			args:    &config.DiskIOAwareArgs{ScoringStrategy: config.BalancedAllocation, Estimator: BlockSizeEstimatorName},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			cs := testClientSet.NewSimpleClientset()
+			fh, err := testutil.NewFramework(ctx, []tf.RegisterPluginFunc{
+				tf.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+				tf.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+			}, nil, "default-scheduler", runtime.WithClientSet(cs),
+				runtime.WithInformerFactory(informers.NewSharedInformerFactory(cs, 0)))
+			assert.Nil(t, err)
+			_, err = New(ctx, tt.args, fh)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	nodes := []*v1.Node{
+		st.MakeNode().Name("node-1").Obj(),
+		st.MakeNode().Name("node-2").Obj(),
+		st.MakeNode().Name("node-3").Obj(),
+		st.MakeNode().Name("node-4").Obj(),
+	}
+	tests := []struct {
+		name string
+		pod  *v1.Pod
+		// disk IO of the pods reserved on node-1
+		reserved     []*v1.Pod
+		expectedCode map[string]framework.Code
+	}{
+		{
+			name: "fits",
+			pod:  ioPod("p", `{"nvme": {"rbps": "60Mi", "blocksize": "1Mi"}}`),
+			expectedCode: map[string]framework.Code{
+				"node-1": framework.Success,
+				"node-2": framework.Success,
+				"node-3": framework.UnschedulableAndUnresolvable,
+				"node-4": framework.UnschedulableAndUnresolvable,
+			},
+		},
+		{
+			name:     "insufficient once reserved",
+			pod:      ioPod("p", `{"nvme": {"rbps": "60Mi", "blocksize": "1Mi"}}`),
+			reserved: []*v1.Pod{ioPod("r", `{"nvme": {"rbps": "50Mi", "blocksize": "1Mi"}}`)},
+			expectedCode: map[string]framework.Code{
+				"node-1": framework.Unschedulable,
+				"node-2": framework.Success,
+				"node-3": framework.UnschedulableAndUnresolvable,
+				"node-4": framework.UnschedulableAndUnresolvable,
+			},
+		},
+		{
+			name: "insufficient operations with small blocks",
+			pod:  ioPod("p", `{"nvme": {"wbps": "8Mi", "blocksize": "4Ki"}}`),
+			expectedCode: map[string]framework.Code{
+				"node-1": framework.Unschedulable,
+				"node-2": framework.Success,
+				"node-3": framework.UnschedulableAndUnresolvable,
+				"node-4": framework.UnschedulableAndUnresolvable,
+			},
+		},
+		{
+			name: "several device classes",
+			pod:  ioPod("p", `{"nvme": {"rbps": "1Mi"}, "hdd": {"wbps": "1Mi"}}`),
+			expectedCode: map[string]framework.Code{
+				"node-1": framework.UnschedulableAndUnresolvable,
+				"node-2": framework.UnschedulableAndUnresolvable,
+				"node-3": framework.UnschedulableAndUnresolvable,
+				"node-4": framework.UnschedulableAndUnresolvable,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			pl := newTestPlugin(ctx, t, &config.DiskIOAwareArgs{ScoringStrategy: config.LeastAllocated, Estimator: BlockSizeEstimatorName}, nodes)
+			for _, pod := range tt.reserved {
+				state := framework.NewCycleState()
+				_, status := pl.PreFilter(ctx, state, pod)
+				assert.True(t, status.IsSuccess())
+				assert.True(t, pl.Reserve(ctx, state, pod, "node-1").IsSuccess())
+			}
+
+			state := framework.NewCycleState()
+			_, status := pl.PreFilter(ctx, state, tt.pod)
+			assert.True(t, status.IsSuccess())
+			for _, node := range nodes {
+				nodeInfo := framework.NewNodeInfo()
+				nodeInfo.SetNode(node)
+				status := pl.Filter(ctx, state, tt.pod, nodeInfo)
+				assert.Equal(t, tt.expectedCode[node.Name], status.Code(), node.Name)
+			}
+		})
+	}
+}
+
+func TestPreFilter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pl := newTestPlugin(ctx, t, &config.DiskIOAwareArgs{ScoringStrategy: config.LeastAllocated, Estimator: BlockSizeEstimatorName}, nil)
+
+	_, status := pl.PreFilter(ctx, framework.NewCycleState(), ioPod("p", ""))
+	assert.Equal(t, framework.Skip, status.Code())
+	_, status = pl.PreFilter(ctx, framework.NewCycleState(), ioPod("p", `{"nvme": {}}`))
+	assert.Equal(t, framework.Skip, status.Code())
+	_, status = pl.PreFilter(ctx, framework.NewCycleState(), ioPod("p", `{"nvme": {"rbps": "fast"}}`))
+	assert.Equal(t, framework.UnschedulableAndUnresolvable, status.Code())
+}
+
+func TestScore(t *testing.T) {
+	nodes := []*v1.Node{
+		st.MakeNode().Name("node-1").Obj(),
+		st.MakeNode().Name("node-2").Obj(),
+		st.MakeNode().Name("node-4").Obj(),
+	}
+	pod := ioPod("p", `{"nvme": {"rbps": "40Mi", "wbps": "80Mi", "blocksize": "1Mi"}}`)
+	reserved := ioPod("r", `{"nvme": {"rbps": "30Mi", "blocksize": "1Mi"}}`)
+	tests := []struct {
+		name     string
+		strategy config.ScoringStrategyType
+		expected map[string]int64
+	}{
+		{
+			name:     "least allocated",
+			strategy: config.LeastAllocated,
+			// node-1: rbps 70%, wbps 80%, riops 7%, wiops 8%
+			expected: map[string]int64{"node-1": 59, "node-2": 92, "node-4": 0},
+		},
+		{
+			name:     "most allocated",
+			strategy: config.MostAllocated,
+			expected: map[string]int64{"node-1": 41, "node-2": 8, "node-4": 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			pl := newTestPlugin(ctx, t, &config.DiskIOAwareArgs{ScoringStrategy: tt.strategy, Estimator: BlockSizeEstimatorName}, nodes)
+			state := framework.NewCycleState()
+			pl.PreFilter(ctx, state, reserved)
+			pl.Reserve(ctx, state, reserved, "node-1")
+
+			state = framework.NewCycleState()
+			pl.PreFilter(ctx, state, pod)
+			for _, node := range nodes {
+				score, status := pl.Score(ctx, state, pod, node.Name)
+				assert.True(t, status.IsSuccess())
+				assert.Equal(t, tt.expected[node.Name], score, node.Name)
+			}
+		})
+	}
+}
+
+func TestReserve(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pl := newTestPlugin(ctx, t, &config.DiskIOAwareArgs{ScoringStrategy: config.LeastAllocated, Estimator: BlockSizeEstimatorName}, nil)
+	nodeInfo := framework.NewNodeInfo()
+	nodeInfo.SetNode(st.MakeNode().Name("node-1").Obj())
+
+	pod := ioPod("p", `{"nvme": {"wbps": "60Mi", "blocksize": "1Mi"}}`)
+	state := framework.NewCycleState()
+	pl.PreFilter(ctx, state, pod)
+	assert.True(t, pl.Reserve(ctx, state, pod, "node-1").IsSuccess())
+	// reserving again doesn't count twice
+	assert.True(t, pl.Reserve(ctx, state, pod, "node-1").IsSuccess())
+	assert.Equal(t, map[string]IOBandwidth{"nvme": {WriteBPS: 60 << 20, WriteIOPS: 60}}, pl.cache.allocated("node-1"))
+
+	other := ioPod("o", `{"nvme": {"wbps": "60Mi", "blocksize": "1Mi"}}`)
+	otherState := framework.NewCycleState()
+	pl.PreFilter(ctx, otherState, other)
+	assert.Equal(t, framework.Unschedulable, pl.Filter(ctx, otherState, other, nodeInfo).Code())
+
+	pl.Unreserve(ctx, state, pod, "node-1")
+	assert.Empty(t, pl.cache.allocated("node-1"))
+	assert.True(t, pl.Filter(ctx, otherState, other, nodeInfo).IsSuccess())
+}
+
+func TestPodEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pl := newTestPlugin(ctx, t, &config.DiskIOAwareArgs{ScoringStrategy: config.LeastAllocated, Estimator: BlockSizeEstimatorName}, nil)
+	allocated := map[string]IOBandwidth{"nvme": {ReadBPS: 10 << 20, ReadIOPS: 10}}
+
+	pod := ioPod("p", `{"nvme": {"rbps": "10Mi", "blocksize": "1Mi"}}`)
+	pod.Spec.NodeName = "node-1"
+	pl.updatePod(pod)
+	assert.Equal(t, allocated, pl.cache.allocated("node-1"))
+
+	// released once terminated
+	terminated := pod.DeepCopy()
+	terminated.Status.Phase = v1.PodSucceeded
+	pl.updatePod(terminated)
+	assert.Empty(t, pl.cache.allocated("node-1"))
+
+	// or deleted
+	pl.updatePod(pod)
+	assert.Equal(t, allocated, pl.cache.allocated("node-1"))
+	pl.deletePod(cache.DeletedFinalStateUnknown{Key: "default/p", Obj: pod})
+	assert.Empty(t, pl.cache.allocated("node-1"))
+
+	// pods without request aren't tracked
+	pl.updatePod(ioPod("q", ""))
+	assert.Empty(t, pl.cache.pods)
+}
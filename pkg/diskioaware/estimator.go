@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diskioaware
+
+import (
+	"sync"
+)
+
+const (
+	// BlockSizeEstimatorName is the name of the default estimator
+	BlockSizeEstimatorName = "BlockSize"
+	// block size assumed for the requests which do not specify it
+	defaultBlockSize = 4 * 1024
+)
+
+// Estimator : normalize the disk IO requested by a pod to the capacity of the devices of a class, as reported by the
+// IO driver. The capacity of a disk depends on the block size and read/write mix of the workloads running on it, in
+// a way specific to the model of the disk, so that the vendors plug in the estimators matching their IO driver.
+type Estimator interface {
+	// Name : name of the estimator, referred to by the plugin args
+	Name() string
+	// EstimateRequest : the normalized bandwidth the request needs on the devices of the class
+	EstimateRequest(deviceClass string, request IORequest) (IOBandwidth, error)
+}
+
+var (
+	estimatorsLock sync.RWMutex
+	estimators     = make(map[string]Estimator)
+)
+
+func init() {
+	RegisterEstimator(blockSizeEstimator{})
+}
+
+// RegisterEstimator : make an estimator available to the plugin under its name, replacing any estimator of the
+// same name. The schedulers built with their own estimators register them before the plugin is created.
+func RegisterEstimator(estimator Estimator) {
+	estimatorsLock.Lock()
+	defer estimatorsLock.Unlock()
+	estimators[estimator.Name()] = estimator
+}
+
+// getEstimator : the estimator registered under a name, and whether it exists
+func getEstimator(name string) (Estimator, bool) {
+	estimatorsLock.RLock()
+	defer estimatorsLock.RUnlock()
+	estimator, ok := estimators[name]
+	return estimator, ok
+}
+
+// blockSizeEstimator : derive the operations from the throughput, and conversely, with the block size of the
+// request, so that the request is accounted against both the throughput and operations capacity of the devices
+type blockSizeEstimator struct{}
+
+var _ Estimator = blockSizeEstimator{}
+
+func (blockSizeEstimator) Name() string {
+	return BlockSizeEstimatorName
+}
+
+func (blockSizeEstimator) EstimateRequest(deviceClass string, request IORequest) (IOBandwidth, error) {
+	blockSize := request.BlockSize
+	if blockSize <= 0 {
+		blockSize = defaultBlockSize
+	}
+	estimate := func(bps, iops int64) (int64, int64) {
+		// ceiling, for a partial block still takes an operation
+		return maxInt64(bps, iops*blockSize), maxInt64(iops, (bps+blockSize-1)/blockSize)
+	}
+	var bandwidth IOBandwidth
+	bandwidth.ReadBPS, bandwidth.ReadIOPS = estimate(request.ReadBPS, request.ReadIOPS)
+	bandwidth.WriteBPS, bandwidth.WriteIOPS = estimate(request.WriteBPS, request.WriteIOPS)
+	return bandwidth, nil
+}
+
+func maxInt64(x, y int64) int64 {
+	if x > y {
+		return x
+	}
+	return y
+}
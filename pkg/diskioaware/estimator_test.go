@@ -0,0 +1,61 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diskioaware
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockSizeEstimator(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  IORequest
+		expected IOBandwidth
+	}{
+		{
+			name:     "operations from throughput",
+			request:  IORequest{IOBandwidth: IOBandwidth{ReadBPS: 1 << 20, WriteBPS: 1 << 20}, BlockSize: 64 << 10},
+			expected: IOBandwidth{ReadBPS: 1 << 20, WriteBPS: 1 << 20, ReadIOPS: 16, WriteIOPS: 16},
+		},
+		{
+			name:     "throughput from operations",
+			request:  IORequest{IOBandwidth: IOBandwidth{ReadIOPS: 100}, BlockSize: 1 << 10},
+			expected: IOBandwidth{ReadBPS: 100 << 10, ReadIOPS: 100},
+		},
+		{
+			name:     "default block size, partial block",
+			request:  IORequest{IOBandwidth: IOBandwidth{WriteBPS: 4097}},
+			expected: IOBandwidth{WriteBPS: 4097, WriteIOPS: 2},
+		},
+		{
+			name:     "the larger of the requested and derived",
+			request:  IORequest{IOBandwidth: IOBandwidth{ReadBPS: 8 << 10, ReadIOPS: 10, WriteBPS: 40 << 10, WriteIOPS: 1}, BlockSize: 4 << 10},
+			expected: IOBandwidth{ReadBPS: 40 << 10, ReadIOPS: 10, WriteBPS: 40 << 10, WriteIOPS: 10},
+		},
+	}
+	estimator, ok := getEstimator(BlockSizeEstimatorName)
+	assert.True(t, ok)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bandwidth, err := estimator.EstimateRequest("nvme", tt.request)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, bandwidth)
+		})
+	}
+}
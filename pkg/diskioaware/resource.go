@@ -0,0 +1,147 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diskioaware
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// PodIORequestAnnotation is the annotation of the pods holding their disk IO requests by device class, e.g.
+	// {"nvme": {"rbps": "20M", "wbps": "30M", "blocksize": "4k"}}
+	PodIORequestAnnotation = "blockio.kubernetes.io/throughput"
+	// NodeIOCapacityAnnotation is the annotation of the nodes holding the normalized disk IO capacity of their
+	// devices by device class, maintained by the IO driver, e.g. {"nvme": {"rbps": "2G", "wbps": "1G"}}
+	NodeIOCapacityAnnotation = "blockio.kubernetes.io/capacity"
+)
+
+// IOBandwidth : disk IO throughput, in bytes per second, and operations per second, of reads and writes
+type IOBandwidth struct {
+	ReadBPS   int64
+	WriteBPS  int64
+	ReadIOPS  int64
+	WriteIOPS int64
+}
+
+// IORequest : disk IO requested by a pod on the devices of a class
+type IORequest struct {
+	IOBandwidth
+	// size of the blocks read and written, in bytes, zero if unknown
+	BlockSize int64
+}
+
+// Add : add the bandwidth
+func (b *IOBandwidth) Add(other IOBandwidth) {
+	b.ReadBPS += other.ReadBPS
+	b.WriteBPS += other.WriteBPS
+	b.ReadIOPS += other.ReadIOPS
+	b.WriteIOPS += other.WriteIOPS
+}
+
+// IsZero : whether no bandwidth is requested
+func (b IOBandwidth) IsZero() bool {
+	return b == IOBandwidth{}
+}
+
+// dimensions : the throughput and operations of reads and writes, in the same order for all bandwidths
+func (b IOBandwidth) dimensions() [4]int64 {
+	return [4]int64{b.ReadBPS, b.WriteBPS, b.ReadIOPS, b.WriteIOPS}
+}
+
+// Fits : whether the bandwidth fits in the capacity once the allocated one is taken
+func (b IOBandwidth) Fits(allocated, capacity IOBandwidth) bool {
+	requested, used, total := b.dimensions(), allocated.dimensions(), capacity.dimensions()
+	for i := range requested {
+		if requested[i] > 0 && used[i]+requested[i] > total[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Utilization : the average fraction of the capacity allocated once the bandwidth is added to the allocated one,
+// over the dimensions of the bandwidth requested
+func (b IOBandwidth) Utilization(allocated, capacity IOBandwidth) float64 {
+	requested, used, total := b.dimensions(), allocated.dimensions(), capacity.dimensions()
+	var sum float64
+	var count int
+	for i := range requested {
+		if requested[i] <= 0 {
+			continue
+		}
+		count++
+		if total[i] <= 0 {
+			sum++
+			continue
+		}
+		sum += math.Min(float64(used[i]+requested[i])/float64(total[i]), 1)
+	}
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+// ioSpec : disk IO of a device class in the annotations, as quantities
+type ioSpec struct {
+	ReadBPS   string `json:"rbps,omitempty"`
+	WriteBPS  string `json:"wbps,omitempty"`
+	ReadIOPS  string `json:"riops,omitempty"`
+	WriteIOPS string `json:"wiops,omitempty"`
+	BlockSize string `json:"blocksize,omitempty"`
+}
+
+// parseIORequests : parse the disk IO by device class of an annotation
+func parseIORequests(annotation string) (map[string]IORequest, error) {
+	var specs map[string]ioSpec
+	if err := json.Unmarshal([]byte(annotation), &specs); err != nil {
+		return nil, err
+	}
+	requests := make(map[string]IORequest, len(specs))
+	for deviceClass, spec := range specs {
+		var request IORequest
+		for _, field := range []struct {
+			name  string
+			value string
+			into  *int64
+		}{
+			{"rbps", spec.ReadBPS, &request.ReadBPS},
+			{"wbps", spec.WriteBPS, &request.WriteBPS},
+			{"riops", spec.ReadIOPS, &request.ReadIOPS},
+			{"wiops", spec.WriteIOPS, &request.WriteIOPS},
+			{"blocksize", spec.BlockSize, &request.BlockSize},
+		} {
+			if field.value == "" {
+				continue
+			}
+			q, err := resource.ParseQuantity(field.value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s of device class %q: %w", field.name, deviceClass, err)
+			}
+			if q.Sign() < 0 {
+				return nil, fmt.Errorf("invalid %s of device class %q: must not be negative", field.name, deviceClass)
+			}
+			*field.into = q.Value()
+		}
+		requests[deviceClass] = request
+	}
+	return requests, nil
+}
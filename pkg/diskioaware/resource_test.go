@@ -0,0 +1,156 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diskioaware
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIORequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		expected   map[string]IORequest
+		wantErr    bool
+	}{
+		{
+			name:       "throughput and operations",
+			annotation: `{"nvme": {"rbps": "20Mi", "wbps": "10M", "riops": "100", "wiops": "50"}}`,
+			expected: map[string]IORequest{
+				"nvme": {IOBandwidth: IOBandwidth{ReadBPS: 20 * 1024 * 1024, WriteBPS: 10000000, ReadIOPS: 100, WriteIOPS: 50}},
+			},
+		},
+		{
+			name:       "block size and several device classes",
+			annotation: `{"nvme": {"rbps": "1Mi", "blocksize": "4Ki"}, "hdd": {"wiops": "10"}}`,
+			expected: map[string]IORequest{
+				"nvme": {IOBandwidth: IOBandwidth{ReadBPS: 1024 * 1024}, BlockSize: 4096},
+				"hdd":  {IOBandwidth: IOBandwidth{WriteIOPS: 10}},
+			},
+		},
+		{
+			name:       "empty",
+			annotation: `{}`,
+			expected:   map[string]IORequest{},
+		},
+		{
+			name:       "invalid json",
+			annotation: `{"nvme": "20M"}`,
+			wantErr:    true,
+		},
+		{
+			name:       "invalid quantity",
+			annotation: `{"nvme": {"rbps": "fast"}}`,
+			wantErr:    true,
+		},
+		{
+			name:       "negative quantity",
+			annotation: `{"nvme": {"wbps": "-1M"}}`,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests, err := parseIORequests(tt.annotation)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, requests)
+		})
+	}
+}
+
+func TestIOBandwidthFits(t *testing.T) {
+	capacity := IOBandwidth{ReadBPS: 100, WriteBPS: 100, ReadIOPS: 10, WriteIOPS: 10}
+	tests := []struct {
+		name      string
+		requested IOBandwidth
+		allocated IOBandwidth
+		expected  bool
+	}{
+		{
+			name:      "fits",
+			requested: IOBandwidth{ReadBPS: 50, ReadIOPS: 5},
+			allocated: IOBandwidth{ReadBPS: 50, ReadIOPS: 5},
+			expected:  true,
+		},
+		{
+			name:      "insufficient throughput",
+			requested: IOBandwidth{WriteBPS: 60},
+			allocated: IOBandwidth{WriteBPS: 50},
+			expected:  false,
+		},
+		{
+			name:      "insufficient operations",
+			requested: IOBandwidth{ReadBPS: 10, WriteIOPS: 1},
+			allocated: IOBandwidth{WriteIOPS: 10},
+			expected:  false,
+		},
+		{
+			name:      "dimensions not requested are ignored",
+			requested: IOBandwidth{ReadBPS: 10},
+			allocated: IOBandwidth{WriteBPS: 200},
+			expected:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.requested.Fits(tt.allocated, capacity))
+		})
+	}
+}
+
+func TestIOBandwidthUtilization(t *testing.T) {
+	capacity := IOBandwidth{ReadBPS: 100, WriteBPS: 100, ReadIOPS: 10}
+	tests := []struct {
+		name      string
+		requested IOBandwidth
+		allocated IOBandwidth
+		expected  float64
+	}{
+		{
+			name:      "average of the dimensions requested",
+			requested: IOBandwidth{ReadBPS: 20, WriteBPS: 40},
+			allocated: IOBandwidth{ReadBPS: 30, ReadIOPS: 10},
+			expected:  0.45,
+		},
+		{
+			name:      "over capacity",
+			requested: IOBandwidth{ReadBPS: 80},
+			allocated: IOBandwidth{ReadBPS: 80},
+			expected:  1,
+		},
+		{
+			name:      "without capacity",
+			requested: IOBandwidth{ReadBPS: 20, WriteIOPS: 1},
+			expected:  0.6,
+		},
+		{
+			name:     "nothing requested",
+			expected: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, tt.requested.Utilization(tt.allocated, capacity), 1e-9)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diskioaware
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// MetricsSource : the normalized disk IO capacity of the devices of the nodes, by device class. It's measured by
+// the IO driver of the nodes, which keeps it up to date with the characteristics of the workloads running on them.
+type MetricsSource interface {
+	// NodeCapacity : the capacity of the node by device class, and whether the node reports it
+	NodeCapacity(node *v1.Node) (map[string]IOBandwidth, bool)
+}
+
+// annotationSource : read the capacity of the nodes from their annotation, maintained by the IO driver
+type annotationSource struct{}
+
+var _ MetricsSource = annotationSource{}
+
+func (annotationSource) NodeCapacity(node *v1.Node) (map[string]IOBandwidth, bool) {
+	annotation, ok := node.Annotations[NodeIOCapacityAnnotation]
+	if !ok {
+		return nil, false
+	}
+	capacities, err := parseIORequests(annotation)
+	if err != nil {
+		klog.ErrorS(err, "Invalid disk IO capacity of node", "node", klog.KObj(node))
+		return nil, false
+	}
+	capacity := make(map[string]IOBandwidth, len(capacities))
+	for deviceClass, c := range capacities {
+		capacity[deviceClass] = c.IOBandwidth
+	}
+	return capacity, true
+}
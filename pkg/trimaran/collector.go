@@ -17,48 +17,130 @@ limitations under the License.
 package trimaran
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/paypal/load-watcher/pkg/watcher"
 	loadwatcherapi "github.com/paypal/load-watcher/pkg/watcher/api"
 
+	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 
 	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
 )
 
 const (
 	metricsUpdateIntervalSeconds = 30
+	// maximum size of the pushed load metrics
+	maxPushedMetricsBytes = 64 << 20
 )
 
 // Collector : get data from load watcher, encapsulating the load watcher and its operations
 //
+// The metrics are either polled from the load watcher, or pushed by it to an endpoint of the scheduler,
+// as soon as they are produced, with the Push ingestion. The endpoint only serves TLS, and authenticates the
+// pushing agents by a bearer token, a client certificate, or both.
+//
 // Trimaran plugins have different, potentially conflicting, objectives. Thus, it is recommended not
-// to enable them concurrently. As such, they are currently designed to each have its own Collector.
-// If a need arises in the future to enable multiple Trimaran plugins, a restructuring to have a single
-// Collector, serving the multiple plugins, may be beneficial for performance reasons.
+// to enable them concurrently. Still, the plugins of all the profiles which use the same TrimaranSpec
+// share a single Collector, acquired through AcquireCollector, so that the metrics are fetched once.
 type Collector struct {
-	// load watcher client
+	// load watcher client, nil with the Push ingestion
 	client loadwatcherapi.Client
+	// server of the endpoint accepting the pushed metrics, nil with the Poll ingestion
+	pushServer *http.Server
+	// listener of the push server
+	pushListener net.Listener
+	// bearer token the pushing agents must present, nil if they're only authenticated by their certificate
+	pushToken []byte
+	// interval of the periodic updates
+	updateInterval time.Duration
 	// data collected by load watcher
 	metrics watcher.WatcherMetrics
 	// for safe access to metrics
 	mu sync.RWMutex
+	// closed once the collector is stopped
+	done <-chan struct{}
+	// cancel the context of the periodic updates
+	cancel context.CancelFunc
+	// to clean up once
+	stopOnce sync.Once
+	// age of the metrics window, in seconds, beyond which the metrics are stale; zero disables the detection
+	stalenessSeconds int64
+	// how the nodes whose metrics are missing or stale are scored
+	fallbackMode pluginConfig.FallbackMode
+	// whether the metrics are missing or stale, guarded by mu
+	degraded bool
+	// forecasters fed with the metrics, guarded by mu
+	forecasters map[*Forecaster]struct{}
 }
 
-// NewCollector : create an instance of a data collector
-func NewCollector(trimaranSpec *pluginConfig.TrimaranSpec) (*Collector, error) {
+// NewCollector : create an instance of a data collector, updating the metrics until the context is done or the
+// collector is stopped. The kube config is used by the Kubelet metric provider.
+func NewCollector(ctx context.Context, trimaranSpec *pluginConfig.TrimaranSpec, kubeConfig *rest.Config) (*Collector, error) {
 	if err := checkSpecs(trimaranSpec); err != nil {
 		return nil, err
 	}
 	klog.V(4).InfoS("Using TrimaranSpec", "type", trimaranSpec.MetricProvider.Type,
-		"address", trimaranSpec.MetricProvider.Address, "watcher", trimaranSpec.WatcherAddress)
+		"address", trimaranSpec.MetricProvider.Address, "watcher", trimaranSpec.WatcherAddress,
+		"ingestion", trimaranSpec.MetricsIngestion, "pushAddress", trimaranSpec.MetricsPushAddress)
+
+	updateInterval := time.Duration(trimaranSpec.MetricsUpdateIntervalSeconds) * time.Second
+	if updateInterval == 0 {
+		updateInterval = metricsUpdateIntervalSeconds * time.Second
+	}
+	collector := &Collector{
+		updateInterval:   updateInterval,
+		stalenessSeconds: trimaranSpec.MetricsStalenessSeconds,
+		fallbackMode:     trimaranSpec.FallbackMode,
+		forecasters:      make(map[*Forecaster]struct{}),
+	}
+	RegisterMetrics()
 
-	var client loadwatcherapi.Client
-	if trimaranSpec.WatcherAddress != "" {
-		client, _ = loadwatcherapi.NewServiceClient(trimaranSpec.WatcherAddress)
+	if trimaranSpec.MetricsIngestion == pluginConfig.PushIngestion {
+		tlsConfig, err := pushTLSConfig(trimaranSpec)
+		if err != nil {
+			return nil, err
+		}
+		if trimaranSpec.MetricsPushTokenFile != "" {
+			token, err := os.ReadFile(trimaranSpec.MetricsPushTokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read the push token: %w", err)
+			}
+			if collector.pushToken = bytes.TrimSpace(token); len(collector.pushToken) == 0 {
+				return nil, fmt.Errorf("empty push token in %v", trimaranSpec.MetricsPushTokenFile)
+			}
+		}
+		listener, err := net.Listen("tcp", trimaranSpec.MetricsPushAddress)
+		if err != nil {
+			return nil, fmt.Errorf("unable to listen for pushed metrics: %w", err)
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc(watcher.BaseUrl, collector.handlePush)
+		collector.pushListener = tls.NewListener(listener, tlsConfig)
+		collector.pushServer = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	} else if trimaranSpec.WatcherAddress != "" {
+		collector.client, _ = loadwatcherapi.NewServiceClient(trimaranSpec.WatcherAddress)
+	} else if trimaranSpec.MetricProvider.Type == pluginConfig.Kubelet {
+		client, err := newKubeletClient(kubeConfig, time.Duration(trimaranSpec.MetricsStalenessSeconds)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		collector.client = client
 	} else {
 		opts := watcher.MetricsProviderOpts{
 			Name:               string(trimaranSpec.MetricProvider.Type),
@@ -66,29 +148,127 @@ func NewCollector(trimaranSpec *pluginConfig.TrimaranSpec) (*Collector, error) {
 			AuthToken:          trimaranSpec.MetricProvider.Token,
 			InsecureSkipVerify: trimaranSpec.MetricProvider.InsecureSkipVerify,
 		}
-		client, _ = loadwatcherapi.NewLibraryClient(opts)
+		collector.client, _ = loadwatcherapi.NewLibraryClient(opts)
 	}
 
-	collector := &Collector{
-		client: client,
-	}
-
-	// populate metrics before returning
-	err := collector.updateMetrics()
-	if err != nil {
-		klog.ErrorS(err, "Unable to populate metrics initially")
+	ctx, collector.cancel = context.WithCancel(ctx)
+	collector.done = ctx.Done()
+	if collector.pushServer != nil {
+		// no metrics until the first push
+		collector.setMetrics(nil)
+		go func() {
+			if err := collector.pushServer.Serve(collector.pushListener); !errors.Is(err, http.ErrServerClosed) {
+				klog.ErrorS(err, "Unable to serve pushed metrics")
+			}
+		}()
+	} else {
+		// populate metrics before returning
+		err := collector.updateMetrics()
+		if err != nil {
+			klog.ErrorS(err, "Unable to populate metrics initially")
+		}
 	}
 	// start periodic updates
-	go func() {
-		metricsUpdaterTicker := time.NewTicker(time.Second * metricsUpdateIntervalSeconds)
-		for range metricsUpdaterTicker.C {
-			err = collector.updateMetrics()
+	go collector.run(ctx)
+	return collector, nil
+}
+
+// run : update the metrics periodically until the context is done. The pushed metrics are not polled, but
+// still checked for staleness.
+func (collector *Collector) run(ctx context.Context) {
+	metricsUpdaterTicker := time.NewTicker(collector.updateInterval)
+	defer metricsUpdaterTicker.Stop()
+	for {
+		select {
+		case <-metricsUpdaterTicker.C:
+			if collector.client == nil {
+				collector.setMetrics(nil)
+				continue
+			}
+			err := collector.updateMetrics()
 			if err != nil {
 				klog.ErrorS(err, "Unable to update metrics")
 			}
+		case <-ctx.Done():
+			collector.stop()
+			return
 		}
-	}()
-	return collector, nil
+	}
+}
+
+// stop : stop the updates of the metrics
+func (collector *Collector) stop() {
+	collector.cancel()
+	collector.stopOnce.Do(func() {
+		if collector.pushServer != nil {
+			if err := collector.pushServer.Close(); err != nil {
+				klog.ErrorS(err, "Unable to close the pushed metrics server")
+			}
+		}
+		collector.mu.Lock()
+		defer collector.mu.Unlock()
+		if collector.degraded {
+			collector.degraded = false
+			degradedCollectors.Dec()
+		}
+	})
+}
+
+// pushTLSConfig : the TLS config of the endpoint accepting the pushed metrics, which verifies the client certificates
+// if a client CA is set
+func pushTLSConfig(trimaranSpec *pluginConfig.TrimaranSpec) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(trimaranSpec.MetricsPushCertFile, trimaranSpec.MetricsPushKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the push certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if trimaranSpec.MetricsPushClientCAFile != "" {
+		caPEM, err := os.ReadFile(trimaranSpec.MetricsPushClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the push client CA: %w", err)
+		}
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificate in the push client CA %v", trimaranSpec.MetricsPushClientCAFile)
+		}
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// authorizedPush : check the bearer token of a push, if one is required
+func (collector *Collector) authorizedPush(req *http.Request) bool {
+	if collector.pushToken == nil {
+		return true
+	}
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), collector.pushToken) == 1
+}
+
+// handlePush : accept the metrics pushed in the load watcher format by an authorized agent
+func (collector *Collector) handlePush(resp http.ResponseWriter, req *http.Request) {
+	if !collector.authorizedPush(req) {
+		resp.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(resp, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if req.Method != http.MethodPost && req.Method != http.MethodPut {
+		resp.Header().Set("Allow", "POST, PUT")
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	metrics := &watcher.WatcherMetrics{}
+	if err := json.NewDecoder(http.MaxBytesReader(resp, req.Body, maxPushedMetricsBytes)).Decode(metrics); err != nil {
+		http.Error(resp, "unable to decode metrics: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if metrics.Data.NodeMetricsMap == nil {
+		http.Error(resp, "no node metrics", http.StatusBadRequest)
+		return
+	}
+	klog.V(6).InfoS("Received pushed metrics", "nodes", len(metrics.Data.NodeMetricsMap), "windowEnd", metrics.Window.End)
+	collector.setMetrics(metrics)
+	resp.WriteHeader(http.StatusOK)
 }
 
 // getAllMetrics : get all metrics from watcher
@@ -107,6 +287,11 @@ func (collector *Collector) GetNodeMetrics(nodeName string) ([]watcher.Metric, *
 		klog.ErrorS(nil, "Metrics not available from watcher")
 		return nil, nil
 	}
+	// This happens if metrics could not be updated for a while
+	if collector.isStale(allMetrics, time.Now()) {
+		klog.ErrorS(nil, "Metrics from watcher are stale", "windowEnd", time.Unix(allMetrics.Window.End, 0))
+		return nil, allMetrics
+	}
 	// Check if node is new (no metrics yet) or metrics are unavailable due to 404 or 500
 	if _, ok := allMetrics.Data.NodeMetricsMap[nodeName]; !ok {
 		klog.ErrorS(nil, "Unable to find metrics for node", "nodeName", nodeName)
@@ -115,17 +300,85 @@ func (collector *Collector) GetNodeMetrics(nodeName string) ([]watcher.Metric, *
 	return allMetrics.Data.NodeMetricsMap[nodeName].Metrics, allMetrics
 }
 
+// GetNodeMetricsOrAllocation : get metrics for a node from watcher. If they are missing or stale and the fallback
+// mode is Allocation, the metrics are derived from the requests of the pods on the node instead, and fromAllocation
+// is true.
+func (collector *Collector) GetNodeMetricsOrAllocation(nodeInfo *framework.NodeInfo) (metrics []watcher.Metric,
+	allMetrics *watcher.WatcherMetrics, fromAllocation bool) {
+	metrics, allMetrics = collector.GetNodeMetrics(nodeInfo.Node().Name)
+	if metrics != nil || collector.fallbackMode != pluginConfig.AllocationFallback {
+		return metrics, allMetrics, false
+	}
+	klog.V(6).InfoS("Deriving metrics for node from allocation", "nodeName", nodeInfo.Node().Name)
+	return AllocationMetrics(nodeInfo), allMetrics, true
+}
+
+// AllocationMetrics : derive the utilization of the node from the requests of its pods, in the form of the average
+// metrics reported by watcher
+func AllocationMetrics(nodeInfo *framework.NodeInfo) []watcher.Metric {
+	percent := func(requested, allocatable int64) float64 {
+		if allocatable <= 0 {
+			return 0
+		}
+		return math.Min(100*float64(requested)/float64(allocatable), 100)
+	}
+	return []watcher.Metric{
+		{
+			Type:     watcher.CPU,
+			Operator: watcher.Average,
+			Value:    percent(nodeInfo.Requested.MilliCPU, nodeInfo.Allocatable.MilliCPU),
+		},
+		{
+			Type:     watcher.Memory,
+			Operator: watcher.Average,
+			Value:    percent(nodeInfo.Requested.Memory, nodeInfo.Allocatable.Memory),
+		},
+	}
+}
+
+// isStale : check whether the metrics window ended longer ago than the staleness threshold
+func (collector *Collector) isStale(metrics *watcher.WatcherMetrics, now time.Time) bool {
+	return collector.stalenessSeconds > 0 && now.Unix()-metrics.Window.End > collector.stalenessSeconds
+}
+
 // checkSpecs : check trimaran specs
 func checkSpecs(trimaranSpec *pluginConfig.TrimaranSpec) error {
-	if trimaranSpec.WatcherAddress == "" {
+	switch trimaranSpec.MetricsIngestion {
+	case "", pluginConfig.PollIngestion:
+	case pluginConfig.PushIngestion:
+		if trimaranSpec.MetricsPushAddress == "" {
+			return fmt.Errorf("MetricsPushAddress is required with the Push MetricsIngestion")
+		}
+		if trimaranSpec.MetricsPushCertFile == "" || trimaranSpec.MetricsPushKeyFile == "" {
+			return fmt.Errorf("MetricsPushCertFile and MetricsPushKeyFile are required with the Push MetricsIngestion")
+		}
+		if trimaranSpec.MetricsPushTokenFile == "" && trimaranSpec.MetricsPushClientCAFile == "" {
+			return fmt.Errorf("MetricsPushTokenFile or MetricsPushClientCAFile is required with the Push MetricsIngestion")
+		}
+	default:
+		return fmt.Errorf("invalid MetricsIngestion, got %v", trimaranSpec.MetricsIngestion)
+	}
+	if trimaranSpec.MetricsIngestion != pluginConfig.PushIngestion && trimaranSpec.WatcherAddress == "" {
 		metricProviderType := string(trimaranSpec.MetricProvider.Type)
 		validMetricProviderType := metricProviderType == string(pluginConfig.KubernetesMetricsServer) ||
 			metricProviderType == string(pluginConfig.Prometheus) ||
-			metricProviderType == string(pluginConfig.SignalFx)
+			metricProviderType == string(pluginConfig.SignalFx) ||
+			metricProviderType == string(pluginConfig.Kubelet)
 		if !validMetricProviderType {
 			return fmt.Errorf("invalid MetricProvider.Type, got %v", trimaranSpec.MetricProvider.Type)
 		}
 	}
+	if trimaranSpec.MetricsUpdateIntervalSeconds < 0 {
+		return fmt.Errorf("invalid MetricsUpdateIntervalSeconds, got %v", trimaranSpec.MetricsUpdateIntervalSeconds)
+	}
+	if trimaranSpec.MetricsStalenessSeconds < 0 {
+		return fmt.Errorf("invalid MetricsStalenessSeconds, got %v", trimaranSpec.MetricsStalenessSeconds)
+	}
+	switch trimaranSpec.FallbackMode {
+	case "", pluginConfig.MinScoreFallback, pluginConfig.AllocationFallback:
+	default:
+		return fmt.Errorf("invalid FallbackMode, got %v", trimaranSpec.FallbackMode)
+	}
 	return nil
 }
 
@@ -133,11 +386,59 @@ func checkSpecs(trimaranSpec *pluginConfig.TrimaranSpec) error {
 func (collector *Collector) updateMetrics() error {
 	metrics, err := collector.client.GetLatestWatcherMetrics()
 	if err != nil {
+		collector.setMetrics(nil)
 		klog.ErrorS(err, "Load watcher client failed")
 		return err
 	}
-	collector.mu.Lock()
-	collector.metrics = *metrics
-	collector.mu.Unlock()
+	collector.setMetrics(metrics)
 	return nil
 }
+
+// setMetrics : store the metrics, if any, and record whether they are missing or stale
+func (collector *Collector) setMetrics(metrics *watcher.WatcherMetrics) {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if metrics != nil {
+		collector.metrics = *metrics
+		for f := range collector.forecasters {
+			f.observe(metrics)
+		}
+	}
+	collector.setDegraded(collector.metrics.Data.NodeMetricsMap == nil || collector.isStale(&collector.metrics, time.Now()))
+}
+
+// addForecaster : feed the forecaster with the current metrics, if any, and all the next ones
+func (collector *Collector) addForecaster(f *Forecaster) {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	collector.forecasters[f] = struct{}{}
+	f.observe(&collector.metrics)
+}
+
+// removeForecaster : stop feeding the forecaster
+func (collector *Collector) removeForecaster(f *Forecaster) {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	delete(collector.forecasters, f)
+}
+
+// setDegraded : record whether the metrics are missing or stale; mu must be held
+func (collector *Collector) setDegraded(degraded bool) {
+	select {
+	case <-collector.done:
+		// the collector is not counted anymore once stopped
+		return
+	default:
+	}
+	if degraded == collector.degraded {
+		return
+	}
+	collector.degraded = degraded
+	if degraded {
+		klog.InfoS("Load metrics are missing or stale; scoring nodes in fallback mode", "fallbackMode", collector.fallbackMode)
+		degradedCollectors.Inc()
+	} else {
+		klog.InfoS("Load metrics are fresh again")
+		degradedCollectors.Dec()
+	}
+}
@@ -17,14 +17,34 @@ limitations under the License.
 package trimaran
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/paypal/load-watcher/pkg/watcher"
 	"github.com/stretchr/testify/assert"
 
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/component-base/metrics/testutil"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+
 	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
 )
 
@@ -74,12 +94,18 @@ var (
 )
 
 func TestNewCollector(t *testing.T) {
-	col, err := NewCollector(&args)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	col, err := NewCollector(ctx, &args, nil)
 	assert.NotNil(t, col)
 	assert.Nil(t, err)
 }
 
 func TestNewCollectorSpecs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 		bytes, err := json.Marshal(watcherResponse)
 		assert.Nil(t, err)
@@ -95,13 +121,16 @@ func TestNewCollectorSpecs(t *testing.T) {
 		MetricProvider: metricProvider,
 	}
 
-	col, err := NewCollector(&trimaranSpec)
+	col, err := NewCollector(ctx, &trimaranSpec, nil)
 	assert.Nil(t, col)
 	expectedErr := "invalid MetricProvider.Type, got " + string(metricProvider.Type)
 	assert.EqualError(t, err, expectedErr)
 }
 
 func TestGetAllMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 		bytes, err := json.Marshal(watcherResponse)
 		assert.Nil(t, err)
@@ -112,7 +141,7 @@ func TestGetAllMetrics(t *testing.T) {
 	trimaranSpec := pluginConfig.TrimaranSpec{
 		WatcherAddress: server.URL,
 	}
-	collector, err := NewCollector(&trimaranSpec)
+	collector, err := NewCollector(ctx, &trimaranSpec, nil)
 	assert.NotNil(t, collector)
 	assert.Nil(t, err)
 
@@ -123,6 +152,9 @@ func TestGetAllMetrics(t *testing.T) {
 }
 
 func TestUpdateMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 		bytes, err := json.Marshal(watcherResponse)
 		assert.Nil(t, err)
@@ -133,7 +165,7 @@ func TestUpdateMetrics(t *testing.T) {
 	trimaranSpec := pluginConfig.TrimaranSpec{
 		WatcherAddress: server.URL,
 	}
-	collector, err := NewCollector(&trimaranSpec)
+	collector, err := NewCollector(ctx, &trimaranSpec, nil)
 	assert.NotNil(t, collector)
 	assert.Nil(t, err)
 
@@ -142,6 +174,9 @@ func TestUpdateMetrics(t *testing.T) {
 }
 
 func TestGetNodeMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 		bytes, err := json.Marshal(watcherResponse)
 		assert.Nil(t, err)
@@ -152,7 +187,7 @@ func TestGetNodeMetrics(t *testing.T) {
 	trimaranSpec := pluginConfig.TrimaranSpec{
 		WatcherAddress: server.URL,
 	}
-	collector, err := NewCollector(&trimaranSpec)
+	collector, err := NewCollector(ctx, &trimaranSpec, nil)
 	assert.NotNil(t, collector)
 	assert.Nil(t, err)
 	nodeName := "node-1"
@@ -164,6 +199,9 @@ func TestGetNodeMetrics(t *testing.T) {
 }
 
 func TestGetNodeMetricsNilForNode(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 		bytes, err := json.Marshal(noWatcherResponseForNode)
 		assert.Nil(t, err)
@@ -174,7 +212,7 @@ func TestGetNodeMetricsNilForNode(t *testing.T) {
 	trimaranSpec := pluginConfig.TrimaranSpec{
 		WatcherAddress: server.URL,
 	}
-	collector, err := NewCollector(&trimaranSpec)
+	collector, err := NewCollector(ctx, &trimaranSpec, nil)
 	assert.NotNil(t, collector)
 	assert.Nil(t, err)
 	nodeName := "node-1"
@@ -187,6 +225,9 @@ func TestGetNodeMetricsNilForNode(t *testing.T) {
 }
 
 func TestNewCollectorLoadWatcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 		bytes, err := json.Marshal(watcherResponse)
 		assert.Nil(t, err)
@@ -204,7 +245,342 @@ func TestNewCollectorLoadWatcher(t *testing.T) {
 		MetricProvider: metricProvider,
 	}
 
-	col, err := NewCollector(&trimaranSpec)
+	col, err := NewCollector(ctx, &trimaranSpec, nil)
 	assert.NotNil(t, col)
 	assert.Nil(t, err)
 }
+
+func TestGetNodeMetricsOrAllocation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	node := st.MakeNode().Name("node-1").Capacity(map[v1.ResourceName]string{
+		v1.ResourceCPU:    "2000m",
+		v1.ResourceMemory: "4Gi",
+	}).Obj()
+	nodeInfo := framework.NewNodeInfo(
+		st.MakePod().Name("p1").Req(map[v1.ResourceName]string{v1.ResourceCPU: "500m", v1.ResourceMemory: "1Gi"}).Obj(),
+		st.MakePod().Name("p2").Req(map[v1.ResourceName]string{v1.ResourceCPU: "1000m"}).Obj(),
+	)
+	nodeInfo.SetNode(node)
+	allocationMetrics := []watcher.Metric{
+		{Type: watcher.CPU, Operator: watcher.Average, Value: 75},
+		{Type: watcher.Memory, Operator: watcher.Average, Value: 25},
+	}
+
+	now := time.Now().Unix()
+	tests := []struct {
+		name               string
+		windowEnd          int64
+		nodeName           string
+		fallbackMode       pluginConfig.FallbackMode
+		wantMetrics        []watcher.Metric
+		wantFromAllocation bool
+		wantDegraded       bool
+	}{
+		{
+			name:         "fresh metrics",
+			windowEnd:    now,
+			nodeName:     "node-1",
+			fallbackMode: pluginConfig.AllocationFallback,
+			wantMetrics:  watcherResponse.Data.NodeMetricsMap["node-1"].Metrics,
+		},
+		{
+			name:         "stale metrics without fallback",
+			windowEnd:    now - 600,
+			nodeName:     "node-1",
+			fallbackMode: pluginConfig.MinScoreFallback,
+			wantDegraded: true,
+		},
+		{
+			name:               "stale metrics with allocation fallback",
+			windowEnd:          now - 600,
+			nodeName:           "node-1",
+			fallbackMode:       pluginConfig.AllocationFallback,
+			wantMetrics:        allocationMetrics,
+			wantFromAllocation: true,
+			wantDegraded:       true,
+		},
+		{
+			name:               "missing node metrics with allocation fallback",
+			windowEnd:          now,
+			nodeName:           "node-2",
+			fallbackMode:       pluginConfig.AllocationFallback,
+			wantMetrics:        allocationMetrics,
+			wantFromAllocation: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := watcherResponse
+			response.Window = watcher.Window{Start: tt.windowEnd - 15*60, End: tt.windowEnd}
+			response.Data = watcher.Data{NodeMetricsMap: map[string]watcher.NodeMetrics{
+				tt.nodeName: watcherResponse.Data.NodeMetricsMap["node-1"],
+			}}
+			server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+				bytes, err := json.Marshal(response)
+				assert.Nil(t, err)
+				resp.Write(bytes)
+			}))
+			defer server.Close()
+
+			before, err := testutil.GetGaugeMetricValue(degradedCollectors)
+			assert.Nil(t, err)
+			collector, err := NewCollector(ctx, &pluginConfig.TrimaranSpec{
+				WatcherAddress:          server.URL,
+				MetricsStalenessSeconds: 300,
+				FallbackMode:            tt.fallbackMode,
+			}, nil)
+			assert.Nil(t, err)
+			defer collector.stop()
+
+			metrics, _, fromAllocation := collector.GetNodeMetricsOrAllocation(nodeInfo)
+			assert.EqualValues(t, tt.wantMetrics, metrics)
+			assert.Equal(t, tt.wantFromAllocation, fromAllocation)
+
+			after, err := testutil.GetGaugeMetricValue(degradedCollectors)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantDegraded, after-before == 1)
+		})
+	}
+}
+
+func TestNewCollectorFallbackSpecs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := NewCollector(ctx, &pluginConfig.TrimaranSpec{WatcherAddress: args.WatcherAddress, FallbackMode: "Spread"}, nil)
+	assert.EqualError(t, err, "invalid FallbackMode, got Spread")
+	_, err = NewCollector(ctx, &pluginConfig.TrimaranSpec{WatcherAddress: args.WatcherAddress, MetricsStalenessSeconds: -1}, nil)
+	assert.EqualError(t, err, "invalid MetricsStalenessSeconds, got -1")
+}
+
+func TestCollectorUpdatesUntilContextDone(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		bytes, err := json.Marshal(watcherResponse)
+		assert.Nil(t, err)
+		resp.Write(bytes)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	collector, err := NewCollector(ctx, &pluginConfig.TrimaranSpec{WatcherAddress: server.URL, MetricsUpdateIntervalSeconds: 1}, nil)
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return requests.Load() >= 2 }, 3*time.Second, 10*time.Millisecond,
+		"expected the metrics to be updated every second")
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		select {
+		case <-collector.done:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond, "expected the collector to stop with its context")
+}
+
+// writePushCert : write a self-signed certificate and key for 127.0.0.1, valid for both servers and clients, to dir
+func writePushCert(t *testing.T, dir string) (certFile, keyFile string, certPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "trimaran-push"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	assert.Nil(t, os.WriteFile(certFile, certPEM, 0600))
+	assert.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile, certPEM
+}
+
+func TestPushedMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	certFile, keyFile, certPEM := writePushCert(t, dir)
+	tokenFile := filepath.Join(dir, "token")
+	assert.Nil(t, os.WriteFile(tokenFile, []byte("secret\n"), 0600))
+
+	before, err := testutil.GetGaugeMetricValue(degradedCollectors)
+	assert.Nil(t, err)
+	collector, err := NewCollector(ctx, &pluginConfig.TrimaranSpec{
+		MetricsIngestion:     pluginConfig.PushIngestion,
+		MetricsPushAddress:   "127.0.0.1:0",
+		MetricsPushCertFile:  certFile,
+		MetricsPushKeyFile:   keyFile,
+		MetricsPushTokenFile: tokenFile,
+	}, nil)
+	assert.Nil(t, err)
+	defer collector.stop()
+	assert.Nil(t, collector.client, "expected the pushed metrics not to be polled")
+	metrics, _ := collector.GetNodeMetrics("node-1")
+	assert.Nil(t, metrics)
+	degraded, err := testutil.GetGaugeMetricValue(degradedCollectors)
+	assert.Nil(t, err)
+	assert.Equal(t, before+1, degraded, "expected the collector to be degraded until the first push")
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(certPEM)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	url := "https://" + collector.pushListener.Addr().String() + watcher.BaseUrl
+	bytes, err := json.Marshal(watcherResponse)
+	assert.Nil(t, err)
+	push := func(token string) int {
+		req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(string(bytes)))
+		assert.Nil(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := client.Do(req)
+		assert.Nil(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	resp, err := http.Post("http://"+collector.pushListener.Addr().String()+watcher.BaseUrl, "application/json", strings.NewReader(string(bytes)))
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "expected plain HTTP to be refused")
+	assert.Equal(t, http.StatusUnauthorized, push("wrong"))
+	metrics, _ = collector.GetNodeMetrics("node-1")
+	assert.Nil(t, metrics, "expected the unauthorized push to be ignored")
+
+	assert.Equal(t, http.StatusOK, push("secret"))
+	metrics, _ = collector.GetNodeMetrics("node-1")
+	assert.EqualValues(t, watcherResponse.Data.NodeMetricsMap["node-1"].Metrics, metrics)
+	after, err := testutil.GetGaugeMetricValue(degradedCollectors)
+	assert.Nil(t, err)
+	assert.Equal(t, before, after, "expected the collector to recover with the pushed metrics")
+}
+
+func TestPushedMetricsClientCert(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	certFile, keyFile, certPEM := writePushCert(t, t.TempDir())
+	collector, err := NewCollector(ctx, &pluginConfig.TrimaranSpec{
+		MetricsIngestion:        pluginConfig.PushIngestion,
+		MetricsPushAddress:      "127.0.0.1:0",
+		MetricsPushCertFile:     certFile,
+		MetricsPushKeyFile:      keyFile,
+		MetricsPushClientCAFile: certFile,
+	}, nil)
+	assert.Nil(t, err)
+	defer collector.stop()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(certPEM)
+	url := "https://" + collector.pushListener.Addr().String() + watcher.BaseUrl
+	body := `{"data":{"NodeMetricsMap":{"node-1":{"metrics":[]}}}}`
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	_, err = client.Post(url, "application/json", strings.NewReader(body))
+	assert.NotNil(t, err, "expected a client without certificate to be refused")
+
+	clientCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	assert.Nil(t, err)
+	client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientCert}}}}
+	resp, err := client.Post(url, "application/json", strings.NewReader(body))
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestHandlePush(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		token      string
+		wantStatus int
+	}{
+		{
+			name:       "pushed metrics",
+			method:     http.MethodPost,
+			body:       `{"data":{"NodeMetricsMap":{"node-1":{"metrics":[]}}}}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "pushed metrics with the token",
+			method:     http.MethodPost,
+			body:       `{"data":{"NodeMetricsMap":{"node-1":{"metrics":[]}}}}`,
+			token:      "secret",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing token",
+			method:     http.MethodPost,
+			body:       `{"data":{"NodeMetricsMap":{"node-1":{"metrics":[]}}}}`,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong token",
+			method:     http.MethodPost,
+			body:       `{"data":{"NodeMetricsMap":{"node-1":{"metrics":[]}}}}`,
+			token:      "guess",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "get",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "malformed metrics",
+			method:     http.MethodPost,
+			body:       `{"data":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "no node metrics",
+			method:     http.MethodPut,
+			body:       `{"data":{}}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &Collector{done: make(chan struct{})}
+			req := httptest.NewRequest(tt.method, watcher.BaseUrl, strings.NewReader(tt.body))
+			if strings.Contains(tt.name, "token") {
+				collector.pushToken = []byte("secret")
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp := httptest.NewRecorder()
+			collector.handlePush(resp, req)
+			assert.Equal(t, tt.wantStatus, resp.Code)
+		})
+	}
+}
+
+func TestNewCollectorIngestionSpecs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := NewCollector(ctx, &pluginConfig.TrimaranSpec{MetricsIngestion: "Stream"}, nil)
+	assert.EqualError(t, err, "invalid MetricsIngestion, got Stream")
+	_, err = NewCollector(ctx, &pluginConfig.TrimaranSpec{MetricsIngestion: pluginConfig.PushIngestion}, nil)
+	assert.EqualError(t, err, "MetricsPushAddress is required with the Push MetricsIngestion")
+	_, err = NewCollector(ctx, &pluginConfig.TrimaranSpec{MetricsIngestion: pluginConfig.PushIngestion, MetricsPushAddress: "127.0.0.1:0"}, nil)
+	assert.EqualError(t, err, "MetricsPushCertFile and MetricsPushKeyFile are required with the Push MetricsIngestion")
+	_, err = NewCollector(ctx, &pluginConfig.TrimaranSpec{MetricsIngestion: pluginConfig.PushIngestion, MetricsPushAddress: "127.0.0.1:0",
+		MetricsPushCertFile: "tls.crt", MetricsPushKeyFile: "tls.key"}, nil)
+	assert.EqualError(t, err, "MetricsPushTokenFile or MetricsPushClientCAFile is required with the Push MetricsIngestion")
+	_, err = NewCollector(ctx, &pluginConfig.TrimaranSpec{WatcherAddress: args.WatcherAddress, MetricsUpdateIntervalSeconds: -1}, nil)
+	assert.EqualError(t, err, "invalid MetricsUpdateIntervalSeconds, got -1")
+}
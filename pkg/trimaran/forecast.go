@@ -0,0 +1,248 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trimaran
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/paypal/load-watcher/pkg/watcher"
+
+	"k8s.io/klog/v2"
+
+	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
+)
+
+const (
+	// maximum number of seasonal components of a series, bounding the memory of a daily season to 5 minute slots
+	maxSeasonSlots = 288
+	// the history of the nodes without metrics for longer, or for a season if longer, is forgotten
+	forecastRetention = time.Hour
+)
+
+// Forecaster : forecast the utilization of the nodes from the history of their load metrics
+//
+// A model is fitted online to the average utilization of each resource of each node, at every update of the
+// metrics of the collector: an exponentially weighted moving average (EWMA), or a Holt-Winters model with additive
+// trend and seasonality (HoltWinters), which anticipates the periodic peaks, daily ones for instance.
+type Forecaster struct {
+	spec pluginConfig.ForecastSpec
+	// width of the seasonal slots, the update interval of the metrics unless the season has too many of them
+	slotWidth time.Duration
+	// number of seasonal slots, zero without seasonality
+	seasonSlots int
+	// current time, overridden in tests
+	now func() time.Time
+	// for safe access to lastObserved and series
+	mu sync.Mutex
+	// time of the last metrics observed
+	lastObserved time.Time
+	// series by node and metric type
+	series map[seriesKey]*series
+}
+
+type seriesKey struct {
+	nodeName   string
+	metricType string
+}
+
+// series : state of the model fitted to the utilization of a resource of a node
+type series struct {
+	// time of the last observation
+	last time.Time
+	// smoothed utilization percent, without the seasonal component
+	level float64
+	// trend of the utilization, in percent per second
+	trend float64
+	// seasonal components of the utilization by slot of the season
+	seasonal []float64
+}
+
+// NewForecaster : create a forecaster fed with the metrics of the collector until the context is done
+func NewForecaster(ctx context.Context, collector *Collector, spec *pluginConfig.ForecastSpec) *Forecaster {
+	f := newForecaster(spec, collector.updateInterval)
+	collector.addForecaster(f)
+	releaseWhenDone(ctx, func() { collector.removeForecaster(f) })
+	return f
+}
+
+func newForecaster(spec *pluginConfig.ForecastSpec, updateInterval time.Duration) *Forecaster {
+	f := &Forecaster{
+		spec:      *spec,
+		slotWidth: updateInterval,
+		now:       time.Now,
+		series:    make(map[seriesKey]*series),
+	}
+	if f.slotWidth <= 0 {
+		f.slotWidth = metricsUpdateIntervalSeconds * time.Second
+	}
+	if spec.Model == pluginConfig.HoltWintersForecast && spec.SeasonPeriodSeconds > 0 {
+		period := time.Duration(spec.SeasonPeriodSeconds) * time.Second
+		if period > maxSeasonSlots*f.slotWidth {
+			f.slotWidth = period / maxSeasonSlots
+		}
+		f.seasonSlots = int((period + f.slotWidth - 1) / f.slotWidth)
+	}
+	klog.V(4).InfoS("Using ForecastSpec", "model", spec.Model, "alpha", spec.Alpha, "beta", spec.Beta,
+		"gamma", spec.Gamma, "seasonPeriodSeconds", spec.SeasonPeriodSeconds, "horizonSeconds", spec.HorizonSeconds,
+		"seasonSlots", f.seasonSlots)
+	return f
+}
+
+// observe : fit the models to the metrics, unless they were already observed
+func (f *Forecaster) observe(metrics *watcher.WatcherMetrics) {
+	if metrics == nil || metrics.Data.NodeMetricsMap == nil {
+		return
+	}
+	// the metrics of the load watchers not reporting their window are observed when received
+	t := f.now()
+	if metrics.Window.End > 0 {
+		t = time.Unix(metrics.Window.End, 0)
+	} else if metrics.Timestamp > 0 {
+		t = time.Unix(metrics.Timestamp, 0)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !t.After(f.lastObserved) {
+		return
+	}
+	f.lastObserved = t
+
+	for nodeName, nodeMetrics := range metrics.Data.NodeMetricsMap {
+		for _, metricType := range MetricTypes {
+			avg, _, found := GetResourceData(nodeMetrics.Metrics, metricType)
+			if !found {
+				continue
+			}
+			key := seriesKey{nodeName: nodeName, metricType: metricType}
+			s, ok := f.series[key]
+			if !ok {
+				s = &series{}
+				if f.seasonSlots > 0 {
+					s.seasonal = make([]float64, f.seasonSlots)
+				}
+				f.series[key] = s
+			}
+			f.fit(s, t, avg)
+		}
+	}
+	// forget the nodes which are gone
+	retention := forecastRetention
+	if period := time.Duration(f.spec.SeasonPeriodSeconds) * time.Second; f.seasonSlots > 0 && period > retention {
+		retention = period
+	}
+	for key, s := range f.series {
+		if t.Sub(s.last) > retention {
+			delete(f.series, key)
+		}
+	}
+}
+
+// fit : update the model of the series with the utilization observed at a time
+func (f *Forecaster) fit(s *series, t time.Time, value float64) {
+	if s.last.IsZero() {
+		s.level = value
+		s.last = t
+		return
+	}
+	elapsed := t.Sub(s.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	alpha := f.spec.Alpha
+	seasonal := f.seasonal(s, t)
+	level := alpha*(value-seasonal) + (1-alpha)*(s.level+s.trend*elapsed)
+	if f.spec.Model == pluginConfig.HoltWintersForecast {
+		beta, gamma := f.spec.Beta, f.spec.Gamma
+		s.trend = beta*(level-s.level)/elapsed + (1-beta)*s.trend
+		if s.seasonal != nil {
+			s.seasonal[f.slot(t)] = gamma*(value-level) + (1-gamma)*seasonal
+		}
+	}
+	s.level = level
+	s.last = t
+}
+
+// slot : the seasonal slot of a time
+func (f *Forecaster) slot(t time.Time) int {
+	period := int64(f.seasonSlots) * int64(f.slotWidth)
+	return int((t.UnixNano() % period) / int64(f.slotWidth))
+}
+
+// seasonal : the seasonal component of the series at a time, zero without seasonality
+func (f *Forecaster) seasonal(s *series, t time.Time) float64 {
+	if s.seasonal == nil {
+		return 0
+	}
+	return s.seasonal[f.slot(t)]
+}
+
+// forecast : the utilization percent of the series forecast at a time
+func (f *Forecaster) forecast(s *series, t time.Time) float64 {
+	value := s.level + s.trend*t.Sub(s.last).Seconds() + f.seasonal(s, t)
+	return math.Max(math.Min(value, 100), 0)
+}
+
+// Forecast : the peak utilization percent of a resource of a node forecast from now over the horizon, and whether
+// the node has a history for the metric type
+func (f *Forecaster) Forecast(nodeName string, metricType string) (float64, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[seriesKey{nodeName: nodeName, metricType: metricType}]
+	if !ok {
+		return 0, false
+	}
+	now := f.now()
+	end := now.Add(time.Duration(f.spec.HorizonSeconds) * time.Second)
+	// the trend is linear, so only the seasonal slots may peak within the horizon
+	peak := math.Max(f.forecast(s, now), f.forecast(s, end))
+	if s.seasonal != nil {
+		for t := now.Truncate(f.slotWidth).Add(f.slotWidth); t.Before(end); t = t.Add(f.slotWidth) {
+			peak = math.Max(peak, f.forecast(s, t))
+		}
+	}
+	return peak, true
+}
+
+// ForecastMetrics : the metrics of a node, with the average utilization of the resources replaced by their peak
+// forecast over the horizon. The resources without history, and the other metrics, are left as is.
+func (f *Forecaster) ForecastMetrics(nodeName string, metrics []watcher.Metric) []watcher.Metric {
+	forecastMetrics := make([]watcher.Metric, 0, len(metrics))
+	forecastTypes := make(map[string]float64)
+	for _, metric := range metrics {
+		if _, ok := forecastTypes[metric.Type]; !ok {
+			if value, found := f.Forecast(nodeName, metric.Type); found {
+				forecastTypes[metric.Type] = value
+				forecastMetrics = append(forecastMetrics, watcher.Metric{
+					Name:     metric.Name,
+					Type:     metric.Type,
+					Operator: watcher.Average,
+					Value:    value,
+				})
+			}
+		}
+		if _, ok := forecastTypes[metric.Type]; ok &&
+			(metric.Operator == watcher.Average || metric.Operator == watcher.Latest || metric.Operator == "") {
+			continue
+		}
+		forecastMetrics = append(forecastMetrics, metric)
+	}
+	klog.V(6).InfoS("Forecast utilization of node", "nodeName", nodeName, "forecast", forecastTypes)
+	return forecastMetrics
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trimaran
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/paypal/load-watcher/pkg/watcher"
+	"github.com/stretchr/testify/assert"
+
+	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
+)
+
+// start of a season of 10 minutes
+var forecastStart = time.Unix(1700000400, 0)
+
+// cpuMetrics : the metrics of a window ending at a time, with the average cpu utilization of the nodes
+func cpuMetrics(end time.Time, cpuByNode map[string]float64) *watcher.WatcherMetrics {
+	nodeMetricsMap := make(map[string]watcher.NodeMetrics, len(cpuByNode))
+	for nodeName, cpu := range cpuByNode {
+		nodeMetricsMap[nodeName] = watcher.NodeMetrics{Metrics: []watcher.Metric{
+			{Type: watcher.CPU, Operator: watcher.Average, Value: cpu},
+		}}
+	}
+	return &watcher.WatcherMetrics{
+		Window: watcher.Window{Duration: "1m", Start: end.Add(-time.Minute).Unix(), End: end.Unix()},
+		Data:   watcher.Data{NodeMetricsMap: nodeMetricsMap},
+	}
+}
+
+func TestForecasterEWMA(t *testing.T) {
+	f := newForecaster(&pluginConfig.ForecastSpec{Model: pluginConfig.EWMAForecast, Alpha: 0.5, HorizonSeconds: 60}, time.Minute)
+	now := forecastStart
+	f.now = func() time.Time { return now }
+
+	_, found := f.Forecast("node-1", watcher.CPU)
+	assert.False(t, found)
+
+	for i, cpu := range []float64{10, 20, 30} {
+		now = forecastStart.Add(time.Duration(i) * time.Minute)
+		f.observe(cpuMetrics(now, map[string]float64{"node-1": cpu}))
+	}
+	// the metrics already observed are ignored
+	f.observe(cpuMetrics(now, map[string]float64{"node-1": 100}))
+
+	forecast, found := f.Forecast("node-1", watcher.CPU)
+	assert.True(t, found)
+	assert.InDelta(t, 22.5, forecast, 1e-9)
+	_, found = f.Forecast("node-1", watcher.Memory)
+	assert.False(t, found)
+}
+
+func TestForecasterHoltWintersTrend(t *testing.T) {
+	f := newForecaster(&pluginConfig.ForecastSpec{Model: pluginConfig.HoltWintersForecast, Alpha: 1, Beta: 1, HorizonSeconds: 60}, time.Minute)
+	now := forecastStart
+	f.now = func() time.Time { return now }
+
+	f.observe(cpuMetrics(now, map[string]float64{"node-1": 10, "node-2": 50}))
+	now = now.Add(time.Minute)
+	f.observe(cpuMetrics(now, map[string]float64{"node-1": 20, "node-2": 40}))
+
+	// rising by 10% a minute
+	forecast, _ := f.Forecast("node-1", watcher.CPU)
+	assert.InDelta(t, 30, forecast, 1e-9)
+	// falling, the peak is the current utilization
+	forecast, _ = f.Forecast("node-2", watcher.CPU)
+	assert.InDelta(t, 40, forecast, 1e-9)
+}
+
+func TestForecasterHoltWintersSeasonality(t *testing.T) {
+	spec := &pluginConfig.ForecastSpec{
+		Model:               pluginConfig.HoltWintersForecast,
+		Alpha:               0.3,
+		Gamma:               0.5,
+		SeasonPeriodSeconds: 600,
+		HorizonSeconds:      120,
+	}
+	f := newForecaster(spec, time.Minute)
+	ewma := newForecaster(&pluginConfig.ForecastSpec{Model: pluginConfig.EWMAForecast, Alpha: 0.3, HorizonSeconds: 120}, time.Minute)
+	now := forecastStart
+	f.now = func() time.Time { return now }
+	ewma.now = f.now
+
+	// 20% utilization, peaking at 80% during the sixth minute of each season of 10 minutes
+	for i := 0; i < 100; i++ {
+		now = forecastStart.Add(time.Duration(i) * time.Minute)
+		cpu := 20.
+		if i%10 == 5 {
+			cpu = 80
+		}
+		f.observe(cpuMetrics(now, map[string]float64{"node-1": cpu}))
+		ewma.observe(cpuMetrics(now, map[string]float64{"node-1": cpu}))
+	}
+
+	// the next season starts, the peak is beyond the horizon
+	now = now.Add(time.Minute)
+	forecast, _ := f.Forecast("node-1", watcher.CPU)
+	assert.Less(t, forecast, 40.)
+	// the peak is within the horizon
+	now = now.Add(4 * time.Minute)
+	forecast, _ = f.Forecast("node-1", watcher.CPU)
+	assert.Greater(t, forecast, 60.)
+	forecast, _ = ewma.Forecast("node-1", watcher.CPU)
+	assert.Less(t, forecast, 40., "expected the EWMA not to anticipate the peak")
+}
+
+func TestForecasterSeasonSlots(t *testing.T) {
+	daily := &pluginConfig.ForecastSpec{Model: pluginConfig.HoltWintersForecast, Alpha: 0.3, SeasonPeriodSeconds: 86400}
+	f := newForecaster(daily, 30*time.Second)
+	assert.Equal(t, maxSeasonSlots, f.seasonSlots)
+	assert.Equal(t, 5*time.Minute, f.slotWidth)
+
+	f = newForecaster(&pluginConfig.ForecastSpec{Model: pluginConfig.HoltWintersForecast, Alpha: 0.3, SeasonPeriodSeconds: 600}, 30*time.Second)
+	assert.Equal(t, 20, f.seasonSlots)
+	assert.Equal(t, 30*time.Second, f.slotWidth)
+
+	f = newForecaster(&pluginConfig.ForecastSpec{Model: pluginConfig.EWMAForecast, Alpha: 0.3, SeasonPeriodSeconds: 600}, 30*time.Second)
+	assert.Equal(t, 0, f.seasonSlots)
+}
+
+func TestForecasterForgetsNodes(t *testing.T) {
+	f := newForecaster(&pluginConfig.ForecastSpec{Model: pluginConfig.EWMAForecast, Alpha: 0.5}, time.Minute)
+	f.observe(cpuMetrics(forecastStart, map[string]float64{"node-1": 10, "node-2": 10}))
+	f.observe(cpuMetrics(forecastStart.Add(forecastRetention), map[string]float64{"node-1": 10}))
+	_, found := f.Forecast("node-2", watcher.CPU)
+	assert.True(t, found)
+
+	f.observe(cpuMetrics(forecastStart.Add(forecastRetention+time.Minute), map[string]float64{"node-1": 10}))
+	_, found = f.Forecast("node-2", watcher.CPU)
+	assert.False(t, found)
+	_, found = f.Forecast("node-1", watcher.CPU)
+	assert.True(t, found)
+}
+
+func TestForecastMetrics(t *testing.T) {
+	f := newForecaster(&pluginConfig.ForecastSpec{Model: pluginConfig.EWMAForecast, Alpha: 1}, time.Minute)
+	f.observe(cpuMetrics(forecastStart, map[string]float64{"node-1": 70}))
+
+	metrics := []watcher.Metric{
+		{Type: watcher.CPU, Operator: watcher.Latest, Value: 50},
+		{Type: watcher.CPU, Operator: watcher.Average, Value: 40},
+		{Type: watcher.CPU, Operator: watcher.Std, Value: 10},
+		{Type: watcher.Memory, Operator: watcher.Average, Value: 30},
+	}
+	assert.Equal(t, []watcher.Metric{
+		{Type: watcher.CPU, Operator: watcher.Average, Value: 70},
+		{Type: watcher.CPU, Operator: watcher.Std, Value: 10},
+		{Type: watcher.Memory, Operator: watcher.Average, Value: 30},
+	}, f.ForecastMetrics("node-1", metrics))
+	assert.Equal(t, metrics, f.ForecastMetrics("node-2", metrics))
+}
+
+func TestNewForecaster(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watcherResponse := cpuMetrics(time.Now(), map[string]float64{"node-1": 40})
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		bytes, err := json.Marshal(watcherResponse)
+		assert.Nil(t, err)
+		resp.Write(bytes)
+	}))
+	defer server.Close()
+
+	collector, err := NewCollector(ctx, &pluginConfig.TrimaranSpec{WatcherAddress: server.URL}, nil)
+	assert.Nil(t, err)
+	defer collector.stop()
+
+	forecasterCtx, forecasterCancel := context.WithCancel(ctx)
+	f := NewForecaster(forecasterCtx, collector, &pluginConfig.ForecastSpec{Model: pluginConfig.EWMAForecast, Alpha: 0.5})
+	// fed with the current metrics
+	forecast, found := f.Forecast("node-1", watcher.CPU)
+	assert.True(t, found)
+	assert.InDelta(t, 40, forecast, 1e-9)
+
+	// and with the next ones
+	collector.setMetrics(cpuMetrics(time.Now().Add(time.Minute), map[string]float64{"node-1": 60}))
+	forecast, _ = f.Forecast("node-1", watcher.CPU)
+	assert.InDelta(t, 50, forecast, 1e-9)
+
+	// until the context is done
+	forecasterCancel()
+	assert.Eventually(t, func() bool {
+		collector.mu.RLock()
+		defer collector.mu.RUnlock()
+		return len(collector.forecasters) == 0
+	}, time.Second, 10*time.Millisecond)
+}
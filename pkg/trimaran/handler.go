@@ -26,51 +26,103 @@ import (
 	"sync"
 	"time"
 
+	"github.com/paypal/load-watcher/pkg/watcher"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientcache "k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
-	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
 )
 
 const (
-	// This is the maximum staleness of metrics possible by load watcher
-	cacheCleanupIntervalMinutes = 5
 	// Time interval in seconds for each metrics agent ingestion.
 	metricsAgentReportingIntervalSeconds = 60
 )
 
+// waiting reasons of the containers which fail to start, and don't use their resources
+var failedToStartReasons = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+	"CrashLoopBackOff":           true,
+}
+
 var _ clientcache.ResourceEventHandler = &PodAssignEventHandler{}
 
-// This event handler watches assigned Pod and caches them locally
+// PodAssignEventHandler watches the pods assigned to the nodes and indexes the recently scheduled ones by node and
+// UID, until the load metrics cover their utilization. The pods count as long as they are running or starting.
 type PodAssignEventHandler struct {
-	// Maintains the node-name to podInfo mapping for pods successfully bound to nodes
-	ScheduledPodsCache map[string][]podInfo
+	// for safe access to nodes and pods
 	sync.RWMutex
+	// pods scheduled recently by node name and UID
+	nodes map[string]map[types.UID]*podInfo
+	// pods scheduled recently by UID
+	pods map[types.UID]*podInfo
+	// time the pods are indexed for after being scheduled
+	ttl time.Duration
+	// current time, overridden in tests
+	now func() time.Time
+	// closed to stop the index cleanup
+	stopCh chan struct{}
 }
 
 // Stores Timestamp and Pod spec info object
 type podInfo struct {
-	// This timestamp is initialised when adding it to ScheduledPodsCache after successful binding
+	// time the pod was scheduled at
 	Timestamp time.Time
 	Pod       *v1.Pod
 }
 
-// Returns a new instance of PodAssignEventHandler, after starting a background go routine for cache cleanup
-func New() *PodAssignEventHandler {
-	p := PodAssignEventHandler{ScheduledPodsCache: make(map[string][]podInfo)}
+// Returns a new instance of PodAssignEventHandler indexing the pods for the ttl, after starting a background go
+// routine for the index cleanup
+func New(ttl time.Duration) *PodAssignEventHandler {
+	if ttl <= 0 {
+		ttl = eventHandlerTTL(&pluginConfig.TrimaranSpec{})
+	}
+	p := PodAssignEventHandler{
+		nodes:  make(map[string]map[types.UID]*podInfo),
+		pods:   make(map[types.UID]*podInfo),
+		ttl:    ttl,
+		now:    time.Now,
+		stopCh: make(chan struct{}),
+	}
 	go func() {
-		cacheCleanerTicker := time.NewTicker(time.Minute * cacheCleanupIntervalMinutes)
-		for range cacheCleanerTicker.C {
-			p.cleanupCache()
+		cacheCleanerTicker := time.NewTicker(ttl)
+		defer cacheCleanerTicker.Stop()
+		for {
+			select {
+			case <-cacheCleanerTicker.C:
+				p.cleanupCache()
+			case <-p.stopCh:
+				return
+			}
 		}
 	}()
 	return &p
 }
 
-// AddToHandle : add event handler to framework handle
-func (p *PodAssignEventHandler) AddToHandle(handle framework.Handle) {
-	handle.SharedInformerFactory().Core().V1().Pods().Informer().AddEventHandler(
+// eventHandlerTTL : the time the pods are indexed for, so that they are until the metrics in use cover them: the
+// reporting interval of the metrics agents, and the maximum age of the metrics, their staleness threshold, or their
+// update interval without staleness detection
+func eventHandlerTTL(trimaranSpec *pluginConfig.TrimaranSpec) time.Duration {
+	metricsAge := time.Duration(trimaranSpec.MetricsStalenessSeconds) * time.Second
+	if metricsAge == 0 {
+		metricsAge = time.Duration(trimaranSpec.MetricsUpdateIntervalSeconds) * time.Second
+		if metricsAge == 0 {
+			metricsAge = metricsUpdateIntervalSeconds * time.Second
+		}
+	}
+	return metricsAgentReportingIntervalSeconds*time.Second + metricsAge
+}
+
+// addToInformer : add event handler to the pod informer
+func (p *PodAssignEventHandler) addToInformer(informer clientcache.SharedIndexInformer) (clientcache.ResourceEventHandlerRegistration, error) {
+	return informer.AddEventHandler(
 		clientcache.FilteringResourceEventHandler{
 			FilterFunc: func(obj interface{}) bool {
 				switch t := obj.(type) {
@@ -92,74 +144,193 @@ func (p *PodAssignEventHandler) AddToHandle(handle framework.Handle) {
 	)
 }
 
+// stop : stop the index cleanup
+func (p *PodAssignEventHandler) stop() {
+	close(p.stopCh)
+}
+
 func (p *PodAssignEventHandler) OnAdd(obj interface{}, _ bool) {
 	pod := obj.(*v1.Pod)
 	p.updateCache(pod)
 }
 
 func (p *PodAssignEventHandler) OnUpdate(oldObj, newObj interface{}) {
-	oldPod := oldObj.(*v1.Pod)
 	newPod := newObj.(*v1.Pod)
+	p.updateCache(newPod)
+}
 
-	if oldPod.Spec.NodeName != newPod.Spec.NodeName {
-		p.updateCache(newPod)
+func (p *PodAssignEventHandler) OnDelete(obj interface{}) {
+	var pod *v1.Pod
+	switch t := obj.(type) {
+	case *v1.Pod:
+		pod = t
+	case clientcache.DeletedFinalStateUnknown:
+		pod, _ = t.Obj.(*v1.Pod)
+	}
+	if pod == nil {
+		return
 	}
+	p.Lock()
+	defer p.Unlock()
+	p.removePod(pod.UID)
 }
 
-func (p *PodAssignEventHandler) OnDelete(obj interface{}) {
-	pod := obj.(*v1.Pod)
-	nodeName := pod.Spec.NodeName
+// updateCache : index the pod on its node if it was scheduled recently, or refresh it if it's indexed already, until
+// it terminates
+func (p *PodAssignEventHandler) updateCache(pod *v1.Pod) {
+	if pod.Spec.NodeName == "" {
+		return
+	}
 	p.Lock()
 	defer p.Unlock()
-	if _, ok := p.ScheduledPodsCache[nodeName]; !ok {
+	if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		p.removePod(pod.UID)
 		return
 	}
-	for i, v := range p.ScheduledPodsCache[nodeName] {
-		n := len(p.ScheduledPodsCache[nodeName])
-		if pod.ObjectMeta.UID == v.Pod.ObjectMeta.UID {
-			klog.V(10).InfoS("Deleting pod", "pod", klog.KObj(v.Pod))
-			copy(p.ScheduledPodsCache[nodeName][i:], p.ScheduledPodsCache[nodeName][i+1:])
-			p.ScheduledPodsCache[nodeName][n-1] = podInfo{}
-			p.ScheduledPodsCache[nodeName] = p.ScheduledPodsCache[nodeName][:n-1]
-			break
+	if info, ok := p.pods[pod.UID]; ok {
+		if info.Pod.Spec.NodeName == pod.Spec.NodeName {
+			info.Pod = pod
+			return
 		}
+		p.removePod(pod.UID)
+	}
+	// the pods scheduled before the scheduler started, or already expired, aren't indexed again
+	timestamp := scheduledTime(pod, p.now())
+	if p.now().Sub(timestamp) >= p.ttl {
+		return
+	}
+	info := &podInfo{Timestamp: timestamp, Pod: pod}
+	p.pods[pod.UID] = info
+	nodePods, ok := p.nodes[pod.Spec.NodeName]
+	if !ok {
+		nodePods = make(map[types.UID]*podInfo)
+		p.nodes[pod.Spec.NodeName] = nodePods
 	}
+	nodePods[pod.UID] = info
 }
 
-func (p *PodAssignEventHandler) updateCache(pod *v1.Pod) {
-	if pod.Spec.NodeName == "" {
+// removePod : remove the pod from the index, if it's indexed. The lock must be held.
+func (p *PodAssignEventHandler) removePod(uid types.UID) {
+	info, ok := p.pods[uid]
+	if !ok {
 		return
 	}
-	p.Lock()
-	p.ScheduledPodsCache[pod.Spec.NodeName] = append(p.ScheduledPodsCache[pod.Spec.NodeName],
-		podInfo{Timestamp: time.Now(), Pod: pod})
-	p.Unlock()
+	klog.V(10).InfoS("Deleting pod", "pod", klog.KObj(info.Pod))
+	delete(p.pods, uid)
+	nodeName := info.Pod.Spec.NodeName
+	delete(p.nodes[nodeName], uid)
+	if len(p.nodes[nodeName]) == 0 {
+		delete(p.nodes, nodeName)
+	}
+}
+
+// UnreportedPods returns the pods scheduled on the node whose utilization may be missing from the metrics fetched
+// over the window, so that the plugins can predict it from their spec. Only the pods running or starting count.
+func (p *PodAssignEventHandler) UnreportedPods(nodeName string, window *watcher.Window) []*v1.Pod {
+	p.RLock()
+	defer p.RUnlock()
+	now := p.now()
+	var infos []*podInfo
+	for _, info := range p.nodes[nodeName] {
+		if now.Sub(info.Timestamp) >= p.ttl || !isRunningOrStarting(info.Pod) {
+			continue
+		}
+		// If the time stamp of the scheduled pod is outside fetched metrics window, or it is within metrics reporting interval seconds, we predict util.
+		// Note that the second condition doesn't guarantee metrics for that pod are not reported yet as the 0 <= t <= 2*metricsAgentReportingIntervalSeconds
+		// t = metricsAgentReportingIntervalSeconds is taken as average case and it doesn't hurt us much if we are
+		// counting metrics twice in case actual t is less than metricsAgentReportingIntervalSeconds
+		if info.Timestamp.Unix() > window.End || info.Timestamp.Unix() <= window.End &&
+			(window.End-info.Timestamp.Unix()) < metricsAgentReportingIntervalSeconds {
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].Timestamp.Equal(infos[j].Timestamp) {
+			return infos[i].Timestamp.Before(infos[j].Timestamp)
+		}
+		return infos[i].Pod.UID < infos[j].Pod.UID
+	})
+	pods := make([]*v1.Pod, 0, len(infos))
+	for _, info := range infos {
+		pods = append(pods, info.Pod)
+	}
+	return pods
+}
+
+// PredictedDeltas returns the usage of the pods scheduled on the node missing from the metrics fetched over the
+// window, predicted for all the resources they request, in millicores for cpu and in units otherwise
+func (p *PodAssignEventHandler) PredictedDeltas(nodeName string, window *watcher.Window, predictor *UtilizationPredictor) map[v1.ResourceName]int64 {
+	pods := p.UnreportedPods(nodeName, window)
+	deltas := make(map[v1.ResourceName]int64)
+	for _, pod := range pods {
+		for resourceName := range podResourceNames(pod, predictor) {
+			deltas[resourceName] += predictor.PredictPodUtilization(pod, resourceName)
+		}
+	}
+	return deltas
+}
+
+// podResourceNames : the resources requested by the pod, and the ones predicted by default
+func podResourceNames(pod *v1.Pod, predictor *UtilizationPredictor) map[v1.ResourceName]struct{} {
+	resourceNames := make(map[v1.ResourceName]struct{})
+	for resourceName := range predictor.DefaultRequests {
+		resourceNames[resourceName] = struct{}{}
+	}
+	for _, container := range pod.Spec.Containers {
+		for resourceName := range container.Resources.Requests {
+			resourceNames[resourceName] = struct{}{}
+		}
+		for resourceName := range container.Resources.Limits {
+			resourceNames[resourceName] = struct{}{}
+		}
+	}
+	for resourceName := range pod.Spec.Overhead {
+		resourceNames[resourceName] = struct{}{}
+	}
+	return resourceNames
 }
 
-// Deletes podInfo entries that are older than metricsAgentReportingIntervalSeconds. Also deletes node entry if empty
+// Deletes the pods indexed for longer than the ttl. Also deletes node entry if empty
 func (p *PodAssignEventHandler) cleanupCache() {
 	p.Lock()
 	defer p.Unlock()
-	for nodeName := range p.ScheduledPodsCache {
-		cache := p.ScheduledPodsCache[nodeName]
-		curTime := time.Now()
-		idx := sort.Search(len(cache), func(i int) bool {
-			return cache[i].Timestamp.Add(metricsAgentReportingIntervalSeconds * time.Second).After(curTime)
-		})
-		if idx == len(cache) {
-			continue
+	now := p.now()
+	for uid, info := range p.pods {
+		if now.Sub(info.Timestamp) >= p.ttl {
+			p.removePod(uid)
 		}
-		n := copy(cache, cache[idx:])
-		for j := n; j < len(cache); j++ {
-			cache[j] = podInfo{}
+	}
+}
+
+// scheduledTime : the time the pod was scheduled at, as reported by its PodScheduled condition, or now if unknown
+func scheduledTime(pod *v1.Pod, now time.Time) time.Time {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled && condition.Status == v1.ConditionTrue && !condition.LastTransitionTime.IsZero() {
+			if t := condition.LastTransitionTime.Time; t.Before(now) {
+				return t
+			}
+			return now
 		}
-		cache = cache[:n]
+	}
+	return now
+}
 
-		if len(cache) == 0 {
-			delete(p.ScheduledPodsCache, nodeName)
-		} else {
-			p.ScheduledPodsCache[nodeName] = cache
+// isRunningOrStarting : whether the pod runs, or starts without its containers failing to
+func isRunningOrStarting(pod *v1.Pod) bool {
+	switch pod.Status.Phase {
+	case v1.PodRunning:
+		return true
+	case v1.PodPending, "":
+		for _, statuses := range [][]v1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+			for _, status := range statuses {
+				if status.State.Waiting != nil && failedToStartReasons[status.State.Waiting.Reason] {
+					return false
+				}
+			}
 		}
+		return true
+	default:
+		return false
 	}
 }
 
@@ -1,77 +1,268 @@
 package trimaran
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/paypal/load-watcher/pkg/watcher"
 	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientcache "k8s.io/client-go/tools/cache"
 
 	st "k8s.io/kubernetes/pkg/scheduler/testing"
+
+	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
 )
 
+// scheduledPod : a pod scheduled on the node at the time, running
+func scheduledPod(name string, nodeName string, scheduledAt time.Time) *v1.Pod {
+	pod := st.MakePod().Name(name).UID(name).Node(nodeName).Phase(v1.PodRunning).Obj()
+	pod.Status.Conditions = []v1.PodCondition{
+		{Type: v1.PodScheduled, Status: v1.ConditionTrue, LastTransitionTime: metav1.NewTime(scheduledAt)},
+	}
+	return pod
+}
+
+// newTestHandler : an event handler indexing the pods for 2 minutes, at a time set by the tests
+func newTestHandler(t *testing.T, now *time.Time) *PodAssignEventHandler {
+	p := New(2 * time.Minute)
+	t.Cleanup(p.stop)
+	p.now = func() time.Time { return *now }
+	return p
+}
+
+// indexedPods : the names of the pods indexed by node
+func indexedPods(p *PodAssignEventHandler) map[string][]string {
+	p.RLock()
+	defer p.RUnlock()
+	pods := make(map[string][]string)
+	for nodeName, nodePods := range p.nodes {
+		for _, info := range nodePods {
+			pods[nodeName] = append(pods[nodeName], info.Pod.Name)
+		}
+	}
+	return pods
+}
+
+func TestHandlerIndex(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	p := newTestHandler(t, &now)
+
+	pod := scheduledPod("pod-1", "node-1", now)
+	p.OnAdd(pod, false)
+	// the updates of the pod on the same node don't duplicate it
+	updated := pod.DeepCopy()
+	updated.Labels = map[string]string{"app": "web"}
+	p.OnUpdate(pod, updated)
+	p.OnUpdate(updated, updated)
+	assert.Equal(t, map[string][]string{"node-1": {"pod-1"}}, indexedPods(p))
+	assert.Same(t, updated, p.pods[pod.UID].Pod)
+	assert.Equal(t, now, p.pods[pod.UID].Timestamp)
+
+	// unassigned pods, and pods scheduled before the ttl, aren't indexed
+	p.OnAdd(st.MakePod().Name("pending").UID("pending").Obj(), false)
+	p.OnAdd(scheduledPod("old", "node-1", now.Add(-time.Hour)), false)
+	p.OnAdd(scheduledPod("pod-2", "node-2", now.Add(-time.Minute)), false)
+	assert.Equal(t, map[string][]string{"node-1": {"pod-1"}, "node-2": {"pod-2"}}, indexedPods(p))
+	assert.Equal(t, now.Add(-time.Minute), p.pods["pod-2"].Timestamp)
+
+	// the terminated pods are removed
+	failed := updated.DeepCopy()
+	failed.Status.Phase = v1.PodFailed
+	failed.Status.Reason = "Evicted"
+	p.OnUpdate(updated, failed)
+	assert.Equal(t, map[string][]string{"node-2": {"pod-2"}}, indexedPods(p))
+
+	// and the deleted ones, even if their final state is unknown
+	p.OnDelete(clientcache.DeletedFinalStateUnknown{Key: "default/pod-2", Obj: scheduledPod("pod-2", "node-2", now)})
+	assert.Empty(t, indexedPods(p))
+	assert.Empty(t, p.pods)
+}
+
 func TestHandlerCacheCleanup(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	p := newTestHandler(t, &now)
+	p.OnAdd(scheduledPod("pod-1", "node-1", now.Add(-90*time.Second)), false)
+	p.OnAdd(scheduledPod("pod-2", "node-1", now.Add(-30*time.Second)), false)
+	p.OnAdd(scheduledPod("pod-3", "node-2", now.Add(-60*time.Second)), false)
+
+	p.cleanupCache()
+	assert.Len(t, p.pods, 3)
+
+	now = now.Add(time.Minute)
+	p.cleanupCache()
+	assert.Equal(t, map[string][]string{"node-1": {"pod-2"}}, indexedPods(p))
+	assert.Len(t, p.pods, 1)
+
+	now = now.Add(time.Minute)
+	p.cleanupCache()
+	assert.Empty(t, indexedPods(p))
+	assert.Empty(t, p.pods)
+}
+
+func TestHandlerUnreportedPods(t *testing.T) {
 	testNode := "node-1"
-	pod1 := st.MakePod().Name("Pod-1").Obj()
-	pod2 := st.MakePod().Name("Pod-2").Obj()
-	pod3 := st.MakePod().Name("Pod-3").Obj()
-	pod4 := st.MakePod().Name("Pod-4").Obj()
+	now := time.Now().Truncate(time.Second)
+	end := now.Add(-time.Minute)
+	window := &watcher.Window{Start: end.Add(-15 * time.Minute).Unix(), End: end.Unix()}
+	p := New(time.Hour)
+	defer p.stop()
+	p.now = func() time.Time { return now }
+
+	starting := scheduledPod("starting", testNode, end.Add(20*time.Second))
+	starting.Status.Phase = v1.PodPending
+	starting.Status.ContainerStatuses = []v1.ContainerStatus{
+		{State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ContainerCreating"}}},
+	}
+	failedToStart := scheduledPod("failed-to-start", testNode, end.Add(30*time.Second))
+	failedToStart.Status.Phase = v1.PodPending
+	failedToStart.Status.ContainerStatuses = []v1.ContainerStatus{
+		{State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}},
+	}
+	for _, pod := range []*v1.Pod{
+		scheduledPod("reported", testNode, end.Add(-5*time.Minute)),
+		scheduledPod("after-window", testNode, end.Add(10*time.Second)),
+		scheduledPod("within-reporting-interval", testNode, end.Add(-10*time.Second)),
+		starting,
+		failedToStart,
+		scheduledPod("other-node", "node-2", end.Add(10*time.Second)),
+	} {
+		p.OnAdd(pod, false)
+	}
 
+	var names []string
+	for _, pod := range p.UnreportedPods(testNode, window) {
+		names = append(names, pod.Name)
+	}
+	assert.Equal(t, []string{"within-reporting-interval", "after-window", "starting"}, names)
+	assert.Empty(t, p.UnreportedPods("node-3", window))
+
+	// the pod counts again once it starts
+	started := failedToStart.DeepCopy()
+	started.Status.Phase = v1.PodRunning
+	started.Status.ContainerStatuses = nil
+	p.OnUpdate(failedToStart, started)
+	assert.Len(t, p.UnreportedPods(testNode, window), 4)
+}
+
+func TestHandlerPredictedDeltas(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	window := &watcher.Window{Start: now.Add(-15 * time.Minute).Unix(), End: now.Add(-time.Minute).Unix()}
+	p := newTestHandler(t, &now)
+	predictor := &UtilizationPredictor{
+		DefaultRequests:    v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")},
+		RequestsMultiplier: 1.5,
+	}
+
+	pod1 := scheduledPod("pod-1", "node-1", now)
+	pod1.Spec.Containers = []v1.Container{{
+		Resources: v1.ResourceRequirements{
+			Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1Gi")},
+			Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("2Gi"), "nvidia.com/gpu": resource.MustParse("1")},
+		},
+	}}
+	pod2 := scheduledPod("pod-2", "node-1", now)
+	pod2.Spec.Containers = []v1.Container{{
+		Resources: v1.ResourceRequirements{
+			Requests: v1.ResourceList{v1.ResourceEphemeralStorage: resource.MustParse("10Gi")},
+		},
+	}}
+	pod2.Spec.Overhead = v1.ResourceList{v1.ResourceMemory: resource.MustParse("100Mi")}
+	p.OnAdd(pod1, false)
+	p.OnAdd(pod2, false)
+
+	assert.Equal(t, map[v1.ResourceName]int64{
+		v1.ResourceCPU:              1500 + 100,
+		v1.ResourceMemory:           2<<30 + 100<<20,
+		v1.ResourceEphemeralStorage: 15 << 30,
+		"nvidia.com/gpu":            1,
+	}, p.PredictedDeltas("node-1", window, predictor))
+	assert.Empty(t, p.PredictedDeltas("node-2", window, predictor))
+}
+
+func TestHandlerConcurrentEvents(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	p := New(time.Hour)
+	defer p.stop()
+	window := &watcher.Window{Start: now.Add(-15 * time.Minute).Unix(), End: now.Add(-time.Minute).Unix()}
+	predictor := &UtilizationPredictor{DefaultRequests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")}}
+	nodes := []string{"node-1", "node-2", "node-3"}
+
+	const workers, podsPerWorker = 8, 50
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		// informer events: the pods are added, updated, and every other one deleted
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < podsPerWorker; i++ {
+				pod := scheduledPod(fmt.Sprintf("pod-%d-%d", w, i), nodes[i%len(nodes)], now)
+				pod.Spec.Containers = []v1.Container{{Name: "app"}}
+				p.OnAdd(pod, false)
+				updated := pod.DeepCopy()
+				updated.Labels = map[string]string{"updated": "true"}
+				p.OnUpdate(pod, updated)
+				if i%2 == 1 {
+					p.OnDelete(updated)
+				}
+			}
+		}(w)
+		// plugins predicting the missing utilization meanwhile
+		go func() {
+			defer wg.Done()
+			for i := 0; i < podsPerWorker; i++ {
+				for _, nodeName := range nodes {
+					p.UnreportedPods(nodeName, window)
+					p.PredictedDeltas(nodeName, window, predictor)
+				}
+				p.cleanupCache()
+			}
+		}()
+	}
+	wg.Wait()
+
+	var total int
+	for _, nodeName := range nodes {
+		pods := p.UnreportedPods(nodeName, window)
+		total += len(pods)
+		assert.Equal(t, int64(100*len(pods)), p.PredictedDeltas(nodeName, window, predictor)[v1.ResourceCPU])
+	}
+	assert.Equal(t, workers*podsPerWorker/2, total)
+	assert.Len(t, p.pods, total)
+	for uid, info := range p.pods {
+		assert.Same(t, info, p.nodes[info.Pod.Spec.NodeName][uid])
+		assert.Equal(t, "true", info.Pod.Labels["updated"])
+	}
+}
+
+func TestEventHandlerTTL(t *testing.T) {
 	tests := []struct {
-		name              string
-		podInfoList       []podInfo
-		podToUpdate       string
-		expectedCacheSize int
-		expectedCachePods []string
+		name     string
+		spec     pluginConfig.TrimaranSpec
+		expected time.Duration
 	}{
 		{
-			name: "OnUpdate doesn't add unassigned pods",
-			podInfoList: []podInfo{
-				{Pod: pod1},
-				{Pod: pod2},
-				{Pod: pod3}},
-			podToUpdate:       "Pod-4",
-			expectedCacheSize: 1,
-			expectedCachePods: []string{"Pod-4"},
+			name:     "default update interval",
+			expected: 90 * time.Second,
 		},
 		{
-			name: "cleanupCache doesn't delete newly added pods",
-			podInfoList: []podInfo{
-				{Pod: pod1},
-				{Pod: pod2},
-				{Pod: pod3},
-				{Timestamp: time.Now(), Pod: pod4}},
-			podToUpdate:       "Pod-5",
-			expectedCacheSize: 2,
-			expectedCachePods: []string{"Pod-4", "Pod-5"},
+			name:     "update interval",
+			spec:     pluginConfig.TrimaranSpec{MetricsUpdateIntervalSeconds: 120},
+			expected: 180 * time.Second,
 		},
 		{
-			name: "cleanupCache deletes old pods",
-			podInfoList: []podInfo{
-				{Timestamp: time.Now().Add(-5 * time.Minute), Pod: pod1},
-				{Timestamp: time.Now().Add(-10 * time.Second), Pod: pod2},
-				{Timestamp: time.Now().Add(-5 * time.Second), Pod: pod3},
-			},
-			expectedCacheSize: 2,
-			expectedCachePods: []string{pod2.Name, pod3.Name},
+			name:     "staleness threshold",
+			spec:     pluginConfig.TrimaranSpec{MetricsUpdateIntervalSeconds: 120, MetricsStalenessSeconds: 300},
+			expected: 360 * time.Second,
 		},
 	}
-
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			p := New()
-			p.ScheduledPodsCache[testNode] = append(p.ScheduledPodsCache[testNode], tt.podInfoList...)
-			if tt.podToUpdate != "" {
-				pod := st.MakePod().Name(tt.podToUpdate).Obj()
-				pod.Spec.NodeName = testNode
-				oldPod := st.MakePod().Name(tt.podToUpdate).Obj()
-				p.OnUpdate(oldPod, pod)
-			}
-			p.cleanupCache()
-			assert.NotNil(t, p.ScheduledPodsCache[testNode])
-			assert.Equal(t, tt.expectedCacheSize, len(p.ScheduledPodsCache[testNode]))
-			for i, v := range p.ScheduledPodsCache[testNode] {
-				assert.Equal(t, tt.expectedCachePods[i], v.Pod.Name)
-			}
+			assert.Equal(t, tt.expected, eventHandlerTTL(&tt.spec))
 		})
 	}
 }
@@ -0,0 +1,268 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trimaran
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/paypal/load-watcher/pkg/watcher"
+	loadwatcherapi "github.com/paypal/load-watcher/pkg/watcher/api"
+	"github.com/prometheus/common/expfmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
+)
+
+const (
+	// resource metrics of the kubelet, scraped through the node proxy of the apiserver
+	nodeCPUUsageMetric    = "node_cpu_usage_seconds_total"
+	nodeMemoryUsageMetric = "node_memory_working_set_bytes"
+	// history of the load metrics of the nodes, as the default window of the load watcher
+	kubeletMetricsWindow = 15 * time.Minute
+	// timeout of the scraping of the nodes
+	kubeletScrapeTimeout = 10 * time.Second
+	// number of nodes scraped concurrently
+	kubeletScrapeWorkers = 16
+)
+
+// kubeletClient : a load watcher client computing the load metrics of the nodes from the resource metrics of their
+// kubelets, without the load watcher. It keeps a rolling history of the utilization of the nodes, from which the
+// average and standard deviation over the window are computed.
+type kubeletClient struct {
+	clientSet kubernetes.Interface
+	// length of the history
+	window time.Duration
+	// age beyond which the metrics of a node are stale and left out; zero disables the detection
+	staleness time.Duration
+	// current time, overridden in tests
+	now func() time.Time
+	// for safe access to history
+	mu sync.Mutex
+	// history of the nodes by name
+	history map[string]*nodeHistory
+}
+
+// nodeHistory : utilization samples of a node
+type nodeHistory struct {
+	// last cumulative cpu usage, from which the utilization of the next sample is derived
+	lastCPUSeconds float64
+	lastCPUTime    time.Time
+	cpu            []usageSample
+	memory         []usageSample
+	// time of the last successful scrape
+	lastScrapeTime time.Time
+}
+
+// usageSample : utilization percent of a resource of a node at a time
+type usageSample struct {
+	time    time.Time
+	percent float64
+}
+
+var _ loadwatcherapi.Client = &kubeletClient{}
+
+// newKubeletClient : create a client scraping the kubelets through the apiserver, leaving out the nodes whose last
+// successful scrape is older than the staleness threshold
+func newKubeletClient(kubeConfig *rest.Config, staleness time.Duration) (*kubeletClient, error) {
+	if kubeConfig == nil {
+		return nil, fmt.Errorf("kube config is required by the %v metric provider", pluginConfig.Kubelet)
+	}
+	config := rest.CopyConfig(kubeConfig)
+	config.Timeout = kubeletScrapeTimeout
+	clientSet, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &kubeletClient{
+		clientSet: clientSet,
+		window:    kubeletMetricsWindow,
+		staleness: staleness,
+		now:       time.Now,
+		history:   make(map[string]*nodeHistory),
+	}, nil
+}
+
+// GetLatestWatcherMetrics : scrape the kubelets of all the nodes, and compute the load metrics over the window.
+// The window ends with the newest successful scrape, so that the metrics are stale when no kubelet can be scraped.
+func (c *kubeletClient) GetLatestWatcherMetrics() (*watcher.WatcherMetrics, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), kubeletScrapeTimeout)
+	defer cancel()
+	nodeList, err := c.clientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list nodes: %w", err)
+	}
+	nodes := nodeList.Items
+
+	type scrapeResult struct {
+		cpuExists    bool
+		cpuSeconds   float64
+		cpuTime      time.Time
+		memoryExists bool
+		memoryBytes  float64
+	}
+	results := make([]*scrapeResult, len(nodes))
+	workqueue.ParallelizeUntil(ctx, kubeletScrapeWorkers, len(nodes), func(i int) {
+		raw, err := c.clientSet.CoreV1().RESTClient().Get().
+			AbsPath("/api/v1/nodes", nodes[i].Name, "proxy", "metrics", "resource").DoRaw(ctx)
+		if err != nil {
+			klog.ErrorS(err, "Unable to scrape the resource metrics of node", "nodeName", nodes[i].Name)
+			return
+		}
+		families, err := (&expfmt.TextParser{}).TextToMetricFamilies(bytes.NewReader(raw))
+		if err != nil {
+			klog.ErrorS(err, "Unable to parse the resource metrics of node", "nodeName", nodes[i].Name)
+			return
+		}
+		result := &scrapeResult{}
+		if family, ok := families[nodeCPUUsageMetric]; ok && len(family.GetMetric()) > 0 {
+			metric := family.GetMetric()[0]
+			result.cpuSeconds = metric.GetCounter().GetValue()
+			result.cpuTime = c.now()
+			if metric.TimestampMs != nil {
+				result.cpuTime = time.UnixMilli(metric.GetTimestampMs())
+			}
+			result.cpuExists = true
+		}
+		if family, ok := families[nodeMemoryUsageMetric]; ok && len(family.GetMetric()) > 0 {
+			result.memoryBytes = family.GetMetric()[0].GetGauge().GetValue()
+			result.memoryExists = true
+		}
+		results[i] = result
+	})
+
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	nodeMetricsMap := make(map[string]watcher.NodeMetrics, len(nodes))
+	listed := make(map[string]bool, len(nodes))
+	var end time.Time
+	for i := range nodes {
+		node := &nodes[i]
+		listed[node.Name] = true
+		h, ok := c.history[node.Name]
+		if !ok {
+			h = &nodeHistory{}
+			c.history[node.Name] = h
+		}
+		if result := results[i]; result != nil {
+			if result.cpuExists {
+				h.addCPUSample(result.cpuSeconds, result.cpuTime, node.Status.Capacity.Cpu().AsApproximateFloat64())
+				h.scraped(result.cpuTime)
+			}
+			if result.memoryExists {
+				if capacity := node.Status.Capacity.Memory().AsApproximateFloat64(); capacity > 0 {
+					h.memory = append(h.memory, usageSample{time: now, percent: 100 * result.memoryBytes / capacity})
+				}
+				h.scraped(now)
+			}
+		}
+		h.prune(now.Add(-c.window))
+		if h.lastScrapeTime.After(end) {
+			end = h.lastScrapeTime
+		}
+		// the node is scored in fallback mode rather than with the samples of a kubelet unreachable for too long
+		if c.staleness > 0 && now.Sub(h.lastScrapeTime) > c.staleness {
+			continue
+		}
+
+		var metrics []watcher.Metric
+		metrics = append(metrics, samplesMetrics(watcher.CPU, h.cpu)...)
+		metrics = append(metrics, samplesMetrics(watcher.Memory, h.memory)...)
+		if len(metrics) > 0 {
+			nodeMetricsMap[node.Name] = watcher.NodeMetrics{Metrics: metrics}
+		}
+	}
+	// forget the nodes which are gone
+	for nodeName := range c.history {
+		if !listed[nodeName] {
+			delete(c.history, nodeName)
+		}
+	}
+
+	window := watcher.Window{Duration: watcher.FifteenMinutes}
+	if !end.IsZero() {
+		window.Start, window.End = end.Add(-c.window).Unix(), end.Unix()
+	}
+	return &watcher.WatcherMetrics{
+		Timestamp: now.Unix(),
+		Window:    window,
+		Source:    string(pluginConfig.Kubelet),
+		Data:      watcher.Data{NodeMetricsMap: nodeMetricsMap},
+	}, nil
+}
+
+// addCPUSample : add the cpu utilization since the last cumulative usage, given the cpu capacity of the node in cores
+func (h *nodeHistory) addCPUSample(cpuSeconds float64, cpuTime time.Time, capacity float64) {
+	lastCPUSeconds, lastCPUTime := h.lastCPUSeconds, h.lastCPUTime
+	h.lastCPUSeconds, h.lastCPUTime = cpuSeconds, cpuTime
+	elapsed := cpuTime.Sub(lastCPUTime).Seconds()
+	// the first usage, or a restarted kubelet, has no rate
+	if lastCPUTime.IsZero() || elapsed <= 0 || cpuSeconds < lastCPUSeconds || capacity <= 0 {
+		return
+	}
+	percent := 100 * (cpuSeconds - lastCPUSeconds) / elapsed / capacity
+	h.cpu = append(h.cpu, usageSample{time: cpuTime, percent: math.Min(percent, 100)})
+}
+
+// scraped : record a successful scrape at the given time
+func (h *nodeHistory) scraped(t time.Time) {
+	if t.After(h.lastScrapeTime) {
+		h.lastScrapeTime = t
+	}
+}
+
+// prune : drop the samples older than the start of the window
+func (h *nodeHistory) prune(start time.Time) {
+	prune := func(samples []usageSample) []usageSample {
+		i := 0
+		for i < len(samples) && samples[i].time.Before(start) {
+			i++
+		}
+		return samples[i:]
+	}
+	h.cpu = prune(h.cpu)
+	h.memory = prune(h.memory)
+}
+
+// samplesMetrics : the average and standard deviation of the samples, as reported by the load watcher
+func samplesMetrics(metricType string, samples []usageSample) []watcher.Metric {
+	if len(samples) == 0 {
+		return nil
+	}
+	var sum, sumSquares float64
+	for _, s := range samples {
+		sum += s.percent
+		sumSquares += s.percent * s.percent
+	}
+	n := float64(len(samples))
+	avg := sum / n
+	std := math.Sqrt(math.Max(sumSquares/n-avg*avg, 0))
+	return []watcher.Metric{
+		{Name: metricType, Type: metricType, Operator: watcher.Average, Value: avg},
+		{Name: metricType, Type: metricType, Operator: watcher.Std, Value: std},
+	}
+}
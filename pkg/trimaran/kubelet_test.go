@@ -0,0 +1,210 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trimaran
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paypal/load-watcher/pkg/watcher"
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+
+	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
+)
+
+// fakeKubelets : stand-in of the apiserver serving the nodes and the resource metrics of their kubelets
+type fakeKubelets struct {
+	mu    sync.Mutex
+	nodes []v1.Node
+	// cumulative cpu usage in seconds, and memory working set in bytes, by node
+	cpuSeconds  map[string]float64
+	memoryBytes map[string]float64
+	now         time.Time
+}
+
+func (f *fakeKubelets) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if req.URL.Path == "/api/v1/nodes" {
+		resp.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(resp).Encode(&v1.NodeList{Items: f.nodes})
+		return
+	}
+	for _, node := range f.nodes {
+		if req.URL.Path != "/api/v1/nodes/"+node.Name+"/proxy/metrics/resource" {
+			continue
+		}
+		if _, ok := f.cpuSeconds[node.Name]; !ok {
+			http.Error(resp, "kubelet unreachable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(resp, "# TYPE %s counter\n%s %v %d\n", nodeCPUUsageMetric, nodeCPUUsageMetric,
+			f.cpuSeconds[node.Name], f.now.UnixMilli())
+		fmt.Fprintf(resp, "# TYPE %s gauge\n%s %v %d\n", nodeMemoryUsageMetric, nodeMemoryUsageMetric,
+			f.memoryBytes[node.Name], f.now.UnixMilli())
+		return
+	}
+	http.NotFound(resp, req)
+}
+
+func (f *fakeKubelets) set(now time.Time, nodeName string, cpuSeconds, memoryBytes float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+	f.cpuSeconds[nodeName] = cpuSeconds
+	f.memoryBytes[nodeName] = memoryBytes
+}
+
+func metricValue(metrics []watcher.Metric, metricType, operator string) (float64, bool) {
+	for _, metric := range metrics {
+		if metric.Type == metricType && metric.Operator == operator {
+			return metric.Value, true
+		}
+	}
+	return 0, false
+}
+
+func TestKubeletClient(t *testing.T) {
+	nodeResources := map[v1.ResourceName]string{
+		v1.ResourceCPU:    "2",
+		v1.ResourceMemory: "1Gi",
+	}
+	kubelets := &fakeKubelets{
+		nodes: []v1.Node{
+			*st.MakeNode().Name("node-1").Capacity(nodeResources).Obj(),
+			*st.MakeNode().Name("node-2").Capacity(nodeResources).Obj(),
+		},
+		cpuSeconds:  map[string]float64{},
+		memoryBytes: map[string]float64{},
+	}
+	server := httptest.NewServer(kubelets)
+	defer server.Close()
+
+	client, err := newKubeletClient(&rest.Config{Host: server.URL}, 5*time.Minute)
+	assert.Nil(t, err)
+	start := time.Unix(1700000000, 0)
+	now := start
+	client.now = func() time.Time { return now }
+
+	// first scrape: the memory utilization is known, the cpu one needs a second usage
+	kubelets.set(now, "node-1", 100, 256*1024*1024)
+	metrics, err := client.GetLatestWatcherMetrics()
+	assert.Nil(t, err)
+	assert.Equal(t, watcher.Window{Duration: watcher.FifteenMinutes, Start: now.Add(-15 * time.Minute).Unix(), End: now.Unix()}, metrics.Window)
+	assert.NotContains(t, metrics.Data.NodeMetricsMap, "node-2", "expected no metrics for the unreachable kubelet")
+	node1 := metrics.Data.NodeMetricsMap["node-1"].Metrics
+	_, found := metricValue(node1, watcher.CPU, watcher.Average)
+	assert.False(t, found)
+	memory, _ := metricValue(node1, watcher.Memory, watcher.Average)
+	assert.InDelta(t, 25, memory, 1e-9)
+
+	// 60 cpu seconds over 60 seconds on 2 cores: 50%
+	now = now.Add(time.Minute)
+	kubelets.set(now, "node-1", 160, 768*1024*1024)
+	metrics, err = client.GetLatestWatcherMetrics()
+	assert.Nil(t, err)
+	node1 = metrics.Data.NodeMetricsMap["node-1"].Metrics
+	cpu, _ := metricValue(node1, watcher.CPU, watcher.Average)
+	assert.InDelta(t, 50, cpu, 1e-9)
+	cpuStd, _ := metricValue(node1, watcher.CPU, watcher.Std)
+	assert.InDelta(t, 0, cpuStd, 1e-9)
+	memory, _ = metricValue(node1, watcher.Memory, watcher.Average)
+	assert.InDelta(t, 50, memory, 1e-9)
+	memoryStd, _ := metricValue(node1, watcher.Memory, watcher.Std)
+	assert.InDelta(t, 25, memoryStd, 1e-9)
+
+	// 12 cpu seconds over 60 seconds on 2 cores: 10%
+	now = now.Add(time.Minute)
+	kubelets.set(now, "node-1", 172, 768*1024*1024)
+	metrics, err = client.GetLatestWatcherMetrics()
+	assert.Nil(t, err)
+	node1 = metrics.Data.NodeMetricsMap["node-1"].Metrics
+	cpu, _ = metricValue(node1, watcher.CPU, watcher.Average)
+	assert.InDelta(t, 30, cpu, 1e-9)
+	cpuStd, _ = metricValue(node1, watcher.CPU, watcher.Std)
+	assert.InDelta(t, 20, cpuStd, 1e-9)
+
+	// the samples out of the window are dropped: the window starts 1m30s after the first scrape
+	now = now.Add(14*time.Minute + 30*time.Second)
+	kubelets.set(now, "node-1", 172, 512*1024*1024)
+	metrics, err = client.GetLatestWatcherMetrics()
+	assert.Nil(t, err)
+	node1 = metrics.Data.NodeMetricsMap["node-1"].Metrics
+	cpu, _ = metricValue(node1, watcher.CPU, watcher.Average)
+	assert.InDelta(t, 5, cpu, 1e-9, "expected the 10% and idle cpu samples in the window")
+	memory, _ = metricValue(node1, watcher.Memory, watcher.Average)
+	assert.InDelta(t, 62.5, memory, 1e-9, "expected the 75% and 50% memory samples in the window")
+
+	// the window ends with the last successful scrape, and the metrics of a kubelet unreachable for longer than
+	// the staleness threshold are left out
+	lastScrape := now
+	kubelets.mu.Lock()
+	delete(kubelets.cpuSeconds, "node-1")
+	kubelets.mu.Unlock()
+	now = now.Add(2 * time.Minute)
+	metrics, err = client.GetLatestWatcherMetrics()
+	assert.Nil(t, err)
+	assert.Equal(t, lastScrape.Unix(), metrics.Window.End)
+	assert.Contains(t, metrics.Data.NodeMetricsMap, "node-1", "expected the recent samples to be kept")
+	now = now.Add(4 * time.Minute)
+	metrics, err = client.GetLatestWatcherMetrics()
+	assert.Nil(t, err)
+	assert.Equal(t, lastScrape.Unix(), metrics.Window.End)
+	assert.NotContains(t, metrics.Data.NodeMetricsMap, "node-1", "expected the stale samples to be left out")
+
+	// the history of the nodes which are gone is forgotten
+	kubelets.mu.Lock()
+	kubelets.nodes = kubelets.nodes[1:]
+	kubelets.mu.Unlock()
+	_, err = client.GetLatestWatcherMetrics()
+	assert.Nil(t, err)
+	assert.NotContains(t, client.history, "node-1")
+}
+
+func TestNewCollectorKubelet(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(&fakeKubelets{
+		nodes:       []v1.Node{*st.MakeNode().Name("node-1").Capacity(map[v1.ResourceName]string{v1.ResourceMemory: "1Gi"}).Obj()},
+		cpuSeconds:  map[string]float64{"node-1": 1},
+		memoryBytes: map[string]float64{"node-1": 512 * 1024 * 1024},
+	})
+	defer server.Close()
+
+	trimaranSpec := pluginConfig.TrimaranSpec{MetricProvider: pluginConfig.MetricProviderSpec{Type: pluginConfig.Kubelet}}
+	_, err := NewCollector(ctx, &trimaranSpec, nil)
+	assert.EqualError(t, err, "kube config is required by the Kubelet metric provider")
+
+	collector, err := NewCollector(ctx, &trimaranSpec, &rest.Config{Host: server.URL})
+	assert.Nil(t, err)
+	defer collector.stop()
+	metrics, _ := collector.GetNodeMetrics("node-1")
+	memory, found := metricValue(metrics, watcher.Memory, watcher.Average)
+	assert.True(t, found)
+	assert.InDelta(t, 50, memory, 1e-9)
+}
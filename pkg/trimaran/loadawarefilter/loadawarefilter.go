@@ -0,0 +1,196 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package loadawarefilter plugin rejects the nodes whose utilization of cpu, memory, network or disk, predicted once
+the pod runs on them, exceeds a hard threshold. Pods of exempt priority classes or namespaces are not filtered.
+It contains plugin for Filter extension point.
+*/
+package loadawarefilter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
+	"sigs.k8s.io/scheduler-plugins/apis/config/validation"
+	"sigs.k8s.io/scheduler-plugins/pkg/trimaran"
+)
+
+const (
+	Name = "LoadAwareFilter"
+
+	// ErrReasonThresholdExceeded is the reason of the nodes rejected for the predicted utilization of a resource.
+	ErrReasonThresholdExceeded = "node(s) would exceed the %v utilization threshold"
+)
+
+// resourceThreshold is the node utilization of a resource above which the nodes are rejected.
+type resourceThreshold struct {
+	name v1.ResourceName
+	// utilization percent
+	threshold int64
+	// type of the metrics of the resource reported by the load watcher
+	metricType string
+}
+
+// LoadAwareFilter : Filter plugin rejecting the nodes which are too loaded to run the pod
+type LoadAwareFilter struct {
+	handle                framework.Handle
+	eventHandler          *trimaran.PodAssignEventHandler
+	collector             *trimaran.Collector
+	args                  *pluginConfig.LoadAwareFilterArgs
+	resourceThresholds    []resourceThreshold
+	predictor             trimaran.UtilizationPredictor
+	exemptPriorityClasses sets.Set[string]
+	exemptNamespaces      sets.Set[string]
+}
+
+var _ framework.FilterPlugin = &LoadAwareFilter{}
+var _ framework.EnqueueExtensions = &LoadAwareFilter{}
+
+// New : create an instance of a LoadAwareFilter plugin
+func New(ctx context.Context, obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+	klog.V(4).InfoS("Creating new instance of the LoadAwareFilter plugin")
+	// cast object into plugin arguments object
+	args, ok := obj.(*pluginConfig.LoadAwareFilterArgs)
+	if !ok {
+		return nil, fmt.Errorf("want args to be of type LoadAwareFilterArgs, got %T", obj)
+	}
+	if err := validation.ValidateLoadAwareFilterArgs(nil, args); err != nil {
+		return nil, err
+	}
+	requestsMultiplier, err := strconv.ParseFloat(args.DefaultRequestsMultiplier, 64)
+	if err != nil {
+		return nil, errors.New("unable to parse DefaultRequestsMultiplier: " + err.Error())
+	}
+
+	resourceThresholds := make([]resourceThreshold, 0, len(args.ResourceThresholds))
+	for name, threshold := range args.ResourceThresholds {
+		resourceThresholds = append(resourceThresholds, resourceThreshold{
+			name:       name,
+			threshold:  threshold,
+			metricType: trimaran.MetricTypes[name],
+		})
+	}
+	// check the resources in a stable order, for the reason of the rejection to be stable
+	sort.Slice(resourceThresholds, func(i, j int) bool {
+		return resourceThresholds[i].name < resourceThresholds[j].name
+	})
+
+	klog.V(4).InfoS("Using LoadAwareFilterArgs",
+		"requestsMilliCores", args.DefaultRequests.Cpu().MilliValue(),
+		"requestsMultiplier", requestsMultiplier,
+		"resourceThresholds", args.ResourceThresholds,
+		"exemptPriorityClasses", args.ExemptPriorityClasses,
+		"exemptNamespaces", args.ExemptNamespaces)
+
+	collector, err := trimaran.AcquireCollector(ctx, &args.TrimaranSpec, handle.KubeConfig())
+	if err != nil {
+		return nil, err
+	}
+	podAssignEventHandler, err := trimaran.AcquireEventHandler(ctx, &args.TrimaranSpec, handle)
+	if err != nil {
+		return nil, err
+	}
+
+	pl := &LoadAwareFilter{
+		handle:             handle,
+		eventHandler:       podAssignEventHandler,
+		collector:          collector,
+		args:               args,
+		resourceThresholds: resourceThresholds,
+		predictor: trimaran.UtilizationPredictor{
+			DefaultRequests:    args.DefaultRequests,
+			RequestsMultiplier: requestsMultiplier,
+		},
+		exemptPriorityClasses: sets.New(args.ExemptPriorityClasses...),
+		exemptNamespaces:      sets.New(args.ExemptNamespaces...),
+	}
+	return pl, nil
+}
+
+// Name : name of plugin
+func (pl *LoadAwareFilter) Name() string {
+	return Name
+}
+
+// Filter : reject the node if the predicted utilization of a resource exceeds its threshold once the pod runs on it
+func (pl *LoadAwareFilter) Filter(ctx context.Context, cycleState *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
+	if pl.isExempt(pod) {
+		return nil
+	}
+	if exceeded := pl.exceededThreshold(pod, nodeInfo, nil); exceeded != "" {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf(ErrReasonThresholdExceeded, exceeded))
+	}
+	return nil
+}
+
+// isExempt : whether the pod is of an exempt priority class or namespace
+func (pl *LoadAwareFilter) isExempt(pod *v1.Pod) bool {
+	return pl.exemptNamespaces.Has(pod.Namespace) ||
+		(pod.Spec.PriorityClassName != "" && pl.exemptPriorityClasses.Has(pod.Spec.PriorityClassName))
+}
+
+// exceededThreshold : the first resource whose predicted utilization exceeds its threshold once the pod runs on the
+// node, or an empty name if none. The usage of the deleted pod, if any, is taken off the node.
+// The nodes without load metrics are not rejected, unless they are derived from allocation in the fallback mode.
+func (pl *LoadAwareFilter) exceededThreshold(pod *v1.Pod, nodeInfo *framework.NodeInfo, deletedPod *v1.Pod) v1.ResourceName {
+	node := nodeInfo.Node()
+	if node == nil {
+		return ""
+	}
+	metrics, allMetrics, fromAllocation := pl.collector.GetNodeMetricsOrAllocation(nodeInfo)
+	if metrics == nil {
+		klog.V(6).InfoS("Failed to get metrics for node; not filtering it", "nodeName", node.Name)
+		return ""
+	}
+	// The requests of the pods scheduled on the node are part of the allocation already.
+	var missingUsage map[v1.ResourceName]int64
+	if !fromAllocation {
+		missingUsage = pl.eventHandler.PredictedDeltas(node.Name, &allMetrics.Window, &pl.predictor)
+	}
+
+	for _, t := range pl.resourceThresholds {
+		nodeUtilPercent, metricFound := trimaran.NodeUtilization(metrics, t.metricType)
+		if !metricFound {
+			klog.V(6).InfoS("Resource metric not found in node metrics; not filtering on it", "nodeName", node.Name, "resource", t.name)
+			continue
+		}
+		predictedUsage := nodeUtilPercent
+		// The usage of the pods is only predicted for the resources they request.
+		if trimaran.IsPredictable(t.name) {
+			predictedUsage = pl.predictor.PredictNodeUtilization(t.name, node, nodeUtilPercent, pod, missingUsage[t.name])
+			if capacity := trimaran.QuantityValue(t.name, node.Status.Capacity[t.name]); deletedPod != nil && capacity != 0 {
+				predictedUsage -= 100 * float64(pl.predictor.PredictPodUtilization(deletedPod, t.name)) / float64(capacity)
+			}
+		}
+		klog.V(6).InfoS("Predicted utilization of resource of host", "nodeName", node.Name, "resource", t.name,
+			"predictedUsage", predictedUsage, "threshold", t.threshold)
+		if predictedUsage > float64(t.threshold) {
+			return t.name
+		}
+	}
+	return ""
+}
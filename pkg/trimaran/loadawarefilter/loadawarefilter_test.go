@@ -0,0 +1,213 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadawarefilter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/paypal/load-watcher/pkg/watcher"
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	testClientSet "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
+	"k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+	tf "k8s.io/kubernetes/pkg/scheduler/testing/framework"
+
+	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
+	cfgv1 "sigs.k8s.io/scheduler-plugins/apis/config/v1"
+	testutil "sigs.k8s.io/scheduler-plugins/test/util"
+)
+
+var nodeResources = map[v1.ResourceName]string{
+	v1.ResourceCPU:    "1000m",
+	v1.ResourceMemory: "1Gi",
+}
+
+// newTestPlugin creates the plugin with a load watcher serving the metrics, and a snapshot of the pods and nodes.
+func newTestPlugin(t *testing.T, ctx context.Context, watcherResponse watcher.WatcherMetrics, args pluginConfig.LoadAwareFilterArgs,
+	pods []*v1.Pod, nodes []*v1.Node) *LoadAwareFilter {
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		bytes, err := json.Marshal(watcherResponse)
+		assert.Nil(t, err)
+		resp.Write(bytes)
+	}))
+	t.Cleanup(server.Close)
+
+	cs := testClientSet.NewSimpleClientset()
+	fh, err := tf.NewFramework(ctx, []tf.RegisterPluginFunc{
+		tf.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		tf.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+	}, "default-scheduler", runtime.WithClientSet(cs),
+		runtime.WithInformerFactory(informers.NewSharedInformerFactory(cs, 0)),
+		runtime.WithSnapshotSharedLister(testutil.NewFakeSharedLister(pods, nodes)))
+	assert.Nil(t, err)
+
+	args.WatcherAddress = server.URL
+	if args.DefaultRequestsMultiplier == "" {
+		args.DefaultRequestsMultiplier = cfgv1.DefaultRequestsMultiplier
+	}
+	if args.ResourceThresholds == nil {
+		args.ResourceThresholds = cfgv1.DefaultResourceThresholds
+	}
+	p, err := New(ctx, &args, fh)
+	assert.Nil(t, err)
+	return p.(*LoadAwareFilter)
+}
+
+func nodeMetrics(metrics map[string][]watcher.Metric) watcher.WatcherMetrics {
+	nodeMetricsMap := make(map[string]watcher.NodeMetrics, len(metrics))
+	for nodeName, m := range metrics {
+		nodeMetricsMap[nodeName] = watcher.NodeMetrics{Metrics: m}
+	}
+	return watcher.WatcherMetrics{Data: watcher.Data{NodeMetricsMap: nodeMetricsMap}}
+}
+
+func withPriorityClassName(pod *v1.Pod, priorityClassName string) *v1.Pod {
+	pod = pod.DeepCopy()
+	pod.Spec.PriorityClassName = priorityClassName
+	return pod
+}
+
+func TestNew(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs := testClientSet.NewSimpleClientset()
+	fh, err := tf.NewFramework(ctx, []tf.RegisterPluginFunc{
+		tf.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		tf.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+	}, "default-scheduler", runtime.WithClientSet(cs), runtime.WithInformerFactory(informers.NewSharedInformerFactory(cs, 0)))
+	assert.Nil(t, err)
+
+	p, err := New(ctx, &pluginConfig.LoadAwareFilterArgs{
+		TrimaranSpec:              pluginConfig.TrimaranSpec{WatcherAddress: "http://deadbeef:2020"},
+		DefaultRequestsMultiplier: cfgv1.DefaultRequestsMultiplier,
+		ResourceThresholds:        cfgv1.DefaultResourceThresholds,
+	}, fh)
+	assert.Nil(t, err)
+	assert.NotNil(t, p)
+
+	_, err = New(ctx, &pluginConfig.LoadAwareFilterArgs{
+		TrimaranSpec:              pluginConfig.TrimaranSpec{WatcherAddress: "http://deadbeef:2020"},
+		DefaultRequestsMultiplier: cfgv1.DefaultRequestsMultiplier,
+		ResourceThresholds:        map[v1.ResourceName]int64{v1.ResourceCPU: 120},
+	}, fh)
+	assert.NotNil(t, err)
+}
+
+func TestFilter(t *testing.T) {
+	node := st.MakeNode().Name("node-1").Capacity(nodeResources).Obj()
+	// predicted to use 150m of cpu, 15% of the node
+	pod := st.MakePod().Name("p").Namespace("default").Req(map[v1.ResourceName]string{v1.ResourceCPU: "100m"}).Obj()
+
+	tests := []struct {
+		name         string
+		pod          *v1.Pod
+		args         pluginConfig.LoadAwareFilterArgs
+		existingPods []*v1.Pod
+		metrics      []watcher.Metric
+		want         *framework.Status
+	}{
+		{
+			name:    "node below the thresholds",
+			pod:     pod,
+			metrics: []watcher.Metric{{Type: watcher.CPU, Operator: watcher.Latest, Value: 50}},
+		},
+		{
+			name:    "node above the cpu threshold with the pod",
+			pod:     pod,
+			metrics: []watcher.Metric{{Type: watcher.CPU, Operator: watcher.Latest, Value: 80}},
+			want:    framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf(ErrReasonThresholdExceeded, v1.ResourceCPU)),
+		},
+		{
+			name: "node above the memory threshold",
+			pod:  pod,
+			metrics: []watcher.Metric{
+				{Type: watcher.CPU, Operator: watcher.Latest, Value: 10},
+				{Type: watcher.Memory, Operator: watcher.Average, Value: 95},
+			},
+			want: framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf(ErrReasonThresholdExceeded, v1.ResourceMemory)),
+		},
+		{
+			name: "node above the network threshold",
+			pod:  pod,
+			args: pluginConfig.LoadAwareFilterArgs{
+				ResourceThresholds: map[v1.ResourceName]int64{v1.ResourceCPU: 90, pluginConfig.ResourceNetwork: 50},
+			},
+			metrics: []watcher.Metric{
+				{Type: watcher.CPU, Operator: watcher.Latest, Value: 10},
+				{Type: watcher.Bandwidth, Operator: watcher.Latest, Value: 60},
+			},
+			want: framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf(ErrReasonThresholdExceeded, pluginConfig.ResourceNetwork)),
+		},
+		{
+			name:    "pod of exempt namespace",
+			pod:     st.MakePod().Name("p").Namespace("kube-system").Req(map[v1.ResourceName]string{v1.ResourceCPU: "100m"}).Obj(),
+			args:    pluginConfig.LoadAwareFilterArgs{ExemptNamespaces: []string{"kube-system"}},
+			metrics: []watcher.Metric{{Type: watcher.CPU, Operator: watcher.Latest, Value: 98}},
+		},
+		{
+			name:    "pod of exempt priority class",
+			pod:     withPriorityClassName(pod, "system-node-critical"),
+			args:    pluginConfig.LoadAwareFilterArgs{ExemptPriorityClasses: []string{"system-node-critical"}},
+			metrics: []watcher.Metric{{Type: watcher.CPU, Operator: watcher.Latest, Value: 98}},
+		},
+		{
+			name: "node without metrics",
+			pod:  pod,
+		},
+		{
+			name: "node without metrics above the threshold with allocation fallback",
+			pod:  pod,
+			args: pluginConfig.LoadAwareFilterArgs{
+				TrimaranSpec: pluginConfig.TrimaranSpec{FallbackMode: pluginConfig.AllocationFallback},
+			},
+			existingPods: []*v1.Pod{
+				st.MakePod().Name("existing").Node("node-1").Req(map[v1.ResourceName]string{v1.ResourceCPU: "800m"}).Obj(),
+			},
+			want: framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf(ErrReasonThresholdExceeded, v1.ResourceCPU)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			metrics := map[string][]watcher.Metric{}
+			if tt.metrics != nil {
+				metrics[node.Name] = tt.metrics
+			}
+			pl := newTestPlugin(t, ctx, nodeMetrics(metrics), tt.args, tt.existingPods, []*v1.Node{node})
+			nodeInfo, err := pl.handle.SnapshotSharedLister().NodeInfos().Get(node.Name)
+			assert.Nil(t, err)
+
+			got := pl.Filter(ctx, framework.NewCycleState(), tt.pod, nodeInfo)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadawarefilter
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	schedutil "k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+// EventsToRegister returns the possible events that may make a pod rejected by this plugin schedulable.
+// The load metrics are refreshed in the background without any event, so a refresh alone doesn't requeue
+// the rejected pods: they're retried with the periodic flush of the unschedulable pods of the scheduler.
+func (pl *LoadAwareFilter) EventsToRegister() []framework.ClusterEventWithHint {
+	return []framework.ClusterEventWithHint{
+		{Event: framework.ClusterEvent{Resource: framework.Node, ActionType: framework.Add | framework.Update}, QueueingHintFn: pl.isSchedulableAfterNodeChange},
+		{Event: framework.ClusterEvent{Resource: framework.Pod, ActionType: framework.Delete}, QueueingHintFn: pl.isSchedulableAfterPodDeleted},
+	}
+}
+
+// isSchedulableAfterNodeChange requeues the pod when the updated node is below the thresholds with the latest
+// metrics of the collector.
+func (pl *LoadAwareFilter) isSchedulableAfterNodeChange(logger klog.Logger, pod *v1.Pod, oldObj, newObj interface{}) (framework.QueueingHint, error) {
+	_, node, err := schedutil.As[*v1.Node](oldObj, newObj)
+	if err != nil {
+		return framework.Queue, err
+	}
+	if exceeded := pl.exceededThreshold(pod, pl.nodeInfo(node), nil); exceeded != "" {
+		logger.V(5).Info("node is still above the utilization threshold", "pod", klog.KObj(pod), "node", klog.KObj(node), "resource", exceeded)
+		return framework.QueueSkip, nil
+	}
+	logger.V(5).Info("node is below the utilization thresholds", "pod", klog.KObj(pod), "node", klog.KObj(node))
+	return framework.Queue, nil
+}
+
+// isSchedulableAfterPodDeleted requeues the pod when an assigned pod is deleted and the node is below the thresholds
+// without its usage, which the metrics may not reflect yet.
+func (pl *LoadAwareFilter) isSchedulableAfterPodDeleted(logger klog.Logger, pod *v1.Pod, oldObj, newObj interface{}) (framework.QueueingHint, error) {
+	deletedPod, _, err := schedutil.As[*v1.Pod](oldObj, newObj)
+	if err != nil {
+		return framework.Queue, err
+	}
+	if deletedPod.Spec.NodeName == "" {
+		logger.V(5).Info("deleted pod was not assigned, it frees no resources", "pod", klog.KObj(pod), "deletedPod", klog.KObj(deletedPod))
+		return framework.QueueSkip, nil
+	}
+	nodeInfo, err := pl.handle.SnapshotSharedLister().NodeInfos().Get(deletedPod.Spec.NodeName)
+	if err != nil || nodeInfo == nil {
+		return framework.Queue, err
+	}
+	if exceeded := pl.exceededThreshold(pod, nodeInfo, deletedPod); exceeded != "" {
+		logger.V(5).Info("node of the deleted pod is still above the utilization threshold", "pod", klog.KObj(pod), "deletedPod", klog.KObj(deletedPod), "resource", exceeded)
+		return framework.QueueSkip, nil
+	}
+	logger.V(5).Info("deleted pod brought its node below the utilization thresholds", "pod", klog.KObj(pod), "deletedPod", klog.KObj(deletedPod))
+	return framework.Queue, nil
+}
+
+// nodeInfo returns the node info of the snapshot with the updated node, or the node alone if it's new.
+func (pl *LoadAwareFilter) nodeInfo(node *v1.Node) *framework.NodeInfo {
+	nodeInfo, err := pl.handle.SnapshotSharedLister().NodeInfos().Get(node.Name)
+	if err != nil || nodeInfo == nil {
+		nodeInfo = framework.NewNodeInfo()
+	} else {
+		nodeInfo = nodeInfo.Snapshot()
+	}
+	nodeInfo.SetNode(node)
+	return nodeInfo
+}
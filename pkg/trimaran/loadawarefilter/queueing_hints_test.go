@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadawarefilter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/paypal/load-watcher/pkg/watcher"
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2/ktesting"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	st "k8s.io/kubernetes/pkg/scheduler/testing"
+
+	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
+)
+
+func TestIsSchedulableAfterNodeChange(t *testing.T) {
+	logger, ctx := ktesting.NewTestContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	hotNode := st.MakeNode().Name("hot").Capacity(nodeResources).Obj()
+	coolNode := st.MakeNode().Name("cool").Capacity(nodeResources).Obj()
+	newNode := st.MakeNode().Name("new").Capacity(nodeResources).Obj()
+	pl := newTestPlugin(t, ctx, nodeMetrics(map[string][]watcher.Metric{
+		hotNode.Name:  {{Type: watcher.CPU, Operator: watcher.Latest, Value: 85}},
+		coolNode.Name: {{Type: watcher.CPU, Operator: watcher.Latest, Value: 50}},
+	}), pluginConfig.LoadAwareFilterArgs{}, nil, []*v1.Node{hotNode, coolNode})
+	pod := st.MakePod().Name("p").Req(map[v1.ResourceName]string{v1.ResourceCPU: "100m"}).Obj()
+
+	tests := []struct {
+		name           string
+		oldObj, newObj interface{}
+		want           framework.QueueingHint
+	}{
+		{
+			name:   "node still above the threshold",
+			oldObj: hotNode,
+			newObj: hotNode,
+			want:   framework.QueueSkip,
+		},
+		{
+			name:   "node below the thresholds",
+			oldObj: coolNode,
+			newObj: coolNode,
+			want:   framework.Queue,
+		},
+		{
+			name:   "new node without metrics",
+			newObj: newNode,
+			want:   framework.Queue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pl.isSchedulableAfterNodeChange(logger, pod, tt.oldObj, tt.newObj)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestIsSchedulableAfterPodDeleted(t *testing.T) {
+	logger, ctx := ktesting.NewTestContext(t)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	node := st.MakeNode().Name("node-1").Capacity(nodeResources).Obj()
+	pl := newTestPlugin(t, ctx, nodeMetrics(map[string][]watcher.Metric{
+		node.Name: {{Type: watcher.CPU, Operator: watcher.Latest, Value: 85}},
+	}), pluginConfig.LoadAwareFilterArgs{}, nil, []*v1.Node{node})
+	// predicted to use 150m of cpu, 15% of the node
+	pod := st.MakePod().Name("p").Req(map[v1.ResourceName]string{v1.ResourceCPU: "100m"}).Obj()
+
+	tests := []struct {
+		name       string
+		deletedPod *v1.Pod
+		want       framework.QueueingHint
+	}{
+		{
+			name:       "unassigned pod",
+			deletedPod: st.MakePod().Name("deleted").Req(map[v1.ResourceName]string{v1.ResourceCPU: "300m"}).Obj(),
+			want:       framework.QueueSkip,
+		},
+		{
+			name:       "assigned pod freeing too little",
+			deletedPod: st.MakePod().Name("deleted").Node(node.Name).Req(map[v1.ResourceName]string{v1.ResourceCPU: "50m"}).Obj(),
+			want:       framework.QueueSkip,
+		},
+		{
+			name:       "assigned pod bringing the node below the threshold",
+			deletedPod: st.MakePod().Name("deleted").Node(node.Name).Req(map[v1.ResourceName]string{v1.ResourceCPU: "300m"}).Obj(),
+			want:       framework.Queue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pl.isSchedulableAfterPodDeleted(logger, pod, tt.deletedPod, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
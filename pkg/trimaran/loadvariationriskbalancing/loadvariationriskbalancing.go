@@ -33,6 +33,7 @@ import (
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 
 	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
+	"sigs.k8s.io/scheduler-plugins/apis/config/validation"
 	"sigs.k8s.io/scheduler-plugins/pkg/trimaran"
 )
 
@@ -47,26 +48,33 @@ type LoadVariationRiskBalancing struct {
 	eventHandler *trimaran.PodAssignEventHandler
 	collector    *trimaran.Collector
 	args         *pluginConfig.LoadVariationRiskBalancingArgs
+	// forecaster of the utilization of the nodes, nil unless the forecasting is enabled
+	forecaster *trimaran.Forecaster
 }
 
 var _ framework.ScorePlugin = &LoadVariationRiskBalancing{}
 
 // New : create an instance of a LoadVariationRiskBalancing plugin
-func New(_ context.Context, obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+func New(ctx context.Context, obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
 	klog.V(4).InfoS("Creating new instance of the LoadVariationRiskBalancing plugin")
 	// cast object into plugin arguments object
 	args, ok := obj.(*pluginConfig.LoadVariationRiskBalancingArgs)
 	if !ok {
 		return nil, fmt.Errorf("want args to be of type LoadVariationRiskBalancingArgs, got %T", obj)
 	}
-	collector, err := trimaran.NewCollector(&args.TrimaranSpec)
+	if err := validation.ValidateLoadVariationRiskBalancingArgs(nil, args); err != nil {
+		return nil, err
+	}
+	collector, err := trimaran.AcquireCollector(ctx, &args.TrimaranSpec, handle.KubeConfig())
 	if err != nil {
 		return nil, err
 	}
 	klog.V(4).InfoS("Using LoadVariationRiskBalancingArgs", "margin", args.SafeVarianceMargin, "sensitivity", args.SafeVarianceSensitivity)
 
-	podAssignEventHandler := trimaran.New()
-	podAssignEventHandler.AddToHandle(handle)
+	podAssignEventHandler, err := trimaran.AcquireEventHandler(ctx, &args.TrimaranSpec, handle)
+	if err != nil {
+		return nil, err
+	}
 
 	pl := &LoadVariationRiskBalancing{
 		handle:       handle,
@@ -74,6 +82,9 @@ func New(_ context.Context, obj runtime.Object, handle framework.Handle) (framew
 		collector:    collector,
 		args:         args,
 	}
+	if args.Forecast != nil {
+		pl.forecaster = trimaran.NewForecaster(ctx, collector, args.Forecast)
+	}
 	return pl, nil
 }
 
@@ -85,12 +96,16 @@ func (pl *LoadVariationRiskBalancing) Score(ctx context.Context, cycleState *fra
 	if err != nil {
 		return score, framework.NewStatus(framework.Error, fmt.Sprintf("getting node %q from Snapshot: %v", nodeName, err))
 	}
-	// get node metrics
-	metrics, _ := pl.collector.GetNodeMetrics(nodeName)
+	// get node metrics, or derive them from allocation in the fallback mode
+	metrics, _, fromAllocation := pl.collector.GetNodeMetricsOrAllocation(nodeInfo)
 	if metrics == nil {
 		klog.InfoS("Failed to get metrics for node; using minimum score", "nodeName", nodeName)
 		return score, nil
 	}
+	// score the utilization forecast over the horizon instead of the current one
+	if pl.forecaster != nil && !fromAllocation {
+		metrics = pl.forecaster.ForecastMetrics(nodeName, metrics)
+	}
 	podRequest := trimaran.GetResourceRequested(pod)
 	node := nodeInfo.Node()
 
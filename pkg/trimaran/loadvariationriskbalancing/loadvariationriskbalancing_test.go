@@ -22,7 +22,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/paypal/load-watcher/pkg/watcher"
 	"github.com/stretchr/testify/assert"
@@ -142,6 +144,7 @@ func TestScore(t *testing.T) {
 		test            string
 		pod             *v1.Pod
 		nodes           []*v1.Node
+		fallbackMode    pluginConfig.FallbackMode
 		watcherResponse watcher.WatcherMetrics
 		expected        framework.NodeScoreList
 	}{
@@ -322,6 +325,18 @@ func TestScore(t *testing.T) {
 				{Name: "node-1", Score: framework.MinNodeScore},
 			},
 		},
+		{
+			test: "404 resp from watcher with allocation fallback",
+			pod:  st.MakePod().Name("p").Obj(),
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-1").Capacity(nodeResources).Obj(),
+			},
+			fallbackMode:    pluginConfig.AllocationFallback,
+			watcherResponse: watcher.WatcherMetrics{},
+			expected: []framework.NodeScore{
+				{Name: "node-1", Score: framework.MaxNodeScore},
+			},
+		},
 	}
 
 	registeredPlugins := []tf.RegisterPluginFunc{
@@ -345,7 +360,7 @@ func TestScore(t *testing.T) {
 			state := framework.NewCycleState()
 
 			loadVariationRiskBalancingArgs := pluginConfig.LoadVariationRiskBalancingArgs{
-				TrimaranSpec:            pluginConfig.TrimaranSpec{WatcherAddress: server.URL},
+				TrimaranSpec:            pluginConfig.TrimaranSpec{WatcherAddress: server.URL, FallbackMode: tt.fallbackMode},
 				SafeVarianceMargin:      cfgv1.DefaultSafeVarianceMargin,
 				SafeVarianceSensitivity: cfgv1.DefaultSafeVarianceSensitivity,
 			}
@@ -377,6 +392,68 @@ func TestScore(t *testing.T) {
 	}
 }
 
+func TestScoreForecast(t *testing.T) {
+	node := st.MakeNode().Name("node-1").Capacity(map[v1.ResourceName]string{v1.ResourceCPU: "1000m"}).Obj()
+	cpuMetrics := func(end time.Time, cpu float64) watcher.WatcherMetrics {
+		return watcher.WatcherMetrics{
+			Window: watcher.Window{Duration: "1m", Start: end.Add(-time.Minute).Unix(), End: end.Unix()},
+			Data: watcher.Data{NodeMetricsMap: map[string]watcher.NodeMetrics{
+				node.Name: {Metrics: []watcher.Metric{{Type: watcher.CPU, Operator: watcher.Average, Value: cpu}}},
+			}},
+		}
+	}
+	var mu sync.Mutex
+	watcherResponse := cpuMetrics(time.Now().Add(-time.Minute), 20)
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		bytes, err := json.Marshal(watcherResponse)
+		assert.Nil(t, err)
+		resp.Write(bytes)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs := testClientSet.NewSimpleClientset()
+	fh, err := tf.NewFramework(ctx, []tf.RegisterPluginFunc{
+		tf.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		tf.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+	}, "default-scheduler", runtime.WithClientSet(cs),
+		runtime.WithInformerFactory(informers.NewSharedInformerFactory(cs, 0)),
+		runtime.WithSnapshotSharedLister(newTestSharedLister(nil, []*v1.Node{node})))
+	assert.Nil(t, err)
+	p, err := New(ctx, &pluginConfig.LoadVariationRiskBalancingArgs{
+		TrimaranSpec:            pluginConfig.TrimaranSpec{WatcherAddress: server.URL, MetricsUpdateIntervalSeconds: 1},
+		SafeVarianceMargin:      cfgv1.DefaultSafeVarianceMargin,
+		SafeVarianceSensitivity: cfgv1.DefaultSafeVarianceSensitivity,
+		Forecast: &pluginConfig.ForecastSpec{
+			Model:          pluginConfig.HoltWintersForecast,
+			Alpha:          1,
+			Beta:           1,
+			HorizonSeconds: 60,
+		},
+	}, fh)
+	assert.Nil(t, err)
+	pl := p.(*LoadVariationRiskBalancing)
+	pod := st.MakePod().Name("p").Obj()
+
+	score, status := pl.Score(ctx, framework.NewCycleState(), pod, node.Name)
+	assert.True(t, status.IsSuccess())
+	assert.Equal(t, int64(90), score)
+
+	// rising by 30% a minute, the utilization is forecast to reach 80% over the horizon
+	mu.Lock()
+	watcherResponse = cpuMetrics(time.Now(), 50)
+	mu.Unlock()
+	assert.Eventually(t, func() bool {
+		score, status = pl.Score(ctx, framework.NewCycleState(), pod, node.Name)
+		return status.IsSuccess() && score <= 61
+	}, 5*time.Second, 100*time.Millisecond)
+	assert.InDelta(t, 60, score, 1)
+}
+
 func newTestSharedLister(pods []*v1.Pod, nodes []*v1.Node) *testSharedLister {
 	nodeInfoMap := make(map[string]*framework.NodeInfo)
 	nodeInfos := make([]*framework.NodeInfo, 0)
@@ -169,18 +169,32 @@ func RegularizedIncomplete(x, a, b float64) float64 {
 	}
 }
 
+// FitDistribution : The beta distribution matching the given mean and standard deviation, nil if none does
+func FitDistribution(mu, sigma float64) *BetaDistribution {
+	m1 := mu
+	m2 := (sigma * sigma) + (mu * mu)
+	betaDist := NewBetaDistribution(1, 1)
+	if !betaDist.MatchMoments(m1, m2) {
+		return nil
+	}
+	return betaDist
+}
+
 // ComputeProbability : The probability that the resource utilization is less than or equal to a given threshold value
 func ComputeProbability(mu, sigma, threshold float64) (float64, *BetaDistribution) {
+	return computeProbability(mu, sigma, threshold, FitDistribution)
+}
+
+// computeProbability : ComputeProbability, with the beta distribution fitted by fit
+func computeProbability(mu, sigma, threshold float64, fit func(mu, sigma float64) *BetaDistribution) (float64, *BetaDistribution) {
 	if mu == 0 || (sigma == 0 && mu <= threshold) {
 		return 1, nil
 	}
 	if sigma == 0 && mu > threshold {
 		return 0, nil
 	}
-	m1 := mu
-	m2 := (sigma * sigma) + (mu * mu)
-	betaDist := NewBetaDistribution(1, 1)
-	if !betaDist.MatchMoments(m1, m2) {
+	betaDist := fit(mu, sigma)
+	if betaDist == nil {
 		return 0, nil
 	}
 	belowLimit := betaDist.DistributionFunction(threshold)
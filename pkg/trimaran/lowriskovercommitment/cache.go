@@ -0,0 +1,71 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lowriskovercommitment
+
+import (
+	"sync"
+
+	"github.com/paypal/load-watcher/pkg/watcher"
+	v1 "k8s.io/api/core/v1"
+)
+
+// betaCache : the beta distributions fitted to the measured load of the nodes, reused across the scheduling
+// cycles until the metrics window changes
+type betaCache struct {
+	sync.Mutex
+	// window of the metrics the distributions were fitted to
+	window  watcher.Window
+	entries map[betaKey]*betaEntry
+}
+
+// betaKey : the node and resource a distribution is fitted to
+type betaKey struct {
+	nodeName     string
+	resourceName v1.ResourceName
+}
+
+// betaEntry : a distribution fitted to the mean and standard deviation of a load
+type betaEntry struct {
+	mu    float64
+	sigma float64
+	dist  *BetaDistribution
+}
+
+func newBetaCache() *betaCache {
+	return &betaCache{
+		entries: make(map[betaKey]*betaEntry),
+	}
+}
+
+// fit : the beta distribution fitted to the load of the resource of the node within the metrics window, reusing
+// the one fitted in an earlier cycle if the load is the same
+func (c *betaCache) fit(window watcher.Window, nodeName string, resourceName v1.ResourceName, mu, sigma float64) *BetaDistribution {
+	c.Lock()
+	defer c.Unlock()
+	// the distributions fitted to an earlier window are outdated
+	if window != c.window {
+		c.window = window
+		c.entries = make(map[betaKey]*betaEntry)
+	}
+	key := betaKey{nodeName: nodeName, resourceName: resourceName}
+	if entry, ok := c.entries[key]; ok && entry.mu == mu && entry.sigma == sigma {
+		return entry.dist
+	}
+	dist := FitDistribution(mu, sigma)
+	c.entries[key] = &betaEntry{mu: mu, sigma: sigma, dist: dist}
+	return dist
+}
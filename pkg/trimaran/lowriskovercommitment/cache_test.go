@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lowriskovercommitment
+
+import (
+	"testing"
+
+	"github.com/paypal/load-watcher/pkg/watcher"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestBetaCache(t *testing.T) {
+	c := newBetaCache()
+	window := watcher.Window{Start: 0, End: 900}
+
+	dist := c.fit(window, "node-1", v1.ResourceCPU, 0.4, 0.1)
+	assert.NotNil(t, dist)
+	assert.InDelta(t, 0.4, dist.Mean(), 1e-9)
+	assert.InDelta(t, 0.01, dist.Variance(), 1e-9)
+
+	// reused within the window, for the same node, resource and load
+	assert.Same(t, dist, c.fit(window, "node-1", v1.ResourceCPU, 0.4, 0.1))
+	assert.NotSame(t, dist, c.fit(window, "node-2", v1.ResourceCPU, 0.4, 0.1))
+	assert.NotSame(t, dist, c.fit(window, "node-1", v1.ResourceMemory, 0.4, 0.1))
+	assert.Len(t, c.entries, 3)
+
+	// refitted once the load changes, such as when it is forecast
+	refitted := c.fit(window, "node-1", v1.ResourceCPU, 0.5, 0.1)
+	assert.InDelta(t, 0.5, refitted.Mean(), 1e-9)
+	assert.Same(t, refitted, c.fit(window, "node-1", v1.ResourceCPU, 0.5, 0.1))
+	assert.Len(t, c.entries, 3)
+
+	// the distributions of the earlier windows are dropped
+	next := watcher.Window{Start: 60, End: 960}
+	assert.NotSame(t, refitted, c.fit(next, "node-1", v1.ResourceCPU, 0.5, 0.1))
+	assert.Len(t, c.entries, 1)
+
+	// no distribution matches a variance beyond the maximum
+	assert.Nil(t, c.fit(next, "node-1", v1.ResourceMemory, 0.5, 0.5))
+	assert.Len(t, c.entries, 2)
+}
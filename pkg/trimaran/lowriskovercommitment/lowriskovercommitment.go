@@ -25,6 +25,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sort"
 
 	"github.com/paypal/load-watcher/pkg/watcher"
 
@@ -35,6 +36,7 @@ import (
 
 	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
 	pluginv1 "sigs.k8s.io/scheduler-plugins/apis/config/v1"
+	"sigs.k8s.io/scheduler-plugins/apis/config/validation"
 	"sigs.k8s.io/scheduler-plugins/pkg/trimaran"
 )
 
@@ -55,17 +57,26 @@ type LowRiskOverCommitment struct {
 	collector           *trimaran.Collector
 	args                *pluginConfig.LowRiskOverCommitmentArgs
 	riskLimitWeightsMap map[v1.ResourceName]float64
+	// resources the risk is computed for, sorted by name
+	riskResources []v1.ResourceName
+	// beta distributions fitted to the load of the nodes, reused within a metrics window
+	cache *betaCache
+	// forecaster of the utilization of the nodes, nil unless the forecasting is enabled
+	forecaster *trimaran.Forecaster
 }
 
 // New : create an instance of a LowRiskOverCommitment plugin
-func New(_ context.Context, obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+func New(ctx context.Context, obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
 	klog.V(4).InfoS("Creating new instance of the LowRiskOverCommitment plugin")
 	// cast object into plugin arguments object
 	args, ok := obj.(*pluginConfig.LowRiskOverCommitmentArgs)
 	if !ok {
 		return nil, fmt.Errorf("want args to be of type LowRiskOverCommitmentArgs, got %T", obj)
 	}
-	collector, err := trimaran.NewCollector(&args.TrimaranSpec)
+	if err := validation.ValidateLowRiskOverCommitmentArgs(nil, args); err != nil {
+		return nil, err
+	}
+	collector, err := trimaran.AcquireCollector(ctx, &args.TrimaranSpec, handle.KubeConfig())
 	if err != nil {
 		return nil, err
 	}
@@ -76,14 +87,24 @@ func New(_ context.Context, obj runtime.Object, handle framework.Handle) (framew
 	for r, w := range args.RiskLimitWeights {
 		m[r] = w
 	}
+	riskResources := make([]v1.ResourceName, 0, len(m))
+	for r := range m {
+		riskResources = append(riskResources, r)
+	}
+	sort.Slice(riskResources, func(i, j int) bool { return riskResources[i] < riskResources[j] })
 	klog.V(4).InfoS("Using LowRiskOverCommitmentArgs", "smoothingWindowSize", args.SmoothingWindowSize,
-		"riskLimitWeights", m)
+		"riskLimitWeights", m, "riskAggregation", args.RiskAggregation, "riskAggregationWeights", args.RiskAggregationWeights)
 
 	pl := &LowRiskOverCommitment{
 		handle:              handle,
 		collector:           collector,
 		args:                args,
 		riskLimitWeightsMap: m,
+		riskResources:       riskResources,
+		cache:               newBetaCache(),
+	}
+	if args.Forecast != nil {
+		pl.forecaster = trimaran.NewForecaster(ctx, collector, args.Forecast)
 	}
 	return pl, nil
 }
@@ -115,8 +136,7 @@ func (pl *LowRiskOverCommitment) Score(ctx context.Context, cycleState *framewor
 	// exclude scoring for best effort pods; this plugin is not concerned about best effort pods
 	podRequests := &podResources.podRequests
 	podLimits := &podResources.podLimits
-	if podRequests.MilliCPU == 0 && podRequests.Memory == 0 &&
-		podLimits.MilliCPU == 0 && podLimits.Memory == 0 {
+	if pl.isBestEffort(podRequests, podLimits) {
 		klog.V(6).InfoS("Skipping scoring best effort pod; using minimum score", "nodeName", nodeName, "pod", klog.KObj(pod))
 		return score, nil
 	}
@@ -125,14 +145,23 @@ func (pl *LowRiskOverCommitment) Score(ctx context.Context, cycleState *framewor
 	if err != nil {
 		return score, framework.NewStatus(framework.Error, fmt.Sprintf("getting node %q from Snapshot: %v", nodeName, err))
 	}
-	// get node metrics
-	metrics, _ := pl.collector.GetNodeMetrics(nodeName)
+	// get node metrics, or derive them from allocation in the fallback mode
+	metrics, allMetrics, fromAllocation := pl.collector.GetNodeMetricsOrAllocation(nodeInfo)
 	if metrics == nil {
 		klog.InfoS("Failed to get metrics for node; using minimum score", "nodeName", nodeName)
 		return score, nil
 	}
+	// score the utilization forecast over the horizon instead of the current one
+	if pl.forecaster != nil && !fromAllocation {
+		metrics = pl.forecaster.ForecastMetrics(nodeName, metrics)
+	}
+	// the distributions fitted to the measured load are reused within its window
+	var window *watcher.Window
+	if allMetrics != nil && !fromAllocation {
+		window = &allMetrics.Window
+	}
 	// calculate score
-	totalScore := pl.computeRank(metrics, nodeInfo, pod, podRequests, podLimits) * float64(framework.MaxNodeScore)
+	totalScore := pl.computeRank(metrics, window, nodeInfo, pod, podRequests, podLimits) * float64(framework.MaxNodeScore)
 	score = int64(math.Round(totalScore))
 	return score, framework.NewStatus(framework.Success, "")
 }
@@ -153,22 +182,64 @@ func (pl *LowRiskOverCommitment) NormalizeScore(context.Context, *framework.Cycl
 }
 
 // computeRank : rank function for the LowRiskOverCommitment
-func (pl *LowRiskOverCommitment) computeRank(metrics []watcher.Metric, nodeInfo *framework.NodeInfo, pod *v1.Pod,
-	podRequests *framework.Resource, podLimits *framework.Resource) float64 {
+func (pl *LowRiskOverCommitment) computeRank(metrics []watcher.Metric, window *watcher.Window, nodeInfo *framework.NodeInfo,
+	pod *v1.Pod, podRequests *framework.Resource, podLimits *framework.Resource) float64 {
 	node := nodeInfo.Node()
 	// calculate risk based on requests and limits
 	nodeRequestsAndLimits := trimaran.GetNodeRequestsAndLimits(nodeInfo.Pods, node, pod, podRequests, podLimits)
-	riskCPU := pl.computeRisk(metrics, v1.ResourceCPU, watcher.CPU, node, nodeRequestsAndLimits)
-	riskMemory := pl.computeRisk(metrics, v1.ResourceMemory, watcher.Memory, node, nodeRequestsAndLimits)
-	rank := 1 - math.Max(riskCPU, riskMemory)
+	risks := make(map[v1.ResourceName]float64, len(pl.riskResources))
+	for _, resourceName := range pl.riskResources {
+		resourceType := metricType(resourceName)
+		// the resources other than CPU and memory are only considered on the nodes having them, with metrics
+		if resourceName != v1.ResourceCPU && resourceName != v1.ResourceMemory {
+			if resourceValue(nodeRequestsAndLimits.Nodecapacity, resourceName) <= 0 {
+				continue
+			}
+			if _, _, found := trimaran.GetResourceData(metrics, resourceType); !found {
+				continue
+			}
+		}
+		risks[resourceName] = pl.computeRisk(metrics, window, resourceName, resourceType, node, nodeRequestsAndLimits)
+	}
+	rank := 1 - pl.aggregateRisk(risks)
 
-	klog.V(6).InfoS("Node rank", "nodeName", node.GetName(), "riskCPU", riskCPU, "riskMemory", riskMemory, "rank", rank)
+	klog.V(6).InfoS("Node rank", "nodeName", node.GetName(), "risks", risks, "rank", rank)
 
 	return rank
 }
 
+// aggregateRisk : combine the risks of the resources of a node into the risk of the node
+func (pl *LowRiskOverCommitment) aggregateRisk(risks map[v1.ResourceName]float64) float64 {
+	switch pl.args.RiskAggregation {
+	case pluginConfig.WeightedMeanRisk:
+		var weightedSum, totalWeight float64
+		for _, resourceName := range pl.riskResources {
+			risk, ok := risks[resourceName]
+			if !ok {
+				continue
+			}
+			weight := 1.0
+			if w, ok := pl.args.RiskAggregationWeights[resourceName]; ok {
+				weight = w
+			}
+			weightedSum += weight * risk
+			totalWeight += weight
+		}
+		if totalWeight == 0 {
+			return 0
+		}
+		return weightedSum / totalWeight
+	default:
+		var maxRisk float64
+		for _, risk := range risks {
+			maxRisk = math.Max(maxRisk, risk)
+		}
+		return maxRisk
+	}
+}
+
 // computeRisk : calculate the risk of scheduling on node for a given resource
-func (pl *LowRiskOverCommitment) computeRisk(metrics []watcher.Metric, resourceName v1.ResourceName,
+func (pl *LowRiskOverCommitment) computeRisk(metrics []watcher.Metric, window *watcher.Window, resourceName v1.ResourceName,
 	resourceType string, node *v1.Node, nodeRequestsAndLimits *trimaran.NodeRequestsAndLimits) float64 {
 	var riskLimit, riskLoad, totalRisk float64
 
@@ -183,24 +254,11 @@ func (pl *LowRiskOverCommitment) computeRisk(metrics []watcher.Metric, resourceN
 	nodeLimitMinusPod := nodeRequestsAndLimits.NodeLimitMinusPod
 	nodeCapacity := nodeRequestsAndLimits.Nodecapacity
 
-	var request, limit, capacity, requestMinusPod, limitMinusPod int64
-	if resourceName == v1.ResourceCPU {
-		request = nodeRequest.MilliCPU
-		limit = nodeLimit.MilliCPU
-		requestMinusPod = nodeRequestMinusPod.MilliCPU
-		limitMinusPod = nodeLimitMinusPod.MilliCPU
-		capacity = nodeCapacity.MilliCPU
-	} else if resourceName == v1.ResourceMemory {
-		request = nodeRequest.Memory
-		limit = nodeLimit.Memory
-		requestMinusPod = nodeRequestMinusPod.Memory
-		limitMinusPod = nodeLimitMinusPod.Memory
-		capacity = nodeCapacity.Memory
-	} else {
-		// invalid resource
-		klog.V(6).InfoS("Unexpected resource", "resourceName", resourceName)
-		return 0
-	}
+	request := resourceValue(nodeRequest, resourceName)
+	limit := resourceValue(nodeLimit, resourceName)
+	requestMinusPod := resourceValue(nodeRequestMinusPod, resourceName)
+	limitMinusPod := resourceValue(nodeLimitMinusPod, resourceName)
+	capacity := resourceValue(nodeCapacity, resourceName)
 
 	// (1) riskLimit : calculate overcommit potential load
 	if limit > capacity {
@@ -222,7 +280,13 @@ func (pl *LowRiskOverCommitment) computeRisk(metrics []watcher.Metric, resourceN
 		// calculate area under beta probability curve beyond total allocated, as overuse risk measure
 		allocThreshold := float64(requestMinusPod) / float64(capacity)
 		allocThreshold = math.Min(math.Max(allocThreshold, 0), 1)
-		allocProb, fitDistribution := ComputeProbability(mu, sigma, allocThreshold)
+		fit := FitDistribution
+		if pl.cache != nil && window != nil {
+			fit = func(mu, sigma float64) *BetaDistribution {
+				return pl.cache.fit(*window, node.Name, resourceName, mu, sigma)
+			}
+		}
+		allocProb, fitDistribution := computeProbability(mu, sigma, allocThreshold, fit)
 		if fitDistribution != nil {
 			klog.V(6).InfoS("FitDistribution", "node", klog.KObj(node), "resource", resourceName, "dist", fitDistribution.Print())
 		}
@@ -253,6 +317,42 @@ func (pl *LowRiskOverCommitment) computeRisk(metrics []watcher.Metric, resourceN
 	return totalRisk
 }
 
+// isBestEffort : whether the pod neither requests nor limits any of the resources the risk is computed for
+func (pl *LowRiskOverCommitment) isBestEffort(podRequests *framework.Resource, podLimits *framework.Resource) bool {
+	for _, resourceName := range pl.riskResources {
+		if resourceValue(podRequests, resourceName) != 0 || resourceValue(podLimits, resourceName) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// metricType : the type of the metrics of the resource reported by the load watcher; the extended resources are
+// reported under their own name
+func metricType(resourceName v1.ResourceName) string {
+	if resourceType, ok := trimaran.MetricTypes[resourceName]; ok {
+		return resourceType
+	}
+	if resourceName == v1.ResourceEphemeralStorage {
+		return watcher.Storage
+	}
+	return string(resourceName)
+}
+
+// resourceValue : the amount of the resource, in milli-cores for the CPU and in units for the other resources
+func resourceValue(r *framework.Resource, resourceName v1.ResourceName) int64 {
+	switch resourceName {
+	case v1.ResourceCPU:
+		return r.MilliCPU
+	case v1.ResourceMemory:
+		return r.Memory
+	case v1.ResourceEphemeralStorage:
+		return r.EphemeralStorage
+	default:
+		return r.ScalarResources[resourceName]
+	}
+}
+
 // CreatePodResourcesStateData : calculate pod resource requests and limits and store as plugin state data
 func CreatePodResourcesStateData(pod *v1.Pod) *PodResourcesStateData {
 	requests := trimaran.GetResourceRequested(pod)
@@ -129,6 +129,18 @@ func TestLowRiskOverCommitment_New(t *testing.T) {
 	badp, err = New(ctx, &badArgs, fh)
 	assert.NotNil(t, badp)
 	assert.Nil(t, err)
+
+	// the forecast is validated
+	forecastArgs := lowRiskOverCommitmentArgs
+	forecastArgs.Forecast = &pluginConfig.ForecastSpec{Model: pluginConfig.EWMAForecast, Alpha: 0.3, HorizonSeconds: 60}
+	p, err = New(ctx, &forecastArgs, fh)
+	assert.NotNil(t, p)
+	assert.Nil(t, err)
+	assert.NotNil(t, p.(*LowRiskOverCommitment).forecaster)
+
+	forecastArgs.Forecast = &pluginConfig.ForecastSpec{Model: pluginConfig.EWMAForecast}
+	_, err = New(ctx, &forecastArgs, fh)
+	assert.NotNil(t, err)
 }
 
 func TestLowRiskOverCommitment_Score(t *testing.T) {
@@ -376,13 +388,149 @@ func TestLowRiskOverCommitment_computeRisk(t *testing.T) {
 	metrics := watcherData_A.NodeMetricsMap[node_A.Name].Metrics
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := pl.computeRisk(metrics, tt.resourceName, tt.resourceType, node_A, tt.nodeRequestsAndLimits); got != tt.want {
+			if got := pl.computeRisk(metrics, nil, tt.resourceName, tt.resourceType, node_A, tt.nodeRequestsAndLimits); got != tt.want {
 				t.Errorf("LowRiskOverCommitment.computeRisk() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
+func TestLowRiskOverCommitment_computeRank(t *testing.T) {
+	node := st.MakeNode().Name("node-B").Capacity(map[v1.ResourceName]string{
+		v1.ResourceCPU:              "4000m",
+		v1.ResourceMemory:           "4Ki",
+		v1.ResourceEphemeralStorage: "10Gi",
+		"nvidia.com/gpu":            "4",
+	}).Obj()
+	podOnNode := st.MakePod().Name("p1").Node(node.Name).Res(map[v1.ResourceName]string{
+		v1.ResourceEphemeralStorage: "4Gi",
+		"nvidia.com/gpu":            "2",
+	}).Obj()
+	nodeInfo := framework.NewNodeInfo(podOnNode)
+	nodeInfo.SetNode(node)
+	// overcommits both the ephemeral storage and the GPUs of the node
+	pod := st.MakePod().Name("p2").
+		Res(map[v1.ResourceName]string{"nvidia.com/gpu": "3"}).
+		Req(map[v1.ResourceName]string{v1.ResourceEphemeralStorage: "8Gi"}).
+		Lim(map[v1.ResourceName]string{v1.ResourceEphemeralStorage: "16Gi"}).Obj()
+	podResources := CreatePodResourcesStateData(pod)
+
+	gpuMetrics := []watcher.Metric{
+		{Type: "nvidia.com/gpu", Operator: watcher.Average, Value: 50},
+	}
+	storageMetrics := []watcher.Metric{
+		{Type: watcher.Storage, Operator: watcher.Average, Value: 30},
+		{Type: watcher.Storage, Operator: watcher.Std, Value: 10},
+	}
+	riskLimitWeights := map[v1.ResourceName]float64{
+		v1.ResourceCPU:              0.5,
+		v1.ResourceMemory:           0.5,
+		v1.ResourceEphemeralStorage: 0.5,
+		"nvidia.com/gpu":            0.2,
+	}
+	allResources := []v1.ResourceName{v1.ResourceCPU, v1.ResourceEphemeralStorage, v1.ResourceMemory, "nvidia.com/gpu"}
+	window := &watcher.Window{Start: 0, End: 900}
+
+	tests := []struct {
+		name                   string
+		metrics                []watcher.Metric
+		riskResources          []v1.ResourceName
+		riskAggregation        pluginConfig.RiskAggregationType
+		riskAggregationWeights map[v1.ResourceName]float64
+		want                   float64
+	}{
+		{
+			name:          "riskiest resource",
+			metrics:       append(append([]watcher.Metric{}, gpuMetrics...), storageMetrics...),
+			riskResources: allResources,
+			want:          0.5,
+		},
+		{
+			name:          "resource without metrics",
+			metrics:       gpuMetrics,
+			riskResources: allResources,
+			want:          0.8,
+		},
+		{
+			name:          "cpu and memory only",
+			metrics:       append(append([]watcher.Metric{}, gpuMetrics...), storageMetrics...),
+			riskResources: []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory},
+			want:          1,
+		},
+		{
+			name:            "mean",
+			metrics:         append(append([]watcher.Metric{}, gpuMetrics...), storageMetrics...),
+			riskResources:   allResources,
+			riskAggregation: pluginConfig.WeightedMeanRisk,
+			want:            1 - (0.5+0.2)/4,
+		},
+		{
+			name:            "weighted mean",
+			metrics:         append(append([]watcher.Metric{}, gpuMetrics...), storageMetrics...),
+			riskResources:   allResources,
+			riskAggregation: pluginConfig.WeightedMeanRisk,
+			riskAggregationWeights: map[v1.ResourceName]float64{
+				v1.ResourceCPU:    0,
+				v1.ResourceMemory: 0,
+				"nvidia.com/gpu":  3,
+			},
+			want: 1 - (0.5+3*0.2)/4,
+		},
+		{
+			name:            "zero weights",
+			metrics:         append(append([]watcher.Metric{}, gpuMetrics...), storageMetrics...),
+			riskResources:   []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory},
+			riskAggregation: pluginConfig.WeightedMeanRisk,
+			riskAggregationWeights: map[v1.ResourceName]float64{
+				v1.ResourceCPU:    0,
+				v1.ResourceMemory: 0,
+			},
+			want: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pl := &LowRiskOverCommitment{
+				args: &pluginConfig.LowRiskOverCommitmentArgs{
+					SmoothingWindowSize:    5,
+					RiskAggregation:        tt.riskAggregation,
+					RiskAggregationWeights: tt.riskAggregationWeights,
+				},
+				riskLimitWeightsMap: riskLimitWeights,
+				riskResources:       tt.riskResources,
+				cache:               newBetaCache(),
+			}
+			got := pl.computeRank(tt.metrics, window, nodeInfo, pod, &podResources.podRequests, &podResources.podLimits)
+			assert.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+func TestLowRiskOverCommitment_computeRiskCache(t *testing.T) {
+	pl := &LowRiskOverCommitment{
+		args:                plugin_A.args,
+		riskLimitWeightsMap: plugin_A.riskLimitWeightsMap,
+		cache:               newBetaCache(),
+	}
+	metrics := []watcher.Metric{
+		{Type: watcher.CPU, Operator: watcher.Average, Value: 40},
+		{Type: watcher.CPU, Operator: watcher.Std, Value: 5},
+	}
+	window := &watcher.Window{Start: 0, End: 900}
+
+	// the distribution fitted to the load is shared by the pods scored within the window
+	risk := pl.computeRisk(metrics, window, v1.ResourceCPU, watcher.CPU, node_A, nrla_A1)
+	assert.Len(t, pl.cache.entries, 1)
+	entry := pl.cache.entries[betaKey{nodeName: node_A.Name, resourceName: v1.ResourceCPU}]
+	assert.NotNil(t, entry)
+	pl.computeRisk(metrics, window, v1.ResourceCPU, watcher.CPU, node_A, nrla_A2)
+	assert.Len(t, pl.cache.entries, 1)
+	assert.Same(t, entry, pl.cache.entries[betaKey{nodeName: node_A.Name, resourceName: v1.ResourceCPU}])
+
+	// and matches the one fitted without the cache
+	assert.Equal(t, risk, pl.computeRisk(metrics, nil, v1.ResourceCPU, watcher.CPU, node_A, nrla_A1))
+}
+
 func newTestSharedLister(pods []*v1.Pod, nodes []*v1.Node) *testSharedLister {
 	nodeInfoMap := make(map[string]*framework.NodeInfo)
 	var nodeInfos []*framework.NodeInfo
@@ -0,0 +1,45 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trimaran
+
+import (
+	"sync"
+
+	basemetrics "k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+var (
+	// degradedCollectors : number of collectors whose load metrics are missing or stale, for which the plugins
+	// score the nodes in their fallback mode
+	degradedCollectors = basemetrics.NewGauge(
+		&basemetrics.GaugeOpts{
+			Subsystem:      "trimaran",
+			Name:           "degraded_collectors",
+			Help:           "Number of Trimaran metrics collectors whose load metrics are missing or stale, for which the nodes are scored in the fallback mode.",
+			StabilityLevel: basemetrics.ALPHA,
+		})
+
+	registerMetrics sync.Once
+)
+
+// RegisterMetrics : register the metrics of the trimaran plugins
+func RegisterMetrics() {
+	registerMetrics.Do(func() {
+		legacyregistry.MustRegister(degradedCollectors)
+	})
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trimaran
+
+import (
+	"math"
+
+	"github.com/paypal/load-watcher/pkg/watcher"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+
+	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
+)
+
+// MetricTypes maps the resources to the type of their metrics reported by the load watcher.
+var MetricTypes = map[v1.ResourceName]string{
+	v1.ResourceCPU:               watcher.CPU,
+	v1.ResourceMemory:            watcher.Memory,
+	pluginConfig.ResourceNetwork: watcher.Bandwidth,
+	pluginConfig.ResourceDisk:    watcher.Storage,
+}
+
+// IsPredictable : whether the usage of the resource by the pods can be predicted from their requests and limits
+func IsPredictable(resourceName v1.ResourceName) bool {
+	return resourceName == v1.ResourceCPU || resourceName == v1.ResourceMemory
+}
+
+// NodeUtilization : the utilization percent of the node reported by the metrics of the given type
+func NodeUtilization(metrics []watcher.Metric, metricType string) (float64, bool) {
+	var utilization float64
+	var metricFound bool
+	for _, metric := range metrics {
+		if metric.Type == metricType {
+			if metric.Operator == watcher.Average || metric.Operator == watcher.Latest {
+				utilization = metric.Value
+				metricFound = true
+			}
+		}
+	}
+	return utilization, metricFound
+}
+
+// UtilizationPredictor : predicts the utilization of the resources of a node once a pod runs on it, from the
+// utilization reported by the metrics and the requests and limits of the pods
+type UtilizationPredictor struct {
+	// usage predicted for containers without requests and limits of a resource
+	DefaultRequests v1.ResourceList
+	// multiplier of the requests predicted for containers without limits
+	RequestsMultiplier float64
+}
+
+// PredictNodeUtilization : the utilization percent of the resource on the node once the pod runs on it, given the
+// utilization reported by the metrics and the usage of the pods missing from it, as predicted by the event handler
+func (p *UtilizationPredictor) PredictNodeUtilization(resourceName v1.ResourceName, node *v1.Node, nodeUtilPercent float64,
+	pod *v1.Pod, missingUsage int64) float64 {
+	nodeCapacity := float64(QuantityValue(resourceName, node.Status.Capacity[resourceName]))
+	nodeUsage := (nodeUtilPercent / 100) * nodeCapacity
+
+	curPodUsage := p.PredictPodUtilization(pod, resourceName)
+	klog.V(6).InfoS("Predicted utilization for pod", "podName", pod.Name, "resource", resourceName, "usage", curPodUsage)
+	klog.V(6).InfoS("Calculating utilization and capacity", "nodeName", node.Name, "resource", resourceName,
+		"usage", nodeUsage, "missingUsage", missingUsage, "capacity", nodeCapacity)
+
+	if nodeCapacity == 0 {
+		return 0
+	}
+	return 100 * (nodeUsage + float64(curPodUsage) + float64(missingUsage)) / nodeCapacity
+}
+
+// PredictPodUtilization : the usage of the resource by the pod, from its containers and overhead
+func (p *UtilizationPredictor) PredictPodUtilization(pod *v1.Pod, resourceName v1.ResourceName) int64 {
+	var usage int64
+	for i := range pod.Spec.Containers {
+		usage += p.PredictContainerUtilization(&pod.Spec.Containers[i], resourceName)
+	}
+	if overhead, ok := pod.Spec.Overhead[resourceName]; ok {
+		usage += QuantityValue(resourceName, overhead)
+	}
+	return usage
+}
+
+// PredictContainerUtilization : the usage of the resource by the container based on its requests/limits, in
+// millicores for cpu and in units otherwise. The default requests are used for containers without requests and limits.
+func (p *UtilizationPredictor) PredictContainerUtilization(container *v1.Container, resourceName v1.ResourceName) int64 {
+	if limits, ok := container.Resources.Limits[resourceName]; ok {
+		return QuantityValue(resourceName, limits)
+	} else if requests, ok := container.Resources.Requests[resourceName]; ok {
+		return int64(math.Round(float64(QuantityValue(resourceName, requests)) * p.RequestsMultiplier))
+	} else {
+		return QuantityValue(resourceName, p.DefaultRequests[resourceName])
+	}
+}
+
+// QuantityValue : the quantity of a resource in millicores for cpu, and in units otherwise
+func QuantityValue(resourceName v1.ResourceName, quantity resource.Quantity) int64 {
+	if resourceName == v1.ResourceCPU {
+		return quantity.MilliValue()
+	}
+	return quantity.Value()
+}
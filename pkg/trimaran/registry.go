@@ -0,0 +1,143 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trimaran
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/client-go/rest"
+	clientcache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
+)
+
+// The collectors and event handlers are shared by the trimaran plugins of all the profiles which use the same
+// TrimaranSpec, so that the metrics are fetched, and the pods watched, once. They are reference counted: each plugin
+// holds a reference until the context it was created with is done, and they are stopped with the last reference.
+var (
+	registryLock        sync.Mutex
+	sharedCollectors    = make(map[pluginConfig.TrimaranSpec]*sharedCollector)
+	sharedEventHandlers = make(map[eventHandlerKey]*sharedEventHandler)
+)
+
+type sharedCollector struct {
+	collector *Collector
+	refs      int
+}
+
+// eventHandlerKey identifies a shared event handler. The pods are watched through the pod informer of the
+// framework handle, which is shared by the profiles of a scheduler.
+type eventHandlerKey struct {
+	trimaranSpec pluginConfig.TrimaranSpec
+	informer     clientcache.SharedIndexInformer
+}
+
+type sharedEventHandler struct {
+	eventHandler *PodAssignEventHandler
+	informer     clientcache.SharedIndexInformer
+	registration clientcache.ResourceEventHandlerRegistration
+	refs         int
+}
+
+// AcquireCollector : get the collector of the TrimaranSpec, shared with the other plugins using the same spec,
+// until the context is done. The kube config is used by the Kubelet metric provider.
+func AcquireCollector(ctx context.Context, trimaranSpec *pluginConfig.TrimaranSpec, kubeConfig *rest.Config) (*Collector, error) {
+	key := *trimaranSpec
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	shared, ok := sharedCollectors[key]
+	if !ok {
+		// the collector outlives the context of the plugin creating it, it's stopped with the last reference
+		collector, err := NewCollector(context.Background(), trimaranSpec, kubeConfig)
+		if err != nil {
+			return nil, err
+		}
+		shared = &sharedCollector{collector: collector}
+		sharedCollectors[key] = shared
+	}
+	shared.refs++
+	releaseWhenDone(ctx, func() { releaseCollector(key) })
+	return shared.collector, nil
+}
+
+func releaseCollector(key pluginConfig.TrimaranSpec) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	shared, ok := sharedCollectors[key]
+	if !ok {
+		return
+	}
+	if shared.refs--; shared.refs > 0 {
+		return
+	}
+	delete(sharedCollectors, key)
+	shared.collector.stop()
+}
+
+// AcquireEventHandler : get the event handler of the TrimaranSpec, watching the pods through the framework handle,
+// shared with the other plugins using the same spec and handle, until the context is done
+func AcquireEventHandler(ctx context.Context, trimaranSpec *pluginConfig.TrimaranSpec, handle framework.Handle) (*PodAssignEventHandler, error) {
+	informer := handle.SharedInformerFactory().Core().V1().Pods().Informer()
+	key := eventHandlerKey{trimaranSpec: *trimaranSpec, informer: informer}
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	shared, ok := sharedEventHandlers[key]
+	if !ok {
+		eventHandler := New(eventHandlerTTL(trimaranSpec))
+		registration, err := eventHandler.addToInformer(informer)
+		if err != nil {
+			eventHandler.stop()
+			return nil, err
+		}
+		shared = &sharedEventHandler{eventHandler: eventHandler, informer: informer, registration: registration}
+		sharedEventHandlers[key] = shared
+	}
+	shared.refs++
+	releaseWhenDone(ctx, func() { releaseEventHandler(key) })
+	return shared.eventHandler, nil
+}
+
+func releaseEventHandler(key eventHandlerKey) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	shared, ok := sharedEventHandlers[key]
+	if !ok {
+		return
+	}
+	if shared.refs--; shared.refs > 0 {
+		return
+	}
+	delete(sharedEventHandlers, key)
+	if err := shared.informer.RemoveEventHandler(shared.registration); err != nil {
+		klog.ErrorS(err, "Unable to remove the pod event handler")
+	}
+	shared.eventHandler.stop()
+}
+
+// releaseWhenDone : call release once the context is done; the reference is held for good if it's never done
+func releaseWhenDone(ctx context.Context, release func()) {
+	if ctx.Done() == nil {
+		return
+	}
+	go func() {
+		<-ctx.Done()
+		release()
+	}()
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trimaran
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/client-go/informers"
+	testClientSet "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
+	"k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	tf "k8s.io/kubernetes/pkg/scheduler/testing/framework"
+
+	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
+)
+
+func isStopped(stopCh <-chan struct{}) bool {
+	select {
+	case <-stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestAcquireCollector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		bytes, err := json.Marshal(watcherResponse)
+		assert.Nil(t, err)
+		resp.Write(bytes)
+	}))
+	defer server.Close()
+
+	spec := pluginConfig.TrimaranSpec{WatcherAddress: server.URL}
+	otherSpec := pluginConfig.TrimaranSpec{WatcherAddress: server.URL + "/"}
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	defer cancel1()
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+
+	collector1, err := AcquireCollector(ctx1, &spec, nil)
+	assert.Nil(t, err)
+	collector2, err := AcquireCollector(ctx2, &spec, nil)
+	assert.Nil(t, err)
+	other, err := AcquireCollector(ctx2, &otherSpec, nil)
+	assert.Nil(t, err)
+	assert.Same(t, collector1, collector2, "expected the plugins with the same spec to share the collector")
+	assert.NotSame(t, collector1, other, "expected the plugins with different specs to have their own collector")
+
+	_, err = AcquireCollector(ctx1, &pluginConfig.TrimaranSpec{}, nil)
+	assert.NotNil(t, err)
+
+	cancel1()
+	assert.Never(t, func() bool { return isStopped(collector1.done) }, 100*time.Millisecond, 10*time.Millisecond,
+		"expected the collector to run while a plugin uses it")
+	cancel2()
+	assert.Eventually(t, func() bool { return isStopped(collector1.done) && isStopped(other.done) },
+		time.Second, 10*time.Millisecond, "expected the collectors to stop with their last plugin")
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	assert.NotContains(t, sharedCollectors, spec)
+	assert.NotContains(t, sharedCollectors, otherSpec)
+}
+
+func TestAcquireEventHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	newHandle := func() framework.Handle {
+		cs := testClientSet.NewSimpleClientset()
+		fh, err := tf.NewFramework(ctx, []tf.RegisterPluginFunc{
+			tf.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+			tf.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+		}, "default-scheduler", runtime.WithClientSet(cs), runtime.WithInformerFactory(informers.NewSharedInformerFactory(cs, 0)))
+		assert.Nil(t, err)
+		return fh
+	}
+	handle, otherHandle := newHandle(), newHandle()
+	spec := pluginConfig.TrimaranSpec{WatcherAddress: "http://deadbeef:2020"}
+	ctx1, cancel1 := context.WithCancel(ctx)
+	defer cancel1()
+	ctx2, cancel2 := context.WithCancel(ctx)
+	defer cancel2()
+
+	eventHandler1, err := AcquireEventHandler(ctx1, &spec, handle)
+	assert.Nil(t, err)
+	eventHandler2, err := AcquireEventHandler(ctx2, &spec, handle)
+	assert.Nil(t, err)
+	other, err := AcquireEventHandler(ctx2, &spec, otherHandle)
+	assert.Nil(t, err)
+	assert.Same(t, eventHandler1, eventHandler2, "expected the plugins with the same spec and informer to share the event handler")
+	assert.NotSame(t, eventHandler1, other, "expected the plugins with different informers to have their own event handler")
+
+	cancel1()
+	assert.Never(t, func() bool { return isStopped(eventHandler1.stopCh) }, 100*time.Millisecond, 10*time.Millisecond,
+		"expected the event handler to run while a plugin uses it")
+	cancel2()
+	assert.Eventually(t, func() bool { return isStopped(eventHandler1.stopCh) && isStopped(other.stopCh) },
+		time.Second, 10*time.Millisecond, "expected the event handlers to stop with their last plugin")
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	assert.Empty(t, sharedEventHandlers)
+}
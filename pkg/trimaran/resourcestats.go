@@ -23,6 +23,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
+	schedutil "k8s.io/kubernetes/pkg/scheduler/util"
 )
 
 const (
@@ -107,21 +108,21 @@ func GetResourceData(metrics []watcher.Metric, resourceType string) (avg float64
 	return avg, stDev, isValid
 }
 
-// GetResourceRequested : calculate the resource requests of a pod (CPU and Memory)
+// GetResourceRequested : calculate the resource requests of a pod
 func GetResourceRequested(pod *v1.Pod) *framework.Resource {
 	return GetEffectiveResource(pod, func(container *v1.Container) v1.ResourceList {
 		return container.Resources.Requests
 	})
 }
 
-// GetResourceLimits : calculate the resource limits of a pod (CPU and Memory)
+// GetResourceLimits : calculate the resource limits of a pod
 func GetResourceLimits(pod *v1.Pod) *framework.Resource {
 	return GetEffectiveResource(pod, func(container *v1.Container) v1.ResourceList {
 		return container.Resources.Limits
 	})
 }
 
-// GetEffectiveResource: calculate effective resources of a pod (CPU and Memory)
+// GetEffectiveResource: calculate effective resources of a pod (CPU, Memory, ephemeral storage and scalar resources)
 func GetEffectiveResource(pod *v1.Pod, fn func(container *v1.Container) v1.ResourceList) *framework.Resource {
 	result := &framework.Resource{}
 	// add up resources of all containers
@@ -130,14 +131,7 @@ func GetEffectiveResource(pod *v1.Pod, fn func(container *v1.Container) v1.Resou
 	}
 	// take max(sum_pod, any_init_container)
 	for _, container := range pod.Spec.InitContainers {
-		for rName, rQuantity := range fn(&container) {
-			switch rName {
-			case v1.ResourceCPU:
-				setMax(&result.MilliCPU, rQuantity.MilliValue())
-			case v1.ResourceMemory:
-				setMax(&result.Memory, rQuantity.Value())
-			}
-		}
+		result.SetMaxResource(fn(&container))
 	}
 	// add any pod overhead
 	if pod.Spec.Overhead != nil {
@@ -166,17 +160,19 @@ func GetNodeRequestsAndLimits(podInfosOnNode []*framework.PodInfo, node *v1.Node
 	// initialization
 	nodeRequest := &framework.Resource{}
 	nodeLimit := &framework.Resource{}
-	nodeRequestMinusPod := &framework.Resource{}
-	nodeLimitMinusPod := &framework.Resource{}
+	var nodeRequestMinusPod, nodeLimitMinusPod *framework.Resource
 	// set capacities
-	nodeCapacity := &framework.Resource{}
 	allocatableResources := node.Status.Allocatable
-	amCpu := allocatableResources[v1.ResourceCPU]
-	capCpu := amCpu.MilliValue()
-	amMem := allocatableResources[v1.ResourceMemory]
-	capMem := amMem.Value()
-	nodeCapacity.MilliCPU = capCpu
-	nodeCapacity.Memory = capMem
+	nodeCapacity := &framework.Resource{
+		MilliCPU:         allocatableResources.Cpu().MilliValue(),
+		Memory:           allocatableResources.Memory().Value(),
+		EphemeralStorage: allocatableResources.StorageEphemeral().Value(),
+	}
+	for rName, rQuantity := range allocatableResources {
+		if schedutil.IsScalarResourceName(rName) {
+			nodeCapacity.SetScalar(rName, rQuantity.Value())
+		}
+	}
 	// get requests and limits for all pods
 	podsOnNode := make([]*v1.Pod, len(podInfosOnNode))
 	for i, pf := range podInfosOnNode {
@@ -187,8 +183,8 @@ func GetNodeRequestsAndLimits(podInfosOnNode []*framework.PodInfo, node *v1.Node
 		var limits *framework.Resource
 		// pending pod is last in sequence
 		if p == pod {
-			*nodeRequestMinusPod = *nodeRequest
-			*nodeLimitMinusPod = *nodeLimit
+			nodeRequestMinusPod = nodeRequest.Clone()
+			nodeLimitMinusPod = nodeLimit.Clone()
 			requested = podRequests
 			limits = podLimits
 		} else {
@@ -200,16 +196,12 @@ func GetNodeRequestsAndLimits(podInfosOnNode []*framework.PodInfo, node *v1.Node
 		}
 
 		// accumulate
-		nodeRequest.MilliCPU += requested.MilliCPU
-		nodeRequest.Memory += requested.Memory
-		nodeLimit.MilliCPU += limits.MilliCPU
-		nodeLimit.Memory += limits.Memory
+		addResources(nodeRequest, requested)
+		addResources(nodeLimit, limits)
 	}
 	// cap requests by node capacity
-	setMin(&nodeRequest.MilliCPU, capCpu)
-	setMin(&nodeRequest.Memory, capMem)
-	setMin(&nodeRequestMinusPod.MilliCPU, capCpu)
-	setMin(&nodeRequestMinusPod.Memory, capMem)
+	capResources(nodeRequest, nodeCapacity)
+	capResources(nodeRequestMinusPod, nodeCapacity)
 
 	klog.V(6).InfoS("Total node resources:", "node", klog.KObj(node),
 		"CPU-req", nodeRequest.MilliCPU, "Memory-req", nodeRequest.Memory,
@@ -236,7 +228,29 @@ func SetMaxLimits(requests *framework.Resource, limits *framework.Resource) {
 	}
 	for k, v := range requests.ScalarResources {
 		if limits.ScalarResources[k] < v {
-			limits.ScalarResources[k] = v
+			limits.SetScalar(k, v)
+		}
+	}
+}
+
+// addResources : x <- x + y, for the CPU, memory, ephemeral storage and scalar resources
+func addResources(x *framework.Resource, y *framework.Resource) {
+	x.MilliCPU += y.MilliCPU
+	x.Memory += y.Memory
+	x.EphemeralStorage += y.EphemeralStorage
+	for k, v := range y.ScalarResources {
+		x.AddScalar(k, v)
+	}
+}
+
+// capResources : x <- min(x, capacity), for the CPU, memory, ephemeral storage and scalar resources
+func capResources(x *framework.Resource, capacity *framework.Resource) {
+	setMin(&x.MilliCPU, capacity.MilliCPU)
+	setMin(&x.Memory, capacity.Memory)
+	setMin(&x.EphemeralStorage, capacity.EphemeralStorage)
+	for k, v := range x.ScalarResources {
+		if c := capacity.ScalarResources[k]; v > c {
+			x.ScalarResources[k] = c
 		}
 	}
 }
@@ -597,6 +597,53 @@ func TestGetNodeRequestsAndLimits(t *testing.T) {
 	}
 }
 
+func TestGetNodeRequestsAndLimitsExtendedResources(t *testing.T) {
+	node := st.MakeNode().Name("test-node").Capacity(map[v1.ResourceName]string{
+		v1.ResourceCPU:              "4000m",
+		v1.ResourceMemory:           "4Ki",
+		v1.ResourceEphemeralStorage: "10Gi",
+		"nvidia.com/gpu":            "4",
+	}).Obj()
+	// the init container needs more storage than the containers
+	podOnNode := st.MakePod().Name("p1").Node(node.Name).
+		Res(map[v1.ResourceName]string{v1.ResourceEphemeralStorage: "2Gi", "nvidia.com/gpu": "2"}).
+		InitReq(map[v1.ResourceName]string{v1.ResourceEphemeralStorage: "4Gi"}).Obj()
+	podInfo, _ := framework.NewPodInfo(podOnNode)
+	pod := st.MakePod().Name("p2").
+		Res(map[v1.ResourceName]string{"nvidia.com/gpu": "3"}).
+		Req(map[v1.ResourceName]string{v1.ResourceEphemeralStorage: "8Gi"}).
+		Lim(map[v1.ResourceName]string{v1.ResourceEphemeralStorage: "16Gi"}).Obj()
+	podRequests := GetResourceRequested(pod)
+	podLimits := GetResourceLimits(pod)
+	SetMaxLimits(podRequests, podLimits)
+
+	got := GetNodeRequestsAndLimits([]*framework.PodInfo{podInfo}, node, pod, podRequests, podLimits)
+	assert.Equal(t, &NodeRequestsAndLimits{
+		NodeRequest: &framework.Resource{
+			EphemeralStorage: 10 << 30,
+			ScalarResources:  map[v1.ResourceName]int64{"nvidia.com/gpu": 4},
+		},
+		NodeLimit: &framework.Resource{
+			EphemeralStorage: 20 << 30,
+			ScalarResources:  map[v1.ResourceName]int64{"nvidia.com/gpu": 5},
+		},
+		NodeRequestMinusPod: &framework.Resource{
+			EphemeralStorage: 4 << 30,
+			ScalarResources:  map[v1.ResourceName]int64{"nvidia.com/gpu": 2},
+		},
+		NodeLimitMinusPod: &framework.Resource{
+			EphemeralStorage: 4 << 30,
+			ScalarResources:  map[v1.ResourceName]int64{"nvidia.com/gpu": 2},
+		},
+		Nodecapacity: &framework.Resource{
+			MilliCPU:         4000,
+			Memory:           4 << 10,
+			EphemeralStorage: 10 << 30,
+			ScalarResources:  map[v1.ResourceName]int64{"nvidia.com/gpu": 4},
+		},
+	}, got)
+}
+
 // getPodWithContainersAndOverhead : length of contCPUReq and contMemReq should be same
 func getPodWithContainersAndOverhead(overhead int64, initCPUReq int64, initMemReq int64, contCPUReq []int64, contMemReq []int64) *v1.Pod {
 	newPod := st.MakePod()
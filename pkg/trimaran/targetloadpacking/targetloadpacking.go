@@ -15,7 +15,8 @@ limitations under the License.
 */
 
 /*
-targetloadpacking package provides K8s scheduler plugin for best-fit variant of bin packing based on CPU utilization around a target load
+targetloadpacking package provides K8s scheduler plugin for best-fit variant of bin packing based on the utilization of CPU,
+and optionally of memory, network and disk, around a target load
 It contains plugin for Score extension point.
 */
 
@@ -28,8 +29,6 @@ import (
 	"math"
 	"strconv"
 
-	"github.com/paypal/load-watcher/pkg/watcher"
-
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/klog/v2"
@@ -37,62 +36,83 @@ import (
 
 	pluginConfig "sigs.k8s.io/scheduler-plugins/apis/config"
 	cfgv1 "sigs.k8s.io/scheduler-plugins/apis/config/v1"
+	"sigs.k8s.io/scheduler-plugins/apis/config/validation"
 	"sigs.k8s.io/scheduler-plugins/pkg/trimaran"
 )
 
 const (
 	Name = "TargetLoadPacking"
-	// Time interval in seconds for each metrics agent ingestion.
-	metricsAgentReportingIntervalSeconds = 60
 )
 
-var (
-	requestsMilliCores           = cfgv1.DefaultRequestsMilliCores
-	hostTargetUtilizationPercent = cfgv1.DefaultTargetUtilizationPercent
-	requestsMultiplier           float64
-)
+// resourceTarget is the node target utilization of a resource.
+type resourceTarget struct {
+	pluginConfig.ResourceTarget
+	// type of the metrics of the resource reported by the load watcher
+	metricType string
+}
 
 type TargetLoadPacking struct {
-	handle       framework.Handle
-	eventHandler *trimaran.PodAssignEventHandler
-	collector    *trimaran.Collector
-	args         *pluginConfig.TargetLoadPackingArgs
+	handle          framework.Handle
+	eventHandler    *trimaran.PodAssignEventHandler
+	collector       *trimaran.Collector
+	args            *pluginConfig.TargetLoadPackingArgs
+	resourceTargets []resourceTarget
+	predictor       trimaran.UtilizationPredictor
 }
 
 var _ framework.ScorePlugin = &TargetLoadPacking{}
 
-func New(_ context.Context, obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
+func New(ctx context.Context, obj runtime.Object, handle framework.Handle) (framework.Plugin, error) {
 	klog.V(4).InfoS("Creating new instance of the TargetLoadPacking plugin")
 	// cast object into plugin arguments object
 	args, ok := obj.(*pluginConfig.TargetLoadPackingArgs)
 	if !ok {
 		return nil, fmt.Errorf("want args to be of type TargetLoadPackingArgs, got %T", obj)
 	}
-	collector, err := trimaran.NewCollector(&args.TrimaranSpec)
-	if err != nil {
+	if err := validation.ValidateTargetLoadPackingArgs(nil, args); err != nil {
 		return nil, err
 	}
-
-	hostTargetUtilizationPercent = args.TargetUtilization
-	requestsMilliCores = args.DefaultRequests.Cpu().MilliValue()
-	requestsMultiplier, err = strconv.ParseFloat(args.DefaultRequestsMultiplier, 64)
+	requestsMultiplier, err := strconv.ParseFloat(args.DefaultRequestsMultiplier, 64)
 	if err != nil {
 		return nil, errors.New("unable to parse DefaultRequestsMultiplier: " + err.Error())
 	}
 
+	targets := args.ResourceTargets
+	if len(targets) == 0 {
+		targets = []pluginConfig.ResourceTarget{{Name: v1.ResourceCPU, TargetUtilization: args.TargetUtilization, Weight: cfgv1.DefaultResourceTargetWeight}}
+	}
+	resourceTargets := make([]resourceTarget, len(targets))
+	for i, target := range targets {
+		resourceTargets[i] = resourceTarget{
+			ResourceTarget: target,
+			metricType:     trimaran.MetricTypes[target.Name],
+		}
+	}
+
 	klog.V(4).InfoS("Using TargetLoadPackingArgs",
-		"requestsMilliCores", requestsMilliCores,
+		"requestsMilliCores", args.DefaultRequests.Cpu().MilliValue(),
 		"requestsMultiplier", requestsMultiplier,
-		"targetUtilization", hostTargetUtilizationPercent)
+		"resourceTargets", targets)
 
-	podAssignEventHandler := trimaran.New()
-	podAssignEventHandler.AddToHandle(handle)
+	collector, err := trimaran.AcquireCollector(ctx, &args.TrimaranSpec, handle.KubeConfig())
+	if err != nil {
+		return nil, err
+	}
+	podAssignEventHandler, err := trimaran.AcquireEventHandler(ctx, &args.TrimaranSpec, handle)
+	if err != nil {
+		return nil, err
+	}
 
 	pl := &TargetLoadPacking{
-		handle:       handle,
-		eventHandler: podAssignEventHandler,
-		collector:    collector,
-		args:         args,
+		handle:          handle,
+		eventHandler:    podAssignEventHandler,
+		collector:       collector,
+		args:            args,
+		resourceTargets: resourceTargets,
+		predictor: trimaran.UtilizationPredictor{
+			DefaultRequests:    args.DefaultRequests,
+			RequestsMultiplier: requestsMultiplier,
+		},
 	}
 	return pl, nil
 }
@@ -108,83 +128,63 @@ func (pl *TargetLoadPacking) Score(ctx context.Context, cycleState *framework.Cy
 		return score, framework.NewStatus(framework.Error, fmt.Sprintf("getting node %q from Snapshot: %v", nodeName, err))
 	}
 
-	// get node metrics
-	metrics, allMetrics := pl.collector.GetNodeMetrics(nodeName)
+	// get node metrics, or derive them from allocation in the fallback mode
+	metrics, allMetrics, fromAllocation := pl.collector.GetNodeMetricsOrAllocation(nodeInfo)
 	if metrics == nil {
 		klog.InfoS("Failed to get metrics for node; using minimum score", "nodeName", nodeName)
 		// Avoid the node by scoring minimum
 		return score, nil
-		// TODO(aqadeer): If this happens for a long time, fall back to allocation based packing. This could mean maintaining failure state across cycles if scheduler doesn't provide this state
-
 	}
-
-	var curPodCPUUsage int64
-	for _, container := range pod.Spec.Containers {
-		curPodCPUUsage += PredictUtilisation(&container)
-	}
-	klog.V(6).InfoS("Predicted utilization for pod", "podName", pod.Name, "cpuUsage", curPodCPUUsage)
-	if pod.Spec.Overhead != nil {
-		curPodCPUUsage += pod.Spec.Overhead.Cpu().MilliValue()
+	// The requests of the pods scheduled on the node are part of the allocation already.
+	var missingUsage map[v1.ResourceName]int64
+	if !fromAllocation {
+		missingUsage = pl.eventHandler.PredictedDeltas(nodeName, &allMetrics.Window, &pl.predictor)
 	}
 
-	var nodeCPUUtilPercent float64
-	var cpuMetricFound bool
-	for _, metric := range metrics {
-		if metric.Type == watcher.CPU {
-			if metric.Operator == watcher.Average || metric.Operator == watcher.Latest {
-				nodeCPUUtilPercent = metric.Value
-				cpuMetricFound = true
+	var weightedScore float64
+	var totalWeight int64
+	for _, target := range pl.resourceTargets {
+		nodeUtilPercent, metricFound := trimaran.NodeUtilization(metrics, target.metricType)
+		if !metricFound {
+			// Only cpu and memory can be derived from allocation, the other resources are left out of the score.
+			if fromAllocation {
+				continue
 			}
+			klog.ErrorS(nil, "Resource metric not found in node metrics", "nodeName", nodeName, "resource", target.Name, "nodeMetrics", metrics)
+			return score, nil
 		}
-	}
-
-	if !cpuMetricFound {
-		klog.ErrorS(nil, "Cpu metric not found in node metrics", "nodeName", nodeName, "nodeMetrics", metrics)
-		return score, nil
-	}
-	nodeCPUCapMillis := float64(nodeInfo.Node().Status.Capacity.Cpu().MilliValue())
-	nodeCPUUtilMillis := (nodeCPUUtilPercent / 100) * nodeCPUCapMillis
-
-	klog.V(6).InfoS("Calculating CPU utilization and capacity", "nodeName", nodeName, "cpuUtilMillis", nodeCPUUtilMillis, "cpuCapMillis", nodeCPUCapMillis)
-
-	var missingCPUUtilMillis int64 = 0
-	pl.eventHandler.RLock()
-	for _, info := range pl.eventHandler.ScheduledPodsCache[nodeName] {
-		// If the time stamp of the scheduled pod is outside fetched metrics window, or it is within metrics reporting interval seconds, we predict util.
-		// Note that the second condition doesn't guarantee metrics for that pod are not reported yet as the 0 <= t <= 2*metricsAgentReportingIntervalSeconds
-		// t = metricsAgentReportingIntervalSeconds is taken as average case and it doesn't hurt us much if we are
-		// counting metrics twice in case actual t is less than metricsAgentReportingIntervalSeconds
-		if info.Timestamp.Unix() > allMetrics.Window.End || info.Timestamp.Unix() <= allMetrics.Window.End &&
-			(allMetrics.Window.End-info.Timestamp.Unix()) < metricsAgentReportingIntervalSeconds {
-			for _, container := range info.Pod.Spec.Containers {
-				missingCPUUtilMillis += PredictUtilisation(&container)
-			}
-			missingCPUUtilMillis += info.Pod.Spec.Overhead.Cpu().MilliValue()
-			klog.V(6).InfoS("Missing utilization for pod", "podName", info.Pod.Name, "missingCPUUtilMillis", missingCPUUtilMillis)
+		predictedUsage := nodeUtilPercent
+		// The usage of the pods, and of the pods whose usage isn't reported yet, is only predicted for the resources
+		// they request.
+		if trimaran.IsPredictable(target.Name) {
+			predictedUsage = pl.predictor.PredictNodeUtilization(target.Name, nodeInfo.Node(), nodeUtilPercent, pod, missingUsage[target.Name])
 		}
-	}
-	pl.eventHandler.RUnlock()
-	klog.V(6).InfoS("Missing utilization for node", "nodeName", nodeName, "missingCPUUtilMillis", missingCPUUtilMillis)
-
-	var predictedCPUUsage float64
-	if nodeCPUCapMillis != 0 {
-		predictedCPUUsage = 100 * (nodeCPUUtilMillis + float64(curPodCPUUsage) + float64(missingCPUUtilMillis)) / nodeCPUCapMillis
-	}
-	if predictedCPUUsage > float64(hostTargetUtilizationPercent) {
-		if predictedCPUUsage > 100 {
+		if predictedUsage > 100 {
 			return score, framework.NewStatus(framework.Success, "")
 		}
-		penalisedScore := int64(math.Round(float64(hostTargetUtilizationPercent) * (100 - predictedCPUUsage) / (100 - float64(hostTargetUtilizationPercent))))
-		klog.V(6).InfoS("Penalised score for host", "nodeName", nodeName, "penalisedScore", penalisedScore)
-		return penalisedScore, framework.NewStatus(framework.Success, "")
+		resourceScore := targetScore(predictedUsage, float64(target.TargetUtilization))
+		klog.V(6).InfoS("Score for resource of host", "nodeName", nodeName, "resource", target.Name,
+			"predictedUsage", predictedUsage, "score", resourceScore)
+		weightedScore += float64(target.Weight) * resourceScore
+		totalWeight += target.Weight
 	}
 
-	score = int64(math.Round((100-float64(hostTargetUtilizationPercent))*
-		predictedCPUUsage/float64(hostTargetUtilizationPercent) + float64(hostTargetUtilizationPercent)))
+	if totalWeight != 0 {
+		score = int64(math.Round(weightedScore / float64(totalWeight)))
+	}
 	klog.V(6).InfoS("Score for host", "nodeName", nodeName, "score", score)
 	return score, framework.NewStatus(framework.Success, "")
 }
 
+// targetScore scores the predicted utilization percent of a resource: the score grows from the target up to 100 as
+// the utilization reaches the target, and is penalised down to 0 as the utilization goes above the target.
+func targetScore(predictedUsage, targetUtilization float64) float64 {
+	if predictedUsage > targetUtilization {
+		return targetUtilization * (100 - predictedUsage) / (100 - targetUtilization)
+	}
+	return (100-targetUtilization)*predictedUsage/targetUtilization + targetUtilization
+}
+
 func (pl *TargetLoadPacking) ScoreExtensions() framework.ScoreExtensions {
 	return pl
 }
@@ -194,12 +194,6 @@ func (pl *TargetLoadPacking) NormalizeScore(context.Context, *framework.CycleSta
 }
 
 // Predict utilization for a container based on its requests/limits
-func PredictUtilisation(container *v1.Container) int64 {
-	if _, ok := container.Resources.Limits[v1.ResourceCPU]; ok {
-		return container.Resources.Limits.Cpu().MilliValue()
-	} else if _, ok := container.Resources.Requests[v1.ResourceCPU]; ok {
-		return int64(math.Round(float64(container.Resources.Requests.Cpu().MilliValue()) * requestsMultiplier))
-	} else {
-		return requestsMilliCores
-	}
+func (pl *TargetLoadPacking) PredictUtilisation(container *v1.Container) int64 {
+	return pl.predictor.PredictContainerUtilization(container, v1.ResourceCPU)
 }
@@ -111,6 +111,42 @@ func TestNew(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestNewPerInstanceArgs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cs := testClientSet.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactory(cs, 0)
+	fh, err := tf.NewFramework(ctx, []tf.RegisterPluginFunc{
+		tf.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		tf.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+	}, "default-scheduler", runtime.WithClientSet(cs), runtime.WithInformerFactory(informerFactory))
+	assert.Nil(t, err)
+
+	newPlugin := func(multiplier string, defaultRequests string) *TargetLoadPacking {
+		p, err := New(ctx, &pluginConfig.TargetLoadPackingArgs{
+			TrimaranSpec:              pluginConfig.TrimaranSpec{WatcherAddress: "http://deadbeef:2020"},
+			DefaultRequests:           v1.ResourceList{v1.ResourceCPU: resource.MustParse(defaultRequests)},
+			DefaultRequestsMultiplier: multiplier,
+			TargetUtilization:         cfgv1.DefaultTargetUtilizationPercent,
+		}, fh)
+		assert.Nil(t, err)
+		return p.(*TargetLoadPacking)
+	}
+	pl1 := newPlugin("1.5", "1000m")
+	pl2 := newPlugin("3", "200m")
+	assert.Same(t, pl1.collector, pl2.collector, "expected the plugins to share the collector")
+	assert.Same(t, pl1.eventHandler, pl2.eventHandler, "expected the plugins to share the event handler")
+
+	burstable := getPodWithContainersAndOverhead(0, 100)
+	burstable.Spec.Containers[0].Resources.Limits = nil
+	bestEffort := st.MakePod().Container("c").Obj()
+	assert.Equal(t, int64(150), pl1.PredictUtilisation(&burstable.Spec.Containers[0]))
+	assert.Equal(t, int64(300), pl2.PredictUtilisation(&burstable.Spec.Containers[0]))
+	assert.Equal(t, int64(1000), pl1.PredictUtilisation(&bestEffort.Spec.Containers[0]))
+	assert.Equal(t, int64(200), pl2.PredictUtilisation(&bestEffort.Spec.Containers[0]))
+}
+
 func TestTargetLoadPackingScoring(t *testing.T) {
 
 	registeredPlugins := []tf.RegisterPluginFunc{
@@ -138,6 +174,9 @@ func TestTargetLoadPackingScoring(t *testing.T) {
 		test            string
 		pod             *v1.Pod
 		nodes           []*v1.Node
+		existingPods    []*v1.Pod
+		resourceTargets []pluginConfig.ResourceTarget
+		fallbackMode    pluginConfig.FallbackMode
 		watcherResponse watcher.WatcherMetrics
 		expected        framework.NodeScoreList
 	}{
@@ -220,6 +259,143 @@ func TestTargetLoadPackingScoring(t *testing.T) {
 				{Name: "node-1", Score: framework.MinNodeScore},
 			},
 		},
+		{
+			test: "cpu and memory weighted",
+			pod:  st.MakePod().Name("p").Obj(),
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-1").Capacity(nodeResources).Obj(),
+			},
+			resourceTargets: []pluginConfig.ResourceTarget{
+				{Name: v1.ResourceCPU, TargetUtilization: 40, Weight: 1},
+				{Name: v1.ResourceMemory, TargetUtilization: 60, Weight: 3},
+			},
+			watcherResponse: watcher.WatcherMetrics{
+				Window: watcher.Window{},
+				Data: watcher.Data{
+					NodeMetricsMap: map[string]watcher.NodeMetrics{
+						"node-1": {
+							Metrics: []watcher.Metric{
+								{
+									Type:     watcher.CPU,
+									Value:    0,
+									Operator: watcher.Latest,
+								},
+								{
+									Type:     watcher.Memory,
+									Value:    70,
+									Operator: watcher.Latest,
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: []framework.NodeScore{
+				// (40 + 3 * 45) / 4
+				{Name: "node-1", Score: 44},
+			},
+		},
+		{
+			test: "excess predicted memory utilization returns min score",
+			pod:  st.MakePod().Name("p").Req(map[v1.ResourceName]string{v1.ResourceMemory: "128Mi"}).Obj(),
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-1").Capacity(nodeResources).Obj(),
+			},
+			resourceTargets: []pluginConfig.ResourceTarget{
+				{Name: v1.ResourceCPU, TargetUtilization: 40, Weight: 1},
+				{Name: v1.ResourceMemory, TargetUtilization: 60, Weight: 1},
+			},
+			watcherResponse: watcher.WatcherMetrics{
+				Window: watcher.Window{},
+				Data: watcher.Data{
+					NodeMetricsMap: map[string]watcher.NodeMetrics{
+						"node-1": {
+							Metrics: []watcher.Metric{
+								{
+									Type:     watcher.CPU,
+									Value:    0,
+									Operator: watcher.Latest,
+								},
+								{
+									Type:     watcher.Memory,
+									Value:    90,
+									Operator: watcher.Latest,
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: []framework.NodeScore{
+				{Name: "node-1", Score: framework.MinNodeScore},
+			},
+		},
+		{
+			test: "network utilization as reported",
+			pod:  st.MakePod().Name("p").Obj(),
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-1").Capacity(nodeResources).Obj(),
+			},
+			resourceTargets: []pluginConfig.ResourceTarget{
+				{Name: v1.ResourceCPU, TargetUtilization: 40, Weight: 1},
+				{Name: pluginConfig.ResourceNetwork, TargetUtilization: 60, Weight: 1},
+			},
+			watcherResponse: watcher.WatcherMetrics{
+				Window: watcher.Window{},
+				Data: watcher.Data{
+					NodeMetricsMap: map[string]watcher.NodeMetrics{
+						"node-1": {
+							Metrics: []watcher.Metric{
+								{
+									Type:     watcher.CPU,
+									Value:    0,
+									Operator: watcher.Latest,
+								},
+								{
+									Type:     watcher.Bandwidth,
+									Value:    80,
+									Operator: watcher.Average,
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: []framework.NodeScore{
+				// (40 + 30) / 2
+				{Name: "node-1", Score: 35},
+			},
+		},
+		{
+			test: "missing memory metric returns min score",
+			pod:  st.MakePod().Name("p").Obj(),
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-1").Capacity(nodeResources).Obj(),
+			},
+			resourceTargets: []pluginConfig.ResourceTarget{
+				{Name: v1.ResourceCPU, TargetUtilization: 40, Weight: 1},
+				{Name: v1.ResourceMemory, TargetUtilization: 60, Weight: 1},
+			},
+			watcherResponse: watcher.WatcherMetrics{
+				Window: watcher.Window{},
+				Data: watcher.Data{
+					NodeMetricsMap: map[string]watcher.NodeMetrics{
+						"node-1": {
+							Metrics: []watcher.Metric{
+								{
+									Type:     watcher.CPU,
+									Value:    0,
+									Operator: watcher.Latest,
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: []framework.NodeScore{
+				{Name: "node-1", Score: framework.MinNodeScore},
+			},
+		},
 		{
 			test: "404 resp from watcher",
 			pod:  st.MakePod().Name("p").Obj(),
@@ -231,6 +407,26 @@ func TestTargetLoadPackingScoring(t *testing.T) {
 				{Name: "node-1", Score: framework.MinNodeScore},
 			},
 		},
+		{
+			test: "404 resp from watcher with allocation fallback",
+			pod:  st.MakePod().Name("p").Obj(),
+			nodes: []*v1.Node{
+				st.MakeNode().Name("node-1").Capacity(nodeResources).Obj(),
+			},
+			existingPods: []*v1.Pod{
+				st.MakePod().Name("p1").Node("node-1").Req(map[v1.ResourceName]string{v1.ResourceCPU: "300m"}).Obj(),
+			},
+			resourceTargets: []pluginConfig.ResourceTarget{
+				{Name: v1.ResourceCPU, TargetUtilization: 40, Weight: 1},
+				{Name: pluginConfig.ResourceNetwork, TargetUtilization: 40, Weight: 1},
+			},
+			fallbackMode:    pluginConfig.AllocationFallback,
+			watcherResponse: watcher.WatcherMetrics{},
+			expected: []framework.NodeScore{
+				// 30% of cpu requested, network left out
+				{Name: "node-1", Score: 85},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -250,15 +446,16 @@ func TestTargetLoadPackingScoring(t *testing.T) {
 
 			cs := testClientSet.NewSimpleClientset()
 			informerFactory := informers.NewSharedInformerFactory(cs, 0)
-			snapshot := newTestSharedLister(nil, nodes)
+			snapshot := newTestSharedLister(tt.existingPods, nodes)
 			fh, err := testutil.NewFramework(ctx, registeredPlugins, []config.PluginConfig{targetLoadPackingConfig},
 				"default-scheduler", runtime.WithClientSet(cs),
 				runtime.WithInformerFactory(informerFactory), runtime.WithSnapshotSharedLister(snapshot))
 			assert.Nil(t, err)
 			targetLoadPackingArgs := pluginConfig.TargetLoadPackingArgs{
-				TrimaranSpec:              pluginConfig.TrimaranSpec{WatcherAddress: server.URL},
+				TrimaranSpec:              pluginConfig.TrimaranSpec{WatcherAddress: server.URL, FallbackMode: tt.fallbackMode},
 				TargetUtilization:         cfgv1.DefaultTargetUtilizationPercent,
 				DefaultRequestsMultiplier: cfgv1.DefaultRequestsMultiplier,
+				ResourceTargets:           tt.resourceTargets,
 			}
 			p, _ := New(ctx, &targetLoadPackingArgs, fh)
 			scorePlugin := p.(framework.ScorePlugin)
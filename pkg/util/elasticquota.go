@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+)
+
+// GetElasticQuotaNamespaceSelector returns the selector of the namespaces, other than its own, whose pods are
+// subject to the ElasticQuota. It returns nil if the ElasticQuota only applies to its own namespace.
+func GetElasticQuotaNamespaceSelector(eq *v1alpha1.ElasticQuota) (labels.Selector, error) {
+	if eq.Spec.NamespaceSelector == nil {
+		return nil, nil
+	}
+	return metav1.LabelSelectorAsSelector(eq.Spec.NamespaceSelector)
+}
+
+// GetElasticQuotaParentName returns the namespace/name of the parent of the ElasticQuota, or "" if it's a root.
+func GetElasticQuotaParentName(eq *v1alpha1.ElasticQuota) string {
+	if eq.Spec.Parent == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v/%v", eq.Spec.Parent.Namespace, eq.Spec.Parent.Name)
+}
+
+// GetElasticQuotaForNamespace returns the ElasticQuota the pods of the namespace are subject to, or nil if there's none:
+// the ElasticQuota of the namespace itself, or else the first ElasticQuota by namespace whose namespaceSelector
+// selects it.
+func GetElasticQuotaForNamespace(ns *v1.Namespace, eqs []v1alpha1.ElasticQuota) *v1alpha1.ElasticQuota {
+	var selecting []*v1alpha1.ElasticQuota
+	for i := range eqs {
+		eq := &eqs[i]
+		if eq.Namespace == ns.Name {
+			return eq
+		}
+		if selector, err := GetElasticQuotaNamespaceSelector(eq); err == nil && selector != nil && selector.Matches(labels.Set(ns.Labels)) {
+			selecting = append(selecting, eq)
+		}
+	}
+	if len(selecting) == 0 {
+		return nil
+	}
+	sort.Slice(selecting, func(i, j int) bool { return selecting[i].Namespace < selecting[j].Namespace })
+	return selecting[0]
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+)
+
+func TestGetElasticQuotaForNamespace(t *testing.T) {
+	makeEQ := func(namespace string, selector map[string]string) v1alpha1.ElasticQuota {
+		eq := v1alpha1.ElasticQuota{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "eq"}}
+		if selector != nil {
+			eq.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: selector}
+		}
+		return eq
+	}
+	team := map[string]string{"team": "a"}
+	tests := []struct {
+		name      string
+		namespace *v1.Namespace
+		eqs       []v1alpha1.ElasticQuota
+		expected  string
+	}{
+		{
+			name:      "own ElasticQuota",
+			namespace: &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns", Labels: team}},
+			eqs:       []v1alpha1.ElasticQuota{makeEQ("a", team), makeEQ("ns", nil)},
+			expected:  "ns",
+		},
+		{
+			name:      "first selecting ElasticQuota by namespace",
+			namespace: &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns", Labels: team}},
+			eqs:       []v1alpha1.ElasticQuota{makeEQ("c", team), makeEQ("b", nil), makeEQ("a", team)},
+			expected:  "a",
+		},
+		{
+			name:      "not selected",
+			namespace: &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}},
+			eqs:       []v1alpha1.ElasticQuota{makeEQ("a", team)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			if eq := GetElasticQuotaForNamespace(tt.namespace, tt.eqs); eq != nil {
+				got = eq.Namespace
+			}
+			if got != tt.expected {
+				t.Errorf("expected the ElasticQuota of namespace %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+	pgclientset "sigs.k8s.io/scheduler-plugins/pkg/generated/clientset/versioned"
+	pginformers "sigs.k8s.io/scheduler-plugins/pkg/generated/informers/externalversions"
+)
+
+// The informer factories of the scheduler-plugins APIs are shared by the plugins of all the profiles, which use the
+// kube config of the scheduler, so that each API is watched once.
+var (
+	schedulingInformersLock sync.Mutex
+	schedulingInformers     = make(map[*rest.Config]*schedulingInformer)
+)
+
+type schedulingInformer struct {
+	client  pgclientset.Interface
+	factory pginformers.SharedInformerFactory
+	// podGroupsServed caches whether the PodGroup API is served, once discovered.
+	podGroupsServed *bool
+}
+
+// getSchedulingInformer returns the shared informer of the kube config, creating it if needed.
+// The lock must be held.
+func getSchedulingInformer(kubeConfig *rest.Config) (*schedulingInformer, error) {
+	if informer, ok := schedulingInformers[kubeConfig]; ok {
+		return informer, nil
+	}
+	client, err := pgclientset.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	informer := &schedulingInformer{client: client, factory: pginformers.NewSharedInformerFactory(client, 0)}
+	schedulingInformers[kubeConfig] = informer
+	return informer, nil
+}
+
+// SchedulingInformerFactory returns the informer factory of the scheduler-plugins APIs shared by the plugins using the
+// kube config. Starting it only starts the informers which aren't running yet.
+func SchedulingInformerFactory(kubeConfig *rest.Config) (pginformers.SharedInformerFactory, error) {
+	schedulingInformersLock.Lock()
+	defer schedulingInformersLock.Unlock()
+	informer, err := getSchedulingInformer(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return informer.factory, nil
+}
+
+// PodGroupsServed returns true if the PodGroup API is served. The API is discovered once per kube config, unless
+// the discovery fails.
+func PodGroupsServed(kubeConfig *rest.Config) (bool, error) {
+	schedulingInformersLock.Lock()
+	defer schedulingInformersLock.Unlock()
+	informer, err := getSchedulingInformer(kubeConfig)
+	if err != nil {
+		return false, err
+	}
+	if informer.podGroupsServed != nil {
+		return *informer.podGroupsServed, nil
+	}
+	served := false
+	resources, err := informer.client.Discovery().ServerResourcesForGroupVersion(v1alpha1.SchemeGroupVersion.String())
+	if err != nil && !apierrors.IsNotFound(err) {
+		// The failure isn't cached, the API is discovered again by the next caller.
+		klog.V(4).InfoS("Failed to discover the scheduling API", "err", err)
+		return false, nil
+	}
+	if err == nil {
+		for _, r := range resources.APIResources {
+			if r.Name == "podgroups" {
+				served = true
+				break
+			}
+		}
+	}
+	informer.podGroupsServed = &served
+	return served, nil
+}
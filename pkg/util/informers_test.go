@@ -0,0 +1,47 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestSchedulingInformerFactory(t *testing.T) {
+	kubeConfig := &rest.Config{Host: "https://localhost:6443"}
+	otherKubeConfig := &rest.Config{Host: "https://localhost:6443"}
+
+	factory, err := SchedulingInformerFactory(kubeConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	same, err := SchedulingInformerFactory(kubeConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if factory != same {
+		t.Errorf("expected the informer factory to be shared by the plugins using the same kube config")
+	}
+	other, err := SchedulingInformerFactory(otherKubeConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if factory == other {
+		t.Errorf("expected another kube config to get its own informer factory")
+	}
+}
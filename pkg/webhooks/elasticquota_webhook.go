@@ -0,0 +1,213 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+)
+
+const (
+	// defaultWeight is the weight of the ElasticQuotas without spec.weight.
+	defaultWeight = 1
+	// defaultPreemptionIntervalSeconds is the interval of spec.reclaimPolicy.maxPreemptions when unset.
+	defaultPreemptionIntervalSeconds = 60
+)
+
+// boundedResources are the resources CapacityScheduling bounds to zero when they're missing from a
+// non-empty spec.max, whereas other missing resources are unbounded.
+var boundedResources = []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory, v1.ResourceEphemeralStorage}
+
+// ElasticQuotaWebhook defaults and validates ElasticQuotas.
+type ElasticQuotaWebhook struct {
+	// Reader lists the ElasticQuotas of a namespace to keep a single one per namespace.
+	Reader client.Reader
+}
+
+var _ admission.CustomDefaulter = &ElasticQuotaWebhook{}
+var _ admission.CustomValidator = &ElasticQuotaWebhook{}
+
+// +kubebuilder:webhook:path=/mutate-scheduling-x-k8s-io-v1alpha1-elasticquota,mutating=true,failurePolicy=fail,sideEffects=None,groups=scheduling.x-k8s.io,resources=elasticquotas,verbs=create;update,versions=v1alpha1,name=melasticquota.scheduling.x-k8s.io,admissionReviewVersions=v1
+
+// +kubebuilder:webhook:path=/validate-scheduling-x-k8s-io-v1alpha1-elasticquota,mutating=false,failurePolicy=fail,sideEffects=None,groups=scheduling.x-k8s.io,resources=elasticquotas,verbs=create;update,versions=v1alpha1,name=velasticquota.scheduling.x-k8s.io,admissionReviewVersions=v1
+
+// SetupWebhookWithManager registers the webhooks with the webhook server of the Manager.
+// ElasticQuotas are read from the API server unless a Reader is set.
+func (w *ElasticQuotaWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	if w.Reader == nil {
+		w.Reader = mgr.GetAPIReader()
+	}
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.ElasticQuota{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
+
+// Default sets the unset spec.weight and spec.reclaimPolicy fields to the values CapacityScheduling uses when
+// they're unset, so that they're visible in the ElasticQuota. spec.max and spec.reclaimPolicy.maxPreemptions
+// aren't defaulted, as leaving them unset doesn't bound the ElasticQuota.
+func (w *ElasticQuotaWebhook) Default(_ context.Context, obj runtime.Object) error {
+	eq, ok := obj.(*v1alpha1.ElasticQuota)
+	if !ok {
+		return apierrs.NewBadRequest(fmt.Sprintf("expected an ElasticQuota but got a %T", obj))
+	}
+	if eq.Spec.Weight == nil {
+		eq.Spec.Weight = pointer.Int32(defaultWeight)
+	}
+	if policy := eq.Spec.ReclaimPolicy; policy != nil {
+		if policy.MinRuntimeSeconds == nil {
+			policy.MinRuntimeSeconds = pointer.Int32(0)
+		}
+		if policy.NoticeSeconds == nil {
+			policy.NoticeSeconds = pointer.Int32(0)
+		}
+		if policy.PreemptionIntervalSeconds == nil {
+			policy.PreemptionIntervalSeconds = pointer.Int32(defaultPreemptionIntervalSeconds)
+		}
+	}
+	return nil
+}
+
+// ValidateCreate validates the spec of the ElasticQuota, and rejects it if its namespace already has one.
+func (w *ElasticQuotaWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	eq, ok := obj.(*v1alpha1.ElasticQuota)
+	if !ok {
+		return nil, apierrs.NewBadRequest(fmt.Sprintf("expected an ElasticQuota but got a %T", obj))
+	}
+	allErrs := validateElasticQuota(eq)
+
+	eqList := &v1alpha1.ElasticQuotaList{}
+	if err := w.Reader.List(ctx, eqList, client.InNamespace(eq.Namespace)); err != nil {
+		return nil, apierrs.NewInternalError(err)
+	}
+	for _, other := range eqList.Items {
+		if other.Name != eq.Name {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("metadata", "namespace"),
+				fmt.Sprintf("namespace %v already has ElasticQuota %v", eq.Namespace, other.Name)))
+			break
+		}
+	}
+	return elasticQuotaWarnings(eq), toInvalidError(eq.Name, v1alpha1.SchemeGroupVersion.WithKind("ElasticQuota").GroupKind(), allErrs)
+}
+
+// ValidateUpdate validates the spec of the updated ElasticQuota.
+func (w *ElasticQuotaWebhook) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	eq, ok := newObj.(*v1alpha1.ElasticQuota)
+	if !ok {
+		return nil, apierrs.NewBadRequest(fmt.Sprintf("expected an ElasticQuota but got a %T", newObj))
+	}
+	return elasticQuotaWarnings(eq), toInvalidError(eq.Name, v1alpha1.SchemeGroupVersion.WithKind("ElasticQuota").GroupKind(), validateElasticQuota(eq))
+}
+
+// ValidateDelete accepts any deletion.
+func (w *ElasticQuotaWebhook) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func validateElasticQuota(eq *v1alpha1.ElasticQuota) field.ErrorList {
+	spec := &eq.Spec
+	specPath := field.NewPath("spec")
+	allErrs := validateNonnegativeResources(spec.Min, specPath.Child("min"))
+	allErrs = append(allErrs, validateNonnegativeResources(spec.Max, specPath.Child("max"))...)
+	if spec.Parent != nil {
+		parentPath := specPath.Child("parent")
+		if spec.Parent.Namespace == "" {
+			allErrs = append(allErrs, field.Required(parentPath.Child("namespace"), ""))
+		}
+		if spec.Parent.Name == "" {
+			allErrs = append(allErrs, field.Required(parentPath.Child("name"), ""))
+		}
+		if spec.Parent.Namespace == eq.Namespace && spec.Parent.Name == eq.Name {
+			allErrs = append(allErrs, field.Invalid(parentPath, spec.Parent, "must not refer to the ElasticQuota itself"))
+		}
+	}
+	allErrs = append(allErrs, validateMinimum(spec.Weight, 1, specPath.Child("weight"))...)
+	if policy := spec.ReclaimPolicy; policy != nil {
+		policyPath := specPath.Child("reclaimPolicy")
+		allErrs = append(allErrs, validateMinimum(policy.MinRuntimeSeconds, 0, policyPath.Child("minRuntimeSeconds"))...)
+		allErrs = append(allErrs, validateMinimum(policy.NoticeSeconds, 0, policyPath.Child("noticeSeconds"))...)
+		allErrs = append(allErrs, validateMinimum(policy.MaxPreemptions, 0, policyPath.Child("maxPreemptions"))...)
+		allErrs = append(allErrs, validateMinimum(policy.PreemptionIntervalSeconds, 1, policyPath.Child("preemptionIntervalSeconds"))...)
+	}
+	priorityClassNames := sets.New[string]()
+	for i, limit := range spec.PriorityClassMax {
+		limitPath := specPath.Child("priorityClassMax").Index(i)
+		if limit.PriorityClassName == "" {
+			allErrs = append(allErrs, field.Required(limitPath.Child("priorityClassName"), ""))
+		} else if priorityClassNames.Has(limit.PriorityClassName) {
+			allErrs = append(allErrs, field.Duplicate(limitPath.Child("priorityClassName"), limit.PriorityClassName))
+		}
+		priorityClassNames.Insert(limit.PriorityClassName)
+		allErrs = append(allErrs, validateNonnegativeResources(limit.Resources, limitPath.Child("resources"))...)
+	}
+	allErrs = append(allErrs, metav1validation.ValidateLabelSelector(spec.NamespaceSelector,
+		metav1validation.LabelSelectorValidationOptions{}, specPath.Child("namespaceSelector"))...)
+	if spec.Max == nil {
+		return allErrs
+	}
+	for name, min := range spec.Min {
+		max, ok := spec.Max[name]
+		if !ok {
+			if !isBoundedResource(name) {
+				continue
+			}
+			max = resourceZero
+		}
+		if min.Cmp(max) > 0 {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("min").Key(string(name)), min.String(),
+				fmt.Sprintf("must be less than or equal to spec.max (%v)", max.String())))
+		}
+	}
+	return allErrs
+}
+
+// elasticQuotaWarnings warns about the resources bounded to zero by spec.max.
+func elasticQuotaWarnings(eq *v1alpha1.ElasticQuota) admission.Warnings {
+	if eq.Spec.Max == nil {
+		return nil
+	}
+	var warnings admission.Warnings
+	for _, name := range boundedResources {
+		if _, ok := eq.Spec.Max[name]; !ok {
+			warnings = append(warnings, fmt.Sprintf("spec.max doesn't set %v, pods requesting %v in namespace %v won't be scheduled", name, name, eq.Namespace))
+		}
+	}
+	return warnings
+}
+
+func isBoundedResource(name v1.ResourceName) bool {
+	for _, n := range boundedResources {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
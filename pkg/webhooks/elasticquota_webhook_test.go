@@ -0,0 +1,236 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+)
+
+func TestElasticQuotaWebhook(t *testing.T) {
+	existing := &v1alpha1.ElasticQuota{ObjectMeta: metav1.ObjectMeta{Name: "eq", Namespace: "taken"}}
+	tests := []struct {
+		name         string
+		namespace    string
+		spec         v1alpha1.ElasticQuotaSpec
+		wantWarnings int
+		wantFields   []string
+	}{
+		{
+			name:      "valid",
+			namespace: "ns",
+			spec: v1alpha1.ElasticQuotaSpec{
+				Min: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1Gi")},
+				Max: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("2Gi"), v1.ResourceEphemeralStorage: resource.MustParse("1Gi")},
+			},
+		},
+		{
+			name:      "unbounded max",
+			namespace: "ns",
+			spec:      v1alpha1.ElasticQuotaSpec{Min: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}},
+		},
+		{
+			name:      "max missing memory and ephemeral-storage",
+			namespace: "ns",
+			spec: v1alpha1.ElasticQuotaSpec{
+				Min: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), "example.com/gpu": resource.MustParse("1")},
+				Max: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+			},
+			wantWarnings: 2,
+		},
+		{
+			name:      "min of a resource missing from max",
+			namespace: "ns",
+			spec: v1alpha1.ElasticQuotaSpec{
+				Min: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1Gi")},
+				Max: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceEphemeralStorage: resource.MustParse("1Gi")},
+			},
+			wantWarnings: 1,
+			wantFields:   []string{"spec.min[memory]"},
+		},
+		{
+			name:      "min greater than max",
+			namespace: "ns",
+			spec: v1alpha1.ElasticQuotaSpec{
+				Min: v1.ResourceList{v1.ResourceCPU: resource.MustParse("3"), "example.com/gpu": resource.MustParse("2")},
+				Max: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("2Gi"), v1.ResourceEphemeralStorage: resource.MustParse("1Gi"), "example.com/gpu": resource.MustParse("1")},
+			},
+			wantFields: []string{"spec.min[cpu]", "spec.min[example.com/gpu]"},
+		},
+		{
+			name:      "negative quantities",
+			namespace: "ns",
+			spec: v1alpha1.ElasticQuotaSpec{
+				Min: v1.ResourceList{v1.ResourceMemory: resource.MustParse("-1Gi")},
+				Max: v1.ResourceList{v1.ResourceCPU: resource.MustParse("-2"), v1.ResourceMemory: resource.MustParse("2Gi"), v1.ResourceEphemeralStorage: resource.MustParse("1Gi")},
+			},
+			wantFields: []string{"spec.min[memory]", "spec.max[cpu]"},
+		},
+		{
+			name:      "parent refers to the ElasticQuota itself",
+			namespace: "ns",
+			spec: v1alpha1.ElasticQuotaSpec{
+				Parent: &v1alpha1.ElasticQuotaReference{Namespace: "ns", Name: "new"},
+			},
+			wantFields: []string{"spec.parent"},
+		},
+		{
+			name:      "parent without name and invalid namespace selector",
+			namespace: "ns",
+			spec: v1alpha1.ElasticQuotaSpec{
+				Parent: &v1alpha1.ElasticQuotaReference{Namespace: "org"},
+				NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "team", Operator: metav1.LabelSelectorOpIn},
+				}},
+			},
+			wantFields: []string{"spec.parent.name", "spec.namespaceSelector.matchExpressions[0].values"},
+		},
+		{
+			name:       "zero weight",
+			namespace:  "ns",
+			spec:       v1alpha1.ElasticQuotaSpec{Weight: pointer.Int32(0)},
+			wantFields: []string{"spec.weight"},
+		},
+		{
+			name:      "invalid reclaimPolicy",
+			namespace: "ns",
+			spec: v1alpha1.ElasticQuotaSpec{ReclaimPolicy: &v1alpha1.ReclaimPolicy{
+				MinRuntimeSeconds:         pointer.Int32(-1),
+				NoticeSeconds:             pointer.Int32(60),
+				MaxPreemptions:            pointer.Int32(-1),
+				PreemptionIntervalSeconds: pointer.Int32(0),
+			}},
+			wantFields: []string{"spec.reclaimPolicy.minRuntimeSeconds", "spec.reclaimPolicy.maxPreemptions", "spec.reclaimPolicy.preemptionIntervalSeconds"},
+		},
+		{
+			name:      "invalid priorityClassMax",
+			namespace: "ns",
+			spec: v1alpha1.ElasticQuotaSpec{PriorityClassMax: []v1alpha1.PriorityClassResources{
+				{PriorityClassName: "low", Resources: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}},
+				{PriorityClassName: "low", Resources: v1.ResourceList{v1.ResourceCPU: resource.MustParse("-1")}},
+				{Resources: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}},
+			}},
+			wantFields: []string{"spec.priorityClassMax[1].priorityClassName", "spec.priorityClassMax[1].resources[cpu]", "spec.priorityClassMax[2].priorityClassName"},
+		},
+		{
+			name:       "second ElasticQuota in a namespace",
+			namespace:  "taken",
+			wantFields: []string{"metadata.namespace"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := runtime.NewScheme()
+			utilruntime.Must(v1alpha1.AddToScheme(s))
+			w := &ElasticQuotaWebhook{Reader: fake.NewClientBuilder().WithScheme(s).WithObjects(existing).Build()}
+			eq := &v1alpha1.ElasticQuota{ObjectMeta: metav1.ObjectMeta{Name: "new", Namespace: tt.namespace}, Spec: tt.spec}
+			warnings, err := w.ValidateCreate(ctx, eq)
+			checkInvalidFields(t, err, tt.wantFields)
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("Want %v warnings, got %v", tt.wantWarnings, warnings)
+			}
+		})
+	}
+}
+
+func TestElasticQuotaWebhookUpdate(t *testing.T) {
+	ctx := context.Background()
+	eq := &v1alpha1.ElasticQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "eq", Namespace: "ns"},
+		Spec: v1alpha1.ElasticQuotaSpec{
+			Min: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+			Max: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("2Gi"), v1.ResourceEphemeralStorage: resource.MustParse("1Gi")},
+		},
+	}
+	s := runtime.NewScheme()
+	utilruntime.Must(v1alpha1.AddToScheme(s))
+	w := &ElasticQuotaWebhook{Reader: fake.NewClientBuilder().WithScheme(s).WithObjects(eq).Build()}
+
+	// The ElasticQuota being updated doesn't conflict with itself.
+	if _, err := w.ValidateCreate(ctx, eq); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	updated := eq.DeepCopy()
+	updated.Spec.Min[v1.ResourceCPU] = resource.MustParse("4")
+	_, err := w.ValidateUpdate(ctx, eq, updated)
+	checkInvalidFields(t, err, []string{"spec.min[cpu]"})
+}
+
+func TestElasticQuotaWebhookDefault(t *testing.T) {
+	max := v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")}
+	tests := []struct {
+		name string
+		spec v1alpha1.ElasticQuotaSpec
+		want v1alpha1.ElasticQuotaSpec
+	}{
+		{
+			name: "weight defaults to 1, max isn't defaulted",
+			spec: v1alpha1.ElasticQuotaSpec{Max: max},
+			want: v1alpha1.ElasticQuotaSpec{Max: max, Weight: pointer.Int32(1)},
+		},
+		{
+			name: "reclaimPolicy fields are defaulted except maxPreemptions",
+			spec: v1alpha1.ElasticQuotaSpec{Weight: pointer.Int32(3), ReclaimPolicy: &v1alpha1.ReclaimPolicy{}},
+			want: v1alpha1.ElasticQuotaSpec{
+				Weight: pointer.Int32(3),
+				ReclaimPolicy: &v1alpha1.ReclaimPolicy{
+					MinRuntimeSeconds:         pointer.Int32(0),
+					NoticeSeconds:             pointer.Int32(0),
+					PreemptionIntervalSeconds: pointer.Int32(60),
+				},
+			},
+		},
+		{
+			name: "set fields are kept",
+			spec: v1alpha1.ElasticQuotaSpec{Weight: pointer.Int32(2), ReclaimPolicy: &v1alpha1.ReclaimPolicy{
+				MinRuntimeSeconds:         pointer.Int32(30),
+				NoticeSeconds:             pointer.Int32(10),
+				MaxPreemptions:            pointer.Int32(1),
+				PreemptionIntervalSeconds: pointer.Int32(300),
+			}},
+			want: v1alpha1.ElasticQuotaSpec{Weight: pointer.Int32(2), ReclaimPolicy: &v1alpha1.ReclaimPolicy{
+				MinRuntimeSeconds:         pointer.Int32(30),
+				NoticeSeconds:             pointer.Int32(10),
+				MaxPreemptions:            pointer.Int32(1),
+				PreemptionIntervalSeconds: pointer.Int32(300),
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eq := &v1alpha1.ElasticQuota{ObjectMeta: metav1.ObjectMeta{Name: "eq", Namespace: "ns"}, Spec: tt.spec}
+			if err := (&ElasticQuotaWebhook{}).Default(context.Background(), eq); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, eq.Spec); diff != "" {
+				t.Errorf("Unexpected spec (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+)
+
+// PodWebhook labels the pods of the workloads annotated with v1alpha1.AutoPodGroupAnnotation with the PodGroup
+// the controller creates for them, so that the pods reach the scheduler as members of the PodGroup.
+type PodWebhook struct {
+	// Workloads are the kinds of workloads the controller creates PodGroups for.
+	Workloads []schema.GroupVersionKind
+	// Reader reads the workloads and ReplicaSets controlling the pods, through the client of the Manager by default.
+	Reader client.Reader
+	// APIReader reads the workloads and ReplicaSets missing from the cache of Reader, as the ones just created,
+	// from the API server by default.
+	APIReader client.Reader
+}
+
+var _ admission.CustomDefaulter = &PodWebhook{}
+
+// +kubebuilder:webhook:path=/mutate--v1-pod,mutating=true,failurePolicy=ignore,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=mpod.scheduling.x-k8s.io,admissionReviewVersions=v1
+
+// SetupWebhookWithManager registers the webhook with the webhook server of the Manager.
+func (w *PodWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	if w.Reader == nil {
+		w.Reader = mgr.GetClient()
+	}
+	if w.APIReader == nil {
+		w.APIReader = mgr.GetAPIReader()
+	}
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&v1.Pod{}).
+		WithDefaulter(w).
+		Complete()
+}
+
+// Default sets the PodGroup label of a created pod to the name of the annotated workload controlling it, either
+// directly or through a ReplicaSet, as for the pods of a Deployment. A PodGroup label set by the user is kept.
+func (w *PodWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return apierrs.NewBadRequest(fmt.Sprintf("expected a Pod but got a %T", obj))
+	}
+	if _, ok := pod.Labels[v1alpha1.PodGroupLabel]; ok {
+		return nil
+	}
+	namespace := pod.Namespace
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.Namespace != "" {
+		namespace = req.Namespace
+	}
+	workload, err := w.workloadOf(ctx, namespace, metav1.GetControllerOf(pod))
+	if err != nil {
+		return err
+	}
+	if workload == nil || workload.GetAnnotations()[v1alpha1.AutoPodGroupAnnotation] != "true" {
+		return nil
+	}
+	log.FromContext(ctx).V(4).Info("Labeling pod with the PodGroup of its workload", "podGroup", workload.GetName(), "namespace", namespace)
+	if pod.Labels == nil {
+		pod.Labels = make(map[string]string)
+	}
+	pod.Labels[v1alpha1.PodGroupLabel] = workload.GetName()
+	return nil
+}
+
+// workloadOf returns the workload of one of the Workloads kinds the reference points to, either directly or
+// through the ReplicaSet it points to. It returns nil if there is no such workload.
+func (w *PodWebhook) workloadOf(ctx context.Context, namespace string, ref *metav1.OwnerReference) (*unstructured.Unstructured, error) {
+	if ref == nil {
+		return nil, nil
+	}
+	if gvk, ok := w.workloadKind(ref); ok {
+		workload := &unstructured.Unstructured{}
+		workload.SetGroupVersionKind(gvk)
+		if found, err := w.get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, ref.UID, workload); !found {
+			return nil, err
+		}
+		return workload, nil
+	}
+	if ref.Kind != "ReplicaSet" || ref.APIVersion != appsv1.SchemeGroupVersion.String() {
+		return nil, nil
+	}
+	rs := &appsv1.ReplicaSet{}
+	if found, err := w.get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, ref.UID, rs); !found {
+		return nil, err
+	}
+	if ref := metav1.GetControllerOf(rs); ref != nil && ref.Kind != "ReplicaSet" {
+		return w.workloadOf(ctx, namespace, ref)
+	}
+	return nil, nil
+}
+
+// workloadKind returns the kind of Workloads the reference points to, if any.
+func (w *PodWebhook) workloadKind(ref *metav1.OwnerReference) (schema.GroupVersionKind, bool) {
+	for _, gvk := range w.Workloads {
+		if ref.Kind == gvk.Kind && ref.APIVersion == gvk.GroupVersion().String() {
+			return gvk, true
+		}
+	}
+	return schema.GroupVersionKind{}, false
+}
+
+// get reads the object of the given name and UID, from Reader or else from APIReader. It returns false if the
+// object doesn't exist, or has another UID.
+func (w *PodWebhook) get(ctx context.Context, key types.NamespacedName, uid types.UID, obj client.Object) (bool, error) {
+	err := w.Reader.Get(ctx, key, obj)
+	if apierrs.IsNotFound(err) || err == nil && obj.GetUID() != uid {
+		err = w.APIReader.Get(ctx, key, obj)
+	}
+	if err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return obj.GetUID() == uid, nil
+}
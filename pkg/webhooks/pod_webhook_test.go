@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+)
+
+func TestPodWebhookDefault(t *testing.T) {
+	jobGVK := batchv1.SchemeGroupVersion.WithKind("Job")
+	deploymentGVK := appsv1.SchemeGroupVersion.WithKind("Deployment")
+	replicaSetGVK := appsv1.SchemeGroupVersion.WithKind("ReplicaSet")
+	annotated := map[string]string{v1alpha1.AutoPodGroupAnnotation: "true"}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "ns", UID: "job-uid", Annotations: annotated}}
+	newJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "new-job", Namespace: "ns", UID: "new-job-uid", Annotations: annotated}}
+	plainJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "plain-job", Namespace: "ns", UID: "plain-job-uid"}}
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "deployment", Namespace: "ns", UID: "deployment-uid", Annotations: annotated}}
+	replicaSet := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "deployment-12345",
+			Namespace:       "ns",
+			UID:             "replicaset-uid",
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(deployment, deploymentGVK)},
+		},
+	}
+	orphanReplicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "orphan", Namespace: "ns", UID: "orphan-uid"}}
+	recreatedJob := job.DeepCopy()
+	recreatedJob.UID = "old-job-uid"
+	makePod := func(owner metav1.Object, gvk schema.GroupVersionKind, labels map[string]string) *v1.Pod {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: "ns", Labels: labels}}
+		if owner != nil {
+			pod.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(owner, gvk)}
+		}
+		return pod
+	}
+
+	tests := []struct {
+		name string
+		pod  *v1.Pod
+		// want is the expected PodGroup label, "" for none.
+		want string
+	}{
+		{
+			name: "pod of an annotated Job",
+			pod:  makePod(job, jobGVK, nil),
+			want: "job",
+		},
+		{
+			name: "pod of a Job missing from the cache",
+			pod:  makePod(newJob, jobGVK, map[string]string{"app": "new-job"}),
+			want: "new-job",
+		},
+		{
+			name: "pod of a Job which isn't annotated",
+			pod:  makePod(plainJob, jobGVK, nil),
+		},
+		{
+			name: "pod of another Job with the same name",
+			pod:  makePod(recreatedJob, jobGVK, nil),
+		},
+		{
+			name: "pod of a ReplicaSet controlled by an annotated Deployment",
+			pod:  makePod(replicaSet, replicaSetGVK, nil),
+			want: "deployment",
+		},
+		{
+			name: "pod of a ReplicaSet without controller",
+			pod:  makePod(orphanReplicaSet, replicaSetGVK, nil),
+		},
+		{
+			name: "pod without controller",
+			pod:  makePod(nil, schema.GroupVersionKind{}, nil),
+		},
+		{
+			name: "PodGroup label set by the user is kept",
+			pod:  makePod(job, jobGVK, map[string]string{v1alpha1.PodGroupLabel: "custom"}),
+			want: "custom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := runtime.NewScheme()
+			utilruntime.Must(clientgoscheme.AddToScheme(s))
+			objs := []client.Object{job, plainJob, deployment, replicaSet, orphanReplicaSet}
+			w := &PodWebhook{
+				Workloads: []schema.GroupVersionKind{jobGVK, deploymentGVK},
+				Reader:    fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).Build(),
+				APIReader: fake.NewClientBuilder().WithScheme(s).WithObjects(append(objs, newJob)...).Build(),
+			}
+			if err := w.Default(context.Background(), tt.pod); err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.pod.Labels[v1alpha1.PodGroupLabel]; got != tt.want {
+				t.Errorf("Want PodGroup label %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+)
+
+// PodGroupWebhook validates PodGroups.
+type PodGroupWebhook struct{}
+
+var _ admission.CustomValidator = &PodGroupWebhook{}
+
+// +kubebuilder:webhook:path=/validate-scheduling-x-k8s-io-v1alpha1-podgroup,mutating=false,failurePolicy=fail,sideEffects=None,groups=scheduling.x-k8s.io,resources=podgroups,verbs=create;update,versions=v1alpha1,name=vpodgroup.scheduling.x-k8s.io,admissionReviewVersions=v1
+
+// SetupWebhookWithManager registers the webhook with the webhook server of the Manager.
+func (w *PodGroupWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.PodGroup{}).
+		WithValidator(w).
+		Complete()
+}
+
+// ValidateCreate validates the spec of the PodGroup.
+func (w *PodGroupWebhook) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	pg, ok := obj.(*v1alpha1.PodGroup)
+	if !ok {
+		return nil, apierrs.NewBadRequest(fmt.Sprintf("expected a PodGroup but got a %T", obj))
+	}
+	return nil, toInvalidError(pg.Name, v1alpha1.SchemeGroupVersion.WithKind("PodGroup").GroupKind(), validatePodGroupSpec(&pg.Spec, nil))
+}
+
+// ValidateUpdate validates the spec of the updated PodGroup. A minMember below 1 is only rejected if it's
+// updated, so that the PodGroups created before the webhook was enabled can still be updated.
+func (w *PodGroupWebhook) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldPG, ok := oldObj.(*v1alpha1.PodGroup)
+	if !ok {
+		return nil, apierrs.NewBadRequest(fmt.Sprintf("expected a PodGroup but got a %T", oldObj))
+	}
+	pg, ok := newObj.(*v1alpha1.PodGroup)
+	if !ok {
+		return nil, apierrs.NewBadRequest(fmt.Sprintf("expected a PodGroup but got a %T", newObj))
+	}
+	return nil, toInvalidError(pg.Name, v1alpha1.SchemeGroupVersion.WithKind("PodGroup").GroupKind(), validatePodGroupSpec(&pg.Spec, &oldPG.Spec))
+}
+
+// ValidateDelete accepts any deletion.
+func (w *PodGroupWebhook) ValidateDelete(context.Context, runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validatePodGroupSpec validates the spec of a PodGroup, given the spec it's updated from if any.
+func validatePodGroupSpec(spec, oldSpec *v1alpha1.PodGroupSpec) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	if spec.MinMember < 1 && (oldSpec == nil || oldSpec.MinMember != spec.MinMember) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("minMember"), spec.MinMember, "must be greater than 0"))
+	}
+	allErrs = append(allErrs, validateNonnegativeResources(spec.MinResources, specPath.Child("minResources"))...)
+	if spec.ScheduleTimeoutSeconds != nil && *spec.ScheduleTimeoutSeconds < 1 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("scheduleTimeoutSeconds"), *spec.ScheduleTimeoutSeconds, "must be greater than 0"))
+	}
+	if spec.TTLSecondsAfterFinished != nil {
+		allErrs = append(allErrs, apivalidation.ValidateNonnegativeField(int64(*spec.TTLSecondsAfterFinished), specPath.Child("ttlSecondsAfterFinished"))...)
+	}
+	if spec.MaxFailedMembers != nil {
+		allErrs = append(allErrs, apivalidation.ValidateNonnegativeField(int64(*spec.MaxFailedMembers), specPath.Child("maxFailedMembers"))...)
+	}
+	return allErrs
+}
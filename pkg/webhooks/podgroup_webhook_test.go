@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+)
+
+func TestPodGroupWebhook(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       v1alpha1.PodGroupSpec
+		wantFields []string
+	}{
+		{
+			name: "valid",
+			spec: v1alpha1.PodGroupSpec{MinMember: 3, ScheduleTimeoutSeconds: pointer.Int32(10), MaxFailedMembers: pointer.Int32(0)},
+		},
+		{
+			name:       "minMember unset",
+			spec:       v1alpha1.PodGroupSpec{},
+			wantFields: []string{"spec.minMember"},
+		},
+		{
+			name:       "negative minMember",
+			spec:       v1alpha1.PodGroupSpec{MinMember: -1},
+			wantFields: []string{"spec.minMember"},
+		},
+		{
+			name: "negative minResources",
+			spec: v1alpha1.PodGroupSpec{MinMember: 2, MinResources: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("-1"),
+				v1.ResourceMemory: resource.MustParse("1Gi"),
+			}},
+			wantFields: []string{"spec.minResources[cpu]"},
+		},
+		{
+			name: "invalid timeouts and thresholds",
+			spec: v1alpha1.PodGroupSpec{
+				MinMember:               2,
+				ScheduleTimeoutSeconds:  pointer.Int32(0),
+				TTLSecondsAfterFinished: pointer.Int32(-1),
+				MaxFailedMembers:        pointer.Int32(-1),
+			},
+			wantFields: []string{"spec.scheduleTimeoutSeconds", "spec.ttlSecondsAfterFinished", "spec.maxFailedMembers"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			w := &PodGroupWebhook{}
+			pg := &v1alpha1.PodGroup{ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "ns"}, Spec: tt.spec}
+			_, err := w.ValidateCreate(ctx, pg)
+			checkInvalidFields(t, err, tt.wantFields)
+			old := pg.DeepCopy()
+			old.Spec.MinMember++
+			_, err = w.ValidateUpdate(ctx, old, pg)
+			checkInvalidFields(t, err, tt.wantFields)
+		})
+	}
+}
+
+func TestPodGroupWebhookUpdate(t *testing.T) {
+	ctx := context.Background()
+	w := &PodGroupWebhook{}
+	old := &v1alpha1.PodGroup{ObjectMeta: metav1.ObjectMeta{Name: "pg", Namespace: "ns"}}
+
+	// A PodGroup created before the webhook was enabled can be updated as long as its minMember is kept.
+	pg := old.DeepCopy()
+	pg.Spec.ScheduleTimeoutSeconds = pointer.Int32(10)
+	_, err := w.ValidateUpdate(ctx, old, pg)
+	checkInvalidFields(t, err, nil)
+
+	pg.Spec.MinMember = -1
+	_, err = w.ValidateUpdate(ctx, old, pg)
+	checkInvalidFields(t, err, []string{"spec.minMember"})
+}
+
+// checkInvalidFields checks that err is an Invalid error on exactly the given fields, or nil if there's none.
+func checkInvalidFields(t *testing.T, err error, wantFields []string) {
+	t.Helper()
+	if len(wantFields) == 0 {
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		return
+	}
+	statusErr, ok := err.(*apierrs.StatusError)
+	if !ok || !apierrs.IsInvalid(err) {
+		t.Fatalf("Want an Invalid error, got %v", err)
+	}
+	gotFields := make(map[string]bool)
+	for _, cause := range statusErr.ErrStatus.Details.Causes {
+		gotFields[cause.Field] = true
+	}
+	if len(gotFields) != len(wantFields) {
+		t.Errorf("Want invalid fields %v, got %v", wantFields, err)
+	}
+	for _, f := range wantFields {
+		if !gotFields[f] {
+			t.Errorf("Want invalid field %v, got %v", f, err)
+		}
+	}
+}
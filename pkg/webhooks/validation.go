@@ -0,0 +1,55 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var resourceZero = resource.MustParse("0")
+
+func validateNonnegativeResources(resources v1.ResourceList, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for name, quantity := range resources {
+		if quantity.Sign() < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(string(name)), quantity.String(), "must be greater than or equal to 0"))
+		}
+	}
+	return allErrs
+}
+
+// validateMinimum validates that the value, if set, is greater than or equal to the minimum.
+func validateMinimum(value *int32, minimum int32, fldPath *field.Path) field.ErrorList {
+	if value == nil || *value >= minimum {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(fldPath, *value, fmt.Sprintf("must be greater than or equal to %d", minimum))}
+}
+
+// toInvalidError returns an Invalid error for the given errors, or nil if there's none.
+func toInvalidError(name string, gk schema.GroupKind, allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrs.NewInvalid(gk, name, allErrs)
+}
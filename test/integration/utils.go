@@ -127,6 +127,20 @@ func (p *podWrapper) Node(name string) *podWrapper {
 	return p
 }
 
+func (p *podWrapper) PriorityClassName(name string) *podWrapper {
+	p.Pod.Spec.PriorityClassName = name
+	return p
+}
+
+func (p *podWrapper) Unschedulable() *podWrapper {
+	p.Pod.Status.Conditions = append(p.Pod.Status.Conditions, v1.PodCondition{
+		Type:   v1.PodScheduled,
+		Status: v1.ConditionFalse,
+		Reason: v1.PodReasonUnschedulable,
+	})
+	return p
+}
+
 func (p *podWrapper) Obj() *v1.Pod {
 	return p.Pod
 }
@@ -153,11 +167,53 @@ func (e *eqWrapper) Max(max v1.ResourceList) *eqWrapper {
 	return e
 }
 
+func (e *eqWrapper) Parent(namespace, name string) *eqWrapper {
+	e.ElasticQuota.Spec.Parent = &v1alpha1.ElasticQuotaReference{Namespace: namespace, Name: name}
+	return e
+}
+
+func (e *eqWrapper) NamespaceSelector(labels map[string]string) *eqWrapper {
+	e.ElasticQuota.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: labels}
+	return e
+}
+
+func (e *eqWrapper) PriorityClassMax(priorityClassName string, max v1.ResourceList) *eqWrapper {
+	e.ElasticQuota.Spec.PriorityClassMax = append(e.ElasticQuota.Spec.PriorityClassMax,
+		v1alpha1.PriorityClassResources{PriorityClassName: priorityClassName, Resources: max})
+	return e
+}
+
 func (e *eqWrapper) Used(used v1.ResourceList) *eqWrapper {
 	e.ElasticQuota.Status.Used = used
 	return e
 }
 
+func (e *eqWrapper) Borrowed(borrowed v1.ResourceList) *eqWrapper {
+	e.ElasticQuota.Status.Borrowed = borrowed
+	return e
+}
+
+func (e *eqWrapper) Lent(lent v1.ResourceList) *eqWrapper {
+	e.ElasticQuota.Status.Lent = lent
+	return e
+}
+
+func (e *eqWrapper) PendingDemand(demand v1.ResourceList) *eqWrapper {
+	e.ElasticQuota.Status.PendingDemand = demand
+	return e
+}
+
+func (e *eqWrapper) PriorityClassUsed(priorityClassName string, used v1.ResourceList) *eqWrapper {
+	e.ElasticQuota.Status.PriorityClassUsed = append(e.ElasticQuota.Status.PriorityClassUsed,
+		v1alpha1.PriorityClassResources{PriorityClassName: priorityClassName, Resources: used})
+	return e
+}
+
+func (e *eqWrapper) Condition(conditionType string, status metav1.ConditionStatus) *eqWrapper {
+	e.ElasticQuota.Status.Conditions = append(e.ElasticQuota.Status.Conditions, metav1.Condition{Type: conditionType, Status: status})
+	return e
+}
+
 func (e *eqWrapper) Obj() *v1alpha1.ElasticQuota {
 	return e.ElasticQuota
 }
@@ -0,0 +1,230 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	schedv1alpha1 "sigs.k8s.io/scheduler-plugins/apis/scheduling/v1alpha1"
+	"sigs.k8s.io/scheduler-plugins/pkg/webhooks"
+)
+
+func TestWebhooks(t *testing.T) {
+	// The webhooks are registered with their own API server, so that they don't interfere with other tests.
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths: []string{
+			filepath.Join("..", "..", "manifests", "crds"),
+		},
+		WebhookInstallOptions: envtest.WebhookInstallOptions{
+			Paths: []string{filepath.Join("..", "..", "config", "webhook", "manifests.yaml")},
+		},
+	}
+	cfg, err := testEnv.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer testEnv.Stop()
+
+	s := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(s))
+	utilruntime.Must(schedv1alpha1.AddToScheme(s))
+	webhookOpts := testEnv.WebhookInstallOptions
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme: s,
+		Metrics: metricsserver.Options{
+			BindAddress: "0",
+		},
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Host:    webhookOpts.LocalServingHost,
+			Port:    webhookOpts.LocalServingPort,
+			CertDir: webhookOpts.LocalServingCertDir,
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (&webhooks.PodGroupWebhook{}).SetupWebhookWithManager(mgr); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&webhooks.ElasticQuotaWebhook{}).SetupWebhookWithManager(mgr); err != nil {
+		t.Fatal(err)
+	}
+	jobGVK := batchv1.SchemeGroupVersion.WithKind("Job")
+	if err := (&webhooks.PodWebhook{Workloads: []schema.GroupVersionKind{jobGVK}}).SetupWebhookWithManager(mgr); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		if err := mgr.Start(ctx); err != nil {
+			panic(err)
+		}
+	}()
+	if err := wait.PollUntilContextTimeout(ctx, 100*time.Millisecond, 10*time.Second, true, func(context.Context) (bool, error) {
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: time.Second}, "tcp",
+			net.JoinHostPort(webhookOpts.LocalServingHost, fmt.Sprint(webhookOpts.LocalServingPort)),
+			&tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			return false, nil
+		}
+		return true, conn.Close()
+	}); err != nil {
+		t.Fatalf("Timed out waiting for the webhook server: %v", err)
+	}
+
+	c, err := client.New(cfg, client.Options{Scheme: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ns := range []string{"ns1", "ns2"} {
+		if err := c.Create(ctx, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("PodGroup without minMember is rejected", func(t *testing.T) {
+		pg := &schedv1alpha1.PodGroup{ObjectMeta: metav1.ObjectMeta{Name: "pg1", Namespace: "ns1"}}
+		if err := c.Create(ctx, pg); !errors.IsInvalid(err) {
+			t.Errorf("Want an Invalid error, got %v", err)
+		}
+	})
+
+	t.Run("PodGroup with negative minResources is rejected", func(t *testing.T) {
+		pg := &schedv1alpha1.PodGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "pg2", Namespace: "ns1"},
+			Spec: schedv1alpha1.PodGroupSpec{
+				MinMember:    2,
+				MinResources: v1.ResourceList{v1.ResourceCPU: resource.MustParse("-1")},
+			},
+		}
+		if err := c.Create(ctx, pg); !errors.IsInvalid(err) {
+			t.Errorf("Want an Invalid error, got %v", err)
+		}
+	})
+
+	t.Run("pod of an annotated Job is labeled with its PodGroup", func(t *testing.T) {
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "job",
+				Namespace:   "ns1",
+				Annotations: map[string]string{schedv1alpha1.AutoPodGroupAnnotation: "true"},
+			},
+			Spec: batchv1.JobSpec{
+				Suspend: pointer.Bool(true),
+				Template: v1.PodTemplateSpec{Spec: v1.PodSpec{
+					RestartPolicy: v1.RestartPolicyNever,
+					Containers:    []v1.Container{{Name: "c", Image: "i"}},
+				}},
+			},
+		}
+		if err := c.Create(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "job-abcde",
+				Namespace:       "ns1",
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(job, jobGVK)},
+			},
+			Spec: v1.PodSpec{Containers: []v1.Container{{Name: "c", Image: "i"}}},
+		}
+		if err := c.Create(ctx, pod); err != nil {
+			t.Fatal(err)
+		}
+		if got := pod.Labels[schedv1alpha1.PodGroupLabel]; got != "job" {
+			t.Errorf("Want PodGroup label %q, got %q", "job", got)
+		}
+	})
+
+	t.Run("ElasticQuota weight and reclaimPolicy are defaulted", func(t *testing.T) {
+		eq := &schedv1alpha1.ElasticQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "eq", Namespace: "ns1"},
+			Spec: schedv1alpha1.ElasticQuotaSpec{
+				Min:           v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+				Max:           v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("2Gi"), v1.ResourceEphemeralStorage: resource.MustParse("1Gi")},
+				ReclaimPolicy: &schedv1alpha1.ReclaimPolicy{},
+			},
+		}
+		if err := c.Create(ctx, eq); err != nil {
+			t.Fatal(err)
+		}
+		defer c.Delete(ctx, eq)
+		if eq.Spec.Weight == nil || *eq.Spec.Weight != 1 {
+			t.Errorf("Want weight 1, got %v", eq.Spec.Weight)
+		}
+		if policy := eq.Spec.ReclaimPolicy; policy.MinRuntimeSeconds == nil || policy.NoticeSeconds == nil || policy.MaxPreemptions != nil {
+			t.Errorf("Want minRuntimeSeconds and noticeSeconds defaulted and maxPreemptions unset, got %+v", policy)
+		}
+		if len(eq.Spec.Max) != 3 {
+			t.Errorf("Want max not defaulted, got %v", eq.Spec.Max)
+		}
+	})
+
+	t.Run("ElasticQuota with min greater than max is rejected", func(t *testing.T) {
+		eq := &schedv1alpha1.ElasticQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "eq", Namespace: "ns1"},
+			Spec: schedv1alpha1.ElasticQuotaSpec{
+				Min: v1.ResourceList{v1.ResourceCPU: resource.MustParse("4")},
+				Max: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+			},
+		}
+		if err := c.Create(ctx, eq); !errors.IsInvalid(err) {
+			t.Errorf("Want an Invalid error, got %v", err)
+		}
+	})
+
+	t.Run("second ElasticQuota in a namespace is rejected", func(t *testing.T) {
+		eq := &schedv1alpha1.ElasticQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "eq1", Namespace: "ns2"},
+			Spec: schedv1alpha1.ElasticQuotaSpec{
+				Min: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1Gi")},
+				Max: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2"), v1.ResourceMemory: resource.MustParse("2Gi")},
+			},
+		}
+		if err := c.Create(ctx, eq); err != nil {
+			t.Fatal(err)
+		}
+		eq2 := &schedv1alpha1.ElasticQuota{ObjectMeta: metav1.ObjectMeta{Name: "eq2", Namespace: "ns2"}}
+		if err := c.Create(ctx, eq2); !errors.IsInvalid(err) {
+			t.Errorf("Want an Invalid error, got %v", err)
+		}
+	})
+}
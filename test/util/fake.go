@@ -285,7 +285,8 @@ func NewFakeClient(objs ...runtime.Object) (client.Client, error) {
 	if err := topologyv1alpha2.AddToScheme(scheme); err != nil {
 		return nil, err
 	}
-	return fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build(), nil
+	return fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).
+		WithStatusSubresource(&v1alpha1.PodGroup{}, &v1alpha1.ElasticQuota{}).Build(), nil
 }
 
 // NewClientOrDie returns a generic controller-runtime client or panic upon any error.